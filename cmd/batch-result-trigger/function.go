@@ -1,6 +1,7 @@
 package trigger
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,15 +9,14 @@ import (
 	"log/slog"
 	"os"
 	"sync"
-	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/dllewellyn/reflex/internal/app/batch"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
 	"github.com/dllewellyn/reflex/internal/platform/schema"
-	"github.com/google/uuid"
 )
 
 func init() {
@@ -43,6 +43,20 @@ func (r *DefaultGCSReader) NewReader(ctx context.Context, bucket, name string) (
 	return r.client.Bucket(bucket).Object(name).NewReader(ctx)
 }
 
+// BlobStoreReader reads result files from a gcs.BlobReader chosen by URL rather than from the bucket
+// named in the event, so results can be replayed from a local directory or an S3-compatible store.
+type BlobStoreReader struct {
+	store gcs.BlobReader
+}
+
+func (r *BlobStoreReader) NewReader(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	data, err := r.store.Read(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
 // EventIngestor handles the core logic of processing files and publishing events.
 type EventIngestor struct {
 	gcs      GCSReader
@@ -57,12 +71,23 @@ var (
 
 func initializeClients(ctx context.Context) error {
 	once.Do(func() {
-		// Init GCS
-		var err error
-		gcsClient, err := storage.NewClient(ctx)
-		if err != nil {
-			initErr = err
-			return
+		// Init the result reader: RESULTS_STORE_URL (gs://, s3:// or file://) if set, otherwise
+		// the GCS bucket named in each event.
+		var reader GCSReader
+		if storeURL := os.Getenv("RESULTS_STORE_URL"); storeURL != "" {
+			store, err := gcs.OpenURL(ctx, storeURL)
+			if err != nil {
+				initErr = err
+				return
+			}
+			reader = &BlobStoreReader{store: store}
+		} else {
+			gcsClient, err := storage.NewClient(ctx)
+			if err != nil {
+				initErr = err
+				return
+			}
+			reader = &DefaultGCSReader{client: gcsClient}
 		}
 
 		// Init Kafka
@@ -97,7 +122,7 @@ func initializeClients(ctx context.Context) error {
 		producer := batch.NewBatchEventProducer(adapter, topic)
 
 		ingestor = &EventIngestor{
-			gcs:      &DefaultGCSReader{client: gcsClient},
+			gcs:      reader,
 			producer: producer,
 		}
 	})
@@ -164,39 +189,13 @@ func (i *EventIngestor) Process(ctx context.Context, data StorageObjectData) err
 	}
 	defer rc.Close()
 
-	streamReader := batch.NewStreamReader(rc)
-
-	count := 0
-	for {
-		var record schema.Record
-		err := streamReader.ReadNext(&record)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			slog.Error("Error reading stream", "error", err)
-			return err
-		}
-
-		// Create Event
-		evtID := uuid.New().String()
-		now := time.Now()
-
-		evt := schema.BatchResultEvent{
-			EventId:   evtID,
-			Timestamp: now,
-			Source: schema.Source{
-				Bucket: data.Bucket,
-				File:   data.Name,
-			},
-			Record: record,
-		}
-
-		if err := i.producer.Produce(ctx, evt); err != nil {
-			slog.Error("Failed to produce event", "error", err)
-			return err
-		}
-		count++
+	count, err := i.producer.PublishResults(ctx, rc, schema.Source{
+		Bucket: data.Bucket,
+		File:   data.Name,
+	})
+	if err != nil {
+		slog.Error("Failed to publish results", "error", err)
+		return err
 	}
 
 	slog.Info("Successfully processed file", "count", count)
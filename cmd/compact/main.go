@@ -0,0 +1,13 @@
+// Command compact merges the raw archive's small objects into larger ones.
+// It is "reflex compact" under the name the deployments use; see cmd/reflex for the configuration.
+package main
+
+import (
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(append([]string{"compact"}, os.Args[1:]...)))
+}
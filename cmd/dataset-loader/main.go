@@ -0,0 +1,13 @@
+// Command dataset-loader loads a HuggingFace dataset into the vector store.
+// It is "reflex dataset" under the name the deployments use; see cmd/reflex for the configuration.
+package main
+
+import (
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(append([]string{"dataset"}, os.Args[1:]...)))
+}
@@ -0,0 +1,28 @@
+// Command reflex runs every Reflex component from a single binary:
+//
+//	reflex serve      run the ingestor API
+//	reflex load       archive raw interactions from Kafka
+//	reflex judge      submit the daily batch analysis to Vertex AI
+//	reflex extract    extract injections from batch results into the vector store
+//	reflex eval       evaluate judge models against labelled prompts
+//	reflex dataset    load a HuggingFace dataset into the vector store
+//	reflex curate     search, inspect, delete, export and import the vector store's signatures
+//	reflex review     list, approve and reject extracted injections awaiting review
+//	reflex compact    merge the raw archive's small objects into larger ones
+//	reflex retention  sweep expired archive data, or erase a user's or conversation's data
+//	reflex dev        run the whole pipeline locally with in-memory backends
+//	reflex config     print the effective configuration
+//
+// Every subcommand reads the shared configuration (see internal/config) from -config or
+// $REFLEX_CONFIG, the environment and its own flags; run "reflex <command> -h" for the flags.
+package main
+
+import (
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
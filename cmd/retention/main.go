@@ -0,0 +1,13 @@
+// Command retention sweeps expired archive data, or erases a user's or conversation's data.
+// It is "reflex retention" under the name the deployments use; see cmd/reflex for the configuration.
+package main
+
+import (
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(append([]string{"retention"}, os.Args[1:]...)))
+}
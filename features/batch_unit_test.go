@@ -56,7 +56,7 @@ func TestBatchService_ProcessDailyConversations(t *testing.T) {
 		ModelID:       "test-model",
 	}, &batch.Prompt{
 		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
-	}, gcsClient, gcsClient, vertexClient, producer)
+	}, gcsClient, gcsClient, vertexClient, producer, nil, nil)
 	ctx := context.Background()
 
 	err := svc.Run(ctx, targetDate)
@@ -133,7 +133,7 @@ func TestBatchService_ConversationsSpanningFiles(t *testing.T) {
 		ModelID:       "test-model",
 	}, &batch.Prompt{
 		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
-	}, gcsClient, gcsClient, vertexClient, producer)
+	}, gcsClient, gcsClient, vertexClient, producer, nil, nil)
 	ctx := context.Background()
 
 	err := svc.Run(ctx, targetDate)
@@ -195,7 +195,7 @@ func TestBatchService_HighRiskAlert(t *testing.T) {
 		ModelID:       "test-model",
 	}, &batch.Prompt{
 		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
-	}, gcsClient, gcsClient, vertexClient, producer)
+	}, gcsClient, gcsClient, vertexClient, producer, nil, nil)
 	ctx := context.Background()
 
 	err := svc.Run(ctx, targetDate)
@@ -246,7 +246,7 @@ func TestBatchService_LowRiskNoAlert(t *testing.T) {
 		ModelID:       "test-model",
 	}, &batch.Prompt{
 		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
-	}, gcsClient, gcsClient, vertexClient, producer)
+	}, gcsClient, gcsClient, vertexClient, producer, nil, nil)
 	ctx := context.Background()
 
 	err := svc.Run(ctx, targetDate)
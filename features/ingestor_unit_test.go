@@ -14,27 +14,6 @@ import (
 	"github.com/dllewellyn/reflex/internal/platform/schema"
 )
 
-type MockIngestorPinecone struct{}
-
-func (m *MockIngestorPinecone) UpsertBatch(ctx context.Context, vectors []*pinecone.Vector) error {
-	return nil
-}
-func (m *MockIngestorPinecone) UpsertInputs(ctx context.Context, inputs []*pinecone.InputRecord) error {
-	return nil
-}
-func (m *MockIngestorPinecone) QueryInput(ctx context.Context, text string, topK int) ([]*pinecone.Match, error) {
-	return nil, nil
-}
-func (m *MockIngestorPinecone) Fetch(ctx context.Context, ids []string) (map[string]*pinecone.Vector, error) {
-	return nil, nil
-}
-func (m *MockIngestorPinecone) DescribeIndexStats(ctx context.Context) (*pinecone.IndexStats, error) {
-	return &pinecone.IndexStats{}, nil
-}
-func (m *MockIngestorPinecone) DeleteAll(ctx context.Context) error {
-	return nil
-}
-
 // TestIngestorService_ValidInteraction tests the scenario:
 // "Successfully ingest a valid interaction"
 func TestIngestorService_ValidInteraction(t *testing.T) {
@@ -46,8 +25,7 @@ func TestIngestorService_ValidInteraction(t *testing.T) {
 	testTopic := getTestTopic()
 	infra.StartConsumer(t, testTopic)
 
-	mockPinecone := &MockIngestorPinecone{}
-	svc := ingestor.NewService(infra.GetProducer(), mockPinecone, ingestor.Config{
+	svc := ingestor.NewService(infra.GetProducer(), pinecone.NewMemoryStore(nil), nil, ingestor.Config{
 		TopicName: testTopic,
 		Port:      "8080",
 	})
@@ -92,7 +70,7 @@ func TestIngestorService_InvalidJSON(t *testing.T) {
 	testTopic := getTestTopic()
 	infra.StartConsumer(t, testTopic)
 
-	svc := ingestor.NewService(infra.GetProducer(), &MockIngestorPinecone{}, ingestor.Config{
+	svc := ingestor.NewService(infra.GetProducer(), pinecone.NewMemoryStore(nil), nil, ingestor.Config{
 		TopicName: testTopic,
 		Port:      "8080",
 	})
@@ -120,7 +98,7 @@ func TestIngestorService_MissingRequiredFields(t *testing.T) {
 	infra := setupTest(t)
 	defer infra.Close()
 
-	svc := ingestor.NewService(infra.GetProducer(), &MockIngestorPinecone{}, ingestor.Config{
+	svc := ingestor.NewService(infra.GetProducer(), pinecone.NewMemoryStore(nil), nil, ingestor.Config{
 		TopicName: getTestTopic(),
 		Port:      "8080",
 	})
@@ -151,7 +129,7 @@ func TestIngestorService_KafkaUnavailable(t *testing.T) {
 	// Given the Ingestor service is running
 	// And the Kafka cluster is unreachable
 	failingProducer := &FailingProducer{}
-	svc := ingestor.NewService(failingProducer, &MockIngestorPinecone{}, ingestor.Config{
+	svc := ingestor.NewService(failingProducer, pinecone.NewMemoryStore(nil), nil, ingestor.Config{
 		TopicName: getTestTopic(),
 		Port:      "8080",
 	})
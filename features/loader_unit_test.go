@@ -34,7 +34,7 @@ func TestLoaderService_ArchiveMessagesToGCS(t *testing.T) {
 	infra.SeedKafka(t, testTopic, events)
 
 	// And the GCS bucket "security-data-lake" is accessible
-	svc := loader.NewService(infra.GetConsumer(), infra.GetGCSWriter(), loader.Config{Topic: testTopic})
+	svc := loader.NewService(infra.GetConsumer(), infra.GetGCSWriter(), nil, nil, loader.Config{Topic: testTopic})
 
 	// When the Loader job is triggered
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Increased timeout for real infra
@@ -59,7 +59,7 @@ func TestLoaderService_HandleZeroMessages(t *testing.T) {
 	infra := setupLoaderTest(t)
 	defer infra.Close()
 
-	svc := loader.NewService(infra.GetConsumer(), infra.GetGCSWriter(), loader.Config{Topic: getTestTopic()})
+	svc := loader.NewService(infra.GetConsumer(), infra.GetGCSWriter(), nil, nil, loader.Config{Topic: getTestTopic()})
 
 	// When the Loader job is triggered
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -98,7 +98,7 @@ func TestLoaderService_GCSFailure(t *testing.T) {
 	mockConsumer.Seed(testTopic, events)
 
 	// And the GCS service is down
-	svc := loader.NewService(mockConsumer, failingGCS, loader.Config{Topic: testTopic})
+	svc := loader.NewService(mockConsumer, failingGCS, nil, nil, loader.Config{Topic: testTopic})
 
 	// When the Loader job is triggered
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
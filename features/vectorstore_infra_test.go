@@ -0,0 +1,302 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/qdrant"
+	"github.com/google/uuid"
+)
+
+// setupVectorStores returns every vector store implementation to run the same tests against. The
+// Qdrant store talks to a local stand-in for the Qdrant REST API unless TEST_QDRANT_URL points at
+// a real instance, e.g. `docker run -p 6333:6333 qdrant/qdrant`.
+func setupVectorStores(t *testing.T) map[string]pinecone.VectorStore {
+	embedder := pinecone.NewNGramEmbedder(3, 256)
+
+	url := os.Getenv("TEST_QDRANT_URL")
+	if url == "" {
+		server := httptest.NewServer(newFakeQdrant())
+		t.Cleanup(server.Close)
+		url = server.URL
+	}
+	collection := "reflex-test-" + uuid.NewString()
+	qdrantStore, err := qdrant.NewClient(context.Background(), http.DefaultClient, embedder, qdrant.Config{
+		URL:        url,
+		Collection: collection,
+	})
+	if err != nil {
+		t.Fatalf("failed to create qdrant store: %v", err)
+	}
+	t.Cleanup(func() {
+		req, _ := http.NewRequest(http.MethodDelete, url+"/collections/"+collection, nil)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	})
+
+	return map[string]pinecone.VectorStore{
+		"memory": pinecone.NewMemoryStore(embedder),
+		"qdrant": qdrantStore,
+	}
+}
+
+// fakeQdrant implements the part of the Qdrant REST API the qdrant package uses, with exact
+// cosine search over points held in memory.
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]map[string]fakePoint
+}
+
+type fakePoint struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+	Score   float32                `json:"score,omitempty"`
+}
+
+func newFakeQdrant() *fakeQdrant {
+	return &fakeQdrant{collections: make(map[string]map[string]fakePoint)}
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/collections/"), "/")
+	points, exists := f.collections[name]
+	if !exists && !(action == "" && r.Method == http.MethodPut) {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": map[string]string{"error": "Collection `" + name + "` doesn't exist!"}})
+		return
+	}
+
+	var body struct {
+		Points  json.RawMessage        `json:"points"`
+		IDs     []string               `json:"ids"`
+		Vector  []float32              `json:"vector"`
+		Limit   int                    `json:"limit"`
+		Offset  string                 `json:"offset"`
+		Filter  map[string]interface{} `json:"filter"`
+		Payload map[string]interface{} `json:"payload"`
+	}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	var result interface{} = true
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		result = map[string]interface{}{"points_count": len(points)}
+	case action == "" && r.Method == http.MethodPut:
+		f.collections[name] = make(map[string]fakePoint)
+	case action == "" && r.Method == http.MethodDelete:
+		delete(f.collections, name)
+	case action == "points" && r.Method == http.MethodPut:
+		var upserts []fakePoint
+		_ = json.Unmarshal(body.Points, &upserts)
+		for _, p := range upserts {
+			p.Vector = unit(p.Vector)
+			points[p.ID] = p
+		}
+	case action == "points/delete":
+		var ids []string
+		_ = json.Unmarshal(body.Points, &ids)
+		for _, id := range ids {
+			delete(points, id)
+		}
+		for id, p := range points {
+			if body.Filter != nil && matchesQdrantFilter(body.Filter, p.Payload) {
+				delete(points, id)
+			}
+		}
+	case action == "points/payload":
+		var ids []string
+		_ = json.Unmarshal(body.Points, &ids)
+		for _, id := range ids {
+			p, ok := points[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": map[string]string{"error": "No point with id " + id + " found"}})
+				return
+			}
+			payload := make(map[string]interface{}, len(p.Payload)+len(body.Payload))
+			for k, v := range p.Payload {
+				payload[k] = v
+			}
+			for k, v := range body.Payload {
+				payload[k] = v
+			}
+			p.Payload = payload
+			points[id] = p
+		}
+	case action == "points/scroll":
+		var ids []string
+		for id, p := range points {
+			if id >= body.Offset && matchesQdrantFilter(body.Filter, p.Payload) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		page := map[string]interface{}{"points": []fakePoint{}, "next_page_offset": nil}
+		if len(ids) > body.Limit {
+			page["next_page_offset"] = ids[body.Limit]
+			ids = ids[:body.Limit]
+		}
+		found := []fakePoint{}
+		for _, id := range ids {
+			found = append(found, fakePoint{ID: id, Payload: points[id].Payload})
+		}
+		page["points"] = found
+		result = page
+	case action == "points" && r.Method == http.MethodPost:
+		found := []fakePoint{}
+		for _, id := range body.IDs {
+			if p, ok := points[id]; ok {
+				found = append(found, p)
+			}
+		}
+		result = found
+	case action == "points/search":
+		query := unit(body.Vector)
+		hits := []fakePoint{}
+		for _, p := range points {
+			if !matchesQdrantFilter(body.Filter, p.Payload) {
+				continue
+			}
+			var score float32
+			for i := range query {
+				score += query[i] * p.Vector[i]
+			}
+			hits = append(hits, fakePoint{ID: p.ID, Payload: p.Payload, Score: score})
+		}
+		sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		if len(hits) > body.Limit {
+			hits = hits[:body.Limit]
+		}
+		result = hits
+	case action == "points/count":
+		count := 0
+		for _, p := range points {
+			if matchesQdrantFilter(body.Filter, p.Payload) {
+				count++
+			}
+		}
+		result = map[string]int{"count": count}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "status": "ok"})
+}
+
+// matchesQdrantFilter evaluates the subset of Qdrant's filter language the qdrant package
+// produces: must, should and must_not clauses of nested filters and match, range and is_empty
+// conditions.
+func matchesQdrantFilter(filter map[string]interface{}, payload map[string]interface{}) bool {
+	if filter == nil {
+		return true
+	}
+	clauses := func(name string) []interface{} {
+		c, _ := filter[name].([]interface{})
+		return c
+	}
+	for _, c := range clauses("must") {
+		if !matchesQdrantCondition(c.(map[string]interface{}), payload) {
+			return false
+		}
+	}
+	for _, c := range clauses("must_not") {
+		if matchesQdrantCondition(c.(map[string]interface{}), payload) {
+			return false
+		}
+	}
+	should := clauses("should")
+	for _, c := range should {
+		if matchesQdrantCondition(c.(map[string]interface{}), payload) {
+			return true
+		}
+	}
+	return len(should) == 0
+}
+
+func matchesQdrantCondition(cond map[string]interface{}, payload map[string]interface{}) bool {
+	if _, nested := cond["key"]; !nested {
+		if empty, ok := cond["is_empty"].(map[string]interface{}); ok {
+			v, present := payload[empty["key"].(string)]
+			list, isList := v.([]interface{})
+			return !present || v == nil || (isList && len(list) == 0)
+		}
+		return matchesQdrantFilter(cond, payload)
+	}
+	value, present := payload[cond["key"].(string)]
+	if !present {
+		return false
+	}
+	values := []interface{}{value}
+	if list, ok := value.([]interface{}); ok {
+		values = list
+	}
+	for _, v := range values {
+		if match, ok := cond["match"].(map[string]interface{}); ok {
+			if want, ok := match["value"]; ok && v == want {
+				return true
+			}
+			if anyOf, ok := match["any"].([]interface{}); ok {
+				for _, want := range anyOf {
+					if v == want {
+						return true
+					}
+				}
+			}
+			continue
+		}
+		n, ok := v.(float64)
+		if !ok {
+			continue
+		}
+		inRange := true
+		for op, bound := range cond["range"].(map[string]interface{}) {
+			b := bound.(float64)
+			switch op {
+			case "gt":
+				inRange = inRange && n > b
+			case "gte":
+				inRange = inRange && n >= b
+			case "lt":
+				inRange = inRange && n < b
+			case "lte":
+				inRange = inRange && n <= b
+			}
+		}
+		if inRange {
+			return true
+		}
+	}
+	return false
+}
+
+func unit(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+	norm := float32(math.Sqrt(sum))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/state"
+)
+
+// Checkpoint records a day's progress, stored under CheckpointName, so that a rerun after a
+// failure resumes rather than starting over. Staged sessions each get a StagedMarker instead,
+// so the checkpoint stays small however many sessions a day has.
+type Checkpoint struct {
+	// JobName is the Vertex AI batch prediction job submitted for the day, once there is one.
+	JobName        string    `json:"job_name,omitempty"`
+	JobSubmittedAt time.Time `json:"job_submitted_at,omitempty"`
+}
+
+// CheckpointName is the name of the batch analyzer's checkpoint for date.
+func CheckpointName(date time.Time) string {
+	return "batch/" + date.Format("2006-01-02")
+}
+
+// StagedMarker records that a session's batch request has been uploaded to staging. It is
+// stored under StagedName.
+type StagedMarker struct {
+	StagedAt time.Time `json:"staged_at"`
+}
+
+// StagedName is the name of the marker recording that sessionID was staged on date.
+func StagedName(date time.Time, sessionID string) string {
+	return CheckpointName(date) + "/staged/" + sessionID
+}
+
+// loadCheckpoint returns the day's checkpoint, or an empty one if there is none or no store.
+func (s *Service) loadCheckpoint(ctx context.Context, date time.Time) (*Checkpoint, error) {
+	cp := &Checkpoint{}
+	if s.checkpoints == nil {
+		return cp, nil
+	}
+	if _, err := s.checkpoints.Get(ctx, CheckpointName(date), cp); err != nil && !errors.Is(err, state.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// isStaged reports whether a previous run staged sessionID. Without a store, or if its marker
+// cannot be read, the session is staged again.
+func (s *Service) isStaged(ctx context.Context, date time.Time, sessionID string) bool {
+	if s.checkpoints == nil {
+		return false
+	}
+	var marker StagedMarker
+	_, err := s.checkpoints.Get(ctx, StagedName(date, sessionID), &marker)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		slog.Warn("Failed to read staged marker", "checkpoint", StagedName(date, sessionID), "error", err)
+	}
+	return err == nil
+}
+
+// markStaged records that sessionID has been staged. Like updateCheckpoint, a failure is
+// logged rather than returned.
+func (s *Service) markStaged(ctx context.Context, date time.Time, sessionID string) {
+	if s.checkpoints == nil {
+		return
+	}
+	if _, err := s.checkpoints.Set(ctx, StagedName(date, sessionID), StagedMarker{StagedAt: time.Now().UTC()}); err != nil {
+		slog.Error("Failed to record checkpoint", "checkpoint", StagedName(date, sessionID), "error", err)
+	}
+}
+
+// markSubmitted records the day's batch prediction job.
+func (s *Service) markSubmitted(ctx context.Context, date time.Time, jobName string) {
+	s.updateCheckpoint(ctx, date, func(cp *Checkpoint) error {
+		cp.JobName = jobName
+		cp.JobSubmittedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// updateCheckpoint applies fn to the day's checkpoint. The staged files and submitted job are
+// the real output, so a failure here is logged; the cost is repeating work on a rerun.
+func (s *Service) updateCheckpoint(ctx context.Context, date time.Time, fn func(*Checkpoint) error) {
+	if s.checkpoints == nil {
+		return
+	}
+	if _, err := state.Update(ctx, s.checkpoints, CheckpointName(date), fn); err != nil {
+		slog.Error("Failed to record checkpoint", "checkpoint", CheckpointName(date), "error", err)
+	}
+}
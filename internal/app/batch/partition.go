@@ -0,0 +1,116 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// hourlyIndex resolves conversations archived with archive.LayoutHourly using the per-hour
+// manifests, so a day is discovered with one listing instead of probing every session prefix.
+type hourlyIndex struct {
+	reader gcs.BlobReader
+	// open decrypts events, dropping those it cannot; nil if the archive is not encrypted.
+	open func(context.Context, []schema.InteractionEvent) ([]schema.InteractionEvent, error)
+	// files maps a conversation ID to the data files that contain its events.
+	files map[string][]archive.ManifestFile
+	// loaded holds decoded events, grouped by conversation, for files already read.
+	loaded  map[string]bool
+	pending map[string][]schema.InteractionEvent
+}
+
+// loadHourlyIndex reads every hourly manifest for the given date.
+func loadHourlyIndex(ctx context.Context, reader gcs.BlobReader, open func(context.Context, []schema.InteractionEvent) ([]schema.InteractionEvent, error), date time.Time) (*hourlyIndex, error) {
+	keys, err := reader.ListFiles(ctx, archive.DayPrefix(date))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	idx := &hourlyIndex{
+		reader:  reader,
+		open:    open,
+		files:   make(map[string][]archive.ManifestFile),
+		loaded:  make(map[string]bool),
+		pending: make(map[string][]schema.InteractionEvent),
+	}
+	for _, key := range keys {
+		if !archive.IsManifestKey(key) {
+			continue
+		}
+		data, err := reader.Read(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", key, err)
+		}
+		manifest, err := archive.ParseManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", key, err)
+		}
+		for _, file := range manifest.Files {
+			for _, conversationID := range file.Conversations {
+				idx.files[conversationID] = append(idx.files[conversationID], file)
+			}
+		}
+	}
+	return idx, nil
+}
+
+// Sessions returns the conversation IDs listed in the day's manifests, sorted.
+func (idx *hourlyIndex) Sessions() []string {
+	sessions := make([]string, 0, len(idx.files))
+	for id := range idx.files {
+		sessions = append(sessions, id)
+	}
+	sort.Strings(sessions)
+	return sessions
+}
+
+// Transcript returns the conversation's events as JSONL, ordered by timestamp.
+// Each data file is read at most once; events are released once their transcript is built.
+func (idx *hourlyIndex) Transcript(ctx context.Context, sessionID string) (string, error) {
+	for _, file := range idx.files[sessionID] {
+		if idx.loaded[file.Key] {
+			continue
+		}
+		data, err := idx.reader.Read(ctx, file.Key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read partition file %s: %w", file.Key, err)
+		}
+		events, err := archive.Decode(file.Format, data)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode partition file %s: %w", file.Key, err)
+		}
+		if idx.open != nil {
+			if events, err = idx.open(ctx, events); err != nil {
+				return "", fmt.Errorf("failed to decrypt partition file %s: %w", file.Key, err)
+			}
+		}
+		for _, event := range events {
+			idx.pending[event.ConversationId] = append(idx.pending[event.ConversationId], event)
+		}
+		idx.loaded[file.Key] = true
+	}
+
+	events := idx.pending[sessionID]
+	delete(idx.pending, sessionID)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	var transcriptBuilder strings.Builder
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return "", fmt.Errorf("marshal error: %w", err)
+		}
+		transcriptBuilder.Write(line)
+		transcriptBuilder.WriteString("\n")
+	}
+	return transcriptBuilder.String(), nil
+}
@@ -2,8 +2,11 @@ package batch
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/google/uuid"
 )
 
 // EventPublisher defines the interface for publishing events.
@@ -31,3 +34,32 @@ func (p *BatchEventProducer) Produce(ctx context.Context, event schema.BatchResu
 	key := event.EventId
 	return p.publisher.Publish(ctx, p.topic, key, event)
 }
+
+// PublishResults reads the records of a batch prediction output file from r and produces a
+// BatchResultEvent for each, attributed to source. It returns the number of events produced.
+func (p *BatchEventProducer) PublishResults(ctx context.Context, r io.Reader, source schema.Source) (int, error) {
+	streamReader := NewStreamReader(r)
+
+	count := 0
+	for {
+		var record schema.Record
+		err := streamReader.ReadNext(&record)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		evt := schema.BatchResultEvent{
+			EventId:   uuid.New().String(),
+			Timestamp: time.Now(),
+			Source:    source,
+			Record:    record,
+		}
+		if err := p.Produce(ctx, evt); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
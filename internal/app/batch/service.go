@@ -1,15 +1,24 @@
 package batch
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
 	"github.com/dllewellyn/reflex/internal/platform/gcs"
 	"github.com/dllewellyn/reflex/internal/platform/kafka"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/state"
 	"github.com/dllewellyn/reflex/internal/platform/vertex"
 )
 
@@ -19,6 +28,13 @@ type Config struct {
 	StagingBucket string
 	OutputBucket  string
 	ModelID       string
+	// Layout is the raw archive layout written by the loader. Defaults to archive.LayoutSession.
+	Layout archive.Layout
+}
+
+// Opener decrypts event content the loader encrypted; see envelope.Opener.
+type Opener interface {
+	OpenEvents(ctx context.Context, events []schema.InteractionEvent) error
 }
 
 type Service struct {
@@ -28,9 +44,13 @@ type Service struct {
 	gcsWriter    gcs.BlobWriter
 	vertexClient vertex.JobClient
 	producer     kafka.Producer
+	opener       Opener
+	checkpoints  state.Store
 }
 
-func NewService(cfg Config, prompt *Prompt, gcsReader gcs.BlobReader, gcsWriter gcs.BlobWriter, vertexClient vertex.JobClient, producer kafka.Producer) *Service {
+// NewService creates the batch service. opener may be nil if the raw archive is not encrypted,
+// and checkpoints may be nil to process every session of a day on each run.
+func NewService(cfg Config, prompt *Prompt, gcsReader gcs.BlobReader, gcsWriter gcs.BlobWriter, vertexClient vertex.JobClient, producer kafka.Producer, opener Opener, checkpoints state.Store) *Service {
 	return &Service{
 		config:       cfg,
 		prompt:       prompt,
@@ -38,28 +58,66 @@ func NewService(cfg Config, prompt *Prompt, gcsReader gcs.BlobReader, gcsWriter
 		gcsWriter:    gcsWriter,
 		vertexClient: vertexClient,
 		producer:     producer,
+		opener:       opener,
+		checkpoints:  checkpoints,
 	}
 }
 
 func (s *Service) Run(ctx context.Context, targetDate time.Time) error {
 	slog.Info("Starting Daily Batch Analyzer", "date", targetDate)
 
-	// 1. List active sessions for the date
-	sessions, err := s.gcsReader.ListActiveSessions(ctx, targetDate)
+	checkpoint, err := s.loadCheckpoint(ctx, targetDate)
 	if err != nil {
 		return err
 	}
-	slog.Info("Found active sessions", "count", len(sessions))
+	if checkpoint.JobName != "" {
+		slog.Info("Batch job already submitted for date, nothing to do", "date", targetDate, "job_name", checkpoint.JobName)
+		return nil
+	}
+
+	// 1. List active sessions for the date
+	var sessions []string
+	transcriptFor := s.reconstructTranscript
+	if s.config.Layout == archive.LayoutHourly {
+		var open func(context.Context, []schema.InteractionEvent) ([]schema.InteractionEvent, error)
+		if s.opener != nil {
+			open = s.openEvents
+		}
+		idx, err := loadHourlyIndex(ctx, s.gcsReader, open, targetDate)
+		if err != nil {
+			return err
+		}
+		sessions = idx.Sessions()
+		transcriptFor = idx.Transcript
+	} else {
+		var err error
+		sessions, err = s.gcsReader.ListActiveSessions(ctx, targetDate)
+		if err != nil {
+			return err
+		}
+	}
+	slog.Info("Found active sessions", "count", len(sessions), "layout", s.config.Layout)
 
 	// 2. For each session, read full history (scaffold logic)
 	processedCount := 0
 	transformer := NewTransformer(s.prompt)
 
 	for _, sessionID := range sessions {
-		if err := s.processSession(ctx, sessionID, targetDate, transformer); err != nil {
+		if s.isStaged(ctx, targetDate, sessionID) {
+			slog.Debug("Session already staged, skipping", "session_id", sessionID)
+			processedCount++
+			continue
+		}
+		transcript, err := transcriptFor(ctx, sessionID)
+		if err != nil {
 			slog.Error("Failed to process session", "session_id", sessionID, "error", err)
 			continue
 		}
+		if err := s.processSession(ctx, sessionID, transcript, targetDate, transformer); err != nil {
+			slog.Error("Failed to process session", "session_id", sessionID, "error", err)
+			continue
+		}
+		s.markStaged(ctx, targetDate, sessionID)
 		processedCount++
 	}
 
@@ -69,16 +127,17 @@ func (s *Service) Run(ctx context.Context, targetDate time.Time) error {
 	}
 
 	// 4. Trigger Batch Job
-	return s.triggerBatchJob(ctx, targetDate, processedCount)
-}
-
-// processSession handles the end-to-end processing of a single session.
-func (s *Service) processSession(ctx context.Context, sessionID string, date time.Time, transformer *Transformer) error {
-	// 1. Reconstruct Transcript
-	transcript, err := s.reconstructTranscript(ctx, sessionID)
+	jobName, err := s.triggerBatchJob(ctx, targetDate, processedCount)
 	if err != nil {
 		return err
 	}
+	s.markSubmitted(ctx, targetDate, jobName)
+	return nil
+}
+
+// processSession handles the end-to-end processing of a single session's transcript.
+func (s *Service) processSession(ctx context.Context, sessionID, transcript string, date time.Time, transformer *Transformer) error {
+	// 1. Skip sessions without content
 	if transcript == "" {
 		return nil // Skip empty sessions
 	}
@@ -100,19 +159,107 @@ func (s *Service) reconstructTranscript(ctx context.Context, sessionID string) (
 		return "", fmt.Errorf("failed to list chunks: %w", err)
 	}
 
+	// Honour compactions: only read chunks the session manifest marks as live.
+	manifest := &archive.SessionManifest{ConversationID: sessionID}
+	manifestKey := archive.SessionManifestKey(sessionID)
+	data, err := s.gcsReader.Read(ctx, manifestKey)
+	switch {
+	case err == nil:
+		if manifest, err = archive.ParseSessionManifest(data); err != nil {
+			return "", fmt.Errorf("failed to read manifest %s: %w", manifestKey, err)
+		}
+	case !errors.Is(err, gcs.ErrNotExist):
+		return "", fmt.Errorf("failed to read manifest %s: %w", manifestKey, err)
+	}
+	chunks = manifest.Live(chunks)
+	sort.Strings(chunks)
+
 	var transcriptBuilder strings.Builder
 	for _, chunkKey := range chunks {
-		data, err := s.gcsReader.Read(ctx, chunkKey)
+		rc, err := gcs.Open(ctx, s.gcsReader, chunkKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to read chunk %s: %w", chunkKey, err)
+		}
+		if s.opener != nil {
+			err = s.copyDecrypted(ctx, &transcriptBuilder, rc)
+		} else {
+			_, err = io.Copy(&transcriptBuilder, rc)
+		}
+		_ = rc.Close()
 		if err != nil {
 			return "", fmt.Errorf("failed to read chunk %s: %w", chunkKey, err)
 		}
-		transcriptBuilder.Write(data)
 		transcriptBuilder.WriteString("\n")
 	}
 
 	return transcriptBuilder.String(), nil
 }
 
+// maxLineSize bounds a single archived event when chunks are decrypted line by line.
+const maxLineSize = 16 << 20
+
+// copyDecrypted copies a JSONL chunk to w, decrypting lines whose event is encrypted. Other
+// lines are copied untouched; lines that cannot be decrypted are dropped.
+func (s *Service) copyDecrypted(ctx context.Context, w io.Writer, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	first := true
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// Only events carrying the encryption field need decoding; anything else is copied as is.
+		if bytes.Contains(line, []byte(`"encryption"`)) {
+			var event schema.InteractionEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				slog.Warn("Skipping event that could not be decoded", "error", err)
+				continue
+			}
+			if event.Encryption != nil {
+				events, err := s.openEvents(ctx, []schema.InteractionEvent{event})
+				if err != nil {
+					return err
+				}
+				if len(events) == 0 {
+					continue
+				}
+				decrypted, err := json.Marshal(events[0])
+				if err != nil {
+					return fmt.Errorf("marshal error: %w", err)
+				}
+				line = decrypted
+			}
+		}
+		if !first {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		first = false
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// openEvents decrypts events one at a time, dropping any that cannot be decrypted so that a
+// single corrupt event does not keep the rest of its conversation from being judged. It only
+// fails if ctx is done.
+func (s *Service) openEvents(ctx context.Context, events []schema.InteractionEvent) ([]schema.InteractionEvent, error) {
+	opened := events[:0]
+	for _, event := range events {
+		one := []schema.InteractionEvent{event}
+		if err := s.opener.OpenEvents(ctx, one); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			slog.Warn("Skipping event that could not be decrypted", "interaction_id", event.InteractionId, "conversation_id", event.ConversationId, "error", err)
+			continue
+		}
+		opened = append(opened, one[0])
+	}
+	return opened, nil
+}
+
 // uploadToStaging writes the batch input file to the staging bucket.
 func (s *Service) uploadToStaging(ctx context.Context, sessionID string, date time.Time, data []byte) error {
 	stagingPath := fmt.Sprintf("staging/%s/%s.jsonl", date.Format("2006/01/02"), sessionID)
@@ -123,7 +270,7 @@ func (s *Service) uploadToStaging(ctx context.Context, sessionID string, date ti
 }
 
 // triggerBatchJob submits the job to Vertex AI.
-func (s *Service) triggerBatchJob(ctx context.Context, date time.Time, sessionCount int) error {
+func (s *Service) triggerBatchJob(ctx context.Context, date time.Time, sessionCount int) (string, error) {
 	// Use wildcard to include all session files for this date
 	inputURI := fmt.Sprintf("gs://%s/staging/%s/*.jsonl", s.config.StagingBucket, date.Format("2006/01/02"))
 	outputURI := fmt.Sprintf("gs://%s/results/%s/", s.config.OutputBucket, date.Format("2006/01/02"))
@@ -155,9 +302,9 @@ func (s *Service) triggerBatchJob(ctx context.Context, date time.Time, sessionCo
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create batch job: %w", err)
+		return "", fmt.Errorf("failed to create batch job: %w", err)
 	}
 
 	slog.Info("Batch Job Triggered Successfully", "job_name", resp.Name, "state", resp.State)
-	return nil
+	return resp.Name, nil
 }
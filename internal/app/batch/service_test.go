@@ -2,11 +2,17 @@ package batch_test
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/dllewellyn/reflex/internal/app/batch"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/envelope"
 	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/state"
 	"github.com/dllewellyn/reflex/internal/platform/vertex"
 )
 
@@ -31,7 +37,7 @@ func TestService_Run(t *testing.T) {
 		ModelID:       "test-model",
 	}, &batch.Prompt{
 		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
-	}, gcsClient, gcsClient, vertexClient, producer)
+	}, gcsClient, gcsClient, vertexClient, producer, nil, nil)
 
 	// Seed GCS with some data for "yesterday"
 	yesterday := time.Now().AddDate(0, 0, -1)
@@ -48,3 +54,264 @@ func TestService_Run(t *testing.T) {
 		t.Errorf("Run() error = %v", err)
 	}
 }
+
+func TestService_Run_HourlyLayout(t *testing.T) {
+	gcsClient := gcs.NewMemoryClient()
+	vertexClient := vertex.NewMemoryClient()
+	ctx := context.Background()
+
+	targetDate := time.Date(2025, 12, 12, 0, 0, 0, 0, time.UTC)
+	hour := targetDate.Add(10 * time.Hour)
+	events := []schema.InteractionEvent{
+		{InteractionId: "int-1", ConversationId: "session-123", Timestamp: hour, Role: "user", Content: "first"},
+		{InteractionId: "int-2", ConversationId: "session-456", Timestamp: hour, Role: "user", Content: "other"},
+	}
+	data, err := archive.Encode(archive.FormatJSONLGzip, events)
+	if err != nil {
+		t.Fatalf("failed to encode partition: %v", err)
+	}
+	key := archive.DataKey(hour, "run-1", archive.FormatJSONLGzip)
+	if err := gcsClient.Write(ctx, key, data); err != nil {
+		t.Fatalf("failed to seed gcs: %v", err)
+	}
+	manifest := archive.NewManifest(hour)
+	manifest.Add(archive.ManifestFile{
+		Key:           key,
+		Format:        archive.FormatJSONLGzip,
+		Conversations: []string{"session-123", "session-456"},
+		EventCount:    len(events),
+	})
+	manifestData, err := manifest.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal manifest: %v", err)
+	}
+	if err := gcsClient.Write(ctx, archive.ManifestKey(hour), manifestData); err != nil {
+		t.Fatalf("failed to seed manifest: %v", err)
+	}
+
+	svc := batch.NewService(batch.Config{
+		ProjectID:     "test-project",
+		Location:      "us-central1",
+		StagingBucket: "staging-bucket",
+		OutputBucket:  "output-bucket",
+		ModelID:       "test-model",
+		Layout:        archive.LayoutHourly,
+	}, &batch.Prompt{
+		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
+	}, gcsClient, gcsClient, vertexClient, &MockProducer{}, nil, nil)
+
+	if err := svc.Run(ctx, targetDate); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	staged, err := gcsClient.ListFiles(ctx, "staging/2025/12/12/")
+	if err != nil {
+		t.Fatalf("failed to list staging files: %v", err)
+	}
+	if len(staged) != 2 {
+		t.Fatalf("expected 2 staged sessions, got %v", staged)
+	}
+	request, err := gcsClient.Read(ctx, "staging/2025/12/12/session-123.jsonl")
+	if err != nil {
+		t.Fatalf("expected staging file for session-123: %v", err)
+	}
+	if !strings.Contains(string(request), "first") || strings.Contains(string(request), "other") {
+		t.Errorf("expected transcript to contain only session-123 events, got %s", request)
+	}
+	if len(vertexClient.GetCreatedJobs()) != 1 {
+		t.Errorf("expected 1 batch job, got %d", len(vertexClient.GetCreatedJobs()))
+	}
+}
+
+func TestService_Run_FileStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := gcs.OpenURL(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open file store: %v", err)
+	}
+	defer store.Close()
+
+	targetDate := time.Date(2025, 12, 12, 10, 0, 0, 0, time.Local)
+	key := "raw/session-123/" + targetDate.Format("2006/01/02/15") + "/chunk-1.jsonl"
+	if err := store.Write(ctx, key, []byte(`{"interaction_id":"1","content":"from disk"}`+"\n")); err != nil {
+		t.Fatalf("failed to seed file store: %v", err)
+	}
+
+	vertexClient := vertex.NewMemoryClient()
+	svc := batch.NewService(batch.Config{
+		ProjectID:     "test-project",
+		Location:      "us-central1",
+		StagingBucket: "staging-bucket",
+		OutputBucket:  "output-bucket",
+		ModelID:       "test-model",
+	}, &batch.Prompt{
+		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
+	}, store, store, vertexClient, &MockProducer{}, nil, nil)
+
+	if err := svc.Run(ctx, targetDate); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	request, err := store.Read(ctx, "staging/2025/12/12/session-123.jsonl")
+	if err != nil {
+		t.Fatalf("expected staging file for session-123: %v", err)
+	}
+	if !strings.Contains(string(request), "from disk") {
+		t.Errorf("expected transcript read from disk, got %s", request)
+	}
+	if len(vertexClient.GetCreatedJobs()) != 1 {
+		t.Errorf("expected 1 batch job, got %d", len(vertexClient.GetCreatedJobs()))
+	}
+}
+
+func TestService_Run_Encrypted(t *testing.T) {
+	ctx := context.Background()
+	provider, err := envelope.NewFileKeyProvider(map[string][]byte{"dev": []byte(strings.Repeat("k", 32))})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	sealer, err := envelope.NewSealer(provider, envelope.Config{DefaultKeyID: "dev"})
+	if err != nil {
+		t.Fatalf("failed to create sealer: %v", err)
+	}
+
+	targetDate := time.Date(2025, 12, 12, 0, 0, 0, 0, time.UTC)
+	hour := targetDate.Add(10 * time.Hour)
+	sealed, err := sealer.SealEvents(ctx, []schema.InteractionEvent{
+		{InteractionId: "int-1", ConversationId: "session-123", Timestamp: hour, Role: "user", Content: "secret plans"},
+		{InteractionId: "int-2", ConversationId: "session-123", Timestamp: hour.Add(time.Second), Role: "user", Content: envelope.Prefix + "typed by the user"},
+		{InteractionId: "int-3", ConversationId: "session-123", Timestamp: hour.Add(2 * time.Second), Role: "user", Content: "tampered with"},
+	})
+	if err != nil {
+		t.Fatalf("failed to seal events: %v", err)
+	}
+	// An event that no longer decrypts is dropped without losing the rest of the session.
+	sealed[2].Content = sealed[2].Content[:len(sealed[2].Content)-4] + "AAAA"
+
+	for _, layout := range []archive.Layout{archive.LayoutSession, archive.LayoutHourly} {
+		t.Run(string(layout), func(t *testing.T) {
+			store := gcs.NewMemoryClient()
+			if layout == archive.LayoutHourly {
+				data, err := archive.Encode(archive.FormatJSONLGzip, sealed)
+				if err != nil {
+					t.Fatalf("failed to encode partition: %v", err)
+				}
+				key := archive.DataKey(hour, "run-1", archive.FormatJSONLGzip)
+				if err := store.Write(ctx, key, data); err != nil {
+					t.Fatalf("failed to seed gcs: %v", err)
+				}
+				manifest := archive.NewManifest(hour)
+				manifest.Add(archive.ManifestFile{Key: key, Format: archive.FormatJSONLGzip, Conversations: []string{"session-123"}, EventCount: len(sealed)})
+				manifestData, err := manifest.Marshal()
+				if err != nil {
+					t.Fatalf("failed to marshal manifest: %v", err)
+				}
+				if err := store.Write(ctx, archive.ManifestKey(hour), manifestData); err != nil {
+					t.Fatalf("failed to seed manifest: %v", err)
+				}
+			} else {
+				// Chunks written before encryption was enabled are mixed in with encrypted ones.
+				chunk := `{"interaction_id":"0","content":"legacy plaintext"}` + "\n"
+				for _, event := range sealed {
+					line, err := json.Marshal(event)
+					if err != nil {
+						t.Fatalf("failed to marshal event: %v", err)
+					}
+					chunk += string(line) + "\n"
+				}
+				key := "raw/session-123/" + hour.Format("2006/01/02/15") + "/chunk-1.jsonl"
+				if err := store.Write(ctx, key, []byte(chunk)); err != nil {
+					t.Fatalf("failed to seed gcs: %v", err)
+				}
+			}
+
+			svc := batch.NewService(batch.Config{
+				ProjectID:     "test-project",
+				Location:      "us-central1",
+				StagingBucket: "staging-bucket",
+				OutputBucket:  "output-bucket",
+				ModelID:       "test-model",
+				Layout:        layout,
+			}, &batch.Prompt{
+				Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
+			}, store, store, vertex.NewMemoryClient(), &MockProducer{}, envelope.NewOpener(provider), nil)
+
+			if err := svc.Run(ctx, targetDate); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			request, err := store.Read(ctx, "staging/2025/12/12/session-123.jsonl")
+			if err != nil {
+				t.Fatalf("expected staging file for session-123: %v", err)
+			}
+			if !strings.Contains(string(request), "secret plans") || !strings.Contains(string(request), envelope.Prefix+"typed by the user") {
+				t.Errorf("expected decrypted transcript, got %s", request)
+			}
+			if strings.Contains(string(request), "int-3") || strings.Contains(string(request), "encryption") {
+				t.Errorf("expected the tampered event to be dropped, got %s", request)
+			}
+			if layout == archive.LayoutSession && !strings.Contains(string(request), "legacy plaintext") {
+				t.Errorf("expected plaintext events to pass through, got %s", request)
+			}
+		})
+	}
+}
+
+func TestService_Run_ResumesFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	gcsClient := gcs.NewMemoryClient()
+	vertexClient := vertex.NewMemoryClient()
+	checkpoints := state.NewMemoryStore()
+
+	targetDate := time.Date(2025, 12, 12, 10, 0, 0, 0, time.Local)
+	for _, session := range []string{"session-1", "session-2"} {
+		key := "raw/" + session + "/" + targetDate.Format("2006/01/02/15") + "/chunk-1.jsonl"
+		if err := gcsClient.Write(ctx, key, []byte(`{"interaction_id":"1","content":"hello from `+session+`"}`)); err != nil {
+			t.Fatalf("failed to seed gcs: %v", err)
+		}
+	}
+	// A previous run staged session-1 and then failed.
+	if _, err := checkpoints.Set(ctx, batch.StagedName(targetDate, "session-1"), batch.StagedMarker{StagedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to seed checkpoint: %v", err)
+	}
+
+	svc := batch.NewService(batch.Config{
+		ProjectID:     "test-project",
+		Location:      "us-central1",
+		StagingBucket: "staging-bucket",
+		OutputBucket:  "output-bucket",
+		ModelID:       "test-model",
+	}, &batch.Prompt{
+		Messages: []batch.Message{{Role: "user", Content: "test {{conversation_transcript}}"}},
+	}, gcsClient, gcsClient, vertexClient, &MockProducer{}, nil, checkpoints)
+
+	if err := svc.Run(ctx, targetDate); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	staged, err := gcsClient.ListFiles(ctx, "staging/2025/12/12/")
+	if err != nil {
+		t.Fatalf("failed to list staging files: %v", err)
+	}
+	if len(staged) != 1 || !strings.HasSuffix(staged[0], "session-2.jsonl") {
+		t.Errorf("expected only session-2 to be staged on resume, got %v", staged)
+	}
+
+	var cp batch.Checkpoint
+	if _, err := checkpoints.Get(ctx, batch.CheckpointName(targetDate), &cp); err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if cp.JobName == "" {
+		t.Errorf("expected a job recorded, got %+v", cp)
+	}
+	var marker batch.StagedMarker
+	if _, err := checkpoints.Get(ctx, batch.StagedName(targetDate, "session-2"), &marker); err != nil || marker.StagedAt.IsZero() {
+		t.Errorf("expected session-2 to be marked staged, got %+v, %v", marker, err)
+	}
+
+	// Once the job is submitted, running the day again does nothing.
+	if err := svc.Run(ctx, targetDate); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if got := len(vertexClient.GetCreatedJobs()); got != 1 {
+		t.Errorf("expected 1 batch job across both runs, got %d", got)
+	}
+}
@@ -0,0 +1,531 @@
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// Store is the blob storage the compactor reads, rewrites and prunes.
+type Store interface {
+	gcs.BlobReader
+	gcs.BlobWriter
+	gcs.BlobDeleter
+}
+
+// manifestRetries bounds how often a manifest swap is retried after losing a race with the loader.
+const manifestRetries = 3
+
+type Config struct {
+	// Layout is the raw archive layout to compact. Defaults to archive.LayoutSession.
+	Layout archive.Layout
+	// Format is the encoding of compacted hourly partitions. Defaults to archive.FormatJSONLGzip.
+	Format archive.Format
+	// DryRun reports what would be compacted without writing or deleting anything.
+	DryRun bool
+}
+
+// UnitReport describes the compaction of a single conversation-day or hourly partition.
+type UnitReport struct {
+	Unit        string   `json:"unit"`
+	Sources     []string `json:"sources"`
+	Output      string   `json:"output,omitempty"`
+	Events      int      `json:"events"`
+	Duplicates  int      `json:"duplicates"`
+	BytesBefore int      `json:"bytes_before"`
+	BytesAfter  int      `json:"bytes_after"`
+	Deleted     int      `json:"deleted"`
+	Skipped     string   `json:"skipped,omitempty"`
+}
+
+// Report summarises a compaction run.
+type Report struct {
+	DryRun bool          `json:"dry_run"`
+	Units  []*UnitReport `json:"units"`
+	Failed int           `json:"failed"`
+}
+
+type Service struct {
+	store  Store
+	layout archive.Layout
+	format archive.Format
+	dryRun bool
+}
+
+func NewService(store Store, cfg Config) *Service {
+	layout := cfg.Layout
+	if layout == "" {
+		layout = archive.LayoutSession
+	}
+	format := cfg.Format
+	if format == "" {
+		format = archive.FormatJSONLGzip
+	}
+	return &Service{
+		store:  store,
+		layout: layout,
+		format: format,
+		dryRun: cfg.DryRun,
+	}
+}
+
+// Run compacts every unit for the given date: each active conversation's chunks for that day
+// with archive.LayoutSession, or each of the day's 24 partitions with archive.LayoutHourly.
+// A failing unit is logged and counted, and does not stop the run.
+func (s *Service) Run(ctx context.Context, date time.Time) (*Report, error) {
+	tr := otel.Tracer("compact-service")
+	ctx, span := tr.Start(ctx, "Run")
+	defer span.End()
+
+	report := &Report{DryRun: s.dryRun}
+
+	if s.layout == archive.LayoutHourly {
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		for h := 0; h < 24; h++ {
+			unit, err := s.CompactHour(ctx, day.Add(time.Duration(h)*time.Hour))
+			if err != nil {
+				slog.Error("Failed to compact partition", "hour", h, "error", err)
+				report.Failed++
+				continue
+			}
+			report.Units = append(report.Units, unit)
+		}
+		return report, nil
+	}
+
+	sessions, err := s.store.ListActiveSessions(ctx, date)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to list active sessions: %w", err)
+	}
+	sort.Strings(sessions)
+
+	for _, sessionID := range sessions {
+		unit, err := s.CompactConversation(ctx, sessionID, date)
+		if err != nil {
+			slog.Error("Failed to compact conversation", "session_id", sessionID, "error", err)
+			report.Failed++
+			continue
+		}
+		report.Units = append(report.Units, unit)
+	}
+	return report, nil
+}
+
+// CompactConversation merges a conversation's chunks for the day containing date into a single
+// chunk sorted by timestamp with duplicate events removed. The new chunk is verified, swapped in
+// through the conversation's manifest, and only then are the originals deleted.
+func (s *Service) CompactConversation(ctx context.Context, conversationID string, date time.Time) (*UnitReport, error) {
+	unit := &UnitReport{Unit: archive.SessionDayPrefix(conversationID, date)}
+
+	manifest, err := s.readSessionManifest(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.store.ListFiles(ctx, unit.Unit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	keys = manifest.Live(keys)
+	sort.Strings(keys)
+	unit.Sources = keys
+
+	if len(keys) < 2 {
+		unit.Skipped = "nothing to compact"
+		return unit, nil
+	}
+
+	var events []schema.InteractionEvent
+	for _, key := range keys {
+		data, err := s.store.Read(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk %s: %w", key, err)
+		}
+		unit.BytesBefore += len(data)
+
+		chunk, err := decodeJSONL(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode chunk %s: %w", key, err)
+		}
+		events = append(events, chunk...)
+	}
+
+	events, unit.Duplicates = sortAndDedupe(events)
+	unit.Events = len(events)
+
+	var data []byte
+	for _, event := range events {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("marshal error: %w", err)
+		}
+		data = append(data, b...)
+		data = append(data, '\n')
+	}
+	unit.BytesAfter = len(data)
+	unit.Output = archive.SessionCompactedKey(conversationID, date, uuid.New().String())
+
+	if s.dryRun {
+		return unit, nil
+	}
+
+	// Record who the chunk belongs to, as the loader does, so retention sweeps apply the tenant's
+	// TTL to it.
+	metadata := map[string]string{
+		"conversation_id": conversationID,
+		"event_count":     strconv.Itoa(len(events)),
+	}
+	if tenants := archive.EventTenants(events); len(tenants) > 0 {
+		metadata["tenant_id"] = strings.Join(tenants, ",")
+	}
+	if err := s.writeVerified(ctx, unit.Output, data, gcs.WriteOptions{Metadata: metadata}, events, decodeJSONL); err != nil {
+		return nil, err
+	}
+
+	// Swap: readers pick up the compacted chunk and ignore the originals from this write onwards.
+	superseded := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		superseded[key] = true
+	}
+	err = s.updateSessionManifest(ctx, conversationID, func(manifest *archive.SessionManifest) {
+		var files []string
+		for _, f := range manifest.Files {
+			if !superseded[f] {
+				files = append(files, f)
+			}
+		}
+		manifest.Files = append(files, unit.Output)
+		manifest.Superseded = append(manifest.Superseded, keys...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := s.deleteAll(ctx, keys)
+	unit.Deleted = len(deleted)
+
+	// Forget originals that are gone; any that failed to delete stay superseded for the next run.
+	err = s.updateSessionManifest(ctx, conversationID, func(manifest *archive.SessionManifest) {
+		var remaining []string
+		for _, key := range manifest.Superseded {
+			if !deleted[key] {
+				remaining = append(remaining, key)
+			}
+		}
+		manifest.Superseded = remaining
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Compacted conversation", "session_id", conversationID, "sources", len(keys), "events", unit.Events, "duplicates", unit.Duplicates)
+	return unit, nil
+}
+
+// CompactHour merges every data file of the hourly partition containing hour into a single file
+// sorted by conversation and timestamp with duplicate events removed. The partition manifest is
+// rewritten to reference only the new file before the originals are deleted.
+func (s *Service) CompactHour(ctx context.Context, hour time.Time) (*UnitReport, error) {
+	unit := &UnitReport{Unit: archive.HourPrefix(hour)}
+
+	manifestKey := archive.ManifestKey(hour)
+	data, err := s.store.Read(ctx, manifestKey)
+	if err != nil {
+		if errors.Is(err, gcs.ErrNotExist) {
+			unit.Skipped = "no manifest"
+			return unit, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest %s: %w", manifestKey, err)
+	}
+	manifest, err := archive.ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range manifest.Files {
+		unit.Sources = append(unit.Sources, file.Key)
+	}
+	if len(manifest.Files) < 2 {
+		unit.Skipped = "nothing to compact"
+		return unit, nil
+	}
+
+	var events []schema.InteractionEvent
+	for _, file := range manifest.Files {
+		data, err := s.store.Read(ctx, file.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read partition file %s: %w", file.Key, err)
+		}
+		unit.BytesBefore += len(data)
+
+		decoded, err := archive.Decode(file.Format, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode partition file %s: %w", file.Key, err)
+		}
+		events = append(events, decoded...)
+	}
+
+	events, unit.Duplicates = sortAndDedupe(events)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].ConversationId < events[j].ConversationId
+	})
+	unit.Events = len(events)
+
+	out, err := archive.Encode(s.format, events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode partition: %w", err)
+	}
+	unit.BytesAfter = len(out)
+	unit.Output = archive.CompactedDataKey(hour, uuid.New().String(), s.format)
+
+	if s.dryRun {
+		return unit, nil
+	}
+
+	format := s.format
+	if err := s.writeVerified(ctx, unit.Output, out, gcs.WriteOptions{}, events, func(b []byte) ([]schema.InteractionEvent, error) {
+		return archive.Decode(format, b)
+	}); err != nil {
+		return nil, err
+	}
+
+	var conversations []string
+	for _, event := range events {
+		if len(conversations) == 0 || conversations[len(conversations)-1] != event.ConversationId {
+			conversations = append(conversations, event.ConversationId)
+		}
+	}
+
+	compacted := make(map[string]bool, len(unit.Sources))
+	for _, key := range unit.Sources {
+		compacted[key] = true
+	}
+	output := archive.ManifestFile{
+		Key:           unit.Output,
+		Format:        s.format,
+		Conversations: conversations,
+		Tenants:       archive.EventTenants(events),
+		EventCount:    len(events),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.swapHourManifest(ctx, hour, output, compacted); err != nil {
+		// The compacted file is not referenced by the manifest, so readers never see it.
+		if delErr := s.store.Delete(ctx, unit.Output); delErr != nil {
+			slog.Warn("Failed to remove unused compacted file", "key", unit.Output, "error", delErr)
+		}
+		return nil, err
+	}
+
+	unit.Deleted = len(s.deleteAll(ctx, unit.Sources))
+
+	slog.Info("Compacted partition", "partition", unit.Unit, "sources", len(unit.Sources), "events", unit.Events, "duplicates", unit.Duplicates)
+	return unit, nil
+}
+
+// swapHourManifest rewrites the manifest of the hour containing hour to reference output in place
+// of the compacted files. The manifest is re-read so files added by a loader run since compaction
+// started are kept, and, when the store supports it, written with a generation precondition that
+// is retried if a loader updates it in between.
+func (s *Service) swapHourManifest(ctx context.Context, hour time.Time, output archive.ManifestFile, compacted map[string]bool) error {
+	manifestKey := archive.ManifestKey(hour)
+	for attempt := 1; ; attempt++ {
+		var generation string
+		if sr, ok := s.store.(gcs.StreamReader); ok {
+			attrs, err := sr.Stat(ctx, manifestKey)
+			if err != nil {
+				return fmt.Errorf("failed to stat manifest %s: %w", manifestKey, err)
+			}
+			generation = attrs.Generation
+		}
+
+		data, err := s.store.Read(ctx, manifestKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-read manifest %s: %w", manifestKey, err)
+		}
+		current, err := archive.ParseManifest(data)
+		if err != nil {
+			return err
+		}
+
+		swapped := archive.NewManifest(hour)
+		swapped.Add(output)
+		for _, file := range current.Files {
+			if !compacted[file.Key] {
+				swapped.Add(file)
+			}
+		}
+		manifestData, err := swapped.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest %s: %w", manifestKey, err)
+		}
+
+		err = s.writeObject(ctx, manifestKey, manifestData, gcs.WriteOptions{IfGenerationMatch: generation})
+		if !errors.Is(err, gcs.ErrPreconditionFailed) || attempt == manifestRetries {
+			return err
+		}
+		slog.Warn("Manifest changed during compaction, retrying", "manifest", manifestKey, "attempt", attempt)
+	}
+}
+
+// writeObject writes data to key through gcs.Create so write options are honoured.
+func (s *Service) writeObject(ctx context.Context, key string, data []byte, opts gcs.WriteOptions) error {
+	w, err := gcs.Create(ctx, s.store, key, opts)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.CloseWithError(err)
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// writeVerified writes data to key with opts and reads it back, checking it decodes to exactly
+// the expected events.
+func (s *Service) writeVerified(ctx context.Context, key string, data []byte, opts gcs.WriteOptions, expected []schema.InteractionEvent, decode func([]byte) ([]schema.InteractionEvent, error)) error {
+	if err := s.writeObject(ctx, key, data, opts); err != nil {
+		return fmt.Errorf("failed to write compacted file: %w", err)
+	}
+
+	written, err := s.store.Read(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to verify compacted file %s: %w", key, err)
+	}
+	if !bytes.Equal(written, data) {
+		return fmt.Errorf("verification failed for %s: content mismatch", key)
+	}
+	decoded, err := decode(written)
+	if err != nil {
+		return fmt.Errorf("verification failed for %s: %w", key, err)
+	}
+	if len(decoded) != len(expected) {
+		return fmt.Errorf("verification failed for %s: expected %d events, found %d", key, len(expected), len(decoded))
+	}
+	for i := range decoded {
+		if eventKey(decoded[i]) != eventKey(expected[i]) {
+			return fmt.Errorf("verification failed for %s: event %d mismatch", key, i)
+		}
+	}
+	return nil
+}
+
+// deleteAll deletes keys, logging failures, and returns the set that was removed.
+func (s *Service) deleteAll(ctx context.Context, keys []string) map[string]bool {
+	deleted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, gcs.ErrNotExist) {
+			slog.Warn("Failed to delete compacted source", "key", key, "error", err)
+			continue
+		}
+		deleted[key] = true
+	}
+	return deleted
+}
+
+func (s *Service) readSessionManifest(ctx context.Context, conversationID string) (*archive.SessionManifest, error) {
+	key := archive.SessionManifestKey(conversationID)
+	data, err := s.store.Read(ctx, key)
+	if err != nil {
+		if errors.Is(err, gcs.ErrNotExist) {
+			return &archive.SessionManifest{ConversationID: conversationID}, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest %s: %w", key, err)
+	}
+	return archive.ParseSessionManifest(data)
+}
+
+// updateSessionManifest applies fn to the conversation's manifest and writes it back. Like
+// swapHourManifest, the write carries a generation precondition when the store supports it, and
+// is retried from a fresh read if another compaction or erasure changed the manifest in between.
+func (s *Service) updateSessionManifest(ctx context.Context, conversationID string, fn func(*archive.SessionManifest)) error {
+	manifestKey := archive.SessionManifestKey(conversationID)
+	for attempt := 1; ; attempt++ {
+		var generation string
+		if sr, ok := s.store.(gcs.StreamReader); ok {
+			attrs, err := sr.Stat(ctx, manifestKey)
+			switch {
+			case err == nil:
+				generation = attrs.Generation
+			case errors.Is(err, gcs.ErrNotExist):
+				generation = gcs.NoGeneration
+			default:
+				return fmt.Errorf("failed to stat manifest %s: %w", manifestKey, err)
+			}
+		}
+
+		manifest, err := s.readSessionManifest(ctx, conversationID)
+		if err != nil {
+			return err
+		}
+		fn(manifest)
+		data, err := manifest.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest %s: %w", manifestKey, err)
+		}
+
+		err = s.writeObject(ctx, manifestKey, data, gcs.WriteOptions{IfGenerationMatch: generation})
+		if !errors.Is(err, gcs.ErrPreconditionFailed) || attempt == manifestRetries {
+			return err
+		}
+		slog.Warn("Manifest changed during compaction, retrying", "manifest", manifestKey, "attempt", attempt)
+	}
+}
+
+// sortAndDedupe orders events by timestamp and drops repeated deliveries of the same event,
+// returning the unique events and the number removed.
+func sortAndDedupe(events []schema.InteractionEvent) ([]schema.InteractionEvent, int) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	seen := make(map[string]bool, len(events))
+	unique := events[:0]
+	for _, event := range events {
+		k := eventKey(event)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, event)
+	}
+	return unique, len(events) - len(unique)
+}
+
+// eventKey identifies an event across redeliveries: the same interaction, role and timestamp.
+// Timestamps are compared at millisecond precision, which is what Parquet partitions retain.
+func eventKey(event schema.InteractionEvent) string {
+	return fmt.Sprintf("%s|%s|%s|%d", event.ConversationId, event.InteractionId, event.Role, event.Timestamp.UnixMilli())
+}
+
+func decodeJSONL(data []byte) ([]schema.InteractionEvent, error) {
+	var events []schema.InteractionEvent
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var event schema.InteractionEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("failed to parse json line: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
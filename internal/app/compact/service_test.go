@@ -0,0 +1,193 @@
+package compact_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/compact"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeChunk(t *testing.T, store *gcs.MemoryClient, key string, events ...schema.InteractionEvent) {
+	t.Helper()
+	var data []byte
+	for _, event := range events {
+		b, err := json.Marshal(event)
+		require.NoError(t, err)
+		data = append(data, b...)
+		data = append(data, '\n')
+	}
+	require.NoError(t, store.Write(context.Background(), key, data))
+}
+
+// racingStore simulates another compaction updating a session manifest between our read and our write.
+type racingStore struct {
+	*gcs.MemoryClient
+	raced bool
+}
+
+func (r *racingStore) Create(ctx context.Context, key string, opts gcs.WriteOptions) (gcs.Writer, error) {
+	if key == archive.SessionManifestKey("conv-1") && !r.raced {
+		r.raced = true
+		manifest := &archive.SessionManifest{ConversationID: "conv-1", Files: []string{"raw/conv-1/2025/12/15/00-compacted-other.jsonl"}}
+		data, err := manifest.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Write(ctx, key, data); err != nil {
+			return nil, err
+		}
+	}
+	return r.MemoryClient.Create(ctx, key, opts)
+}
+
+func TestService_CompactConversation(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2025, 12, 16, 10, 0, 0, 0, time.Local)
+	first := schema.InteractionEvent{InteractionId: "int-1", ConversationId: "conv-1", Timestamp: date, Role: "user", Content: "Hello"}
+	second := schema.InteractionEvent{InteractionId: "int-2", ConversationId: "conv-1", Timestamp: date.Add(time.Minute), Role: "model", Content: "Hi"}
+
+	seed := func() *gcs.MemoryClient {
+		store := gcs.NewMemoryClient()
+		writeChunk(t, store, "raw/conv-1/2025/12/16/10/chunk-b.jsonl", second, first)
+		writeChunk(t, store, "raw/conv-1/2025/12/16/11/chunk-a.jsonl", first)
+		return store
+	}
+
+	t.Run("merges, dedupes and swaps", func(t *testing.T) {
+		store := seed()
+		svc := compact.NewService(store, compact.Config{})
+
+		report, err := svc.Run(ctx, date)
+		require.NoError(t, err)
+		require.Len(t, report.Units, 1)
+		unit := report.Units[0]
+		assert.Equal(t, 0, report.Failed)
+		assert.Len(t, unit.Sources, 2)
+		assert.Equal(t, 2, unit.Events)
+		assert.Equal(t, 1, unit.Duplicates)
+		assert.Equal(t, 2, unit.Deleted)
+
+		keys, err := store.ListFiles(ctx, archive.SessionPrefix("conv-1"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{unit.Output, archive.SessionManifestKey("conv-1")}, keys)
+
+		data, err := store.Read(ctx, unit.Output)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"interaction_id":"int-1"`)
+		assert.Contains(t, lines[1], `"interaction_id":"int-2"`)
+
+		data, err = store.Read(ctx, archive.SessionManifestKey("conv-1"))
+		require.NoError(t, err)
+		manifest, err := archive.ParseSessionManifest(data)
+		require.NoError(t, err)
+		assert.Equal(t, []string{unit.Output}, manifest.Files)
+		assert.Empty(t, manifest.Superseded)
+
+		// A second run has nothing left to merge.
+		again, err := svc.CompactConversation(ctx, "conv-1", date)
+		require.NoError(t, err)
+		assert.NotEmpty(t, again.Skipped)
+	})
+
+	t.Run("dry run leaves the archive untouched", func(t *testing.T) {
+		store := seed()
+		svc := compact.NewService(store, compact.Config{DryRun: true})
+
+		unit, err := svc.CompactConversation(ctx, "conv-1", date)
+		require.NoError(t, err)
+		assert.Equal(t, 1, unit.Duplicates)
+		assert.Equal(t, 0, unit.Deleted)
+
+		keys, err := store.ListFiles(ctx, archive.SessionPrefix("conv-1"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, unit.Sources, keys)
+	})
+
+	t.Run("keeps a concurrent manifest update", func(t *testing.T) {
+		store := &racingStore{MemoryClient: seed()}
+		svc := compact.NewService(store, compact.Config{})
+
+		unit, err := svc.CompactConversation(ctx, "conv-1", date)
+		require.NoError(t, err)
+		assert.True(t, store.raced)
+
+		data, err := store.Read(ctx, archive.SessionManifestKey("conv-1"))
+		require.NoError(t, err)
+		manifest, err := archive.ParseSessionManifest(data)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"raw/conv-1/2025/12/15/00-compacted-other.jsonl", unit.Output}, manifest.Files)
+		assert.Empty(t, manifest.Superseded)
+	})
+}
+
+func TestService_CompactHour(t *testing.T) {
+	ctx := context.Background()
+	hour := time.Date(2025, 12, 16, 10, 0, 0, 0, time.UTC)
+	store := gcs.NewMemoryClient()
+
+	manifest := archive.NewManifest(hour)
+	runs := [][]schema.InteractionEvent{
+		{
+			{InteractionId: "int-2", ConversationId: "conv-b", Timestamp: hour.Add(2 * time.Minute), Role: "user", Content: "Hey"},
+			{InteractionId: "int-1", ConversationId: "conv-a", Timestamp: hour.Add(time.Minute), Role: "user", Content: "Hello"},
+		},
+		{
+			{InteractionId: "int-1", ConversationId: "conv-a", Timestamp: hour.Add(time.Minute), Role: "user", Content: "Hello"},
+			{InteractionId: "int-3", ConversationId: "conv-a", Timestamp: hour.Add(3 * time.Minute), Role: "model", Content: "Hi"},
+		},
+	}
+	for i, events := range runs {
+		key := archive.DataKey(hour, []string{"run-1", "run-2"}[i], archive.FormatJSONLGzip)
+		data, err := archive.Encode(archive.FormatJSONLGzip, events)
+		require.NoError(t, err)
+		require.NoError(t, store.Write(ctx, key, data))
+		manifest.Add(archive.ManifestFile{Key: key, Format: archive.FormatJSONLGzip, EventCount: len(events)})
+	}
+	data, err := manifest.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, store.Write(ctx, archive.ManifestKey(hour), data))
+
+	svc := compact.NewService(store, compact.Config{Layout: archive.LayoutHourly, Format: archive.FormatParquet})
+	report, err := svc.Run(ctx, hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Failed)
+
+	var unit *compact.UnitReport
+	for _, u := range report.Units {
+		if u.Output != "" {
+			require.Nil(t, unit, "only one partition should be compacted")
+			unit = u
+		}
+	}
+	require.NotNil(t, unit)
+	assert.Equal(t, 3, unit.Events)
+	assert.Equal(t, 1, unit.Duplicates)
+	assert.Equal(t, 2, unit.Deleted)
+
+	data, err = store.Read(ctx, archive.ManifestKey(hour))
+	require.NoError(t, err)
+	swapped, err := archive.ParseManifest(data)
+	require.NoError(t, err)
+	require.Len(t, swapped.Files, 1)
+	assert.Equal(t, unit.Output, swapped.Files[0].Key)
+	assert.Equal(t, []string{"conv-a", "conv-b"}, swapped.Conversations())
+
+	data, err = store.Read(ctx, unit.Output)
+	require.NoError(t, err)
+	events, err := archive.Decode(archive.FormatParquet, data)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "int-1", events[0].InteractionId)
+	assert.Equal(t, "int-3", events[1].InteractionId)
+	assert.Equal(t, "int-2", events[2].InteractionId)
+}
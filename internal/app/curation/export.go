@@ -0,0 +1,118 @@
+package curation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"go.opentelemetry.io/otel"
+)
+
+// ExportOptions selects what Export writes.
+type ExportOptions struct {
+	// Filter selects the records to export; nil exports every record.
+	Filter pinecone.Filter
+	// Values includes each record's embedding, so records without text can be imported into a
+	// store using the same embedding model.
+	Values bool
+}
+
+// Export writes the records matching opts.Filter to w as JSONL, one Record per line, and returns
+// how many it wrote.
+func (s *Service) Export(ctx context.Context, w io.Writer, opts ExportOptions) (int, error) {
+	ctx, span := otel.Tracer("curation-service").Start(ctx, "Export")
+	defer span.End()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	err := s.walk(ctx, opts.Filter, opts.Values, func(records []*Record) error {
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("failed to write record %s: %w", r.ID, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return count, err
+}
+
+// Import upserts the records in r, JSONL as written by Export, and returns how many it upserted.
+// Records with text are embedded again by the store; records without text need their values.
+// Blank lines are skipped.
+func (s *Service) Import(ctx context.Context, r io.Reader) (int, error) {
+	ctx, span := otel.Tracer("curation-service").Start(ctx, "Import")
+	defer span.End()
+
+	var inputs []*pinecone.InputRecord
+	var vectors []*pinecone.Vector
+	count := 0
+	flush := func() error {
+		if len(inputs) > 0 {
+			if err := s.store.UpsertInputs(ctx, inputs); err != nil {
+				return fmt.Errorf("failed to upsert records: %w", err)
+			}
+			count += len(inputs)
+			inputs = inputs[:0]
+		}
+		if len(vectors) > 0 {
+			if err := s.store.UpsertBatch(ctx, vectors); err != nil {
+				return fmt.Errorf("failed to upsert vectors: %w", err)
+			}
+			count += len(vectors)
+			vectors = vectors[:0]
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			span.RecordError(err)
+			return count, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		if rec.ID == "" {
+			return count, fmt.Errorf("line %d: record has no id", line)
+		}
+
+		metadata := rec.metadata()
+		switch {
+		case rec.Text != "":
+			delete(metadata, textField)
+			inputs = append(inputs, &pinecone.InputRecord{ID: rec.ID, Text: rec.Text, Metadata: metadata})
+		case len(rec.Values) > 0:
+			vectors = append(vectors, &pinecone.Vector{ID: rec.ID, Values: rec.Values, Metadata: metadata})
+		default:
+			return count, fmt.Errorf("line %d: record %s has neither text nor values", line, rec.ID)
+		}
+		if len(inputs) >= s.batchSize || len(vectors) >= s.batchSize {
+			if err := flush(); err != nil {
+				span.RecordError(err)
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		span.RecordError(err)
+		return count, fmt.Errorf("failed to read records: %w", err)
+	}
+	if err := flush(); err != nil {
+		span.RecordError(err)
+		return count, err
+	}
+	return count, nil
+}
@@ -0,0 +1,90 @@
+package curation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"go.opentelemetry.io/otel"
+)
+
+// reviewedByField records the analyst who resolved a flagged signature's reports.
+const reviewedByField = "reviewed_by"
+
+// Review statuses of flagged signatures. Feedback marks them pending; an analyst confirms or
+// dismisses the reports.
+const (
+	ReviewPending   = "pending"
+	ReviewConfirmed = "confirmed"
+	ReviewDismissed = "dismissed"
+)
+
+// DefaultFeedbackPenalty is the factor a signature's weight is multiplied by when an analyst
+// confirms the false positives reported against it, unless Config.FeedbackPenalty says otherwise.
+const DefaultFeedbackPenalty = 0.5
+
+// ErrNotPending is returned by ResolveFeedback when some of the requested signatures are not
+// awaiting review.
+var ErrNotPending = errors.New("signatures not pending review")
+
+// FeedbackDecision is an analyst's decision on the false positives reported against signatures.
+type FeedbackDecision struct {
+	IDs []string
+	// Confirm accepts the reports, multiplying each signature's weight by the feedback penalty;
+	// otherwise they are dismissed and the weight is left alone.
+	Confirm    bool
+	ReviewedBy string
+}
+
+// ResolveFeedback records decision on the signatures pending review among its IDs and returns
+// them as updated. If some are not pending, it resolves the others and returns an error wrapping
+// ErrNotPending that names the rest.
+func (s *Service) ResolveFeedback(ctx context.Context, decision FeedbackDecision) ([]*Record, error) {
+	ctx, span := otel.Tracer("curation-service").Start(ctx, "ResolveFeedback")
+	defer span.End()
+
+	if len(decision.IDs) == 0 {
+		return nil, errors.New("feedback decision requires IDs")
+	}
+	records, skipped, err := s.fetch(ctx, decision.IDs, false)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	status := ReviewDismissed
+	if decision.Confirm {
+		status = ReviewConfirmed
+	}
+	var resolved []*Record
+	for _, r := range records {
+		if r.Metadata[pinecone.ReviewStatusField] != ReviewPending {
+			skipped = append(skipped, r.ID)
+			continue
+		}
+		update := map[string]interface{}{pinecone.ReviewStatusField: status}
+		if decision.ReviewedBy != "" {
+			update[reviewedByField] = decision.ReviewedBy
+		}
+		if decision.Confirm {
+			update[pinecone.WeightField] = pinecone.Weight(r.Metadata) * s.penalty
+		}
+		if err := s.store.UpdateMetadata(ctx, r.ID, update); err != nil {
+			span.RecordError(err)
+			return resolved, fmt.Errorf("failed to update signature %s: %w", r.ID, err)
+		}
+		for k, v := range update {
+			r.Metadata[k] = v
+		}
+		resolved = append(resolved, r)
+	}
+
+	slog.Info("Resolved feedback", "status", status, "signatures", len(resolved), "reviewed_by", decision.ReviewedBy)
+	if len(skipped) > 0 {
+		return resolved, fmt.Errorf("%w: %s", ErrNotPending, strings.Join(skipped, ", "))
+	}
+	return resolved, nil
+}
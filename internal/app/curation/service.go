@@ -0,0 +1,314 @@
+// Package curation lets analysts review and correct the signature set in the vector store: search
+// it for arbitrary text, inspect records, resolve the false positives reported against them,
+// delete them with an audit trail and export or import it as JSONL.
+package curation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// textField is the metadata field the vector stores keep a record's text in.
+const textField = "chunk_text"
+
+// ErrNotFound is returned by Inspect when some of the requested records are not stored.
+var ErrNotFound = errors.New("records not found")
+
+// Record is a stored signature as analysts see it and as it is exported.
+type Record struct {
+	ID string `json:"id"`
+	// Text is the text the record was embedded from.
+	Text string `json:"text,omitempty"`
+	// Label and Source repeat the label and source metadata fields: the canonical label and the
+	// dataset, or auto-extracted, the record came from.
+	Label  string `json:"label,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Score is the similarity to the query, for search results.
+	Score float32 `json:"score,omitempty"`
+	// Metadata holds every metadata field except the text.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Values is the embedding, exported only on request.
+	Values []float32 `json:"values,omitempty"`
+}
+
+type Config struct {
+	// BatchSize bounds the records fetched, listed or upserted per request. Defaults to 96, the
+	// most Pinecone embeds in one upsert.
+	BatchSize int
+	// Now stamps audit records. Defaults to time.Now.
+	Now func() time.Time
+	// FeedbackPenalty multiplies the weight of a signature when an analyst confirms the false
+	// positives reported against it. Defaults to DefaultFeedbackPenalty.
+	FeedbackPenalty float64
+}
+
+type Service struct {
+	store     pinecone.VectorStore
+	audit     gcs.BlobWriter
+	batchSize int
+	now       func() time.Time
+	penalty   float64
+}
+
+// NewService creates the curation service for store. audit receives a record of every deletion;
+// it may be nil, in which case only dry-run deletions are allowed.
+func NewService(store pinecone.VectorStore, audit gcs.BlobWriter, cfg Config) *Service {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 96
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.FeedbackPenalty <= 0 || cfg.FeedbackPenalty > 1 {
+		cfg.FeedbackPenalty = DefaultFeedbackPenalty
+	}
+	return &Service{store: store, audit: audit, batchSize: cfg.BatchSize, now: cfg.Now, penalty: cfg.FeedbackPenalty}
+}
+
+// Search returns the topK records matching filter most similar to text.
+func (s *Service) Search(ctx context.Context, text string, topK int, filter pinecone.Filter) ([]*Record, error) {
+	ctx, span := otel.Tracer("curation-service").Start(ctx, "Search")
+	defer span.End()
+
+	matches, err := s.store.QueryInput(ctx, text, topK, filter)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to search vector store: %w", err)
+	}
+	records := make([]*Record, len(matches))
+	for i, m := range matches {
+		records[i] = newRecord(m.ID, m.Metadata, nil)
+		records[i].Score = m.Score
+	}
+	return records, nil
+}
+
+// Inspect returns the records with ids, in the same order. If some are not stored, it returns the
+// others with an error wrapping ErrNotFound that names the missing IDs.
+func (s *Service) Inspect(ctx context.Context, ids []string) ([]*Record, error) {
+	ctx, span := otel.Tracer("curation-service").Start(ctx, "Inspect")
+	defer span.End()
+
+	records, missing, err := s.fetch(ctx, ids, false)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return records, fmt.Errorf("%w: %s", ErrNotFound, strings.Join(missing, ", "))
+	}
+	return records, nil
+}
+
+// fetch returns the stored records of ids in order, and the IDs that are not stored.
+func (s *Service) fetch(ctx context.Context, ids []string, values bool) ([]*Record, []string, error) {
+	var records []*Record
+	var missing []string
+	for start := 0; start < len(ids); start += s.batchSize {
+		batch := ids[start:min(start+s.batchSize, len(ids))]
+		vectors, err := s.store.Fetch(ctx, batch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch records: %w", err)
+		}
+		for _, id := range batch {
+			v, ok := vectors[id]
+			if !ok {
+				missing = append(missing, id)
+				continue
+			}
+			var embedding []float32
+			if values {
+				embedding = v.Values
+			}
+			records = append(records, newRecord(id, v.Metadata, embedding))
+		}
+	}
+	return records, missing, nil
+}
+
+// walk calls fn with every stored record matching filter, a page at a time.
+func (s *Service) walk(ctx context.Context, filter pinecone.Filter, values bool, fn func([]*Record) error) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	paginator := pinecone.NewListPaginator(s.store, pinecone.ListOptions{Limit: s.batchSize})
+	for paginator.HasMorePages() {
+		ids, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list records: %w", err)
+		}
+		records, _, err := s.fetch(ctx, ids, values)
+		if err != nil {
+			return err
+		}
+		matched := records[:0]
+		for _, r := range records {
+			if filter.Match(r.metadata()) {
+				matched = append(matched, r)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if err := fn(matched); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRequest selects the records to delete, by ID or by filter.
+type DeleteRequest struct {
+	IDs    []string
+	Filter pinecone.Filter
+	// RequestedBy and Reason are recorded in the audit record, e.g. an analyst and a ticket.
+	RequestedBy string
+	Reason      string
+	// DryRun reports what would be deleted without deleting it or writing an audit record.
+	DryRun bool
+}
+
+// AuditRecord is the durable record of a deletion. It keeps the deleted records, text and
+// metadata included, so they can be restored with Import, one per line.
+type AuditRecord struct {
+	ID          string          `json:"id"`
+	RequestedBy string          `json:"requested_by,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	Namespace   string          `json:"namespace,omitempty"`
+	Filter      pinecone.Filter `json:"filter,omitempty"`
+	DryRun      bool            `json:"dry_run"`
+	StartedAt   time.Time       `json:"started_at"`
+	Deleted     []*Record       `json:"deleted"`
+	// NotFound lists requested IDs that were not stored.
+	NotFound []string `json:"not_found,omitempty"`
+}
+
+// AuditKey returns the key of the audit record with the given ID for a deletion started at t.
+func AuditKey(t time.Time, id string) string {
+	return fmt.Sprintf("audit/curation/%s/%s.json", t.UTC().Format("2006/01/02"), id)
+}
+
+// Delete deletes the requested records. Records selected by filter are found by listing the
+// store, so this works on stores that cannot delete by filter themselves. The audit record is
+// written before anything is deleted, so there is one for every deletion even if it fails part
+// way.
+func (s *Service) Delete(ctx context.Context, req DeleteRequest) (*AuditRecord, error) {
+	ctx, span := otel.Tracer("curation-service").Start(ctx, "Delete")
+	defer span.End()
+
+	if len(req.IDs) == 0 && len(req.Filter) == 0 {
+		return nil, errors.New("deletion requires IDs or a filter")
+	}
+	if len(req.IDs) > 0 && len(req.Filter) > 0 {
+		return nil, errors.New("delete either by IDs or by filter, not both")
+	}
+	if s.audit == nil && !req.DryRun {
+		return nil, errors.New("deletion requires an audit store")
+	}
+
+	record := &AuditRecord{
+		ID:          uuid.New().String(),
+		RequestedBy: req.RequestedBy,
+		Reason:      req.Reason,
+		Namespace:   s.store.Namespace(),
+		Filter:      req.Filter,
+		DryRun:      req.DryRun,
+		StartedAt:   s.now(),
+		Deleted:     []*Record{},
+	}
+	if len(req.IDs) > 0 {
+		found, missing, err := s.fetch(ctx, req.IDs, false)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		record.Deleted = append(record.Deleted, found...)
+		record.NotFound = missing
+	} else {
+		err := s.walk(ctx, req.Filter, false, func(records []*Record) error {
+			record.Deleted = append(record.Deleted, records...)
+			return nil
+		})
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+	if req.DryRun || len(record.Deleted) == 0 {
+		return record, nil
+	}
+
+	if err := s.writeAudit(ctx, record); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	ids := make([]string, len(record.Deleted))
+	for i, r := range record.Deleted {
+		ids[i] = r.ID
+	}
+	for start := 0; start < len(ids); start += s.batchSize {
+		if err := s.store.Delete(ctx, ids[start:min(start+s.batchSize, len(ids))]); err != nil {
+			span.RecordError(err)
+			return record, fmt.Errorf("failed to delete records (audit %s): %w", record.ID, err)
+		}
+	}
+
+	slog.Info("Deleted signatures", "audit_id", record.ID, "deleted", len(ids),
+		"requested_by", req.RequestedBy, "reason", req.Reason)
+	return record, nil
+}
+
+func (s *Service) writeAudit(ctx context.Context, record *AuditRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	key := AuditKey(record.StartedAt, record.ID)
+	if err := s.audit.Write(ctx, key, data); err != nil {
+		return fmt.Errorf("failed to write audit record %s: %w", key, err)
+	}
+	return nil
+}
+
+func newRecord(id string, metadata map[string]interface{}, values []float32) *Record {
+	r := &Record{ID: id, Metadata: make(map[string]interface{}, len(metadata)), Values: values}
+	for k, v := range metadata {
+		if k == textField {
+			r.Text, _ = v.(string)
+			continue
+		}
+		r.Metadata[k] = v
+	}
+	r.Label, _ = metadata["label"].(string)
+	r.Source, _ = metadata["source"].(string)
+	return r
+}
+
+// metadata returns the record's metadata as stored: its metadata with its label, source and
+// text.
+func (r *Record) metadata() map[string]interface{} {
+	metadata := make(map[string]interface{}, len(r.Metadata)+3)
+	for k, v := range r.Metadata {
+		metadata[k] = v
+	}
+	if r.Label != "" {
+		metadata["label"] = r.Label
+	}
+	if r.Source != "" {
+		metadata["source"] = r.Source
+	}
+	if r.Text != "" {
+		metadata[textField] = r.Text
+	}
+	return metadata
+}
@@ -0,0 +1,60 @@
+package dataset_loader
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/huggingface"
+	"github.com/dllewellyn/reflex/internal/platform/state"
+)
+
+// Checkpoint records how far a dataset has been loaded. It is stored under CheckpointName after
+// every batch, so an interrupted load resumes from the last batch rather than from row zero.
+type Checkpoint struct {
+	Dataset string `json:"dataset"`
+	Split   string `json:"split"`
+	// Position is where reading resumes: the revision, shard and row offset after the last batch.
+	Position  huggingface.Position `json:"position"`
+	Processed int                  `json:"processed"`
+	Upserted  int                  `json:"upserted"`
+	Skipped   int                  `json:"skipped"`
+	Failed    int                  `json:"failed"`
+	// Done is set once every row has been loaded; later runs skip the dataset until its revision
+	// changes or the load is restarted.
+	Done      bool      `json:"done"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointName is the name of the checkpoint for a dataset's split.
+func CheckpointName(datasetID, split string) string {
+	return "dataset/" + datasetID + "/" + split
+}
+
+// loadCheckpoint returns the dataset's checkpoint, or an empty one if there is none, checkpoints
+// are disabled or the load is being restarted.
+func (s *Service) loadCheckpoint(ctx context.Context, dataset Dataset) (Checkpoint, error) {
+	cp := Checkpoint{Dataset: dataset.ID, Split: dataset.Split}
+	if s.checkpoints == nil || s.config.Restart {
+		return cp, nil
+	}
+	_, err := s.checkpoints.Get(ctx, CheckpointName(dataset.ID, dataset.Split), &cp)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		return cp, err
+	}
+	return cp, nil
+}
+
+// saveCheckpoint records progress. The rows are already upserted, so a failure is logged rather
+// than returned; at worst the next run repeats the batch.
+func (s *Service) saveCheckpoint(ctx context.Context, cp Checkpoint) {
+	if s.checkpoints == nil {
+		return
+	}
+	cp.UpdatedAt = time.Now().UTC()
+	name := CheckpointName(cp.Dataset, cp.Split)
+	if _, err := s.checkpoints.Set(ctx, name, &cp); err != nil {
+		log.Printf("Warning: failed to record checkpoint %s: %v", name, err)
+	}
+}
@@ -0,0 +1,85 @@
+package dataset_loader_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dllewellyn/reflex/internal/app/dataset_loader"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/state"
+)
+
+func TestService_RunManifest_ResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	for shard := 0; shard < 2; shard++ {
+		var lines []string
+		for row := 0; row < 3; row++ {
+			lines = append(lines, fmt.Sprintf(`{"text": "shard %d row %d", "label": 1}`, shard, row))
+		}
+		path := filepath.Join(dir, fmt.Sprintf("train-%05d.jsonl", shard))
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := &dataset_loader.Manifest{Datasets: []dataset_loader.Dataset{{ID: "file://" + dir}}}
+	cfg := dataset_loader.Config{BatchSize: 2, HFSplit: "train", HFTextCol: "text", HFLabelCol: "label", Labels: map[string]string{"0": "benign", "1": "injection"}}
+	checkpoints := state.NewMemoryStore()
+
+	var upserted []string
+	failAfter := 1
+	store := &MockVectorStore{
+		UpsertInputsFunc: func(ctx context.Context, inputs []*pinecone.InputRecord) error {
+			if failAfter == 0 {
+				return errors.New("index unavailable")
+			}
+			failAfter--
+			for _, input := range inputs {
+				upserted = append(upserted, input.Text)
+			}
+			return nil
+		},
+	}
+	svc := dataset_loader.NewService(cfg, dataset_loader.NewSources(&MockDownloader{}), store, checkpoints)
+
+	// The second batch fails, leaving a checkpoint after the first.
+	if _, err := svc.RunManifest(context.Background(), manifest); err == nil {
+		t.Fatal("Expected the interrupted load to fail")
+	}
+	if len(upserted) != 2 {
+		t.Fatalf("Expected 2 records before the failure, got %v", upserted)
+	}
+
+	failAfter = -1
+	summary, err := svc.RunManifest(context.Background(), manifest)
+	if err != nil {
+		t.Fatalf("Resumed load failed: %v", err)
+	}
+	if len(upserted) != 6 || upserted[2] != "shard 0 row 2" {
+		t.Errorf("Expected the remaining 4 records without repeats, got %v", upserted)
+	}
+	result := summary.Datasets[0]
+	if result.ResumedFrom == nil || filepath.Base(result.ResumedFrom.Shard) != "train-00000.jsonl" || result.ResumedFrom.Row != 2 {
+		t.Errorf("Expected to resume from row 2 of the first shard, got %+v", result.ResumedFrom)
+	}
+	if summary.Processed != 6 || summary.Upserted != 6 || summary.Skipped != 0 || summary.Failed != 0 {
+		t.Errorf("Expected the report to cover both runs, got %+v", summary)
+	}
+
+	// A finished dataset is skipped until the load is restarted.
+	summary, err = svc.RunManifest(context.Background(), manifest)
+	if err != nil || !summary.Datasets[0].AlreadyLoaded || len(upserted) != 6 {
+		t.Errorf("Expected the loaded dataset to be skipped, got %+v (err %v)", summary, err)
+	}
+
+	cfg.Restart = true
+	svc = dataset_loader.NewService(cfg, dataset_loader.NewSources(&MockDownloader{}), store, checkpoints)
+	summary, err = svc.RunManifest(context.Background(), manifest)
+	if err != nil || summary.Processed != 6 || len(upserted) != 12 {
+		t.Errorf("Expected a restart to load every row again, got %+v (err %v, %d upserted)", summary, err, len(upserted))
+	}
+}
@@ -0,0 +1,462 @@
+package dataset_loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter selects the rows of a dataset to load. It is parsed from an expression such as
+//
+//	lang == "en" and (score >= 0.5 or source in ("redteam", "bounty")) and len(text) <= 4000
+//
+// Expressions combine comparisons with and, or, not and parentheses. A comparison relates a
+// column, len(column) (its length in characters), a number, a quoted string or true/false with
+// ==, !=, <, <=, > or >=, or tests membership with in (...) and not in (...). Values that both
+// look like numbers are compared as numbers, so 1, 1.0 and "1" are equal; anything else is
+// compared as text with ==, != and in, while <, <=, > and >= never match text. A comparison with a
+// missing column only matches with != and not in.
+type Filter struct {
+	expr     string
+	root     condition
+	matchAll bool
+}
+
+// ParseFilter parses a filter expression. An empty expression matches every row.
+func ParseFilter(expr string) (*Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return &Filter{matchAll: true}, nil
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// equalsFilter matches rows whose column formats as value, the behaviour of HF_FILTER_COL and
+// HF_FILTER_VAL.
+func equalsFilter(column, value string) *Filter {
+	return &Filter{
+		expr: fmt.Sprintf("%s == %q", column, value),
+		root: &comparison{left: columnOperand(column), op: "==", right: literalOperand{v: value}},
+	}
+}
+
+// Match reports whether row passes the filter.
+func (f *Filter) Match(row SourceRecord) bool {
+	return f.matchAll || f.root.eval(row)
+}
+
+// String returns the expression the filter was parsed from.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+type condition interface {
+	eval(row SourceRecord) bool
+}
+
+type andCondition struct{ left, right condition }
+
+func (c *andCondition) eval(row SourceRecord) bool { return c.left.eval(row) && c.right.eval(row) }
+
+type orCondition struct{ left, right condition }
+
+func (c *orCondition) eval(row SourceRecord) bool { return c.left.eval(row) || c.right.eval(row) }
+
+type notCondition struct{ inner condition }
+
+func (c *notCondition) eval(row SourceRecord) bool { return !c.inner.eval(row) }
+
+type comparison struct {
+	left, right operand
+	op          string
+}
+
+func (c *comparison) eval(row SourceRecord) bool {
+	left, right := c.left.value(row), c.right.value(row)
+	switch c.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+	l, lok := number(left)
+	r, rok := number(right)
+	if !lok || !rok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+type inCondition struct {
+	left   operand
+	values []operand
+	negate bool
+}
+
+func (c *inCondition) eval(row SourceRecord) bool {
+	left := c.left.value(row)
+	for _, v := range c.values {
+		if equal(left, v.value(row)) {
+			return !c.negate
+		}
+	}
+	return c.negate
+}
+
+// operand yields a value from a row; nil means the column is missing.
+type operand interface {
+	value(row SourceRecord) interface{}
+}
+
+type columnOperand string
+
+func (o columnOperand) value(row SourceRecord) interface{} { return row[string(o)] }
+
+type lenOperand string
+
+func (o lenOperand) value(row SourceRecord) interface{} {
+	v, ok := row[string(o)]
+	if !ok || v == nil {
+		return nil
+	}
+	return float64(utf8.RuneCountInString(text(v)))
+}
+
+type literalOperand struct{ v interface{} }
+
+func (o literalOperand) value(SourceRecord) interface{} { return o.v }
+
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x == y
+		}
+	}
+	return text(a) == text(b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func text(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:j])})
+			i = j
+		default:
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); two == "==" || two == "!=" || two == "<=" || two == ">=" {
+					tokens = append(tokens, token{kind: tokenSymbol, text: two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("<>(),[]", r) {
+				return nil, fmt.Errorf("unexpected %q at position %d", r, i)
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(r)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.pos]
+}
+
+// keyword reports whether the next token is the keyword word, consuming it if so.
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); !p.done() && t.kind == tokenIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// symbol reports whether the next token is sym, consuming it if so.
+func (p *parser) symbol(sym string) bool {
+	if t := p.peek(); !p.done() && t.kind == tokenSymbol && t.text == sym {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(sym string) error {
+	if p.symbol(sym) {
+		return nil
+	}
+	if p.done() {
+		return fmt.Errorf("expected %q at end of expression", sym)
+	}
+	return fmt.Errorf("expected %q, got %q", sym, p.peek().text)
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.keyword("not") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notCondition{inner: inner}, nil
+	}
+	if p.symbol("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	negate := p.keyword("not")
+	if p.keyword("in") {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inCondition{left: left, values: values, negate: negate}, nil
+	}
+	if negate {
+		return nil, fmt.Errorf("expected \"in\" after \"not\"")
+	}
+	op := p.peek()
+	if p.done() || op.kind != tokenSymbol || !isComparison(op.text) {
+		if p.done() {
+			return nil, fmt.Errorf("expected a comparison at end of expression")
+		}
+		return nil, fmt.Errorf("expected a comparison, got %q", op.text)
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{left: left, op: op.text, right: right}, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseList() ([]operand, error) {
+	closing := ")"
+	if p.symbol("[") {
+		closing = "]"
+	} else if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []operand
+	for {
+		v, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.symbol(closing) {
+			return values, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	if p.done() {
+		return nil, fmt.Errorf("expected a value at end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokenString:
+		return literalOperand{v: t.text}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literalOperand{v: n}, nil
+	case tokenIdent:
+		switch strings.ToLower(t.text) {
+		case "true", "false":
+			return literalOperand{v: strings.ToLower(t.text)}, nil
+		case "len":
+			if !p.symbol("(") {
+				break
+			}
+			column := p.peek()
+			if p.done() || column.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a column in len()")
+			}
+			p.pos++
+			return lenOperand(column.text), p.expect(")")
+		case "and", "or", "not", "in":
+			return nil, fmt.Errorf("expected a value, got %q", t.text)
+		}
+		return columnOperand(t.text), nil
+	}
+	return nil, fmt.Errorf("expected a value, got %q", t.text)
+}
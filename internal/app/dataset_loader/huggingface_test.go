@@ -0,0 +1,266 @@
+package dataset_loader_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/dataset_loader"
+	"github.com/dllewellyn/reflex/internal/platform/huggingface"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/parquet-go/parquet-go"
+)
+
+// fakeHub serves dataset info and files the way the HuggingFace Hub does, for a private dataset
+// that requires a token.
+type fakeHub struct {
+	mu        sync.Mutex
+	token     string
+	infos     map[string]map[string]any // "<repo>@<revision>" -> info
+	files     map[string][]byte         // request path -> content
+	downloads []string
+}
+
+func (h *fakeHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+h.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/datasets/") && strings.Contains(r.URL.Path, "/revision/"):
+		repo, revision, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/datasets/"), "/revision/")
+		info, ok := h.infos[repo+"@"+revision]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(info)
+	default:
+		content, ok := h.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h.mu.Lock()
+		h.downloads = append(h.downloads, r.URL.Path)
+		h.mu.Unlock()
+		_, _ = w.Write(content)
+	}
+}
+
+func siblings(names ...string) []map[string]string {
+	var out []map[string]string
+	for _, name := range names {
+		out = append(out, map[string]string{"rfilename": name})
+	}
+	return out
+}
+
+func TestService_RunManifest_HuggingFaceShards(t *testing.T) {
+	type row struct {
+		Text  string `parquet:"text"`
+		Label int64  `parquet:"label"`
+	}
+	parquetPath := filepath.Join(t.TempDir(), "0.parquet")
+	if err := parquet.WriteFile(parquetPath, []row{{Text: "converted", Label: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := os.ReadFile(parquetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := &fakeHub{
+		token: "hf_secret",
+		infos: map[string]map[string]any{
+			"org/sharded@main": {"sha": "c0ffee", "siblings": siblings(
+				"README.md",
+				"data/train-00001-of-00002.jsonl",
+				"data/train-00000-of-00002.jsonl",
+				"data/train_adversarial-00000-of-00001.jsonl",
+				"data/test-00000-of-00001.jsonl",
+			)},
+			"org/sharded@v1":     {"sha": "0ld", "siblings": siblings("data/train-00000-of-00001.jsonl")},
+			"org/converted@main": {"sha": "beef", "siblings": siblings("README.md", "loader.py")},
+			// The conversion keeps its own commit; other configurations and splits are ignored.
+			"org/converted@refs/convert/parquet": {"sha": "c0nv", "siblings": siblings(
+				".gitattributes",
+				"default/train/0000.parquet",
+				"default/test/0000.parquet",
+				"other/train/0000.parquet",
+			)},
+		},
+		files: map[string][]byte{
+			"/datasets/org/sharded/resolve/c0ffee/data/train-00000-of-00002.jsonl":             []byte(`{"text": "shard zero", "label": 1}` + "\n"),
+			"/datasets/org/sharded/resolve/c0ffee/data/train-00001-of-00002.jsonl":             []byte(`{"text": "shard one", "label": 1}` + "\n"),
+			"/datasets/org/sharded/resolve/c0ffee/data/train_adversarial-00000-of-00001.jsonl": []byte(`{"text": "adversarial", "label": 1}` + "\n"),
+			"/datasets/org/sharded/resolve/c0ffee/data/test-00000-of-00001.jsonl":              []byte(`{"text": "test row", "label": 0}` + "\n"),
+			"/datasets/org/sharded/resolve/0ld/data/train-00000-of-00001.jsonl":                []byte(`{"text": "old revision", "label": 1}` + "\n"),
+			"/datasets/org/converted/resolve/c0nv/default/train/0000.parquet":                  converted,
+		},
+	}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	var texts []string
+	store := &MockVectorStore{
+		UpsertInputsFunc: func(ctx context.Context, inputs []*pinecone.InputRecord) error {
+			for _, input := range inputs {
+				texts = append(texts, input.Text)
+			}
+			return nil
+		},
+	}
+	cacheDir := t.TempDir()
+	client := huggingface.NewClient(&http.Client{Timeout: time.Minute}, huggingface.Config{
+		Endpoint: server.URL,
+		Token:    "hf_secret",
+		CacheDir: cacheDir,
+	})
+	svc := dataset_loader.NewService(dataset_loader.Config{BatchSize: 10, HFSplit: "train", HFTextCol: "text", HFLabelCol: "label", Labels: map[string]string{"0": "benign", "1": "injection"}}, client, store, nil)
+	manifest := &dataset_loader.Manifest{Datasets: []dataset_loader.Dataset{
+		{ID: "org/sharded"},
+		{ID: "org/sharded@v1"},
+		{ID: "org/converted"},
+	}}
+
+	summary, err := svc.RunManifest(context.Background(), manifest)
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v (summary %+v)", err, summary)
+	}
+	// Both shards, in order; not train_adversarial or test.
+	want := []string{"shard zero", "shard one", "old revision", "converted"}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, texts)
+	}
+	if len(hub.downloads) != 4 {
+		t.Errorf("Expected 4 downloads, got %v", hub.downloads)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "org", "sharded", "c0ffee", "data", "train-00000-of-00002.jsonl")); err != nil {
+		t.Errorf("Expected shard cached by commit: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "org", "converted", "c0nv", "default", "train", "0000.parquet")); err != nil {
+		t.Errorf("Expected converted file cached by the conversion's commit: %v", err)
+	}
+
+	// A second run reads every file from the cache.
+	hub.downloads = nil
+	texts = nil
+	if _, err := svc.RunManifest(context.Background(), manifest); err != nil {
+		t.Fatalf("Expected success from cache, got error: %v", err)
+	}
+	if len(hub.downloads) != 0 {
+		t.Errorf("Expected no downloads, got %v", hub.downloads)
+	}
+	if len(texts) != len(want) {
+		t.Errorf("Expected %v from cache, got %v", want, texts)
+	}
+}
+
+func TestHuggingFace_RequiresToken(t *testing.T) {
+	hub := &fakeHub{token: "hf_secret", infos: map[string]map[string]any{
+		"org/private@main": {"sha": "1", "siblings": siblings("train.jsonl")},
+	}}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	client := huggingface.NewClient(&http.Client{Timeout: time.Minute}, huggingface.Config{Endpoint: server.URL})
+	_, err := client.DownloadAndRead(context.Background(), "org/private", "train")
+	if err == nil || !strings.Contains(err.Error(), "private or gated") {
+		t.Errorf("Expected an error suggesting a token, got: %v", err)
+	}
+}
+
+func TestHuggingFace_ConvertedParquetRevision(t *testing.T) {
+	type row struct {
+		Text string `parquet:"text"`
+	}
+	parquetPath := filepath.Join(t.TempDir(), "0.parquet")
+	if err := parquet.WriteFile(parquetPath, []row{{Text: "converted"}}); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := os.ReadFile(parquetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := &fakeHub{
+		token: "hf_secret",
+		infos: map[string]map[string]any{
+			"org/converted@main":                 {"sha": "beef", "siblings": siblings("loader.py")},
+			"org/converted@v1":                   {"sha": "0ld", "siblings": siblings("loader.py")},
+			"org/converted@refs/convert/parquet": {"sha": "c0nv", "siblings": siblings("default/train/0000.parquet")},
+		},
+		files: map[string][]byte{
+			"/datasets/org/converted/resolve/c0nv/default/train/0000.parquet": converted,
+		},
+	}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	client := huggingface.NewClient(&http.Client{Timeout: time.Minute}, huggingface.Config{Endpoint: server.URL, Token: "hf_secret"})
+
+	// The conversion reflects main, not the pinned revision, so it is not read in its place.
+	_, err = client.DownloadAndRead(context.Background(), "org/converted@v1", "train")
+	if err == nil || !strings.Contains(err.Error(), "converts only main") {
+		t.Errorf("Expected a pinned revision without files to be rejected, got: %v", err)
+	}
+	if len(hub.downloads) != 0 {
+		t.Errorf("Expected no downloads, got %v", hub.downloads)
+	}
+
+	// At main, positions record the conversion's commit rather than main's.
+	reader, err := client.DownloadAndRead(context.Background(), "org/converted@main", "train")
+	if err != nil {
+		t.Fatalf("Expected the converted files at main, got error: %v", err)
+	}
+	defer reader.Close()
+	rows := make([]map[string]interface{}, 10)
+	if n, err := reader.Read(rows); n != 1 || rows[0]["text"] != "converted" {
+		t.Fatalf("Expected the converted row, got %d rows (%v): %v", n, rows[:n], err)
+	}
+	positioned, ok := reader.(interface{ Position() huggingface.Position })
+	if !ok {
+		t.Fatalf("Expected a reader with positions, got %T", reader)
+	}
+	if got := positioned.Position().Revision; got != "c0nv" {
+		t.Errorf("Expected position at the conversion's commit c0nv, got %q", got)
+	}
+}
+
+func TestSplitFiles(t *testing.T) {
+	files := []string{
+		"README.md",
+		"data/train-00001-of-00002.parquet",
+		"data/train-00000-of-00002.parquet",
+		"data/train-00000-of-00001.jsonl",
+		"data/train_adversarial-00000-of-00001.parquet",
+		"default/validation/0000.parquet",
+		"test.csv",
+	}
+	cases := map[string][]string{
+		"train":      {"data/train-00000-of-00002.parquet", "data/train-00001-of-00002.parquet"},
+		"validation": {"default/validation/0000.parquet"},
+		"test":       {"test.csv"},
+		"dev":        nil,
+	}
+	for split, want := range cases {
+		got := huggingface.SplitFiles(files, split)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("SplitFiles(%q) = %v, want %v", split, got, want)
+		}
+	}
+
+	unsplit := []string{"b.jsonl", "a.jsonl"}
+	got := huggingface.SplitFiles(unsplit, "train")
+	sort.Strings(unsplit)
+	if strings.Join(got, ",") != strings.Join(unsplit, ",") {
+		t.Errorf("Expected an unsplit repository to be read as train, got %v", got)
+	}
+}
@@ -0,0 +1,69 @@
+package dataset_loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Canonical labels stored on every record, whatever the source dataset calls them.
+const (
+	LabelInjection = "injection"
+	LabelJailbreak = "jailbreak"
+	LabelBenign    = "benign"
+)
+
+// CanonicalLabels lists the labels a record may be stored with.
+var CanonicalLabels = []string{LabelInjection, LabelJailbreak, LabelBenign}
+
+// labelAliases maps label values that mean the same thing in every dataset, compared case
+// insensitively, to canonical labels. Values whose meaning depends on the dataset, such as 0 and
+// 1, need an entry in the dataset's label mapping.
+var labelAliases = map[string]string{
+	"injection":        LabelInjection,
+	"prompt_injection": LabelInjection,
+	"prompt-injection": LabelInjection,
+	"jailbreak":        LabelJailbreak,
+	"benign":           LabelBenign,
+	"safe":             LabelBenign,
+	"legit":            LabelBenign,
+	"legitimate":       LabelBenign,
+}
+
+// IsCanonicalLabel reports whether label is one of CanonicalLabels.
+func IsCanonicalLabel(label string) bool {
+	for _, l := range CanonicalLabels {
+		if label == l {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLabel maps a dataset's label value to a canonical label, first through mapping (keyed
+// by the value as it is formatted, e.g. "1" or "true") and then through the aliases shared by all
+// datasets. It fails for a value neither recognises.
+func NormalizeLabel(value string, mapping map[string]string) (string, error) {
+	if label, ok := mapping[value]; ok {
+		return label, nil
+	}
+	if label, ok := labelAliases[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return label, nil
+	}
+	return "", fmt.Errorf("label %q has no canonical mapping", value)
+}
+
+// validateLabels checks that mapping only maps to canonical labels.
+func validateLabels(mapping map[string]string) error {
+	var invalid []string
+	for value, label := range mapping {
+		if !IsCanonicalLabel(label) {
+			invalid = append(invalid, fmt.Sprintf("%q: %q", value, label))
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Strings(invalid)
+	return fmt.Errorf("labels must map to one of %s, not %s", strings.Join(CanonicalLabels, ", "), strings.Join(invalid, ", "))
+}
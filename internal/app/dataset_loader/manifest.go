@@ -0,0 +1,116 @@
+package dataset_loader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lists datasets to load in one run, in order:
+//
+//	datasets:
+//	  - id: deepset/prompt-injections
+//	    license: apache-2.0
+//	    labels: {"0": benign, "1": injection}
+//	  - id: jackhhao/jailbreak-classification
+//	    split: test
+//	    text_column: prompt
+//	    label_column: type
+//	    filter: len(prompt) <= 4000
+type Manifest struct {
+	Datasets []Dataset `yaml:"datasets"`
+}
+
+// Dataset is one dataset in a Manifest. Empty fields take their value from Config.
+type Dataset struct {
+	ID          string `yaml:"id"`
+	Split       string `yaml:"split"`
+	TextColumn  string `yaml:"text_column"`
+	LabelColumn string `yaml:"label_column"`
+	// Filter is an expression selecting the rows to load; see ParseFilter.
+	Filter string `yaml:"filter"`
+	// FilterColumn and FilterValue load only rows whose column has the value. They predate
+	// Filter and cannot be combined with it.
+	FilterColumn string `yaml:"filter_column"`
+	FilterValue  string `yaml:"filter_value"`
+	// Labels maps the dataset's label values, formatted as strings, to canonical labels. Values
+	// without an entry are normalised by NormalizeLabel; rows whose label cannot be are rejected.
+	Labels map[string]string `yaml:"labels"`
+	// License is recorded on each record as provenance.
+	License string `yaml:"license"`
+}
+
+// LoadManifest reads a Manifest from a YAML file.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Validate checks that the manifest lists at least one dataset and that each has an ID, a valid
+// filter and a label mapping to canonical labels.
+func (m *Manifest) Validate() error {
+	if len(m.Datasets) == 0 {
+		return errors.New("no datasets listed")
+	}
+	var errs []error
+	for i, d := range m.Datasets {
+		if d.ID == "" {
+			errs = append(errs, fmt.Errorf("dataset %d has no id", i+1))
+		}
+		if _, err := d.filter(); err != nil {
+			errs = append(errs, fmt.Errorf("dataset %d: %w", i+1, err))
+		}
+		if err := validateLabels(d.Labels); err != nil {
+			errs = append(errs, fmt.Errorf("dataset %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// filter compiles the dataset's row filter.
+func (d Dataset) filter() (*Filter, error) {
+	if d.Filter != "" && d.FilterColumn != "" {
+		return nil, errors.New("filter and filter_column cannot both be set")
+	}
+	if d.FilterColumn != "" {
+		return equalsFilter(d.FilterColumn, d.FilterValue), nil
+	}
+	return ParseFilter(d.Filter)
+}
+
+// withDefaults returns d with empty fields filled from cfg.
+func (d Dataset) withDefaults(cfg Config) Dataset {
+	if d.ID == "" {
+		d.ID = cfg.HFDatasetID
+	}
+	if d.Split == "" {
+		d.Split = cfg.HFSplit
+	}
+	if d.TextColumn == "" {
+		d.TextColumn = cfg.HFTextCol
+	}
+	if d.LabelColumn == "" {
+		d.LabelColumn = cfg.HFLabelCol
+	}
+	if d.Filter == "" && d.FilterColumn == "" {
+		d.Filter = cfg.Filter
+		d.FilterColumn = cfg.HFFilterCol
+		d.FilterValue = cfg.HFFilterVal
+	}
+	if d.Labels == nil {
+		d.Labels = cfg.Labels
+	}
+	return d
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/dllewellyn/reflex/internal/platform/huggingface"
 	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/state"
 )
 
 // Downloader defines the interface for downloading and reading datasets.
@@ -21,20 +22,56 @@ type Service struct {
 	config      Config
 	hfClient    Downloader
 	vectorStore pinecone.VectorStore
+	// checkpoints records progress through each dataset; nil disables resuming.
+	checkpoints state.Store
 }
 
-// NewService creates a new instance of the dataset loader service.
-func NewService(cfg Config, hfClient Downloader, vectorStore pinecone.VectorStore) *Service {
+// NewService creates a new instance of the dataset loader service. checkpoints may be nil, in
+// which case every run starts each dataset from its first row.
+func NewService(cfg Config, hfClient Downloader, vectorStore pinecone.VectorStore, checkpoints state.Store) *Service {
 	return &Service{
 		config:      cfg,
 		hfClient:    hfClient,
 		vectorStore: vectorStore,
+		checkpoints: checkpoints,
 	}
 }
 
-// Run executes the dataset loading process.
+// Summary reports what a run loaded, per dataset and in total. Row counts include progress made
+// by earlier runs of a resumed dataset.
+type Summary struct {
+	Datasets  []DatasetSummary `json:"datasets"`
+	Processed int              `json:"processed"`
+	Upserted  int              `json:"upserted"`
+	Skipped   int              `json:"skipped"`
+	// Failed is the number of rows that could not be mapped to a record, including rows rejected
+	// because their label has no canonical mapping.
+	Failed int `json:"failed"`
+	// FailedDatasets is the number of datasets that could not be loaded completely.
+	FailedDatasets int `json:"failed_datasets"`
+}
+
+// DatasetSummary reports what a run loaded from one dataset.
+type DatasetSummary struct {
+	ID        string `json:"id"`
+	Split     string `json:"split"`
+	Processed int    `json:"processed"`
+	Upserted  int    `json:"upserted"`
+	Skipped   int    `json:"skipped"`
+	Failed    int    `json:"failed"`
+	// ResumedFrom is where an interrupted load was resumed.
+	ResumedFrom *huggingface.Position `json:"resumed_from,omitempty"`
+	// AlreadyLoaded is set when the dataset was loaded completely by an earlier run.
+	AlreadyLoaded bool   `json:"already_loaded,omitempty"`
+	Error         string `json:"error,omitempty"`
+
+	// upserted counts the records upserted by this run alone.
+	upserted int
+}
+
+// Run executes the dataset loading process for the dataset in the service's config.
 func (s *Service) Run(ctx context.Context) error {
-	log.Printf("Starting dataset loader service with config: %+v\n", s.config)
+	log.Printf("Starting dataset loader service for dataset %s (split: %s)", s.config.HFDatasetID, s.config.HFSplit)
 
 	// Get initial index stats
 	initialStats, err := s.vectorStore.DescribeIndexStats(ctx)
@@ -43,24 +80,136 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 	log.Printf("Initial index record count: %d", initialStats.TotalVectorCount)
 
+	summary, err := s.loadDataset(ctx, Dataset{}.withDefaults(s.config))
+	if err != nil {
+		return err
+	}
+	return s.verify(ctx, initialStats.TotalVectorCount, summary.upserted)
+}
+
+// RunManifest loads each dataset in manifest in turn. A dataset that fails is recorded in the
+// summary and the run moves on to the next; an error is returned once all have been tried.
+func (s *Service) RunManifest(ctx context.Context, manifest *Manifest) (*Summary, error) {
+	log.Printf("Starting dataset loader service for %d datasets", len(manifest.Datasets))
+
+	initialStats, err := s.vectorStore.DescribeIndexStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial index stats: %w", err)
+	}
+	log.Printf("Initial index record count: %d", initialStats.TotalVectorCount)
+
+	summary := &Summary{}
+	upserted := 0
+	for _, dataset := range manifest.Datasets {
+		result, err := s.loadDataset(ctx, dataset.withDefaults(s.config))
+		if err != nil {
+			log.Printf("Failed to load dataset %s (split: %s): %v", result.ID, result.Split, err)
+			result.Error = err.Error()
+			summary.FailedDatasets++
+		}
+		summary.Datasets = append(summary.Datasets, result)
+		summary.Processed += result.Processed
+		summary.Upserted += result.Upserted
+		summary.Skipped += result.Skipped
+		summary.Failed += result.Failed
+		upserted += result.upserted
+		if ctx.Err() != nil {
+			return summary, ctx.Err()
+		}
+	}
+
+	if err := s.verify(ctx, initialStats.TotalVectorCount, upserted); err != nil {
+		return summary, err
+	}
+	if summary.FailedDatasets > 0 {
+		return summary, fmt.Errorf("%d of %d datasets failed to load", summary.FailedDatasets, len(manifest.Datasets))
+	}
+	return summary, nil
+}
+
+// loadDataset downloads one dataset and upserts its rows, tagging each record with where it came
+// from. Progress is checkpointed after every batch and an interrupted load resumes from the last
+// checkpoint. The summary counts what was done before any error.
+func (s *Service) loadDataset(ctx context.Context, dataset Dataset) (DatasetSummary, error) {
+	summary := DatasetSummary{ID: dataset.ID, Split: dataset.Split}
+
+	cp, err := s.loadCheckpoint(ctx, dataset)
+	if err != nil {
+		return summary, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	filter, err := dataset.filter()
+	if err != nil {
+		return summary, err
+	}
+	if err := validateLabels(dataset.Labels); err != nil {
+		return summary, err
+	}
+
+	mapping := s.config
+	mapping.HFTextCol = dataset.TextColumn
+	mapping.HFLabelCol = dataset.LabelColumn
+
+	provenance := map[string]interface{}{
+		"source":       dataset.ID,
+		"source_split": dataset.Split,
+		"ingested_at":  time.Now().UTC().Format(time.RFC3339),
+	}
+	if dataset.License != "" {
+		provenance["license"] = dataset.License
+	}
+
 	// 1. Download and open the dataset file (Parquet, JSONL, or JSON)
-	log.Printf("Downloading dataset %s (split: %s)...", s.config.HFDatasetID, s.config.HFSplit)
-	reader, err := s.hfClient.DownloadAndRead(ctx, s.config.HFDatasetID, s.config.HFSplit)
+	log.Printf("Downloading dataset %s (split: %s)...", dataset.ID, dataset.Split)
+	reader, err := s.hfClient.DownloadAndRead(ctx, dataset.ID, dataset.Split)
 	if err != nil {
-		return fmt.Errorf("failed to download dataset: %w", err)
+		return summary, fmt.Errorf("failed to download dataset: %w", err)
 	}
 	defer reader.Close()
 
-	// 2. Process rows in batches
+	// Readers that know their position report the revision and shard; for others, position is
+	// the number of rows read.
+	seeker, _ := reader.(huggingface.Seeker)
+	position := func(rowsRead int64) huggingface.Position {
+		if seeker != nil {
+			return seeker.Position()
+		}
+		return huggingface.Position{Row: rowsRead}
+	}
+	if !cp.UpdatedAt.IsZero() && cp.Position.Revision != position(0).Revision {
+		log.Printf("Dataset %s changed since it was checkpointed (revision %q, now %q), starting over", dataset.ID, cp.Position.Revision, position(0).Revision)
+		cp = Checkpoint{Dataset: dataset.ID, Split: dataset.Split}
+	}
+
+	// 2. Resume from the checkpoint
+	summary.Processed, summary.Upserted, summary.Skipped, summary.Failed = cp.Processed, cp.Upserted, cp.Skipped, cp.Failed
+	if cp.Done {
+		log.Printf("Dataset %s (split: %s) was already loaded, skipping", dataset.ID, dataset.Split)
+		summary.AlreadyLoaded = true
+		return summary, nil
+	}
+	rowsRead := int64(0)
+	if cp.Position.Shard != "" || cp.Position.Row > 0 {
+		log.Printf("Resuming dataset %s from %+v", dataset.ID, cp.Position)
+		resumed := cp.Position
+		summary.ResumedFrom = &resumed
+		if seeker != nil {
+			if err := seeker.Seek(cp.Position); err != nil {
+				return summary, fmt.Errorf("failed to resume from checkpoint: %w", err)
+			}
+		} else if err := discard(reader, cp.Position.Row); err != nil {
+			return summary, fmt.Errorf("failed to resume from checkpoint: %w", err)
+		}
+		rowsRead = cp.Position.Row
+	}
+
+	// 3. Process rows in batches
 	batchSize := s.config.BatchSize
 	rows := make([]map[string]interface{}, batchSize)
 
-	totalProcessed := 0
-	totalUpserted := 0
-	totalSkipped := 0
-
 	for {
 		n, err := reader.Read(rows)
+		rowsRead += int64(n)
 		if n > 0 {
 			// Process the batch
 			inputs := make([]*pinecone.InputRecord, 0, n)
@@ -68,29 +217,31 @@ func (s *Service) Run(ctx context.Context) error {
 			for i := 0; i < n; i++ {
 				row := rows[i]
 
-				// Apply filter if configured
-				if s.config.HFFilterCol != "" {
-					val, ok := row[s.config.HFFilterCol]
-					if !ok {
-						// Filter column missing, treat as mismatch/skip? Or error?
-						// Let's safe skip and log widely if needed, but for now just skip.
-						totalSkipped++
-						continue
-					}
-
-					// Convert to string for comparison
-					strVal := fmt.Sprintf("%v", val)
-					if strVal != s.config.HFFilterVal {
-						totalSkipped++
-						continue
-					}
+				if !filter.Match(SourceRecord(row)) {
+					summary.Skipped++
+					continue
 				}
 
-				record, err := MapRowToIngestionRecord(SourceRecord(row), s.config)
+				record, err := MapRowToIngestionRecord(SourceRecord(row), mapping)
 				if err != nil {
 					log.Printf("Warning: skipping row due to error: %v", err)
+					summary.Failed++
 					continue
 				}
+				label, err := NormalizeLabel(record.Label, dataset.Labels)
+				if err != nil {
+					log.Printf("Warning: rejecting row %s: %v", record.ID, err)
+					summary.Failed++
+					continue
+				}
+				if label != record.Label {
+					record.Metadata["source_label"] = record.Label
+					record.Label = label
+					record.Metadata["label"] = label
+				}
+				for k, v := range provenance {
+					record.Metadata[k] = v
+				}
 
 				inputs = append(inputs, &pinecone.InputRecord{
 					ID:       record.ID,
@@ -103,7 +254,7 @@ func (s *Service) Run(ctx context.Context) error {
 			// Deduplication: Check for existing records
 			existingVectors, err := s.vectorStore.Fetch(ctx, inputIDs)
 			if err != nil {
-				return fmt.Errorf("failed to check for existing records: %w", err)
+				return summary, fmt.Errorf("failed to check for existing records: %w", err)
 			}
 
 			newInputs := make([]*pinecone.InputRecord, 0, len(inputs))
@@ -111,7 +262,7 @@ func (s *Service) Run(ctx context.Context) error {
 				if _, exists := existingVectors[input.ID]; !exists {
 					newInputs = append(newInputs, input)
 				} else {
-					totalSkipped++
+					summary.Skipped++
 				}
 			}
 
@@ -119,12 +270,13 @@ func (s *Service) Run(ctx context.Context) error {
 			if len(newInputs) > 0 {
 				log.Printf("Upserting batch of %d records (skipped %d duplicates)...", len(newInputs), len(inputs)-len(newInputs))
 				if err := s.vectorStore.UpsertInputs(ctx, newInputs); err != nil {
-					return fmt.Errorf("failed to upsert batch: %w", err)
+					return summary, fmt.Errorf("failed to upsert batch: %w", err)
 				}
-				totalUpserted += len(newInputs)
+				summary.Upserted += len(newInputs)
+				summary.upserted += len(newInputs)
 
 				// Immediate verification check for the first batch or periodically
-				if totalUpserted == len(newInputs) {
+				if summary.upserted == len(newInputs) {
 					// Check a few IDs to see if they exist
 					checkIDs := []string{newInputs[0].ID}
 					found, err := s.vectorStore.Fetch(ctx, checkIDs)
@@ -140,27 +292,60 @@ func (s *Service) Run(ctx context.Context) error {
 			} else {
 				log.Printf("Skipped all %d records in batch as duplicates.", len(inputs))
 			}
-			totalProcessed += len(inputs)
-			log.Printf("Processed %d records so far (upserted: %d, skipped: %d)", totalProcessed, totalUpserted, totalSkipped)
+			summary.Processed += len(inputs)
+			log.Printf("Processed %d records so far (upserted: %d, skipped: %d, failed: %d)", summary.Processed, summary.Upserted, summary.Skipped, summary.Failed)
+
+			cp.Position = position(rowsRead)
+			cp.Processed, cp.Upserted, cp.Skipped, cp.Failed = summary.Processed, summary.Upserted, summary.Skipped, summary.Failed
+			s.saveCheckpoint(ctx, cp)
 		}
 		if err != nil {
 			if err == context.Canceled {
-				return ctx.Err()
+				return summary, ctx.Err()
 			}
 			if err == io.EOF {
 				break
 			}
 			if err == context.DeadlineExceeded {
-				return err
+				return summary, err
 			}
 
 			// Return any other error
-			return fmt.Errorf("error reading dataset: %w", err)
+			return summary, fmt.Errorf("error reading dataset: %w", err)
 		}
 	}
 
-	log.Printf("Dataset ingestion complete. Total processed: %d, Upserted: %d, Skipped: %d", totalProcessed, totalUpserted, totalSkipped)
+	cp.Position = position(rowsRead)
+	cp.Done = true
+	s.saveCheckpoint(ctx, cp)
+
+	log.Printf("Dataset %s ingestion complete. Total processed: %d, Upserted: %d, Skipped: %d, Failed: %d", dataset.ID, summary.Processed, summary.Upserted, summary.Skipped, summary.Failed)
+	return summary, nil
+}
+
+// discard reads and drops n rows, to resume a reader that cannot seek.
+func discard(reader huggingface.DatasetReader, n int64) error {
+	buf := make([]map[string]interface{}, 256)
+	for n > 0 {
+		want := n
+		if want > int64(len(buf)) {
+			want = int64(len(buf))
+		}
+		read, err := reader.Read(buf[:want])
+		n -= int64(read)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// verify checks the index grew by the number of records upserted. Index updates are eventually
+// consistent, so a mismatch is only logged.
+func (s *Service) verify(ctx context.Context, initialCount uint32, upserted int) error {
 	// Verification with delay
 	log.Println("Waiting 5 seconds for index consistency before final verification...")
 	select {
@@ -175,7 +360,7 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 	log.Printf("Final index record count: %d", finalStats.TotalVectorCount)
 
-	expectedCount := int(initialStats.TotalVectorCount) + totalUpserted
+	expectedCount := int(initialCount) + upserted
 	if int(finalStats.TotalVectorCount) != expectedCount {
 		log.Printf("Warning: Verification mismatch. Expected %d records, found %d. (Note: Index updates might be eventually consistent)", expectedCount, finalStats.TotalVectorCount)
 	} else {
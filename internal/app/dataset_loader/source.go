@@ -0,0 +1,27 @@
+package dataset_loader
+
+import (
+	"context"
+
+	"github.com/dllewellyn/reflex/internal/platform/huggingface"
+)
+
+// Sources picks a Downloader for each dataset by its ID: file:// IDs are read from local files,
+// anything else is downloaded from HuggingFace.
+type Sources struct {
+	remote Downloader
+	local  Downloader
+}
+
+// NewSources creates Sources reading remote datasets with remote and local ones with
+// huggingface.LocalClient.
+func NewSources(remote Downloader) *Sources {
+	return &Sources{remote: remote, local: huggingface.NewLocalClient()}
+}
+
+func (s *Sources) DownloadAndRead(ctx context.Context, datasetID, split string) (huggingface.DatasetReader, error) {
+	if huggingface.IsLocal(datasetID) {
+		return s.local.DownloadAndRead(ctx, datasetID, split)
+	}
+	return s.remote.DownloadAndRead(ctx, datasetID, split)
+}
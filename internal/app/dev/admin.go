@@ -0,0 +1,123 @@
+package dev
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/review"
+)
+
+// Handler serves the ingestor API alongside the admin endpoints:
+//
+//	GET  /admin/status                      the clock, job runs, consumer lag and vector count
+//	POST /admin/clock/advance?by=1h         advance the clock, running the jobs that fall due
+//	POST /admin/jobs/{name}?date=2025-12-12 run load, judge, extract or pipeline now
+//	GET  /admin/review                      list the extracted injections waiting for approval
+//	POST /admin/review/approve?ids=a,b      approve them into the vector store
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", s.ingestor.Handler())
+	mux.HandleFunc("GET /admin/status", s.handleStatus)
+	mux.HandleFunc("POST /admin/clock/advance", s.handleAdvance)
+	mux.HandleFunc("POST /admin/jobs/{name}", s.handleRunJob)
+	mux.HandleFunc("GET /admin/review", s.handleListReview)
+	mux.HandleFunc("POST /admin/review/approve", s.handleApprove)
+	return mux
+}
+
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeStatus(w, r)
+}
+
+func (s *Service) handleAdvance(w http.ResponseWriter, r *http.Request) {
+	by, err := time.ParseDuration(r.URL.Query().Get("by"))
+	if err != nil || by <= 0 {
+		http.Error(w, "by must be a positive duration, e.g. 1h or 24h", http.StatusBadRequest)
+		return
+	}
+	if err := s.Advance(r.Context(), by); err != nil {
+		slog.Error("Failed to advance dev clock", "by", by, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeStatus(w, r)
+}
+
+func (s *Service) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch name {
+	case JobLoad, JobJudge, JobExtract, JobPipeline:
+	default:
+		http.Error(w, "unknown job "+name, http.StatusNotFound)
+		return
+	}
+
+	var date time.Time
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		var err error
+		if date, err = time.Parse(time.DateOnly, raw); err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.RunJob(r.Context(), name, date); err != nil {
+		slog.Error("Dev job failed", "job", name, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeStatus(w, r)
+}
+
+func (s *Service) handleListReview(w http.ResponseWriter, r *http.Request) {
+	pending, err := s.review.List(r.Context(), review.StatusPending)
+	if err != nil {
+		slog.Error("Failed to list review queue", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(pending); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
+
+func (s *Service) handleApprove(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "ids must list the candidates to approve", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.Approve(r.Context(), ids); errors.Is(err, review.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		slog.Error("Failed to approve candidates", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeStatus(w, r)
+}
+
+func (s *Service) writeStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.Status(r.Context())
+	if err != nil {
+		slog.Error("Failed to get dev status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,38 @@
+package dev
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the simulated time dev mode runs on. It only moves when advanced, so hourly and
+// daily jobs run when asked rather than on the wall clock.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a Clock reading start, in UTC.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start.UTC()}
+}
+
+// Now returns the simulated time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d and returns the times before and after.
+func (c *Clock) Advance(d time.Duration) (from, to time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from = c.now
+	c.now = c.now.Add(d)
+	return from, c.now
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,75 @@
+package dev
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/platform/genai"
+	"gopkg.in/yaml.v3"
+)
+
+// Script scripts the fake LLM that stands in for the judge and extractor models:
+//
+//	judge:
+//	  - match: '(?i)ignore previous instructions'
+//	    response: '{"is_prompt_injection": true, "confidence": 0.9, "severity": "high"}'
+//	  - response: '{"is_prompt_injection": false}'
+//	extract:
+//	  - match: '(?i)[^"\n]*ignore previous instructions[^"\n]*'
+//	    response: '{{.Match}}'
+//	  - response: None
+//
+// Judge rules see the user turns of each batch request (the system instruction is left out, as
+// its examples would match most rules); extract rules see the full extraction prompt. See
+// genai.Rule for the template fields.
+type Script struct {
+	Judge   []genai.Rule `yaml:"judge"`
+	Extract []genai.Rule `yaml:"extract"`
+}
+
+// attackPhrases are the phrases the default script treats as prompt injection.
+const attackPhrases = `ignore (?:all |any )?(?:previous|prior|above) instructions|developer mode|reveal (?:your|the) system prompt|you are now DAN`
+
+// DefaultScript flags transcripts containing a few well-known attack phrases and extracts the
+// messages that contain them.
+func DefaultScript() Script {
+	return Script{
+		Judge: []genai.Rule{
+			{
+				Match:    `(?i)` + attackPhrases,
+				Response: `{"is_prompt_injection": true, "confidence": 0.95, "severity": "high", "analysis": "Scripted verdict: the transcript contains a known attack phrase."}`,
+			},
+			{
+				Response: `{"is_prompt_injection": false, "confidence": 0.9, "severity": "none", "analysis": "Scripted verdict: no known attack phrase."}`,
+			},
+		},
+		Extract: []genai.Rule{
+			{
+				// Transcripts are JSON lines, so a message's content ends at a quote.
+				Match:    `(?i)[^"\n]*(?:` + attackPhrases + `)[^"\n]*`,
+				Response: "{{range .Matches}}{{.}}\n{{end}}",
+			},
+			{Response: "None"},
+		},
+	}
+}
+
+// LoadScript reads a Script from a YAML file. Sections the file leaves out keep their defaults.
+func LoadScript(path string) (Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Script{}, fmt.Errorf("failed to read script: %w", err)
+	}
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return Script{}, fmt.Errorf("failed to parse script: %w", err)
+	}
+	defaults := DefaultScript()
+	if len(script.Judge) == 0 {
+		script.Judge = defaults.Judge
+	}
+	if len(script.Extract) == 0 {
+		script.Extract = defaults.Extract
+	}
+	return script, nil
+}
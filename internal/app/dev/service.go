@@ -0,0 +1,393 @@
+// Package dev runs the whole pipeline in one process for local development: the ingestor API,
+// the loader, the daily judge batch job and the extractor, wired to an in-memory Kafka broker,
+// a filesystem blob store, an in-memory vector store and a scripted LLM. What the judge flags is
+// approved without review; missed attacks reported through /feedback wait in a review queue.
+//
+// Time is simulated. Events are stamped with the dev clock, and jobs that run hourly or daily in
+// production run when the clock is advanced past an hour or day boundary, or on demand through
+// the admin endpoints, so a day of traffic can be pushed through the pipeline in seconds.
+package dev
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/batch"
+	"github.com/dllewellyn/reflex/internal/app/extract"
+	"github.com/dllewellyn/reflex/internal/app/ingestor"
+	"github.com/dllewellyn/reflex/internal/app/loader"
+	"github.com/dllewellyn/reflex/internal/app/review"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/genai"
+	"github.com/dllewellyn/reflex/internal/platform/kafka"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/state"
+)
+
+// Job names accepted by RunJob.
+const (
+	JobLoad     = "load"
+	JobJudge    = "judge"
+	JobExtract  = "extract"
+	JobPipeline = "pipeline"
+)
+
+const (
+	loaderGroup  = "loader-consumer"
+	extractGroup = "extract-injections-consumer"
+
+	stagingBucket = "staging"
+	resultsBucket = "results"
+	reviewBucket  = "review"
+
+	// reviewer is recorded on the candidates approved through the admin endpoints.
+	reviewer = "dev"
+)
+
+type Config struct {
+	// DataDir holds the raw archive, staged batch inputs, batch results and the review queue.
+	// Required.
+	DataDir string
+	Port    string
+	// Topic carries interaction events. Defaults to "raw-interactions".
+	Topic string
+	// ResultsTopic carries batch result events. Defaults to "batch-results".
+	ResultsTopic string
+	// FeedbackTopic carries feedback posted to /feedback. Defaults to "feedback".
+	FeedbackTopic string
+	// FeedbackToken is the bearer token /feedback requires; /feedback is disabled without it.
+	FeedbackToken string
+	Layout        archive.Layout
+	Format        archive.Format
+
+	JudgePromptPath   string
+	ExtractPromptPath string
+	JudgeModel        string
+	// Script scripts the fake LLM. Defaults to DefaultScript.
+	Script Script
+	// Start is the initial simulated time. Defaults to the current time.
+	Start time.Time
+}
+
+// JobStatus records the runs of one job.
+type JobStatus struct {
+	Runs int `json:"runs"`
+	// LastRun is the simulated time of the last run.
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Status is a snapshot of the dev pipeline.
+type Status struct {
+	Now  time.Time            `json:"now"`
+	Jobs map[string]JobStatus `json:"jobs"`
+	// Lag is the number of messages each consumer has yet to commit.
+	Lag     map[string]int64 `json:"lag"`
+	Vectors uint32           `json:"vectors"`
+	// PendingReview is the number of extracted injections waiting for approval.
+	PendingReview int `json:"pending_review"`
+	// LLMCalls is the number of prompts the scripted judge and extractor have answered.
+	LLMCalls map[string]int `json:"llm_calls"`
+}
+
+type Service struct {
+	clock        *Clock
+	broker       *kafka.MemoryBroker
+	vectorStore  *pinecone.MemoryStore
+	ingestor     *ingestor.Service
+	loader       *loader.Service
+	judge        *batch.Service
+	extractor    *extract.Processor
+	review       *review.Queue
+	judgeLLM     *genai.ScriptedClient
+	extractLLM   *genai.ScriptedClient
+	topic        string
+	resultsTopic string
+	port         string
+
+	// mu serialises job runs, as the production schedules never overlap them.
+	mu   sync.Mutex
+	jobs map[string]*JobStatus
+}
+
+// NewService wires the pipeline. redactor may be nil, in which case prompts are published verbatim.
+func NewService(redactor ingestor.Redactor, cfg Config) (*Service, error) {
+	if cfg.DataDir == "" {
+		return nil, errors.New("dev mode requires a data directory")
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = "raw-interactions"
+	}
+	if cfg.ResultsTopic == "" {
+		cfg.ResultsTopic = "batch-results"
+	}
+	if cfg.FeedbackTopic == "" {
+		cfg.FeedbackTopic = "feedback"
+	}
+	if cfg.Script.Judge == nil && cfg.Script.Extract == nil {
+		cfg.Script = DefaultScript()
+	}
+	if cfg.Start.IsZero() {
+		cfg.Start = time.Now()
+	}
+
+	rawStore, err := gcs.NewFileClient(filepath.Join(cfg.DataDir, "archive"))
+	if err != nil {
+		return nil, err
+	}
+	stagingStore, err := gcs.NewFileClient(filepath.Join(cfg.DataDir, stagingBucket))
+	if err != nil {
+		return nil, err
+	}
+	resultsStore, err := gcs.NewFileClient(filepath.Join(cfg.DataDir, resultsBucket))
+	if err != nil {
+		return nil, err
+	}
+	reviewStore, err := gcs.NewFileClient(filepath.Join(cfg.DataDir, reviewBucket))
+	if err != nil {
+		return nil, err
+	}
+
+	judgeLLM, err := genai.NewScriptedClient(cfg.Script.Judge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid judge script: %w", err)
+	}
+	extractLLM, err := genai.NewScriptedClient(cfg.Script.Extract)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extract script: %w", err)
+	}
+
+	prompt, err := batch.LoadPrompt(cfg.JudgePromptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load judge prompt: %w", err)
+	}
+
+	clock := NewClock(cfg.Start)
+	broker := kafka.NewMemoryBroker()
+	vectorStore := pinecone.NewMemoryStore(nil)
+	checkpoints := state.NewMemoryStore()
+	queue := review.NewQueue(reviewStore, vectorStore, review.Config{Rules: review.Rules{All: true}, Now: clock.Now})
+
+	results := batch.NewBatchEventProducer(broker, cfg.ResultsTopic)
+	vertexClient := &localVertex{
+		staging: stagingStore,
+		results: resultsStore,
+		llm:     judgeLLM,
+		now:     clock.Now,
+		onOutput: func(ctx context.Context, bucket, key string) error {
+			data, err := resultsStore.Read(ctx, key)
+			if err != nil {
+				return err
+			}
+			count, err := results.PublishResults(ctx, bytes.NewReader(data), schema.Source{Bucket: bucket, File: key})
+			if err != nil {
+				return err
+			}
+			slog.Info("Published batch results", "file", key, "count", count)
+			return nil
+		},
+	}
+
+	extractCfg := extract.Config{
+		PromptPath: cfg.ExtractPromptPath,
+		KafkaTopic: cfg.ResultsTopic,
+	}
+	reader := extract.NewBrokerResultReader(broker.Consumer(extractGroup), cfg.ResultsTopic)
+
+	return &Service{
+		clock:       clock,
+		broker:      broker,
+		vectorStore: vectorStore,
+		ingestor: ingestor.NewService(broker, vectorStore, redactor, ingestor.Config{
+			TopicName:     cfg.Topic,
+			Port:          cfg.Port,
+			Now:           clock.Now,
+			FeedbackTopic: cfg.FeedbackTopic,
+			FeedbackToken: cfg.FeedbackToken,
+			ResultsTopic:  cfg.ResultsTopic,
+		}),
+		loader: loader.NewService(broker.Consumer(loaderGroup), rawStore, nil, checkpoints, loader.Config{
+			Topic:  cfg.Topic,
+			Layout: cfg.Layout,
+			Format: cfg.Format,
+		}),
+		judge: batch.NewService(batch.Config{
+			ProjectID:     "dev",
+			Location:      "local",
+			StagingBucket: stagingBucket,
+			OutputBucket:  resultsBucket,
+			ModelID:       cfg.JudgeModel,
+			Layout:        cfg.Layout,
+		}, prompt, rawStore, stagingStore, vertexClient, nil, nil, checkpoints),
+		extractor:    extract.NewProcessor(reader, extract.NewExtractor(extractLLM, cfg.ExtractPromptPath), vectorStore, checkpoints, queue, extractCfg),
+		review:       queue,
+		judgeLLM:     judgeLLM,
+		extractLLM:   extractLLM,
+		topic:        cfg.Topic,
+		resultsTopic: cfg.ResultsTopic,
+		port:         cfg.Port,
+		jobs:         make(map[string]*JobStatus),
+	}, nil
+}
+
+// Now returns the simulated time.
+func (s *Service) Now() time.Time {
+	return s.clock.Now()
+}
+
+// Advance moves the clock forward by d and runs the jobs that would have been scheduled in
+// between: the loader once if an hour boundary was crossed, then the judge and extractor for
+// each day that ended.
+func (s *Service) Advance(ctx context.Context, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("cannot move the clock backwards by %s", d)
+	}
+	from, to := s.clock.Advance(d)
+	slog.Info("Advanced dev clock", "from", from, "to", to)
+
+	if !to.Truncate(time.Hour).After(from.Truncate(time.Hour)) {
+		return nil
+	}
+	if err := s.RunJob(ctx, JobLoad, time.Time{}); err != nil {
+		return err
+	}
+	for day := startOfDay(from); !day.AddDate(0, 0, 1).After(to); day = day.AddDate(0, 0, 1) {
+		if err := s.RunJob(ctx, JobJudge, day); err != nil {
+			return err
+		}
+		if err := s.RunJob(ctx, JobExtract, time.Time{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunJob runs the named job now. date selects the day the judge analyses; if zero, the judge
+// analyses the current simulated day. The pipeline job runs load, judge and extract in turn.
+func (s *Service) RunJob(ctx context.Context, name string, date time.Time) error {
+	if date.IsZero() {
+		date = s.clock.Now()
+	}
+	date = startOfDay(date)
+
+	switch name {
+	case JobLoad:
+		return s.run(ctx, name, s.loader.RunOnce)
+	case JobJudge:
+		return s.run(ctx, name, func(ctx context.Context) error { return s.judge.Run(ctx, date) })
+	case JobExtract:
+		return s.run(ctx, name, s.extractor.Process)
+	case JobPipeline:
+		for _, job := range []string{JobLoad, JobJudge, JobExtract} {
+			if err := s.RunJob(ctx, job, date); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown job %q", name)
+	}
+}
+
+func (s *Service) run(ctx context.Context, name string, job func(context.Context) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slog.Info("Running dev job", "job", name, "now", s.clock.Now())
+	err := job(ctx)
+
+	status, ok := s.jobs[name]
+	if !ok {
+		status = &JobStatus{}
+		s.jobs[name] = status
+	}
+	now := s.clock.Now()
+	status.Runs++
+	status.LastRun = &now
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+		return fmt.Errorf("%s job failed: %w", name, err)
+	}
+	return nil
+}
+
+// Approve approves the pending review candidates with ids, upserting them into the vector store.
+// If some are not pending, it approves the others and returns an error wrapping
+// review.ErrNotFound.
+func (s *Service) Approve(ctx context.Context, ids []string) ([]*review.Candidate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.review.Approve(ctx, ids, reviewer, "")
+}
+
+// Status returns a snapshot of the clock, job runs, consumer lag, vector store and review queue.
+func (s *Service) Status(ctx context.Context) (*Status, error) {
+	stats, err := s.vectorStore.DescribeIndexStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.review.List(ctx, review.StatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	jobs := make(map[string]JobStatus, len(s.jobs))
+	for name, status := range s.jobs {
+		jobs[name] = *status
+	}
+	s.mu.Unlock()
+
+	return &Status{
+		Now:  s.clock.Now(),
+		Jobs: jobs,
+		Lag: map[string]int64{
+			JobLoad:    s.broker.Lag(loaderGroup, s.topic),
+			JobExtract: s.broker.Lag(extractGroup, s.resultsTopic),
+		},
+		Vectors:       stats.TotalVectorCount,
+		PendingReview: len(pending),
+		LLMCalls: map[string]int{
+			JobJudge:   s.judgeLLM.Calls(),
+			JobExtract: s.extractLLM.Calls(),
+		},
+	}, nil
+}
+
+// Run serves the ingestor API and the admin endpoints until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              ":" + s.port,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      5 * time.Minute,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	slog.Info("Starting dev server", "port", s.port, "now", s.clock.Now())
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Dev server shutdown failed", "error", err)
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package dev
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"github.com/dllewellyn/reflex/internal/app/batch"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/genai"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/vertex"
+	"github.com/google/uuid"
+	"github.com/googleapis/gax-go/v2"
+)
+
+// predictionsFile is the name of the output file localVertex writes under the output prefix.
+const predictionsFile = "predictions.jsonl"
+
+// localVertex runs batch prediction jobs in process. Each staged request is answered by the
+// judge LLM, the predictions are written to the results store in Vertex AI's output format, and
+// the file is handed to onOutput, as the Cloud Storage trigger would be.
+type localVertex struct {
+	staging  gcs.BlobReader
+	results  gcs.BlobWriter
+	llm      genai.ClientInterface
+	now      func() time.Time
+	onOutput func(ctx context.Context, bucket, key string) error
+}
+
+var _ vertex.JobClient = (*localVertex)(nil)
+
+// CreateBatchPredictionJob runs the job to completion before returning.
+func (v *localVertex) CreateBatchPredictionJob(ctx context.Context, req *aiplatformpb.CreateBatchPredictionJobRequest, opts ...gax.CallOption) (*aiplatformpb.BatchPredictionJob, error) {
+	job := req.GetBatchPredictionJob()
+	uris := job.GetInputConfig().GetGcsSource().GetUris()
+	if len(uris) != 1 {
+		return nil, fmt.Errorf("expected one input URI, got %d", len(uris))
+	}
+	_, inputKey, err := splitURI(uris[0])
+	if err != nil {
+		return nil, err
+	}
+	outputBucket, outputPrefix, err := splitURI(job.GetOutputConfig().GetGcsDestination().GetOutputUriPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	// Inputs are given as a directory wildcard, e.g. staging/2025/12/12/*.jsonl.
+	inputPrefix := inputKey
+	if i := strings.Index(inputPrefix, "*"); i >= 0 {
+		inputPrefix = inputPrefix[:i]
+	}
+	keys, err := v.staging.ListFiles(ctx, inputPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch inputs: %w", err)
+	}
+	sort.Strings(keys)
+
+	var out bytes.Buffer
+	count := 0
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".jsonl") {
+			continue
+		}
+		data, err := v.staging.Read(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch input %s: %w", key, err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var request batch.BatchRequest
+			if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+				return nil, fmt.Errorf("failed to parse batch input %s: %w", key, err)
+			}
+			line, err := json.Marshal(v.predict(ctx, job.GetModel(), request))
+			if err != nil {
+				return nil, err
+			}
+			out.Write(line)
+			out.WriteByte('\n')
+			count++
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read batch input %s: %w", key, err)
+		}
+	}
+
+	outputKey := strings.TrimSuffix(outputPrefix, "/") + "/" + predictionsFile
+	if err := v.results.Write(ctx, outputKey, out.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write predictions: %w", err)
+	}
+	slog.Info("Local batch prediction job complete", "display_name", job.GetDisplayName(), "requests", count, "output", outputKey)
+
+	if err := v.onOutput(ctx, outputBucket, outputKey); err != nil {
+		return nil, fmt.Errorf("failed to process predictions: %w", err)
+	}
+
+	return &aiplatformpb.BatchPredictionJob{
+		Name:        fmt.Sprintf("%s/batchPredictionJobs/%s", req.GetParent(), uuid.New().String()),
+		DisplayName: job.GetDisplayName(),
+		State:       aiplatformpb.JobState_JOB_STATE_SUCCEEDED,
+	}, nil
+}
+
+// predict answers one batch request. As in Vertex AI's output, a failed request is recorded
+// with its status rather than failing the job.
+func (v *localVertex) predict(ctx context.Context, model string, request batch.BatchRequest) schema.Record {
+	now := v.now()
+	record := schema.Record{ProcessedTime: &now, Request: &schema.RecordRequest{}}
+
+	var prompt []string
+	for _, content := range request.Request.Contents {
+		role := content.Role
+		elem := schema.RecordRequestContentsElem{Role: &role}
+		for _, part := range content.Parts {
+			text := part.Text
+			elem.Parts = append(elem.Parts, schema.RecordRequestContentsElemPartsElem{Text: &text})
+			prompt = append(prompt, part.Text)
+		}
+		record.Request.Contents = append(record.Request.Contents, elem)
+	}
+
+	text, err := v.llm.GenerateContent(ctx, model, strings.Join(prompt, "\n"))
+	if err != nil {
+		status := err.Error()
+		record.Status = &status
+		return record
+	}
+	record.Response = &schema.RecordResponse{
+		Candidates: []schema.RecordResponseCandidatesElem{{
+			Content: schema.RecordResponseCandidatesElemContent{
+				Parts: []schema.RecordResponseCandidatesElemContentPartsElem{{Text: text}},
+			},
+		}},
+	}
+	return record
+}
+
+// splitURI splits gs://bucket/key into its bucket and key.
+func splitURI(uri string) (bucket, key string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "gs" {
+		return "", "", fmt.Errorf("invalid GCS URI %q", uri)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
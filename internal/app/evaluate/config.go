@@ -0,0 +1,20 @@
+package evaluate
+
+import "time"
+
+// Config configures an evaluation run.
+type Config struct {
+	ProjectID string
+	Location  string
+	// PromptPath is the prompt file whose system message is evaluated.
+	PromptPath string
+	// TestDataPath is a JSON array of TestItems.
+	TestDataPath string
+	// OutputPath receives the results as JSON. Nothing is written if empty.
+	OutputPath string
+	// Models are evaluated in order. Models under publishers/mistralai/ are called through
+	// rawPredict, everything else through the Gemini API.
+	Models []string
+	// Interval is the delay between requests, to stay under rate limits.
+	Interval time.Duration
+}
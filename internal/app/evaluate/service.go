@@ -0,0 +1,186 @@
+// Package evaluate measures how well candidate judge models classify a labelled set of prompts
+// with the security judge prompt.
+package evaluate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrModelNotFound is returned by a Generator when the model does not exist or is not accessible.
+var ErrModelNotFound = errors.New("model not found")
+
+// Generator sends a prompt to a model and returns its text response.
+type Generator interface {
+	Generate(ctx context.Context, model, prompt string) (string, error)
+}
+
+type PromptConfig struct {
+	Messages []struct {
+		Role    string `yaml:"role"`
+		Content string `yaml:"content"`
+	} `yaml:"messages"`
+}
+
+type TestItem struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type JudgeResponse struct {
+	IsPromptInjection bool `json:"is_prompt_injection"`
+}
+
+type EvaluationResult struct {
+	ModelID   string  `json:"model_id"`
+	Accuracy  float64 `json:"accuracy"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1Score   float64 `json:"f1_score"`
+	Errors    int     `json:"errors"`
+}
+
+type Service struct {
+	generator Generator
+	config    Config
+	sleep     func(time.Duration)
+}
+
+func NewService(generator Generator, cfg Config) *Service {
+	return &Service{generator: generator, config: cfg, sleep: time.Sleep}
+}
+
+// Run evaluates every configured model against the test data and writes the results to
+// Config.OutputPath.
+func (s *Service) Run(ctx context.Context) ([]EvaluationResult, error) {
+	systemInstruction, err := loadSystemInstruction(s.config.PromptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	testDataBytes, err := os.ReadFile(s.config.TestDataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.config.TestDataPath, err)
+	}
+	var testItems []TestItem
+	if err := json.Unmarshal(testDataBytes, &testItems); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.config.TestDataPath, err)
+	}
+
+	results := []EvaluationResult{}
+	for _, model := range s.config.Models {
+		slog.Info("Evaluating model", "model", model, "prompts", len(testItems))
+		results = append(results, s.evaluateModel(ctx, model, systemInstruction, testItems))
+	}
+
+	if s.config.OutputPath != "" {
+		outBytes, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal results: %w", err)
+		}
+		if err := os.WriteFile(s.config.OutputPath, outBytes, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write results file: %w", err)
+		}
+	}
+	return results, nil
+}
+
+func loadSystemInstruction(path string) (string, error) {
+	promptBytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var promptCfg PromptConfig
+	if err := yaml.Unmarshal(promptBytes, &promptCfg); err != nil {
+		return "", fmt.Errorf("failed to parse prompt YAML: %w", err)
+	}
+	for _, msg := range promptCfg.Messages {
+		if msg.Role == "system" {
+			return msg.Content, nil
+		}
+	}
+	return "", fmt.Errorf("no system message found in %s", path)
+}
+
+func (s *Service) evaluateModel(ctx context.Context, model, systemInstruction string, testItems []TestItem) EvaluationResult {
+	var c confusion
+	errCount := 0
+
+	for _, item := range testItems {
+		// Rate limit
+		s.sleep(s.config.Interval)
+
+		fullPrompt := fmt.Sprintf("%s\n\nTranscript:\n%s", systemInstruction, item.Text)
+		respStr, err := s.generator.Generate(ctx, model, fullPrompt)
+		if err != nil {
+			if errors.Is(err, ErrModelNotFound) {
+				slog.Warn("Model not found or accessible, skipping", "model", model, "error", err)
+				errCount = len(testItems)
+				break
+			}
+			slog.Error("Error generating judgement", "model", model, "error", err)
+			errCount++
+			continue
+		}
+
+		var judgeResponse JudgeResponse
+		if err := json.Unmarshal([]byte(respStr), &judgeResponse); err != nil {
+			slog.Error("Error unmarshaling judge response", "model", model, "error", err, "response", respStr)
+			errCount++
+			continue
+		}
+		c.add(item.Type == "attack", judgeResponse.IsPromptInjection)
+	}
+
+	res := c.result(model)
+	res.Errors = errCount
+	return res
+}
+
+// confusion counts predictions against expectations, with attacks as the positive class.
+type confusion struct {
+	tp, fp, tn, fn int
+}
+
+func (c *confusion) add(expected, predicted bool) {
+	switch {
+	case expected && predicted:
+		c.tp++
+	case expected:
+		c.fn++
+	case predicted:
+		c.fp++
+	default:
+		c.tn++
+	}
+}
+
+func (c confusion) result(model string) EvaluationResult {
+	res := EvaluationResult{ModelID: model}
+	if total := c.tp + c.fp + c.tn + c.fn; total > 0 {
+		res.Accuracy = float64(c.tp+c.tn) / float64(total)
+	}
+	if c.tp+c.fp > 0 {
+		res.Precision = float64(c.tp) / float64(c.tp+c.fp)
+	}
+	if c.tp+c.fn > 0 {
+		res.Recall = float64(c.tp) / float64(c.tp+c.fn)
+	}
+	if res.Precision+res.Recall > 0 {
+		res.F1Score = 2 * (res.Precision * res.Recall) / (res.Precision + res.Recall)
+	}
+	return res
+}
+
+// isMistral reports whether model is served through rawPredict rather than the Gemini API.
+func isMistral(model string) bool {
+	return strings.HasPrefix(model, "publishers/mistralai/")
+}
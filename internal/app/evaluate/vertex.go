@@ -0,0 +1,122 @@
+package evaluate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/genai"
+)
+
+// VertexGenerator calls models on Vertex AI: Gemini models through the GenAI SDK and Mistral
+// models through rawPredict.
+type VertexGenerator struct {
+	client   *genai.Client
+	project  string
+	location string
+}
+
+func NewVertexGenerator(ctx context.Context, project, location string) (*VertexGenerator, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		Project:  project,
+		Location: location,
+		Backend:  genai.BackendVertexAI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GenAI client: %w", err)
+	}
+	return &VertexGenerator{client: client, project: project, location: location}, nil
+}
+
+func (g *VertexGenerator) Generate(ctx context.Context, model, prompt string) (string, error) {
+	if isMistral(model) {
+		return g.callMistral(ctx, model, prompt)
+	}
+
+	temp := float32(0.0)
+	resp, err := g.client.Models.GenerateContent(ctx, model, genai.Text(prompt), &genai.GenerateContentConfig{
+		Temperature: &temp,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
+			return "", fmt.Errorf("%w: %v", ErrModelNotFound, err)
+		}
+		return "", err
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from %s", model)
+	}
+	var respStr string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		respStr += part.Text
+	}
+	return respStr, nil
+}
+
+func (g *VertexGenerator) callMistral(ctx context.Context, model, prompt string) (string, error) {
+	// Endpoint: https://us-central1-aiplatform.googleapis.com/v1/projects/{PROJECT}/locations/us-central1/publishers/mistralai/models/{MODEL}:rawPredict
+	url := fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/%s:rawPredict", g.location, g.project, g.location, model)
+
+	bodyMap := map[string]interface{}{
+		"instances": []interface{}{
+			map[string]interface{}{
+				"messages": []interface{}{
+					map[string]interface{}{"role": "user", "content": prompt},
+				},
+			},
+		},
+		"parameters": map[string]interface{}{
+			"maxOutputTokens": 1024,
+			"temperature":     0.0,
+		},
+	}
+	jsonBody, _ := json.Marshal(bodyMap)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", err
+	}
+
+	creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return "", fmt.Errorf("auth error: %w", err)
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("token error: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s", ErrModelNotFound, model)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("api error status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// rawPredict usually returns {"predictions": ["response text"]}
+	var result map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return "", err
+	}
+	if preds, ok := result["predictions"].([]interface{}); ok && len(preds) > 0 {
+		if s, ok := preds[0].(string); ok {
+			return s, nil
+		}
+	}
+	return string(bodyBytes), nil
+}
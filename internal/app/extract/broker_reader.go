@@ -0,0 +1,55 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/dllewellyn/reflex/internal/platform/kafka"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// BrokerResultReader reads batch result events from an in-process kafka.MemoryBroker, for local
+// development. Unlike KafkaResultReader it does not wait for an idle timeout: the channel closes
+// once every result published so far has been delivered.
+type BrokerResultReader struct {
+	consumer *kafka.BrokerConsumer
+	topic    string
+}
+
+func NewBrokerResultReader(consumer *kafka.BrokerConsumer, topic string) *BrokerResultReader {
+	return &BrokerResultReader{consumer: consumer, topic: topic}
+}
+
+func (r *BrokerResultReader) ReadResults(ctx context.Context) (<-chan BatchResult, <-chan error, func()) {
+	out := make(chan BatchResult)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+
+		for _, msg := range r.consumer.Fetch(r.topic) {
+			msg := msg
+			commit := func() { r.consumer.CommitMessage(msg) }
+
+			var event schema.BatchResultEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				slog.Warn("Failed to unmarshal event", "error", err, "raw", string(msg.Value))
+				commit()
+				continue
+			}
+
+			result := resultFromEvent(event)
+			result.Commit = commit
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Results that were fetched but not committed are delivered again on the next read.
+	return out, errCh, func() { _ = r.consumer.Close() }
+}
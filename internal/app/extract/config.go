@@ -1,17 +1,31 @@
 package extract
 
 type Config struct {
-	GCPProjectID string `envconfig:"GCP_PROJECT_ID" required:"true"`
-	GCPLocation  string `envconfig:"GCP_LOCATION" default:"us-central1"`
+	GCPProjectID string
+	GCPLocation  string
 
-	PromptPath            string `envconfig:"PROMPT_PATH" default:"prompts/extract-injection.prompt.yml"`
-	PineconeAPIKey        string `envconfig:"PINECONE_API_KEY" required:"true"`
-	PineconeIndexHost     string `envconfig:"PINECONE_INDEX_HOST" required:"true"`
-	KafkaBootstrapServers string `envconfig:"KAFKA_BOOTSTRAP_SERVERS"`
-	KafkaTopic            string `envconfig:"KAFKA_TOPIC_BATCH_RESULTS"`
-	KafkaGroupID          string `envconfig:"KAFKA_CONSUMER_GROUP_ID" default:"extract-injections-group"`
-	KafkaAPIKey           string `envconfig:"KAFKA_API_KEY"`
-	KafkaAPISecret        string `envconfig:"KAFKA_API_SECRET"`
-	IdleTimeoutSeconds    int    `envconfig:"IDLE_TIMEOUT_SECONDS" default:"30"`
-	DryRun                bool   `envconfig:"DRY_RUN" default:"false"`
+	PromptPath            string
+	PineconeAPIKey        string
+	PineconeIndexHost     string
+	KafkaBootstrapServers string
+	KafkaTopic            string
+	KafkaAPIKey           string
+	KafkaAPISecret        string
+	IdleTimeoutSeconds    int
+	DryRun                bool
+	CheckpointStoreURL    string
+	// GroundingMinSimilarity is how closely an extracted line must match a user turn to be kept.
+	GroundingMinSimilarity float64
+	// DuplicateThreshold is the embedding similarity to a stored signature at which an extracted
+	// line is merged into it rather than upserted.
+	DuplicateThreshold float64
+	// RunDuplicateThreshold is the word similarity to a line kept earlier in the run at which an
+	// extracted line is merged into it rather than upserted.
+	RunDuplicateThreshold float64
+
+	// ReviewQueueURL holds extracted injections for review instead of upserting them; unset
+	// upserts them all.
+	ReviewQueueURL              string
+	ReviewAutoApproveConfidence float64
+	ReviewAutoApproveSeverities []string
 }
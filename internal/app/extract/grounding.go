@@ -0,0 +1,242 @@
+package extract
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// DefaultMinSimilarity is the word-level similarity an extracted line needs to the closest span
+// of a user turn to be kept, unless Config.GroundingMinSimilarity says otherwise.
+const DefaultMinSimilarity = 0.8
+
+const (
+	// maxGroundingWords is the longest line, in words, matched against spans of a turn rather
+	// than only verbatim. Both the line and the transcript come from outside, so the work of
+	// comparing them has to be bounded.
+	maxGroundingWords = 200
+	// maxGroundingCells bounds the edit distance cells computed grounding one line across all
+	// turns; past it, the closest span found so far is used.
+	maxGroundingCells = 1 << 24
+)
+
+// Turn is a user turn of a transcript, which extracted injections must come from.
+type Turn struct {
+	InteractionID  string
+	ConversationID string
+	Content        string
+}
+
+// UserTurns returns the user turns in transcript, whose lines are the JSON interaction events the
+// batch analyzer gave the judge. A transcript without any, such as a bare prompt, is one turn.
+func UserTurns(transcript string) []Turn {
+	var turns []Turn
+	parsed := false
+	for _, line := range strings.Split(transcript, "\n") {
+		start := strings.IndexByte(line, '{')
+		if start < 0 {
+			continue
+		}
+		var event schema.InteractionEvent
+		if err := json.Unmarshal([]byte(line[start:]), &event); err != nil || event.Content == "" {
+			continue
+		}
+		parsed = true
+		if event.Role != schema.RoleUser {
+			continue
+		}
+		turns = append(turns, Turn{InteractionID: event.InteractionId, ConversationID: event.ConversationId, Content: event.Content})
+	}
+	if !parsed {
+		return []Turn{{Content: transcript}}
+	}
+	return turns
+}
+
+// Grounding is where in a transcript an extracted line was found.
+type Grounding struct {
+	// Text is the span of the turn the line matched, which replaces the line when they differ.
+	Text string
+	Turn Turn
+	// Similarity is the word-level similarity between the line and Text, from 0 to 1.
+	Similarity float64
+}
+
+// Exact reports whether the line was found verbatim.
+func (g Grounding) Exact(line string) bool {
+	return g.Text == line
+}
+
+// Ground finds the span of turns most similar to line, comparing words without case or
+// surrounding punctuation, and reports whether it is at least minSimilarity. Spans between
+// three quarters and five quarters of the line's length are considered. A line of more than
+// maxGroundingWords words is only found verbatim.
+func Ground(line string, turns []Turn, minSimilarity float64) (Grounding, bool) {
+	for _, turn := range turns {
+		if line != "" && strings.Contains(turn.Content, line) {
+			return Grounding{Text: line, Turn: turn, Similarity: 1}, true
+		}
+	}
+
+	want := normalizedWords(line)
+	if len(want) == 0 || len(want) > maxGroundingWords {
+		return Grounding{}, false
+	}
+	var best Grounding
+	budget := maxGroundingCells
+	for _, turn := range turns {
+		floor := max(minSimilarity, best.Similarity)
+		if g, ok := closestSpan(want, turn, floor, &budget); ok && g.Similarity > best.Similarity {
+			best = g
+		}
+	}
+	return best, best.Similarity > 0 && best.Similarity >= minSimilarity
+}
+
+// word is a word of a turn, with the byte offsets of its span.
+type word struct {
+	norm       string
+	start, end int
+}
+
+// closestSpan returns the span of turn whose words are the fewest edits from want, skipping spans
+// that cannot reach floor. budget is the edit distance cells left to compute, which it reduces;
+// once it runs out, the closest span so far is returned.
+func closestSpan(want []string, turn Turn, floor float64, budget *int) (Grounding, bool) {
+	words := turnWords(turn.Content)
+	minLen, maxLen := len(want)-len(want)/4, len(want)+len(want)/4
+	if minLen < 1 {
+		minLen = 1
+	}
+
+	// Every word of want left unmatched is an edit, so a span starting at i has at most as many
+	// matches as the words of want among words[i:i+maxLen], and a similarity of at most
+	// 1 - (len(want) - matches) / maxLen. Starts that cannot reach floor are skipped without
+	// computing the edit distance.
+	inWant := make(map[string]bool, len(want))
+	for _, w := range want {
+		inWant[w] = true
+	}
+	matches := 0
+	for _, w := range words[:min(maxLen, len(words))] {
+		if inWant[w.norm] {
+			matches++
+		}
+	}
+
+	var best Grounding
+	found := false
+	prev := make([]int, maxLen+1)
+	cur := make([]int, maxLen+1)
+	for i := range words {
+		if i > 0 {
+			if inWant[words[i-1].norm] {
+				matches--
+			}
+			if end := i - 1 + maxLen; end < len(words) && inWant[words[end].norm] {
+				matches++
+			}
+		}
+		span := words[i:]
+		if len(span) > maxLen {
+			span = span[:maxLen]
+		}
+		if len(span) < minLen {
+			break
+		}
+		if 1-float64(len(want)-matches)/float64(maxLen) < max(floor, best.Similarity) {
+			continue
+		}
+		if *budget -= len(want) * len(span); *budget < 0 {
+			break
+		}
+		// prev[l] ends as the edit distance between want and the first l words of span.
+		for l := range prev[:len(span)+1] {
+			prev[l] = l
+		}
+		for r, w := range want {
+			cur[0] = r + 1
+			for l, s := range span {
+				cost := 1
+				if s.norm == w {
+					cost = 0
+				}
+				cur[l+1] = min(prev[l]+cost, prev[l+1]+1, cur[l]+1)
+			}
+			prev, cur = cur, prev
+		}
+		for l := minLen; l <= len(span); l++ {
+			similarity := 1 - float64(prev[l])/float64(max(len(want), l))
+			if similarity > best.Similarity {
+				best = Grounding{Text: turn.Content[span[0].start:span[l-1].end], Turn: turn, Similarity: similarity}
+				found = true
+			}
+		}
+	}
+	return best, found
+}
+
+// turnWords splits text on white space, dropping words that are only punctuation.
+func turnWords(text string) []word {
+	var words []word
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if norm := normalizeWord(text[start:end]); norm != "" {
+			words = append(words, word{norm: norm, start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			flush(i)
+		} else if start < 0 {
+			start = i
+		}
+	}
+	flush(len(text))
+	return words
+}
+
+// wordSimilarity returns 1 less the word-level edit distance between a and b over the length of
+// the longer, the measure lines are grounded by.
+func wordSimilarity(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i, w := range a {
+		cur[0] = i + 1
+		for j, v := range b {
+			cost := 1
+			if v == w {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j]+cost, prev[j+1]+1, cur[j]+1)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(b)])/float64(max(len(a), len(b)))
+}
+
+func normalizedWords(text string) []string {
+	var words []string
+	for _, w := range turnWords(text) {
+		words = append(words, w.norm)
+	}
+	return words
+}
+
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
@@ -100,68 +100,13 @@ func (k *KafkaResultReader) ReadResults(ctx context.Context) (<-chan BatchResult
 					continue
 				}
 
-				// The record field is now strongly typed in the generated code.
-				// We can access it directly.
-
-				slog.Info("Received event", "event_id", event.EventId)
-
-				// Validation logic adjusted for the new schema structure
-				if event.Record.Request == nil || len(event.Record.Request.Contents) == 0 {
-					slog.Warn("Validation failed: Request has no contents", "event_id", event.EventId, "source", event.Source)
-				} else if len(event.Record.Request.Contents[0].Parts) == 0 {
-					slog.Warn("Validation failed: Request content has no parts", "event_id", event.EventId, "source", event.Source)
-				}
-
-				if event.Record.Response == nil || len(event.Record.Response.Candidates) == 0 {
-					slog.Warn("Validation failed: Response has no candidates", "event_id", event.EventId, "source", event.Source)
-				} else if len(event.Record.Response.Candidates[0].Content.Parts) == 0 {
-					slog.Warn("Validation failed: Response candidate has no content parts", "event_id", event.EventId, "source", event.Source)
-				}
-
-				// Safely access fields given pointers in generated code
-				var candidates []Candidate
-				if event.Record.Response != nil {
-					for _, c := range event.Record.Response.Candidates {
-						// c.Content is a value of type CandidateContent
-						// We need to map it to our domain Content (which has Parts []Part)
-						// Our domain Part has Text string.
-
-						var parts []Part
-						for _, p := range c.Content.Parts {
-							// p is ContentPartsPart, p.Text is string
-							parts = append(parts, Part{Text: p.Text})
-						}
-						// domain.Content
-						content := Content{Parts: parts}
-						candidates = append(candidates, Candidate{Content: content})
-					}
-				}
-
-				var contents []Content
-				if event.Record.Request != nil {
-					for _, c := range event.Record.Request.Contents {
-						var parts []Part
-						for _, p := range c.Parts {
-							if p.Text != nil {
-								// p.Text in generated Request/Content/Part is *string
-								parts = append(parts, Part{Text: *p.Text})
-							}
-						}
-						contents = append(contents, Content{Parts: parts})
+				result := resultFromEvent(event)
+				result.Commit = func() {
+					if _, err := c.CommitMessage(msg); err != nil {
+						slog.Error("Failed to commit message", "error", err, "event_id", event.EventId)
 					}
 				}
 
-				result := BatchResult{
-					EventID:  event.EventId,
-					Response: Response{Candidates: candidates},
-					Request:  Request{Contents: contents},
-					Commit: func() {
-						if _, err := c.CommitMessage(msg); err != nil {
-							slog.Error("Failed to commit message", "error", err, "event_id", event.EventId)
-						}
-					},
-				}
-
 				select {
 				case out <- result:
 				case <-ctx.Done():
@@ -173,3 +118,61 @@ func (k *KafkaResultReader) ReadResults(ctx context.Context) (<-chan BatchResult
 
 	return out, errCh, teardown
 }
+
+// resultFromEvent converts a batch result event into a BatchResult, logging records that are
+// missing the parts the processor needs.
+func resultFromEvent(event schema.BatchResultEvent) BatchResult {
+	slog.Info("Received event", "event_id", event.EventId)
+
+	// Validation logic adjusted for the new schema structure
+	if event.Record.Request == nil || len(event.Record.Request.Contents) == 0 {
+		slog.Warn("Validation failed: Request has no contents", "event_id", event.EventId, "source", event.Source)
+	} else if len(event.Record.Request.Contents[0].Parts) == 0 {
+		slog.Warn("Validation failed: Request content has no parts", "event_id", event.EventId, "source", event.Source)
+	}
+
+	if event.Record.Response == nil || len(event.Record.Response.Candidates) == 0 {
+		slog.Warn("Validation failed: Response has no candidates", "event_id", event.EventId, "source", event.Source)
+	} else if len(event.Record.Response.Candidates[0].Content.Parts) == 0 {
+		slog.Warn("Validation failed: Response candidate has no content parts", "event_id", event.EventId, "source", event.Source)
+	}
+
+	// Safely access fields given pointers in generated code
+	var candidates []Candidate
+	if event.Record.Response != nil {
+		for _, c := range event.Record.Response.Candidates {
+			// c.Content is a value of type CandidateContent
+			// We need to map it to our domain Content (which has Parts []Part)
+			// Our domain Part has Text string.
+
+			var parts []Part
+			for _, p := range c.Content.Parts {
+				// p is ContentPartsPart, p.Text is string
+				parts = append(parts, Part{Text: p.Text})
+			}
+			// domain.Content
+			content := Content{Parts: parts}
+			candidates = append(candidates, Candidate{Content: content})
+		}
+	}
+
+	var contents []Content
+	if event.Record.Request != nil {
+		for _, c := range event.Record.Request.Contents {
+			var parts []Part
+			for _, p := range c.Parts {
+				if p.Text != nil {
+					// p.Text in generated Request/Content/Part is *string
+					parts = append(parts, Part{Text: *p.Text})
+				}
+			}
+			contents = append(contents, Content{Parts: parts})
+		}
+	}
+
+	return BatchResult{
+		EventID:  event.EventId,
+		Response: Response{Candidates: candidates},
+		Request:  Request{Contents: contents},
+	}
+}
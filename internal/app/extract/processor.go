@@ -10,23 +10,148 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dllewellyn/reflex/internal/app/review"
 	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/state"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DefaultDuplicateThreshold is the similarity at which an extracted injection is merged into the
+// stored signature it resembles, unless Config.DuplicateThreshold says otherwise.
+const DefaultDuplicateThreshold = 0.95
+
+// DefaultRunDuplicateThreshold is the word similarity at which an extracted injection is merged
+// into one kept earlier in the run, unless Config.RunDuplicateThreshold says otherwise. Words are
+// compared by edit distance rather than embedding, so one word changed in a line of seven or
+// more merges, much as it would with a stored signature.
+const DefaultRunDuplicateThreshold = 0.85
+
+// Metadata fields counting how often an extracted signature, or a near-duplicate of it, was seen.
+const (
+	seenCountField = "seen_count"
+	lastSeenField  = "last_seen"
 )
 
 type Processor struct {
-	reader    ResultReader
-	extractor *Extractor
-	pinecone  pinecone.VectorStore
-	config    Config
+	reader      ResultReader
+	extractor   *Extractor
+	pinecone    pinecone.VectorStore
+	checkpoints state.Store
+	queue       ReviewQueue
+	config      Config
+
+	grounded   metric.Int64Counter
+	signatures metric.Int64Counter
+	// run counts what the current run extracted.
+	run runStats
+	// accepted holds the records the current run is upserting, which may not be searchable in
+	// the store yet, for near-duplicates later in the run to be merged into.
+	accepted []acceptedRecord
+}
+
+// acceptedRecord is a record accepted earlier in the run, with its normalized words.
+type acceptedRecord struct {
+	record *pinecone.InputRecord
+	words  []string
+}
+
+// runStats counts extracted lines by whether they were found in their transcript, and the
+// signatures made from them by whether they were new or merged into a near-duplicate.
+type runStats struct {
+	exact, repaired, hallucinated int64
+	new, merged                   int64
+}
+
+// HallucinationRate is the fraction of extracted lines that were not found in their transcript.
+func (g runStats) HallucinationRate() float64 {
+	total := g.exact + g.repaired + g.hallucinated
+	if total == 0 {
+		return 0
+	}
+	return float64(g.hallucinated) / float64(total)
+}
+
+// ReviewQueue holds extracted injections for review. Submit returns the candidates that need no
+// review, which are upserted straight away.
+type ReviewQueue interface {
+	Submit(ctx context.Context, candidates []*review.Candidate) ([]*review.Candidate, error)
 }
 
-func NewProcessor(reader ResultReader, extractor *Extractor, pc pinecone.VectorStore, cfg Config) *Processor {
+// NewProcessor creates a Processor. checkpoints may be nil to skip recording progress, and queue
+// may be nil to upsert every extracted injection without review.
+func NewProcessor(reader ResultReader, extractor *Extractor, pc pinecone.VectorStore, checkpoints state.Store, queue ReviewQueue, cfg Config) *Processor {
+	if cfg.GroundingMinSimilarity <= 0 {
+		cfg.GroundingMinSimilarity = DefaultMinSimilarity
+	}
+	if cfg.DuplicateThreshold <= 0 {
+		cfg.DuplicateThreshold = DefaultDuplicateThreshold
+	}
+	if cfg.RunDuplicateThreshold <= 0 {
+		cfg.RunDuplicateThreshold = DefaultRunDuplicateThreshold
+	}
+	meter := otel.Meter("extract-processor")
+	grounded, err := meter.Int64Counter("extract.grounding.lines",
+		metric.WithDescription("Extracted lines by grounding outcome: exact, repaired or hallucinated"))
+	if err != nil {
+		slog.Warn("Failed to create grounding metric", "error", err)
+	}
+	signatures, err := meter.Int64Counter("extract.signatures",
+		metric.WithDescription("Extracted signatures by outcome: new or merged into a near-duplicate"))
+	if err != nil {
+		slog.Warn("Failed to create signature metric", "error", err)
+	}
 	return &Processor{
-		reader:    reader,
-		extractor: extractor,
-		pinecone:  pc,
-		config:    cfg,
+		reader:      reader,
+		extractor:   extractor,
+		pinecone:    pc,
+		checkpoints: checkpoints,
+		queue:       queue,
+		config:      cfg,
+		grounded:    grounded,
+		signatures:  signatures,
+	}
+}
+
+// Checkpoint is the extractor's record of what it has processed, stored under CheckpointName.
+// It is advanced each time upserted records are committed.
+type Checkpoint struct {
+	LastEventID string    `json:"last_event_id"`
+	Results     int64     `json:"results"`
+	Upserted    int64     `json:"upserted"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CheckpointName is the name of the extractor's checkpoint for topic.
+func CheckpointName(topic string) string {
+	return "extract/" + topic
+}
+
+// progress counts what has been committed since the last checkpoint.
+type progress struct {
+	lastEventID string
+	results     int64
+	upserted    int64
+}
+
+// recordCheckpoint adds committed progress to the checkpoint. Offsets are already committed, so
+// a failure is logged rather than returned.
+func (p *Processor) recordCheckpoint(ctx context.Context, done progress) {
+	if p.checkpoints == nil || done.results == 0 {
+		return
+	}
+	name := CheckpointName(p.config.KafkaTopic)
+	_, err := state.Update(ctx, p.checkpoints, name, func(cp *Checkpoint) error {
+		cp.LastEventID = done.lastEventID
+		cp.Results += done.results
+		cp.Upserted += done.upserted
+		cp.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to record checkpoint", "checkpoint", name, "error", err)
 	}
 }
 
@@ -38,9 +163,23 @@ func (p *Processor) Process(ctx context.Context) error {
 	results, errCh, teardown := p.reader.ReadResults(ctx)
 	defer teardown()
 
+	p.run = runStats{}
+	p.accepted = nil
+	defer func() {
+		slog.Info("Extraction summary",
+			"exact", p.run.exact,
+			"repaired", p.run.repaired,
+			"hallucinated", p.run.hallucinated,
+			"hallucination_rate", p.run.HallucinationRate(),
+			"new", p.run.new,
+			"merged", p.run.merged)
+		span.SetAttributes(attribute.Float64("hallucination_rate", p.run.HallucinationRate()))
+	}()
+
 	batchSize := 96
 	var batch []*pinecone.InputRecord
 	var commits []func()
+	var pending progress
 
 	for result := range results {
 		// Check for error from reader
@@ -64,10 +203,15 @@ func (p *Processor) Process(ctx context.Context) error {
 		if result.Commit != nil {
 			commits = append(commits, result.Commit)
 		}
+		pending.lastEventID = result.EventID
+		pending.results++
 
 		if len(records) == 0 {
 			continue
 		}
+		for _, record := range records {
+			p.accepted = append(p.accepted, acceptedRecord{record: record, words: normalizedWords(record.Text)})
+		}
 
 		if p.config.DryRun {
 			for _, record := range records {
@@ -77,6 +221,7 @@ func (p *Processor) Process(ctx context.Context) error {
 		}
 
 		batch = append(batch, records...)
+		pending.upserted += int64(len(records))
 
 		if len(batch) >= batchSize {
 			if err := p.upsertBatch(ctx, batch); err != nil {
@@ -87,6 +232,8 @@ func (p *Processor) Process(ctx context.Context) error {
 				commit()
 			}
 			commits = commits[:0]
+			p.recordCheckpoint(ctx, pending)
+			pending = progress{}
 		}
 	}
 
@@ -114,6 +261,7 @@ func (p *Processor) Process(ctx context.Context) error {
 			commit()
 		}
 	}
+	p.recordCheckpoint(ctx, pending)
 
 	return nil
 }
@@ -144,6 +292,13 @@ func (p *Processor) processResult(ctx context.Context, result BatchResult) ([]*p
 		slog.Debug("Not a prompt injection - skipping", "event_id", result.EventID, "confidence", judgeOutput.Confidence)
 		return nil, nil
 	}
+	// Missed attacks reported through feedback carry a verdict nobody judged, so what is
+	// extracted from them must wait for review.
+	fromFeedback := strings.HasPrefix(result.EventID, schema.FeedbackResultPrefix)
+	if fromFeedback && p.queue == nil {
+		slog.Warn("Skipping reported missed attack: it needs review and there is no review queue", "event_id", result.EventID)
+		return nil, nil
+	}
 
 	if len(result.Request.Contents) == 0 || len(result.Request.Contents[0].Parts) == 0 {
 		slog.Error("No request contents")
@@ -160,33 +315,226 @@ func (p *Processor) processResult(ctx context.Context, result BatchResult) ([]*p
 
 	slog.Info("Extraction complete", "event_id", result.EventID, "candidates_count", len(injections))
 
+	if len(injections) == 0 {
+		return nil, fmt.Errorf("expected prompt injections to be extracted but instead got 0 records: %s", transcript)
+	}
+
+	turns := UserTurns(transcript)
 	var records []*pinecone.InputRecord
+	seen := make(map[string]bool)
 	for _, injection := range injections {
-		if injection == "" {
-			slog.Error("Empty injection")
+		grounding, ok := p.ground(ctx, result.EventID, injection, turns)
+		if !ok {
 			continue
 		}
 
-		id := generateID(injection)
-		record := &pinecone.InputRecord{
-			ID:   id,
-			Text: injection,
-			Metadata: map[string]interface{}{
-				"source":       "auto-extracted",
-				"label":        "injection",
-				"extracted_at": time.Now().Format(time.RFC3339),
-			},
+		id := generateID(grounding.Text)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		now := time.Now().Format(time.RFC3339)
+		metadata := map[string]interface{}{
+			"source":               "auto-extracted",
+			"label":                "injection",
+			"extracted_at":         now,
+			"grounding_similarity": grounding.Similarity,
+			seenCountField:         1.0,
+			lastSeenField:          now,
 		}
-		records = append(records, record)
+		if grounding.Turn.InteractionID != "" {
+			metadata["interaction_id"] = grounding.Turn.InteractionID
+		}
+		if grounding.Turn.ConversationID != "" {
+			metadata["conversation_id"] = grounding.Turn.ConversationID
+		}
+		records = append(records, &pinecone.InputRecord{ID: id, Text: grounding.Text, Metadata: metadata})
 	}
 
 	if len(records) == 0 {
-		return nil, fmt.Errorf("expected prompt injections to be extracted but instead got 0 records: %s", transcript)
+		slog.Warn("None of the extracted injections were found in the transcript", "event_id", result.EventID, "candidates_count", len(injections))
+		return nil, nil
+	}
+
+	records = p.mergeDuplicates(ctx, result.EventID, records)
+	if len(records) == 0 {
+		return nil, nil
 	}
 
+	if p.queue != nil {
+		return p.review(ctx, result.EventID, judgeOutput, fromFeedback, records)
+	}
 	return records, nil
 }
 
+// review submits records to the review queue and returns those it approved. Records from
+// feedback are submitted as such, so the queue's rules never approve them.
+func (p *Processor) review(ctx context.Context, eventID string, judgeOutput JudgeOutput, fromFeedback bool, records []*pinecone.InputRecord) ([]*pinecone.InputRecord, error) {
+	candidates := make([]*review.Candidate, len(records))
+	for i, record := range records {
+		candidates[i] = &review.Candidate{
+			ID:         record.ID,
+			Text:       record.Text,
+			Metadata:   record.Metadata,
+			EventID:    eventID,
+			Confidence: judgeOutput.Confidence,
+			Severity:   judgeOutput.Severity,
+			Analysis:   judgeOutput.Analysis,
+		}
+		if fromFeedback {
+			candidates[i].Source = review.SourceFeedback
+		}
+	}
+	if p.config.DryRun {
+		for _, c := range candidates {
+			slog.Info("Dry Run: Would submit for review", "event_id", eventID, "id", c.ID, "text_len", len(c.Text))
+		}
+		return nil, nil
+	}
+
+	approved, err := p.queue.Submit(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit candidates for review: %w", err)
+	}
+	slog.Info("Submitted candidates for review", "event_id", eventID, "candidates", len(candidates), "approved", len(approved))
+
+	approvedRecords := make([]*pinecone.InputRecord, len(approved))
+	for i, c := range approved {
+		approvedRecords[i] = c.Record()
+	}
+	return approvedRecords, nil
+}
+
+// ground finds injection in the user turns of its transcript. A line found only approximately is
+// replaced by the span it matched; one not found at all was made up by the extractor and dropped.
+func (p *Processor) ground(ctx context.Context, eventID, injection string, turns []Turn) (Grounding, bool) {
+	grounding, ok := Ground(injection, turns, p.config.GroundingMinSimilarity)
+	outcome := "exact"
+	switch {
+	case !ok:
+		outcome = "hallucinated"
+		p.run.hallucinated++
+		slog.Warn("Dropping injection not found in the transcript", "event_id", eventID, "text_len", len(injection), "similarity", grounding.Similarity)
+	case !grounding.Exact(injection):
+		outcome = "repaired"
+		p.run.repaired++
+		slog.Info("Snapped injection to the transcript", "event_id", eventID, "interaction_id", grounding.Turn.InteractionID, "similarity", grounding.Similarity)
+	default:
+		p.run.exact++
+	}
+	if p.grounded != nil {
+		p.grounded.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+	}
+	return grounding, ok
+}
+
+// mergeDuplicates returns the records that are not near-duplicates of an injection signature
+// already stored, or of a record accepted earlier in the run. A near-duplicate is merged into that
+// signature instead, which has its seen count and last seen time updated rather than a second
+// vector added. Records accepted earlier in the run may not be searchable in the store yet, so
+// they are compared word by word, as lines are grounded, rather than by embedding.
+func (p *Processor) mergeDuplicates(ctx context.Context, eventID string, records []*pinecone.InputRecord) []*pinecone.InputRecord {
+	var fresh []*pinecone.InputRecord
+	var local []acceptedRecord
+	for _, record := range records {
+		merged, err := p.mergeDuplicate(ctx, eventID, record)
+		if err != nil {
+			// Upserting a duplicate is better than losing a new signature.
+			slog.Warn("Failed to look for a near-duplicate", "event_id", eventID, "id", record.ID, "error", err)
+		}
+		words := normalizedWords(record.Text)
+		if !merged {
+			merged = p.mergeAccepted(ctx, eventID, record, words, local)
+		}
+		outcome := "merged"
+		if merged {
+			p.run.merged++
+		} else {
+			outcome = "new"
+			p.run.new++
+			fresh = append(fresh, record)
+			local = append(local, acceptedRecord{record: record, words: words})
+		}
+		if p.signatures != nil {
+			p.signatures.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+		}
+	}
+	return fresh
+}
+
+// mergeAccepted merges record, whose normalized words are words, into the record it is most
+// similar to among those accepted earlier in the run and local, those kept earlier from the same
+// result, if their words are at least the run duplicate threshold similar. It reports whether it
+// did.
+// The earlier record's seen count is raised before it is upserted, and in the store in case it
+// already was.
+func (p *Processor) mergeAccepted(ctx context.Context, eventID string, record *pinecone.InputRecord, words []string, local []acceptedRecord) bool {
+	var match *pinecone.InputRecord
+	best := 0.0
+	for _, earlier := range [][]acceptedRecord{p.accepted, local} {
+		for _, a := range earlier {
+			// Words differing in number need at least that many edits, which bounds the similarity.
+			if float64(min(len(words), len(a.words))) < p.config.RunDuplicateThreshold*float64(max(len(words), len(a.words))) {
+				continue
+			}
+			if similarity := wordSimilarity(words, a.words); similarity >= p.config.RunDuplicateThreshold && similarity > best {
+				match, best = a.record, similarity
+			}
+		}
+	}
+	if match == nil {
+		return false
+	}
+	if p.config.DryRun {
+		slog.Info("Dry Run: Would merge into near-duplicate from this run", "event_id", eventID, "id", record.ID, "duplicate_of", match.ID, "similarity", best)
+		return true
+	}
+	seen, _ := match.Metadata[seenCountField].(float64)
+	update := map[string]interface{}{
+		seenCountField: max(seen, 1) + 1,
+		lastSeenField:  time.Now().Format(time.RFC3339),
+	}
+	for k, v := range update {
+		match.Metadata[k] = v
+	}
+	if err := p.pinecone.UpdateMetadata(ctx, match.ID, update); err != nil {
+		slog.Warn("Failed to update near-duplicate from this run", "event_id", eventID, "id", match.ID, "error", err)
+	}
+	slog.Info("Merged injection into near-duplicate from this run", "event_id", eventID, "id", record.ID, "duplicate_of", match.ID, "similarity", best)
+	return true
+}
+
+// mergeDuplicate merges record into the most similar injection signature if it scores at least
+// the duplicate threshold, and reports whether it did.
+func (p *Processor) mergeDuplicate(ctx context.Context, eventID string, record *pinecone.InputRecord) (bool, error) {
+	matches, err := p.pinecone.QueryInput(ctx, record.Text, 1, pinecone.LabelFilter("injection", "jailbreak"))
+	if err != nil {
+		return false, err
+	}
+	if len(matches) == 0 || float64(matches[0].Score) < p.config.DuplicateThreshold {
+		return false, nil
+	}
+	match := matches[0]
+	seen, _ := match.Metadata[seenCountField].(float64)
+	if seen < 1 {
+		// Signatures stored before seen counts were kept had been seen once.
+		seen = 1
+	}
+	if p.config.DryRun {
+		slog.Info("Dry Run: Would merge into near-duplicate", "event_id", eventID, "id", record.ID, "duplicate_of", match.ID, "score", match.Score)
+		return true, nil
+	}
+	err = p.pinecone.UpdateMetadata(ctx, match.ID, map[string]interface{}{
+		seenCountField: seen + 1,
+		lastSeenField:  time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to merge into %s: %w", match.ID, err)
+	}
+	slog.Info("Merged injection into near-duplicate", "event_id", eventID, "id", record.ID, "duplicate_of", match.ID, "score", match.Score)
+	return true, nil
+}
+
 func (p *Processor) parseJudgeOutput(rawOutput string) (JudgeOutput, error) {
 	rawOutput = strings.TrimPrefix(rawOutput, "```json")
 	rawOutput = strings.TrimSuffix(rawOutput, "```")
@@ -0,0 +1,270 @@
+package ingestor
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/app/ingestor/server"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/google/uuid"
+)
+
+// Metadata fields feedback sets on the auto-extracted signatures it holds responsible for false
+// positives. Flagged signatures can be listed with reflex curate -filter='{"review_status": "pending"}'
+// and their weight is only lowered when an analyst confirms the reports with reflex curate -confirm.
+const (
+	reportsField              = "false_positive_reports"
+	reportedInteractionsField = "false_positive_interactions"
+	lastFeedbackIDField       = "last_feedback_id"
+)
+
+// maxReportedInteractions bounds the interactions kept in a signature's
+// false_positive_interactions field; the oldest are dropped first.
+const maxReportedInteractions = 50
+
+// SubmitFeedback is the HTTP handler recording a correction to a verdict. Callers must present the
+// feedback token as a bearer token. The correction is published to the feedback topic before
+// anything else, so there is a record of every signature it flags.
+func (s *Service) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	if s.feedbackTopic == "" || s.feedbackToken == "" {
+		http.Error(w, "Feedback is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if !s.authorizedForFeedback(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req server.FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("Failed to decode feedback request body", "error", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if msg := validateFeedback(req); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	event := schema.FeedbackEvent{
+		FeedbackId:    uuid.New().String(),
+		InteractionId: req.InteractionId,
+		Timestamp:     s.now(),
+		Verdict:       schema.Verdict(req.Verdict),
+		TenantId:      req.TenantId,
+		SubmittedBy:   req.SubmittedBy,
+		Comment:       req.Comment,
+	}
+	if req.SignatureIds != nil {
+		event.SignatureIds = *req.SignatureIds
+	}
+	if req.Prompt != nil {
+		// A missed attack is redacted like any other prompt before it reaches Kafka.
+		content, err := s.redact(ctx, req.TenantId, *req.Prompt)
+		if err != nil {
+			slog.Error("Failed to redact prompt", "interaction_id", req.InteractionId, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		event.Content = &content
+	}
+
+	if err := s.producer.Publish(ctx, s.feedbackTopic, event.InteractionId, event); err != nil {
+		slog.Error("Failed to publish to Kafka (feedback)", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := server.FeedbackResponse{FeedbackId: event.FeedbackId, FlaggedIds: []string{}}
+	switch req.Verdict {
+	case server.FalsePositive:
+		flagged, err := s.flagSignatures(ctx, event.FeedbackId, event.InteractionId, event.SignatureIds)
+		if err != nil {
+			slog.Error("Failed to flag signatures", "feedback_id", event.FeedbackId, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		resp.FlaggedIds = flagged
+	case server.FalseNegative:
+		queued, err := s.queueExtraction(ctx, event, req.ConversationId)
+		if err != nil {
+			slog.Error("Failed to queue missed attack for extraction", "feedback_id", event.FeedbackId, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		resp.QueuedForExtraction = queued
+	}
+	slog.Info("Recorded feedback",
+		"feedback_id", event.FeedbackId,
+		"interaction_id", event.InteractionId,
+		"verdict", event.Verdict,
+		"flagged", len(resp.FlaggedIds),
+		"queued", resp.QueuedForExtraction)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
+
+// authorizedForFeedback reports whether r carries the feedback token as a bearer token.
+func (s *Service) authorizedForFeedback(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.feedbackToken)) == 1
+}
+
+// validateFeedback returns why req is invalid, or "" if it is valid.
+func validateFeedback(req server.FeedbackRequest) string {
+	if req.InteractionId == "" {
+		return "interaction_id is required"
+	}
+	switch req.Verdict {
+	case server.FalsePositive:
+	case server.FalseNegative:
+		if req.Prompt == nil || *req.Prompt == "" {
+			return "prompt is required for a false negative"
+		}
+	default:
+		return fmt.Sprintf("verdict must be %s or %s", server.FalsePositive, server.FalseNegative)
+	}
+	return ""
+}
+
+// redact returns text as it may be published for tenant.
+func (s *Service) redact(ctx context.Context, tenant *string, text string) (string, error) {
+	if s.redactor == nil {
+		return text, nil
+	}
+	var name string
+	if tenant != nil {
+		name = *tenant
+	}
+	result, err := s.redactor.Redact(ctx, name, text)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// flagSignatures marks the auto-extracted signatures among ids for review and records
+// interactionID as a report against them, returning the IDs it flagged. Their weight is left
+// alone until an analyst confirms the reports, and an interaction is only counted once per
+// signature however often it is reported. Signatures loaded from datasets are left alone: they
+// were labelled by people, so removing one is an analyst's call.
+func (s *Service) flagSignatures(ctx context.Context, feedbackID, interactionID string, ids []string) ([]string, error) {
+	flagged := []string{}
+	if len(ids) == 0 {
+		return flagged, nil
+	}
+	vectors, err := s.vectorStore.Fetch(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signatures: %w", err)
+	}
+	for _, id := range ids {
+		v, ok := vectors[id]
+		if !ok || v.Metadata["source"] != "auto-extracted" {
+			continue
+		}
+		flagged = append(flagged, id)
+		interactions := reportedInteractions(v.Metadata)
+		if slices.Contains(interactions, interactionID) {
+			continue
+		}
+		reports, _ := v.Metadata[reportsField].(float64)
+		interactions = append(interactions, interactionID)
+		if len(interactions) > maxReportedInteractions {
+			interactions = interactions[len(interactions)-maxReportedInteractions:]
+		}
+		err := s.vectorStore.UpdateMetadata(ctx, id, map[string]interface{}{
+			pinecone.ReviewStatusField: "pending",
+			reportsField:               reports + 1,
+			reportedInteractionsField:  interactions,
+			lastFeedbackIDField:        feedbackID,
+		})
+		if err != nil {
+			return flagged[:len(flagged)-1], fmt.Errorf("failed to flag signature %s: %w", id, err)
+		}
+	}
+	return flagged, nil
+}
+
+// reportedInteractions returns the interactions false positives have been reported for against a
+// signature. Stores return the list as []string or, after a JSON round trip, []interface{}.
+func reportedInteractions(metadata map[string]interface{}) []string {
+	switch list := metadata[reportedInteractionsField].(type) {
+	case []string:
+		return slices.Clone(list)
+	case []interface{}:
+		interactions := make([]string, 0, len(list))
+		for _, v := range list {
+			if id, ok := v.(string); ok {
+				interactions = append(interactions, id)
+			}
+		}
+		return interactions
+	}
+	return nil
+}
+
+// queueExtraction publishes the missed attack in event to the results topic as a result the
+// judge flagged, so the extractor extracts its payload. The result's event ID marks it as
+// feedback, so the extractor holds what it extracts for review whatever the review rules say. It
+// reports whether it queued it, which it cannot without a results topic.
+func (s *Service) queueExtraction(ctx context.Context, event schema.FeedbackEvent, conversationID *string) (bool, error) {
+	if s.resultsTopic == "" {
+		return false, nil
+	}
+	// The transcript is the interaction as the batch analyzer would have given it to the judge.
+	interaction := schema.InteractionEvent{
+		InteractionId: event.InteractionId,
+		Timestamp:     event.Timestamp,
+		Role:          schema.RoleUser,
+		Content:       *event.Content,
+		TenantId:      event.TenantId,
+	}
+	if conversationID != nil {
+		interaction.ConversationId = *conversationID
+	}
+	transcript, err := json.Marshal(interaction)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	verdict, err := json.Marshal(map[string]interface{}{
+		"is_prompt_injection": true,
+		"confidence":          1.0,
+		"severity":            "HIGH",
+		"analysis":            "Missed attack reported through feedback " + event.FeedbackId,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal verdict: %w", err)
+	}
+
+	text := string(transcript) + "\n"
+	result := schema.BatchResultEvent{
+		EventId:   schema.FeedbackResultPrefix + event.FeedbackId,
+		Timestamp: event.Timestamp,
+		Record: schema.Record{
+			Request: &schema.RecordRequest{Contents: []schema.RecordRequestContentsElem{
+				{Parts: []schema.RecordRequestContentsElemPartsElem{{Text: &text}}},
+			}},
+			Response: &schema.RecordResponse{Candidates: []schema.RecordResponseCandidatesElem{
+				{Content: schema.RecordResponseCandidatesElemContent{Parts: []schema.RecordResponseCandidatesElemContentPartsElem{{Text: string(verdict)}}}},
+			}},
+		},
+	}
+	// Batch results are keyed by event ID, as the batch result producer does.
+	if err := s.producer.Publish(ctx, s.resultsTopic, result.EventId, result); err != nil {
+		return false, fmt.Errorf("failed to publish to results topic: %w", err)
+	}
+	return true, nil
+}
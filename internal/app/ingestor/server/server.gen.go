@@ -6,6 +6,7 @@ package server
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
@@ -17,21 +18,130 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	FeedbackTokenScopes = "feedbackToken.Scopes"
+)
+
+// Defines values for Decision.
+const (
+	Allow Decision = "allow"
+	Block Decision = "block"
+)
+
+// Defines values for FeedbackRequestVerdict.
+const (
+	FalseNegative FeedbackRequestVerdict = "false_negative"
+	FalsePositive FeedbackRequestVerdict = "false_positive"
+)
+
+// Defines values for SignatureMatchOrigin.
+const (
+	AutoExtracted SignatureMatchOrigin = "auto-extracted"
+	Dataset       SignatureMatchOrigin = "dataset"
+	Other         SignatureMatchOrigin = "other"
+)
+
 // AnalyzeRequest defines model for AnalyzeRequest.
 type AnalyzeRequest struct {
-	ConversationId string        `json:"conversation_id"`
-	InteractionId  string        `json:"interaction_id"`
-	Prompt         string        `json:"prompt"`
-	User           *UserMetadata `json:"user,omitempty"`
+	ConversationId string `json:"conversation_id"`
+	InteractionId  string `json:"interaction_id"`
+	Prompt         string `json:"prompt"`
+
+	// TenantId Tenant the interaction belongs to. Selects its retention policy.
+	TenantId *string       `json:"tenant_id,omitempty"`
+	User     *UserMetadata `json:"user,omitempty"`
 }
 
 // AnalyzeResponse defines model for AnalyzeResponse.
 type AnalyzeResponse struct {
-	InteractionId     string  `json:"interaction_id"`
-	IsPromptInjection bool    `json:"is_prompt_injection"`
-	Score             float32 `json:"score"`
+	// Decision The policy decision, block when the score is above the threshold.
+	Decision          Decision `json:"decision"`
+	InteractionId     string   `json:"interaction_id"`
+	IsPromptInjection bool     `json:"is_prompt_injection"`
+
+	// Matches The signatures most similar to the prompt, best first.
+	Matches []SignatureMatch `json:"matches"`
+
+	// Score Highest similarity between a chunk of the prompt and a stored signature.
+	Score float32 `json:"score"`
+
+	// Threshold Score above which the prompt is blocked.
+	Threshold float32 `json:"threshold"`
+}
+
+// ChunkEvidence defines model for ChunkEvidence.
+type ChunkEvidence struct {
+	// End Offset just past the chunk's last character.
+	End   int     `json:"end"`
+	Score float32 `json:"score"`
+
+	// Start Offset of the chunk's first character in the prompt, counting Unicode code points.
+	Start int `json:"start"`
+}
+
+// Decision The policy decision, block when the score is above the threshold.
+type Decision string
+
+// FeedbackRequest defines model for FeedbackRequest.
+type FeedbackRequest struct {
+	Comment        *string `json:"comment,omitempty"`
+	ConversationId *string `json:"conversation_id,omitempty"`
+	InteractionId  string  `json:"interaction_id"`
+
+	// Prompt For a false negative, the missed attack. Required for false negatives.
+	Prompt *string `json:"prompt,omitempty"`
+
+	// SignatureIds For a false positive, the IDs of the matches responsible, as returned by /analyze.
+	SignatureIds *[]string `json:"signature_ids,omitempty"`
+
+	// SubmittedBy Who is reporting the feedback, e.g. an analyst or a client application.
+	SubmittedBy *string `json:"submitted_by,omitempty"`
+
+	// TenantId Tenant the interaction belongs to. Selects its redaction policy.
+	TenantId *string `json:"tenant_id,omitempty"`
+
+	// Verdict false_positive when a benign prompt was flagged, false_negative when an attack was missed.
+	Verdict FeedbackRequestVerdict `json:"verdict"`
+}
+
+// FeedbackRequestVerdict false_positive when a benign prompt was flagged, false_negative when an attack was missed.
+type FeedbackRequestVerdict string
+
+// FeedbackResponse defines model for FeedbackResponse.
+type FeedbackResponse struct {
+	FeedbackId string `json:"feedback_id"`
+
+	// FlaggedIds Auto-extracted signatures marked for review and down-weighted.
+	FlaggedIds []string `json:"flagged_ids"`
+
+	// QueuedForExtraction Whether the missed attack was queued for extraction into the signature store.
+	QueuedForExtraction bool `json:"queued_for_extraction"`
 }
 
+// SignatureMatch defines model for SignatureMatch.
+type SignatureMatch struct {
+	// Chunks The chunks of the prompt the signature matched.
+	Chunks []ChunkEvidence `json:"chunks"`
+
+	// Id ID of the stored signature, for looking it up with reflex curate.
+	Id string `json:"id"`
+
+	// Label Canonical label of the signature, e.g. injection or jailbreak.
+	Label *string `json:"label,omitempty"`
+
+	// Origin Whether the signature was loaded from a dataset or extracted from past traffic.
+	Origin SignatureMatchOrigin `json:"origin"`
+
+	// Score Highest similarity between the signature and a chunk of the prompt, weighted down for each false positive reported against the signature.
+	Score float32 `json:"score"`
+
+	// Source Dataset the signature was loaded from, or auto-extracted.
+	Source *string `json:"source,omitempty"`
+}
+
+// SignatureMatchOrigin Whether the signature was loaded from a dataset or extracted from past traffic.
+type SignatureMatchOrigin string
+
 // UserMetadata defines model for UserMetadata.
 type UserMetadata struct {
 	SourceIp  *string `json:"source_ip,omitempty"`
@@ -42,11 +152,17 @@ type UserMetadata struct {
 // AnalyzeInteractionJSONRequestBody defines body for AnalyzeInteraction for application/json ContentType.
 type AnalyzeInteractionJSONRequestBody = AnalyzeRequest
 
+// SubmitFeedbackJSONRequestBody defines body for SubmitFeedback for application/json ContentType.
+type SubmitFeedbackJSONRequestBody = FeedbackRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// Submit interaction for asynchronous security analysis
 	// (POST /analyze)
 	AnalyzeInteraction(w http.ResponseWriter, r *http.Request)
+	// Report a wrong verdict on an interaction
+	// (POST /feedback)
+	SubmitFeedback(w http.ResponseWriter, r *http.Request)
 }
 
 // Unimplemented server implementation that returns http.StatusNotImplemented for each endpoint.
@@ -59,6 +175,12 @@ func (_ Unimplemented) AnalyzeInteraction(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Report a wrong verdict on an interaction
+// (POST /feedback)
+func (_ Unimplemented) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // ServerInterfaceWrapper converts contexts to parameters.
 type ServerInterfaceWrapper struct {
 	Handler            ServerInterface
@@ -82,6 +204,26 @@ func (siw *ServerInterfaceWrapper) AnalyzeInteraction(w http.ResponseWriter, r *
 	handler.ServeHTTP(w, r)
 }
 
+// SubmitFeedback operation middleware
+func (siw *ServerInterfaceWrapper) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, FeedbackTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SubmitFeedback(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -198,6 +340,9 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/analyze", wrapper.AnalyzeInteraction)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/feedback", wrapper.SubmitFeedback)
+	})
 
 	return r
 }
@@ -205,16 +350,36 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/6xTT0/bShD/Kta8d3hPCrHz4CHqG5V6iAQSArUXhKKNPUkW2TvLzCxSivzdq911IZBQ",
-	"eujN2h3P7+8+QUO9J4dOBeonkGaDvUmf58502+94jQ8BReOJZ/LIajHdN+QekcWoJbewbTzSrUeoQZSt",
-	"W8MwAesU2TS/GvFMvdeDV0GQ48XfjCuo4a/yhWs5Ei2/CvIlqmmNGhiGCTA+BMvYQn37Fn6yx/kZ/m7y",
-	"E56W99hohH82QDw5wX0HfkOdlUVGWFgX91pyO3NLog6Ni4PSECeIFXFvFGpYdWQUnnm50C+RP5aYFx1G",
-	"PqTylYF7EoUCN7iw/t2AFmaN7v38Dhsz7BEZUltWFIdblIatz2bB+dW8WBEX1q1R1Lp1cXFxWezolmiS",
-	"1S6um6ch4uL8ag4TiGHnLbNpNa0iKfLojLdQw3E6moA3ukliS5MTTzZQ7nw0I9Vl3kYueWD+Ag45DhT9",
-	"TO12fBc6OmK872yTfi/vJUefi/tRrd88vuF17MoB00FuZiL/X1X9efSx+Qn+TSpxRKwU1yihS006yQxe",
-	"z83do+lsW4xKin9uEkbxzVKXqP0bf/3/8K+K7ExX3CA/IhdfmCk/AAl9b3gLNdyEZW91tw2pK0a2rtkw",
-	"OQpSCDaBrW4LM3LOeiRtFahvnyBwBzVsVH1dlh01ptuQaH1WncXO7N5LXZZ2LNnR7PjT6dnJaXUyO66m",
-	"QY4adMqmm005uKnxHoa74UcAAAD//83Gg3BiBQAA",
+	"H4sIAAAAAAAC/7RYYW/cNhL9KwPdAb0DlF27yRW5vU9p7OCMNtfCTq8f4mBBUaMVbYpUOCNv94r97weS",
+	"0q60y7UdNP1i2BLFeZx58+bRv2fSNq01aJiyxe8ZyRobEX59Y4Te/A+v8XOHxP5J62yLjhWG99KaB3Qk",
+	"WFmzVKV/xJsWs0VG7JRZZds8U4bRCfnYktbZpuXkK0YjDPcflkjSqdZvlS2yD+EVcI0wigEFamtWBGxn",
+	"cIMaJRMoJnDIaMKK1molN7MsPw7XETof6a8Oq2yR/WW+T828z8v8F0L3HlmUgkW23eaZw8+dclhmi4+H",
+	"p82PUrQ77addeFvcoWQffpdvaq0hPE54iVJROP7jGC+Gdc8rgKJlRLVUxmPpI/TrCms1irBXI1jWSIli",
+	"1AikVkZw55CgscRAqlFaOGAbihQj5FAgMVTKEfsSKMaGnjrOzbDzex8/8CJCE86Jjf+bpHV4DOvfalXj",
+	"HoriDRTIa0QDAmTdmXuw1QgdCFOCAGLrsNwfyAOtrGsEZ4us0lbwnjymawp0AVPtkGqrE1y98fBAFPYB",
+	"YV0rWY9jKoJCW3mP5XPiPEW4mIl0TfM9gcZw93VNkfKtT9PlgyrRyAQl0STO+1NVETLcdcTQCopdGvL9",
+	"DYH2D2QtPGx0oz70Z1nFXO7q+XTaiYXjkxBsNYkdeLcPDspMuCltZ1iZFfxilLQlQvjRWmWYUkAPihGh",
+	"5CEnwxlSKb0YtfFxH0V9gqFUeWQHrGuMYMO+njWRT/7RrpazEL1rPBqhtV1neRY+H+HY9/07xLIQ8v4R",
+	"gW8aNGlp/uriP83EO+tAQCU0IRhcCVYPmIfDNooISxDMQt7P4LovAFTWHaynpMjv2nqpSno8cGtJ7QNf",
+	"XdDAp75hwEWpVoXGHIT/mztnsIRiA3MR5Xwic8cT7lDJuqJRzFgui80xuF9r6yvvsLUuENWDqfoy5oCz",
+	"1QyEgRCZGMJRpFZoGETbaiVDuZJp+ZqjtuxXPDJqH9CVSiYKHzK/HDIfaS+gQKNWZpDMtSCotFitsMxj",
+	"qZZDzfsPTM+PsDQyZtwb0yBZ3j8YNkl0y1O6O5wn1e/7Pjs12IcSnuqS/rBpxr7p2L7A3zhoWjmZw8Ld",
+	"953h8EHhOgy40q7NizWqVc1Yfhk7P3fYYbmsrFv2AZMy9muNXKM7btdQjrhJQLXfxDMrGoUd/jiHR+zZ",
+	"OZGDYoyzN83VKcSpKh24jGMx9DPkhPeJ7w7MxPQwUTLKZ5ue6dxN1CLVqVcXA4ZDD5OHfGtr771sKIau",
+	"hbXiGhxWGn8D2TnBmGxVLQrUx7HeCmONkkJDWLALvI8Y5GhnP7wa3QmlC4fiPhnIOrVST9Bpn1BPJW1F",
+	"6ankbAMCvCUPQ39HrOFdNCFOVJWSYx3ov8jyTEyaKMsz60Mmx+aXm80p8GgyE/Yzh6ErQ4/GDhGyPphG",
+	"vfz7ploJZeiAac9zq2Q7JxOnuOhz+Giu8zBZJhlLFPRQMsf2tC91PnRVqiEnN62jdowHWKo2KVz+JrcU",
+	"q1PmJbxOau32CIhPFsrOF/TG9+ZUsT/Yezxh5JRZoW/DbwjeXV5efP/m7Q/LDz/9cPmfWUiE38qLGgqH",
+	"bp+9mrnNttvgniqbUPufrwIx4u6+mX/88f14LpPfS7H2m131EODNz1dxSEXfmZ3PzmZnoedaNKJV2SJ7",
+	"GR7lWSu4DkccDIz/vbXRH/oKBA9xVXosccHVPngWa47E39ty0/+TgPsyjCzI/I7i4Ihy95QYHvwnYjvl",
+	"FrsOw4M4XgP4b8/Ovn70uH8Mf1AVv4QUwTVSpwN9X0UEBwJtHoRWJfQngb8FSgn4r7I6QPu7//Qf6U8Z",
+	"nREabtA9oINL52y8g1DXNMJt/EUzuMeJS/NcEbQxsnbW2I5gIHPvEhWFPeYDocfVngK4RmldSV67rHOD",
+	"qJuJBwW2rZIzOO2ip7Jxa0Z2xYgGS1AGJhYdhMNjJzO6rAWJHB/ZGon/ujVco3K9pIIisEZvQNs1+sG4",
+	"s4m9VZbWVMo1FDBGgaU8SPUIYJTAWxNmSj87Ij6NFcdU77acJuGRO4zHtvdEt2Zkinz8GvX44FBgFS6A",
+	"DHdWGUp6pluT5Qe9GqkxWNE/qU8Pb5TPatRv/4Twpzt1WAORzlj+wV59dXZ+/Ol7ReSpaR2snTWrcX/4",
+	"efGFPe5Xv0xcVYdNFYGxDGhEobGMmtB3ebb4eDSsPn7afhqrxnWgO4gea3+VgcC/cWPFdFKARmHjzul+",
+	"ZC3mc22l0LUlXrw+e+2Hy/g9LebzYSC+OH/5z+9ev/ru7NX5y7NZRy8kGnZCn89cZ2aibbPtp+3/BwDa",
+	"g72QmBYAAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
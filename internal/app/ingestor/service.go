@@ -5,48 +5,128 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/dllewellyn/reflex/internal/app/ingestor/server"
+	"github.com/dllewellyn/reflex/internal/app/redaction"
 	"github.com/dllewellyn/reflex/internal/platform/kafka"
 	"github.com/dllewellyn/reflex/internal/platform/pinecone"
 	"github.com/dllewellyn/reflex/internal/platform/schema"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// DefaultMatchLabels are the labels of the records prompts are compared with unless
+// Config.MatchLabels says otherwise.
+var DefaultMatchLabels = []string{"injection", "jailbreak"}
+
+const (
+	// DefaultThreshold is the score above which a prompt is blocked unless Config.Threshold says
+	// otherwise.
+	DefaultThreshold = 0.84
+	// DefaultMaxMatches is the number of matches returned unless Config.MaxMatches says otherwise.
+	DefaultMaxMatches = 3
+)
+
 type Config struct {
 	TopicName         string
 	Port              string
+	// Now stamps published events. Defaults to time.Now; dev mode supplies a simulated clock.
+	Now func() time.Time
+	// MatchLabels restricts the similarity search to records with one of these labels, so that
+	// benign records loaded from datasets do not raise scores. Defaults to DefaultMatchLabels;
+	// "*" searches every record.
+	MatchLabels []string
+	// Threshold is the score above which a prompt is blocked. Defaults to DefaultThreshold.
+	Threshold float32
+	// MaxMatches bounds the signatures returned with each analysis, and those looked up per
+	// chunk. Defaults to DefaultMaxMatches.
+	MaxMatches int
+	// FeedbackTopic receives the corrections posted to /feedback, which is disabled without it.
+	FeedbackTopic string
+	// ResultsTopic is the extractor's input; missed attacks reported as feedback are queued on it
+	// for extraction. Without it they are only recorded.
+	ResultsTopic string
+	// FeedbackToken is the bearer token callers of /feedback must present; /feedback is disabled
+	// without it.
+	FeedbackToken string
+}
+
+// Redactor removes sensitive values from prompts before they leave the ingestor.
+type Redactor interface {
+	Redact(ctx context.Context, tenant, text string) (*redaction.Result, error)
 }
 
 type Service struct {
 	producer      kafka.Producer
 	vectorStore   pinecone.VectorStore
+	redactor      Redactor
 	topic         string
 	port          string
+	now           func() time.Time
+	filter        pinecone.Filter
+	threshold     float32
+	maxMatches    int
+	feedbackTopic string
+	resultsTopic  string
+	feedbackToken string
 }
 
 // Ensure Service implements ServerInterface
 var _ server.ServerInterface = (*Service)(nil)
 
-func NewService(producer kafka.Producer, vectorStore pinecone.VectorStore, cfg Config) *Service {
+// NewService creates the ingestor. redactor may be nil, in which case prompts are published verbatim.
+func NewService(producer kafka.Producer, vectorStore pinecone.VectorStore, redactor Redactor, cfg Config) *Service {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultThreshold
+	}
+	if cfg.MaxMatches <= 0 {
+		cfg.MaxMatches = DefaultMaxMatches
+	}
 	return &Service{
 		producer:      producer,
 		vectorStore:   vectorStore,
+		redactor:      redactor,
 		topic:         cfg.TopicName,
 		port:          cfg.Port,
+		now:           now,
+		filter:        matchFilter(cfg.MatchLabels),
+		threshold:     cfg.Threshold,
+		maxMatches:    cfg.MaxMatches,
+		feedbackTopic: cfg.FeedbackTopic,
+		resultsTopic:  cfg.ResultsTopic,
+		feedbackToken: cfg.FeedbackToken,
 	}
 }
 
-func (s *Service) Run(ctx context.Context) error {
-	// Create the handler from the service implementation
-	baseHandler := server.Handler(s)
-	handler := otelhttp.NewHandler(baseHandler, "ingest")
+// matchFilter returns the vector search filter for labels.
+func matchFilter(labels []string) pinecone.Filter {
+	if len(labels) == 0 {
+		labels = DefaultMatchLabels
+	}
+	for _, label := range labels {
+		if label == "*" {
+			return nil
+		}
+	}
+	return pinecone.LabelFilter(labels...)
+}
+
+// Handler returns the ingestor API as an http.Handler, for serving alongside other routes.
+func (s *Service) Handler() http.Handler {
+	return otelhttp.NewHandler(server.Handler(s), "ingest")
+}
 
+func (s *Service) Run(ctx context.Context) error {
 	server := &http.Server{
 		Addr:              ":" + s.port,
-		Handler:           handler,
+		Handler:           s.Handler(),
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       15 * time.Second,
 		WriteTimeout:      15 * time.Second,
@@ -80,7 +160,7 @@ func (s *Service) AnalyzeInteraction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Server-side timestamp
-	timestamp := time.Now()
+	timestamp := s.now()
 
 	// Transform to InteractionEvent
 	event := schema.InteractionEvent{
@@ -89,45 +169,73 @@ func (s *Service) AnalyzeInteraction(w http.ResponseWriter, r *http.Request) {
 		Timestamp:      timestamp,
 		Role:           schema.RoleUser, // Defaulting to user
 		Content:        req.Prompt,
+		TenantId:       req.TenantId,
+	}
+	if req.User != nil {
+		event.UserId = req.User.UserId
+	}
+
+	// Redact before publishing so sensitive values never reach Kafka or the archive. Fail closed:
+	// an unredacted prompt is never published in place of a redacted one.
+	if s.redactor != nil {
+		var tenant string
+		if req.TenantId != nil {
+			tenant = *req.TenantId
+		}
+		result, err := s.redactor.Redact(r.Context(), tenant, req.Prompt)
+		if err != nil {
+			slog.Error("Failed to redact prompt", "interaction_id", req.InteractionId, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		event.Content = result.Text
+		if len(result.Counts) > 0 {
+			event.Redactions = result.Counts
+			slog.Info("Redacted prompt", "interaction_id", req.InteractionId, "redactions", result.Counts)
+		}
 	}
 
-	// Check for jailbreak attempts
-	// Check for jailbreak attempts using sliding window chunking
+	// Check for jailbreak attempts using sliding window chunking. The unredacted prompt is used so
+	// that redaction placeholders do not mask an attack.
 	windowSize := 75
 	overlap := 20
 	chunks := chunkText(req.Prompt, windowSize, overlap)
 
-	var maxScore float32
-	var maxScoreMatchID string
-	var isInjection bool
-
+	found := make(map[string]*server.SignatureMatch)
 	for _, chunk := range chunks {
-		matches, err := s.vectorStore.QueryInput(r.Context(), chunk, 1)
+		matches, err := s.vectorStore.QueryInput(r.Context(), chunk.text, s.maxMatches, s.filter)
 		if err != nil {
+			// Fail safe: a prompt that could not be checked is not reported as clean.
 			slog.Error("Failed to query vector database", "error", err)
-			// Continue checking other chunks or fail? Failsafe might be to log and continue,
-			// but for security, if we can't check, we might want to be cautious.
-			// However, the original code returned 500. Let's stick to that if any query fails for now,
-			// or maybe just log error and continue if it's transient?
-			// Given it's security, let's fail safe.
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
-
-		if len(matches) > 0 {
-			if matches[0].Score > maxScore {
-				maxScore = matches[0].Score
-				maxScoreMatchID = matches[0].ID
+		for _, m := range matches {
+			match, ok := found[m.ID]
+			if !ok {
+				match = newSignatureMatch(m)
+				found[m.ID] = match
 			}
+			// Signatures reported as false positives count for less.
+			score := m.Score * float32(pinecone.Weight(m.Metadata))
+			match.Score = max(match.Score, score)
+			match.Chunks = append(match.Chunks, server.ChunkEvidence{Start: chunk.start, End: chunk.end, Score: score})
 		}
 	}
+	matches := topMatches(found, s.maxMatches)
 
-	if maxScore > 0.84 {
-		isInjection = true
+	var maxScore float32
+	if len(matches) > 0 {
+		maxScore = matches[0].Score
+	}
+	isInjection := maxScore > s.threshold
+	decision := server.Allow
+	if isInjection {
+		decision = server.Block
 		slog.Warn("Jailbreak attempt detected",
 			"interaction_id", req.InteractionId,
 			"score", maxScore,
-			"matched_id", maxScoreMatchID)
+			"matched_id", matches[0].Id)
 	}
 
 	ctx := r.Context()
@@ -143,6 +251,9 @@ func (s *Service) AnalyzeInteraction(w http.ResponseWriter, r *http.Request) {
 		InteractionId:     req.InteractionId,
 		Score:             maxScore,
 		IsPromptInjection: isInjection,
+		Decision:          decision,
+		Threshold:         s.threshold,
+		Matches:           matches,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -152,16 +263,62 @@ func (s *Service) AnalyzeInteraction(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func chunkText(text string, windowSize, overlap int) []string {
+// newSignatureMatch describes the stored signature m for the response, without its score or
+// chunks.
+func newSignatureMatch(m *pinecone.Match) *server.SignatureMatch {
+	match := &server.SignatureMatch{Id: m.ID, Origin: server.Other, Chunks: []server.ChunkEvidence{}}
+	if label, ok := m.Metadata["label"].(string); ok && label != "" {
+		match.Label = &label
+	}
+	if source, ok := m.Metadata["source"].(string); ok && source != "" {
+		match.Source = &source
+	}
+	switch {
+	case m.Metadata["source"] == "auto-extracted":
+		match.Origin = server.AutoExtracted
+	case m.Metadata["source_split"] != nil:
+		match.Origin = server.Dataset
+	}
+	return match
+}
+
+// topMatches returns the n best matches in found, best first, with each match's chunks in prompt
+// order.
+func topMatches(found map[string]*server.SignatureMatch, n int) []server.SignatureMatch {
+	matches := make([]server.SignatureMatch, 0, len(found))
+	for _, m := range found {
+		sort.Slice(m.Chunks, func(i, j int) bool { return m.Chunks[i].Start < m.Chunks[j].Start })
+		matches = append(matches, *m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Id < matches[j].Id
+	})
+	if len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches
+}
+
+// textChunk is a window of a prompt's words, with the offsets of its first character and just
+// past its last in the prompt, counting runes.
+type textChunk struct {
+	text       string
+	start, end int
+}
+
+func chunkText(text string, windowSize, overlap int) []textChunk {
 	if text == "" {
-		return []string{}
+		return []textChunk{}
 	}
-	words := strings.Fields(text)
+	words, starts, ends := splitWords(text)
 	if len(words) <= windowSize {
-		return []string{text}
+		return []textChunk{{text: text, start: 0, end: len([]rune(text))}}
 	}
 
-	var chunks []string
+	var chunks []textChunk
 	step := windowSize - overlap
 	if step < 1 {
 		step = 1
@@ -172,7 +329,7 @@ func chunkText(text string, windowSize, overlap int) []string {
 		if end > len(words) {
 			end = len(words)
 		}
-		chunk := strings.Join(words[i:end], " ")
+		chunk := textChunk{text: strings.Join(words[i:end], " "), start: starts[i], end: ends[end-1]}
 		chunks = append(chunks, chunk)
 		// If we've reached the end of the text, stop
 		if end == len(words) {
@@ -181,3 +338,29 @@ func chunkText(text string, windowSize, overlap int) []string {
 	}
 	return chunks
 }
+
+// splitWords splits text like strings.Fields, also returning the rune offsets each word starts
+// and ends at.
+func splitWords(text string) (words []string, starts, ends []int) {
+	wordStart, runeStart := -1, 0
+	offset := 0
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if wordStart >= 0 {
+				words = append(words, text[wordStart:i])
+				starts = append(starts, runeStart)
+				ends = append(ends, offset)
+				wordStart = -1
+			}
+		} else if wordStart < 0 {
+			wordStart, runeStart = i, offset
+		}
+		offset++
+	}
+	if wordStart >= 0 {
+		words = append(words, text[wordStart:])
+		starts = append(starts, runeStart)
+		ends = append(ends, offset)
+	}
+	return words, starts, ends
+}
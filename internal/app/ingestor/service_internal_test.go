@@ -52,9 +52,33 @@ func TestChunkText(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := chunkText(tt.text, tt.windowSize, tt.overlap); !reflect.DeepEqual(got, tt.want) {
+			got := []string{}
+			for _, chunk := range chunkText(tt.text, tt.windowSize, tt.overlap) {
+				got = append(got, chunk.text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("chunkText() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestChunkText_Offsets(t *testing.T) {
+	text := "  héllo  wörld\tone\ntwo "
+	got := chunkText(text, 2, 0)
+	want := []textChunk{
+		{text: "héllo wörld", start: 2, end: 14},
+		{text: "one two", start: 15, end: 22},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("chunkText() = %+v, want %+v", got, want)
+	}
+	runes := []rune(text)
+	if span := string(runes[got[0].start:got[0].end]); span != "héllo  wörld" {
+		t.Errorf("first chunk spans %q", span)
+	}
+
+	if got := chunkText("short prompt", 5, 2); !reflect.DeepEqual(got, []textChunk{{text: "short prompt", start: 0, end: 12}}) {
+		t.Errorf("chunkText() = %+v for a prompt within one window", got)
+	}
+}
@@ -3,32 +3,102 @@ package loader
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dllewellyn/reflex/internal/platform/archive"
 	"github.com/dllewellyn/reflex/internal/platform/gcs"
 	"github.com/dllewellyn/reflex/internal/platform/kafka"
 	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/state"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
 
 type Config struct {
 	Topic string
+	// Layout selects the archive layout. Defaults to archive.LayoutSession.
+	Layout archive.Layout
+	// Format is the data file encoding used by archive.LayoutHourly. Defaults to archive.FormatJSONLGzip.
+	Format archive.Format
+
+	// The following only apply to RunStream.
+
+	// FlushMaxEvents flushes once a single conversation has buffered this many events. Defaults to 1000.
+	FlushMaxEvents int
+	// FlushMaxBytes flushes once a single conversation has buffered this many bytes of JSON. Defaults to 1 MiB.
+	FlushMaxBytes int
+	// FlushMaxAge flushes once the oldest buffered event has waited this long. Defaults to 5 minutes.
+	FlushMaxAge time.Duration
+	// MaxBufferBytes flushes once all conversations together have buffered this many bytes. Defaults to 64 MiB.
+	MaxBufferBytes int
+}
+
+// Sealer encrypts event content before it is archived; see envelope.Sealer.
+type Sealer interface {
+	SealEvents(ctx context.Context, events []schema.InteractionEvent) ([]schema.InteractionEvent, error)
 }
 
 type Service struct {
 	consumer  kafka.Consumer
 	gcsWriter gcs.BlobWriter
-	topic     string
+	sealer    Sealer
+	// checkpoints records what has been archived; nil disables checkpointing.
+	checkpoints state.Store
+	topic       string
+	layout      archive.Layout
+	format      archive.Format
+
+	flushMaxEvents int
+	flushMaxBytes  int
+	flushMaxAge    time.Duration
+	maxBufferBytes int
 }
 
-func NewService(consumer kafka.Consumer, gcsWriter gcs.BlobWriter, cfg Config) *Service {
+// NewService creates the loader. sealer may be nil, in which case content is archived in plaintext,
+// and checkpoints may be nil to skip recording a checkpoint after each write.
+func NewService(consumer kafka.Consumer, gcsWriter gcs.BlobWriter, sealer Sealer, checkpoints state.Store, cfg Config) *Service {
+	layout := cfg.Layout
+	if layout == "" {
+		layout = archive.LayoutSession
+	}
+	format := cfg.Format
+	if format == "" {
+		format = archive.FormatJSONLGzip
+	}
+	flushMaxEvents := cfg.FlushMaxEvents
+	if flushMaxEvents <= 0 {
+		flushMaxEvents = 1000
+	}
+	flushMaxBytes := cfg.FlushMaxBytes
+	if flushMaxBytes <= 0 {
+		flushMaxBytes = 1 << 20
+	}
+	flushMaxAge := cfg.FlushMaxAge
+	if flushMaxAge <= 0 {
+		flushMaxAge = 5 * time.Minute
+	}
+	maxBufferBytes := cfg.MaxBufferBytes
+	if maxBufferBytes <= 0 {
+		maxBufferBytes = 64 << 20
+	}
 	return &Service{
-		consumer:  consumer,
-		gcsWriter: gcsWriter,
-		topic:     cfg.Topic,
+		consumer:       consumer,
+		gcsWriter:      gcsWriter,
+		sealer:         sealer,
+		checkpoints:    checkpoints,
+		topic:          cfg.Topic,
+		layout:         layout,
+		format:         format,
+		flushMaxEvents: flushMaxEvents,
+		flushMaxBytes:  flushMaxBytes,
+		flushMaxAge:    flushMaxAge,
+		maxBufferBytes: maxBufferBytes,
 	}
 }
 
@@ -66,9 +136,97 @@ func (s *Service) RunOnce(ctx context.Context) error {
 		return nil
 	}
 
-	slog.Info("Writing batch to GCS", "sessions", len(sessionBuffers))
+	slog.Info("Writing batch to GCS", "sessions", len(sessionBuffers), "layout", s.layout)
+
+	if err := s.write(ctx, sessionBuffers); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	slog.Info("Batch complete")
+	if err := s.consumer.Commit(); err != nil {
+		slog.Error("Failed to commit offsets", "error", err)
+		// We processed and wrote to GCS, so we should probably not fail the job hard if commit fails,
+		// but it risks duplicate processing next time.
+		// For now return error.
+		return fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	return nil
+}
+
+// Checkpoint is the loader's record of what it has archived, stored under CheckpointName.
+type Checkpoint struct {
+	// Watermark is the latest event timestamp archived so far.
+	Watermark time.Time `json:"watermark"`
+	// Events and Flushes count what has been archived since the checkpoint was created.
+	Events  int64 `json:"events"`
+	Flushes int64 `json:"flushes"`
+	// LastFlush is when the most recent write completed.
+	LastFlush time.Time `json:"last_flush"`
+}
+
+// CheckpointName is the name of the loader's checkpoint for topic.
+func CheckpointName(topic string) string {
+	return "loader/" + topic
+}
+
+// recordCheckpoint advances the checkpoint past events that have just been archived. Kafka
+// offsets remain the source of truth for where consumption resumes, so a failure here is
+// logged rather than holding back the offset commit.
+func (s *Service) recordCheckpoint(ctx context.Context, sessionBuffers map[string][]schema.InteractionEvent) {
+	if s.checkpoints == nil {
+		return
+	}
+	cp, err := state.Update(ctx, s.checkpoints, CheckpointName(s.topic), func(cp *Checkpoint) error {
+		for _, events := range sessionBuffers {
+			for _, event := range events {
+				if event.Timestamp.After(cp.Watermark) {
+					cp.Watermark = event.Timestamp
+				}
+				cp.Events++
+			}
+		}
+		cp.Flushes++
+		cp.LastFlush = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to record checkpoint", "checkpoint", CheckpointName(s.topic), "error", err)
+		return
+	}
+	slog.Info("Checkpoint recorded", "checkpoint", CheckpointName(s.topic), "watermark", cp.Watermark, "events", cp.Events)
+}
+
+// write persists the buffered events using the configured layout and records a checkpoint.
+func (s *Service) write(ctx context.Context, sessionBuffers map[string][]schema.InteractionEvent) error {
+	if err := s.writeLayout(ctx, sessionBuffers); err != nil {
+		return err
+	}
+	s.recordCheckpoint(ctx, sessionBuffers)
+	return nil
+}
+
+// writeLayout persists the buffered events using the configured layout.
+func (s *Service) writeLayout(ctx context.Context, sessionBuffers map[string][]schema.InteractionEvent) error {
+	if s.sealer != nil {
+		sealed := make(map[string][]schema.InteractionEvent, len(sessionBuffers))
+		for sessionID, events := range sessionBuffers {
+			var err error
+			if sealed[sessionID], err = s.sealer.SealEvents(ctx, events); err != nil {
+				return fmt.Errorf("failed to encrypt session %s: %w", sessionID, err)
+			}
+		}
+		sessionBuffers = sealed
+	}
+	if s.layout == archive.LayoutHourly {
+		return s.writeHourlyPartitions(ctx, sessionBuffers)
+	}
+	return s.writeSessionChunks(ctx, sessionBuffers)
+}
 
-	// Write each session's buffer to GCS
+// writeSessionChunks writes one JSONL chunk per conversation under
+// raw/<conversation_id>/YYYY/MM/DD/HH/chunk-<uuid>.jsonl.
+func (s *Service) writeSessionChunks(ctx context.Context, sessionBuffers map[string][]schema.InteractionEvent) error {
 	for sessionID, events := range sessionBuffers {
 		if len(events) == 0 {
 			continue
@@ -84,29 +242,167 @@ func (s *Service) RunOnce(ctx context.Context) error {
 		key := fmt.Sprintf("raw/%s/%d/%02d/%02d/%02d/chunk-%s.jsonl",
 			sessionID, t.Year(), t.Month(), t.Day(), t.Hour(), uuid.New().String())
 
-		// Serialize events to JSONL
-		var data []byte
+		// Record who the chunk belongs to so retention sweeps can apply the tenant's TTL without
+		// reading it.
+		metadata := map[string]string{
+			"conversation_id": sessionID,
+			"event_count":     strconv.Itoa(len(events)),
+		}
+		if tenants := archive.EventTenants(events); len(tenants) > 0 {
+			metadata["tenant_id"] = strings.Join(tenants, ",")
+		}
+
+		// Stream events to the chunk as JSONL
+		w, err := gcs.Create(ctx, s.gcsWriter, key, gcs.WriteOptions{Metadata: metadata})
+		if err != nil {
+			return fmt.Errorf("gcs write error for session %s: %w", sessionID, err)
+		}
+		enc := json.NewEncoder(w)
 		for _, event := range events {
-			b, err := json.Marshal(event)
-			if err != nil {
+			if err := enc.Encode(event); err != nil {
+				_ = w.CloseWithError(err)
 				return fmt.Errorf("marshal error: %w", err)
 			}
-			data = append(data, b...)
-			data = append(data, '\n')
 		}
-
-		if err := s.gcsWriter.Write(ctx, key, data); err != nil {
+		if err := w.Close(); err != nil {
 			return fmt.Errorf("gcs write error for session %s: %w", sessionID, err)
 		}
 	}
+	return nil
+}
 
-	slog.Info("Batch complete")
-	if err := s.consumer.Commit(); err != nil {
-		slog.Error("Failed to commit offsets", "error", err)
-		// We processed and wrote to GCS, so we should probably not fail the job hard if commit fails,
-		// but it risks duplicate processing next time.
-		// For now return error.
-		return fmt.Errorf("failed to commit offsets: %w", err)
+// writeHourlyPartitions writes a single compressed file per hour under raw/dt=YYYY-MM-DD/hr=HH/
+// and records it in that hour's manifest. The manifest is read back and rewritten, so the
+// writer must also implement gcs.BlobReader.
+func (s *Service) writeHourlyPartitions(ctx context.Context, sessionBuffers map[string][]schema.InteractionEvent) error {
+	reader, ok := s.gcsWriter.(gcs.BlobReader)
+	if !ok {
+		return fmt.Errorf("hourly layout requires a blob store that supports reads")
+	}
+
+	now := time.Now()
+	hours := make(map[string][]schema.InteractionEvent)
+	hourTimes := make(map[string]time.Time)
+	for _, events := range sessionBuffers {
+		for _, event := range events {
+			t := event.Timestamp
+			if t.IsZero() {
+				t = now
+			}
+			prefix := archive.HourPrefix(t)
+			hours[prefix] = append(hours[prefix], event)
+			hourTimes[prefix] = t
+		}
+	}
+
+	runID := uuid.New().String()
+	for prefix, events := range hours {
+		t := hourTimes[prefix]
+
+		// Keep each conversation contiguous and in order so readers can stream it.
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].ConversationId != events[j].ConversationId {
+				return events[i].ConversationId < events[j].ConversationId
+			}
+			return events[i].Timestamp.Before(events[j].Timestamp)
+		})
+
+		key := archive.DataKey(t, runID, s.format)
+		w, err := gcs.Create(ctx, s.gcsWriter, key, gcs.WriteOptions{Metadata: map[string]string{
+			"event_count": strconv.Itoa(len(events)),
+		}})
+		if err != nil {
+			return fmt.Errorf("gcs write error for partition %s: %w", prefix, err)
+		}
+		if err := archive.EncodeTo(w, s.format, events); err != nil {
+			_ = w.CloseWithError(err)
+			return fmt.Errorf("failed to encode partition %s: %w", prefix, err)
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("gcs write error for partition %s: %w", prefix, err)
+		}
+
+		var conversations []string
+		for _, event := range events {
+			if len(conversations) == 0 || conversations[len(conversations)-1] != event.ConversationId {
+				conversations = append(conversations, event.ConversationId)
+			}
+		}
+
+		if err := s.updateManifest(ctx, reader, t, archive.ManifestFile{
+			Key:           key,
+			Format:        s.format,
+			Conversations: conversations,
+			Tenants:       archive.EventTenants(events),
+			EventCount:    len(events),
+			CreatedAt:     now,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifestRetries bounds how often updateManifest retries after losing a race with another writer.
+const manifestRetries = 5
+
+// updateManifest adds file to the manifest of the hour containing t, creating it if needed.
+// When the store supports it the manifest is rewritten with a generation precondition, and the
+// read-modify-write is retried if another writer updated it in the meantime.
+func (s *Service) updateManifest(ctx context.Context, reader gcs.BlobReader, t time.Time, file archive.ManifestFile) error {
+	key := archive.ManifestKey(t)
+	for attempt := 1; ; attempt++ {
+		err := s.tryUpdateManifest(ctx, reader, key, t, file)
+		if !errors.Is(err, gcs.ErrPreconditionFailed) || attempt == manifestRetries {
+			return err
+		}
+		slog.Warn("Manifest changed concurrently, retrying", "manifest", key, "attempt", attempt)
+	}
+}
+
+func (s *Service) tryUpdateManifest(ctx context.Context, reader gcs.BlobReader, key string, t time.Time, file archive.ManifestFile) error {
+	// Capture the generation before reading so a concurrent update fails our write.
+	var generation string
+	if sr, ok := reader.(gcs.StreamReader); ok {
+		attrs, err := sr.Stat(ctx, key)
+		switch {
+		case err == nil:
+			generation = attrs.Generation
+		case errors.Is(err, gcs.ErrNotExist):
+			generation = gcs.NoGeneration
+		default:
+			return fmt.Errorf("failed to stat manifest %s: %w", key, err)
+		}
+	}
+
+	manifest := archive.NewManifest(t)
+	data, err := reader.Read(ctx, key)
+	switch {
+	case err == nil:
+		if manifest, err = archive.ParseManifest(data); err != nil {
+			return fmt.Errorf("failed to read manifest %s: %w", key, err)
+		}
+	case !errors.Is(err, gcs.ErrNotExist):
+		return fmt.Errorf("failed to read manifest %s: %w", key, err)
+	}
+
+	manifest.Add(file)
+
+	data, err = manifest.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest %s: %w", key, err)
+	}
+
+	w, err := gcs.Create(ctx, s.gcsWriter, key, gcs.WriteOptions{IfGenerationMatch: generation})
+	if err != nil {
+		return fmt.Errorf("gcs write error for manifest %s: %w", key, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.CloseWithError(err)
+		return fmt.Errorf("gcs write error for manifest %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("gcs write error for manifest %s: %w", key, err)
 	}
 	return nil
 }
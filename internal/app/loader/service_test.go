@@ -3,6 +3,8 @@ package loader_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -10,7 +12,12 @@ import (
 	"time"
 
 	"github.com/dllewellyn/reflex/internal/app/loader"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/envelope"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/kafka"
 	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/dllewellyn/reflex/internal/platform/state"
 )
 
 // --- Mocks ---
@@ -78,6 +85,53 @@ func (m *MockGCSWriter) Write(ctx context.Context, key string, data []byte) erro
 	return nil
 }
 
+// CountingConsumer is a streaming consumer that records offset commits.
+type CountingConsumer struct {
+	*kafka.MemoryConsumer
+	mu      sync.Mutex
+	commits int
+}
+
+func (c *CountingConsumer) Commit() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commits++
+	return nil
+}
+
+func (c *CountingConsumer) Commits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commits
+}
+
+// RacingStore simulates another loader updating a manifest between our read and our write.
+type RacingStore struct {
+	*gcs.MemoryClient
+	raced bool
+}
+
+func (r *RacingStore) Create(ctx context.Context, key string, opts gcs.WriteOptions) (gcs.Writer, error) {
+	if archive.IsManifestKey(key) && !r.raced {
+		r.raced = true
+		manifest := archive.NewManifest(time.Now())
+		if data, err := r.Read(ctx, key); err == nil {
+			if manifest, err = archive.ParseManifest(data); err != nil {
+				return nil, err
+			}
+		}
+		manifest.Add(archive.ManifestFile{Key: "concurrent-run", Format: archive.FormatJSONLGzip})
+		data, err := manifest.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Write(ctx, key, data); err != nil {
+			return nil, err
+		}
+	}
+	return r.MemoryClient.Create(ctx, key, opts)
+}
+
 // --- Tests ---
 
 func TestService_RunOnce(t *testing.T) {
@@ -108,7 +162,7 @@ func TestService_RunOnce(t *testing.T) {
 	if topic == "" {
 		topic = "test-topic"
 	}
-	svc := loader.NewService(mockConsumer, mockWriter, loader.Config{Topic: topic})
+	svc := loader.NewService(mockConsumer, mockWriter, nil, nil, loader.Config{Topic: topic})
 
 	// We need to override the silence timer duration in the service for testing,
 	// but the service hardcodes it to 10s.
@@ -163,3 +217,318 @@ func TestService_RunOnce(t *testing.T) {
 		t.Errorf("Expected first event ID int-1, got %s", loadedEvent.InteractionId)
 	}
 }
+
+func TestService_RunOnce_HourlyLayout(t *testing.T) {
+	for _, format := range []archive.Format{archive.FormatJSONLGzip, archive.FormatParquet} {
+		t.Run(string(format), func(t *testing.T) {
+			hour := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+			events := []*schema.InteractionEvent{
+				{InteractionId: "int-1", ConversationId: "conv-b", Timestamp: hour.Add(time.Minute), Role: "user", Content: "Hello"},
+				{InteractionId: "int-2", ConversationId: "conv-a", Timestamp: hour.Add(2 * time.Minute), Role: "user", Content: "Hi"},
+				{InteractionId: "int-3", ConversationId: "conv-a", Timestamp: hour.Add(time.Hour), Role: "model", Content: "Next hour"},
+			}
+
+			store := gcs.NewMemoryClient()
+			svc := loader.NewService(&MockConsumer{Messages: events}, store, nil, nil, loader.Config{
+				Topic:  "test-topic",
+				Layout: archive.LayoutHourly,
+				Format: format,
+			})
+
+			ctx := context.Background()
+			if err := svc.RunOnce(ctx); err != nil {
+				t.Fatalf("RunOnce failed: %v", err)
+			}
+			// A second run for the same hour must extend, not replace, the manifest.
+			svc = loader.NewService(&MockConsumer{Messages: events[:1]}, store, nil, nil, loader.Config{
+				Topic:  "test-topic",
+				Layout: archive.LayoutHourly,
+				Format: format,
+			})
+			if err := svc.RunOnce(ctx); err != nil {
+				t.Fatalf("second RunOnce failed: %v", err)
+			}
+
+			data, err := store.Read(ctx, archive.ManifestKey(hour))
+			if err != nil {
+				t.Fatalf("expected manifest for hour 10: %v", err)
+			}
+			manifest, err := archive.ParseManifest(data)
+			if err != nil {
+				t.Fatalf("failed to parse manifest: %v", err)
+			}
+			if len(manifest.Files) != 2 {
+				t.Fatalf("expected 2 files in manifest, got %d", len(manifest.Files))
+			}
+			if got := manifest.Conversations(); len(got) != 2 || got[0] != "conv-a" || got[1] != "conv-b" {
+				t.Errorf("expected conversations [conv-a conv-b], got %v", got)
+			}
+
+			file := manifest.Files[0]
+			if !strings.HasPrefix(file.Key, "raw/dt=2025-12-12/hr=10/part-") || !strings.HasSuffix(file.Key, format.Extension()) {
+				t.Errorf("unexpected data key %s", file.Key)
+			}
+			raw, err := store.Read(ctx, file.Key)
+			if err != nil {
+				t.Fatalf("failed to read data file: %v", err)
+			}
+			decoded, err := archive.Decode(file.Format, raw)
+			if err != nil {
+				t.Fatalf("failed to decode data file: %v", err)
+			}
+			if len(decoded) != 2 || decoded[0].InteractionId != "int-2" || decoded[1].InteractionId != "int-1" {
+				t.Errorf("expected events sorted by conversation, got %+v", decoded)
+			}
+			if !decoded[0].Timestamp.Equal(events[1].Timestamp) {
+				t.Errorf("expected timestamp %v, got %v", events[1].Timestamp, decoded[0].Timestamp)
+			}
+
+			if _, err := store.Read(ctx, archive.ManifestKey(hour.Add(time.Hour))); err != nil {
+				t.Errorf("expected manifest for hour 11: %v", err)
+			}
+		})
+	}
+}
+
+func TestService_RunStream(t *testing.T) {
+	now := time.Now()
+	newEvent := func(i int) schema.InteractionEvent {
+		return schema.InteractionEvent{
+			InteractionId:  fmt.Sprintf("int-%d", i),
+			ConversationId: "conv-1",
+			Timestamp:      now.Add(time.Duration(i) * time.Second),
+			Role:           "user",
+			Content:        "Hello",
+		}
+	}
+
+	t.Run("flushes on event limit and shutdown", func(t *testing.T) {
+		var events []schema.InteractionEvent
+		for i := 0; i < 5; i++ {
+			events = append(events, newEvent(i))
+		}
+		consumer := &CountingConsumer{MemoryConsumer: kafka.NewMemoryConsumer()}
+		consumer.Seed("test-topic", events)
+		store := gcs.NewMemoryClient()
+		svc := loader.NewService(consumer, store, nil, nil, loader.Config{
+			Topic:          "test-topic",
+			FlushMaxEvents: 2,
+			FlushMaxAge:    time.Hour,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if err := svc.RunStream(ctx); err != nil {
+			t.Fatalf("RunStream failed: %v", err)
+		}
+
+		keys, err := store.ListFiles(context.Background(), "raw/conv-1/")
+		if err != nil {
+			t.Fatalf("ListFiles failed: %v", err)
+		}
+		// Two size-triggered flushes of two events, then the fifth event on shutdown.
+		if len(keys) != 3 {
+			t.Errorf("expected 3 chunks, got %d: %v", len(keys), keys)
+		}
+		if got := consumer.Commits(); got != 3 {
+			t.Errorf("expected a commit per flush (3), got %d", got)
+		}
+	})
+
+	t.Run("flushes on age while running", func(t *testing.T) {
+		consumer := &CountingConsumer{MemoryConsumer: kafka.NewMemoryConsumer()}
+		consumer.Seed("test-topic", []schema.InteractionEvent{newEvent(0)})
+		store := gcs.NewMemoryClient()
+		svc := loader.NewService(consumer, store, nil, nil, loader.Config{
+			Topic:       "test-topic",
+			FlushMaxAge: 50 * time.Millisecond,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		done := make(chan error, 1)
+		go func() { done <- svc.RunStream(ctx) }()
+
+		deadline := time.After(2 * time.Second)
+		for consumer.Commits() == 0 {
+			select {
+			case err := <-done:
+				t.Fatalf("RunStream returned before flushing: %v", err)
+			case <-deadline:
+				t.Fatal("timed out waiting for age-based flush")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("RunStream failed: %v", err)
+		}
+		keys, _ := store.ListFiles(context.Background(), "raw/conv-1/")
+		if len(keys) != 1 {
+			t.Errorf("expected 1 chunk, got %d", len(keys))
+		}
+		if got := consumer.Commits(); got != 1 {
+			t.Errorf("expected no commit for the empty shutdown flush, got %d commits", got)
+		}
+	})
+}
+
+func TestService_RunOnce_HourlyLayout_ConcurrentManifestUpdate(t *testing.T) {
+	hour := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+	events := []*schema.InteractionEvent{
+		{InteractionId: "int-1", ConversationId: "conv-a", Timestamp: hour, Role: "user", Content: "Hello"},
+	}
+	store := &RacingStore{MemoryClient: gcs.NewMemoryClient()}
+	svc := loader.NewService(&MockConsumer{Messages: events}, store, nil, nil, loader.Config{
+		Topic:  "test-topic",
+		Layout: archive.LayoutHourly,
+	})
+
+	ctx := context.Background()
+	if err := svc.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	data, err := store.Read(ctx, archive.ManifestKey(hour))
+	if err != nil {
+		t.Fatalf("expected manifest: %v", err)
+	}
+	manifest, err := archive.ParseManifest(data)
+	if err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	// The conditional write must fail against the concurrent update and retry on top of it.
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected our file and the concurrent run's file, got %+v", manifest.Files)
+	}
+
+	ours := manifest.Files[0].Key
+	if ours == "concurrent-run" {
+		ours = manifest.Files[1].Key
+	}
+	attrs, err := store.Stat(ctx, ours)
+	if err != nil {
+		t.Fatalf("failed to stat data file: %v", err)
+	}
+	if attrs.Metadata["event_count"] != "1" {
+		t.Errorf("expected event_count metadata, got %v", attrs.Metadata)
+	}
+}
+
+func TestService_RunOnce_Encrypted(t *testing.T) {
+	hour := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+	acme := "acme"
+	events := []*schema.InteractionEvent{
+		{InteractionId: "int-1", ConversationId: "conv-a", Timestamp: hour, Role: "user", Content: "my password is hunter2", TenantId: &acme},
+		{InteractionId: "int-2", ConversationId: "conv-b", Timestamp: hour, Role: "user", Content: "Hello"},
+		// User content that looks sealed is still encrypted.
+		{InteractionId: "int-3", ConversationId: "conv-b", Timestamp: hour.Add(time.Second), Role: "user", Content: envelope.Prefix + "not really"},
+	}
+	devKey := []byte(strings.Repeat("d", 32))
+	provider, err := envelope.NewFileKeyProvider(map[string][]byte{
+		"dev":  devKey,
+		"acme": []byte(strings.Repeat("a", 32)),
+	})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	sealer, err := envelope.NewSealer(provider, envelope.Config{
+		DefaultKeyID: "dev",
+		TenantKeyIDs: map[string]string{"acme": "acme"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create sealer: %v", err)
+	}
+
+	store := gcs.NewMemoryClient()
+	svc := loader.NewService(&MockConsumer{Messages: events}, store, sealer, nil, loader.Config{
+		Topic:  "test-topic",
+		Layout: archive.LayoutHourly,
+	})
+	ctx := context.Background()
+	if err := svc.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	data, err := store.Read(ctx, archive.ManifestKey(hour))
+	if err != nil {
+		t.Fatalf("expected manifest: %v", err)
+	}
+	manifest, err := archive.ParseManifest(data)
+	if err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	if got := manifest.Files[0].Tenants; len(got) != 1 || got[0] != "acme" {
+		t.Errorf("expected tenants to stay readable, got %v", got)
+	}
+
+	raw, err := store.Read(ctx, manifest.Files[0].Key)
+	if err != nil {
+		t.Fatalf("failed to read data file: %v", err)
+	}
+	decoded, err := archive.Decode(manifest.Files[0].Format, raw)
+	if err != nil {
+		t.Fatalf("failed to decode data file: %v", err)
+	}
+	for _, event := range decoded {
+		if !envelope.IsSealed(event) || !strings.HasPrefix(event.Content, envelope.Prefix) || strings.Contains(event.Content, "not really") {
+			t.Errorf("expected %s content to be encrypted, got %q", event.InteractionId, event.Content)
+		}
+	}
+	if decoded[0].ConversationId != "conv-a" {
+		t.Fatalf("expected events sorted by conversation, got %+v", decoded)
+	}
+
+	if err := envelope.NewOpener(provider).OpenEvents(ctx, decoded); err != nil {
+		t.Fatalf("failed to decrypt events: %v", err)
+	}
+	if decoded[0].Content != "my password is hunter2" || decoded[1].Content != "Hello" || decoded[2].Content != envelope.Prefix+"not really" {
+		t.Errorf("unexpected decrypted content: %+v", decoded)
+	}
+
+	// acme's data key is wrapped with acme's own key, so the default key alone cannot read it.
+	devOnly, err := envelope.NewFileKeyProvider(map[string][]byte{"dev": devKey})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	sealed, err := archive.Decode(manifest.Files[0].Format, raw)
+	if err != nil {
+		t.Fatalf("failed to decode data file: %v", err)
+	}
+	if err := envelope.NewOpener(devOnly).OpenEvents(ctx, sealed[1:]); err != nil {
+		t.Errorf("expected the default tenant to decrypt with the default key: %v", err)
+	}
+	if err := envelope.NewOpener(devOnly).OpenEvents(ctx, sealed[:1]); !errors.Is(err, envelope.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey for acme without its key, got %v", err)
+	}
+}
+
+func TestService_RunOnce_RecordsCheckpoint(t *testing.T) {
+	hour := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+	events := []*schema.InteractionEvent{
+		{InteractionId: "int-1", ConversationId: "conv-a", Timestamp: hour, Role: "user", Content: "Hello"},
+		{InteractionId: "int-2", ConversationId: "conv-b", Timestamp: hour.Add(time.Minute), Role: "user", Content: "Hi"},
+	}
+	checkpoints := state.NewMemoryStore()
+	ctx := context.Background()
+
+	for run := 0; run < 2; run++ {
+		svc := loader.NewService(&MockConsumer{Messages: events}, gcs.NewMemoryClient(), nil, checkpoints, loader.Config{Topic: "test-topic"})
+		if err := svc.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+	}
+
+	var cp loader.Checkpoint
+	version, err := checkpoints.Get(ctx, loader.CheckpointName("test-topic"), &cp)
+	if err != nil {
+		t.Fatalf("expected checkpoint: %v", err)
+	}
+	if version != 2 || cp.Flushes != 2 || cp.Events != 4 {
+		t.Errorf("expected two flushes of two events, got version %d %+v", version, cp)
+	}
+	if !cp.Watermark.Equal(hour.Add(time.Minute)) {
+		t.Errorf("expected watermark %v, got %v", hour.Add(time.Minute), cp.Watermark)
+	}
+}
@@ -0,0 +1,257 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/kafka"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// shutdownFlushTimeout bounds the final flush after the stream context is cancelled.
+const shutdownFlushTimeout = 30 * time.Second
+
+// conversationBuffer holds the events buffered for one conversation since the last flush.
+type conversationBuffer struct {
+	events []schema.InteractionEvent
+	bytes  int
+}
+
+// streamBuffer holds everything consumed since the last flush. The consuming goroutine owns it;
+// the mutex only guards reads from the metrics callback.
+type streamBuffer struct {
+	mu            sync.Mutex
+	conversations map[string]*conversationBuffer
+	events        int
+	bytes         int
+	oldest        time.Time
+}
+
+func newStreamBuffer() *streamBuffer {
+	return &streamBuffer{conversations: make(map[string]*conversationBuffer)}
+}
+
+// add buffers event and returns the conversation's buffer after the append.
+func (b *streamBuffer) add(event schema.InteractionEvent, size int) *conversationBuffer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	conv, ok := b.conversations[event.ConversationId]
+	if !ok {
+		conv = &conversationBuffer{}
+		b.conversations[event.ConversationId] = conv
+	}
+	conv.events = append(conv.events, event)
+	conv.bytes += size
+	b.events++
+	b.bytes += size
+	if b.oldest.IsZero() {
+		b.oldest = time.Now()
+	}
+	return conv
+}
+
+// drain empties the buffer and returns its contents grouped by conversation.
+func (b *streamBuffer) drain() map[string][]schema.InteractionEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make(map[string][]schema.InteractionEvent, len(b.conversations))
+	for id, conv := range b.conversations {
+		out[id] = conv.events
+	}
+	b.conversations = make(map[string]*conversationBuffer)
+	b.events = 0
+	b.bytes = 0
+	b.oldest = time.Time{}
+	return out
+}
+
+type bufferStats struct {
+	conversations int
+	events        int
+	bytes         int
+	age           time.Duration
+}
+
+func (b *streamBuffer) stats() bufferStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := bufferStats{conversations: len(b.conversations), events: b.events, bytes: b.bytes}
+	if !b.oldest.IsZero() {
+		st.age = time.Since(b.oldest)
+	}
+	return st
+}
+
+// streamMetrics records the loader's buffer depth and flush activity.
+type streamMetrics struct {
+	flushes       metric.Int64Counter
+	flushedEvents metric.Int64Counter
+	registration  metric.Registration
+}
+
+func newStreamMetrics(buf *streamBuffer) (*streamMetrics, error) {
+	meter := otel.Meter("loader-service")
+
+	bufferedEvents, err := meter.Int64ObservableGauge("loader.buffer.events",
+		metric.WithDescription("Events buffered in memory awaiting flush"))
+	if err != nil {
+		return nil, err
+	}
+	bufferedBytes, err := meter.Int64ObservableGauge("loader.buffer.bytes",
+		metric.WithDescription("Bytes of serialized events buffered in memory awaiting flush"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	bufferedConversations, err := meter.Int64ObservableGauge("loader.buffer.conversations",
+		metric.WithDescription("Conversations with events buffered in memory"))
+	if err != nil {
+		return nil, err
+	}
+	bufferAge, err := meter.Float64ObservableGauge("loader.buffer.age",
+		metric.WithDescription("Time the oldest buffered event has waited for a flush"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		st := buf.stats()
+		o.ObserveInt64(bufferedEvents, int64(st.events))
+		o.ObserveInt64(bufferedBytes, int64(st.bytes))
+		o.ObserveInt64(bufferedConversations, int64(st.conversations))
+		o.ObserveFloat64(bufferAge, st.age.Seconds())
+		return nil
+	}, bufferedEvents, bufferedBytes, bufferedConversations, bufferAge)
+	if err != nil {
+		return nil, err
+	}
+
+	flushes, err := meter.Int64Counter("loader.flushes",
+		metric.WithDescription("Buffer flushes, by trigger"))
+	if err != nil {
+		return nil, err
+	}
+	flushedEvents, err := meter.Int64Counter("loader.flushed.events",
+		metric.WithDescription("Events written to the archive by buffer flushes"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamMetrics{flushes: flushes, flushedEvents: flushedEvents, registration: registration}, nil
+}
+
+// RunStream consumes messages until the context is cancelled, flushing buffered events to GCS and
+// committing offsets whenever a conversation reaches FlushMaxEvents or FlushMaxBytes, the oldest
+// buffered event reaches FlushMaxAge, or the whole buffer reaches MaxBufferBytes. On cancellation
+// (e.g. SIGTERM) whatever is buffered is flushed and committed before returning.
+//
+// Kafka offsets are committed per partition rather than per conversation, so every flush writes
+// all buffered conversations: committing after a partial flush would acknowledge events that are
+// still only held in memory.
+func (s *Service) RunStream(ctx context.Context) error {
+	consumer, ok := s.consumer.(kafka.StreamingConsumer)
+	if !ok {
+		return fmt.Errorf("streaming mode requires a consumer that supports ConsumeStream")
+	}
+
+	buf := newStreamBuffer()
+	metrics, err := newStreamMetrics(buf)
+	if err != nil {
+		return fmt.Errorf("failed to register loader metrics: %w", err)
+	}
+	defer func() {
+		if err := metrics.registration.Unregister(); err != nil {
+			slog.Warn("Failed to unregister loader metrics", "error", err)
+		}
+	}()
+
+	slog.Info("Streaming messages from Kafka...", "topic", s.topic, "layout", s.layout,
+		"flush_max_events", s.flushMaxEvents, "flush_max_bytes", s.flushMaxBytes,
+		"flush_max_age", s.flushMaxAge, "max_buffer_bytes", s.maxBufferBytes)
+
+	// Check the age trigger often enough to honour FlushMaxAge without spinning.
+	interval := min(s.flushMaxAge/4, time.Second)
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	err = consumer.ConsumeStream(ctx, s.topic, func(ctx context.Context, msg *schema.InteractionEvent) error {
+		if msg == nil {
+			return nil
+		}
+
+		b, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("marshal error: %w", err)
+		}
+		conv := buf.add(*msg, len(b)+1)
+
+		switch {
+		case len(conv.events) >= s.flushMaxEvents:
+			return s.flush(ctx, buf, metrics, "max_events")
+		case conv.bytes >= s.flushMaxBytes:
+			return s.flush(ctx, buf, metrics, "max_bytes")
+		case buf.stats().bytes >= s.maxBufferBytes:
+			return s.flush(ctx, buf, metrics, "max_buffer_bytes")
+		}
+		return nil
+	}, interval, func(ctx context.Context) error {
+		if st := buf.stats(); st.events > 0 && st.age >= s.flushMaxAge {
+			return s.flush(ctx, buf, metrics, "max_age")
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("consumption error: %w", err)
+	}
+
+	slog.Info("Stream stopped, flushing remaining buffer", "events", buf.stats().events)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownFlushTimeout)
+	defer cancel()
+	if err := s.flush(shutdownCtx, buf, metrics, "shutdown"); err != nil {
+		return err
+	}
+
+	slog.Info("Stream shut down cleanly")
+	return nil
+}
+
+// flush writes everything buffered and then commits offsets. If the write fails nothing is
+// committed, so the events are redelivered after a restart.
+func (s *Service) flush(ctx context.Context, buf *streamBuffer, metrics *streamMetrics, reason string) error {
+	st := buf.stats()
+	if st.events == 0 {
+		return nil
+	}
+
+	tr := otel.Tracer("loader-service")
+	ctx, span := tr.Start(ctx, "Flush")
+	defer span.End()
+
+	slog.Info("Flushing buffer", "reason", reason, "conversations", st.conversations, "events", st.events, "bytes", st.bytes, "age", st.age)
+
+	if err := s.write(ctx, buf.drain()); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if err := s.consumer.Commit(); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to commit offsets: %w", err)
+	}
+
+	attrs := metric.WithAttributes(attribute.String("reason", reason))
+	metrics.flushes.Add(ctx, 1, attrs)
+	metrics.flushedEvents.Add(ctx, int64(st.events), attrs)
+	return nil
+}
@@ -0,0 +1,203 @@
+package redaction
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind is a category of sensitive value.
+type Kind string
+
+const (
+	KindEmail  Kind = "email"
+	KindPhone  Kind = "phone"
+	KindCard   Kind = "card"
+	KindSecret Kind = "secret"
+)
+
+// AllKinds lists every kind in priority order: where matches overlap, the earlier kind wins.
+var AllKinds = []Kind{KindSecret, KindCard, KindEmail, KindPhone}
+
+// Match is a sensitive value found at text[Start:End].
+type Match struct {
+	Kind  Kind
+	Start int
+	End   int
+}
+
+// Detector finds sensitive values of one kind.
+type Detector interface {
+	Kind() Kind
+	Find(text string) []Match
+}
+
+// regexDetector reports every match of a pattern that passes an optional validation.
+type regexDetector struct {
+	kind     Kind
+	patterns []*regexp.Regexp
+	valid    func(value string) bool
+}
+
+func (d *regexDetector) Kind() Kind { return d.kind }
+
+func (d *regexDetector) Find(text string) []Match {
+	var matches []Match
+	for _, re := range d.patterns {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if d.valid != nil && !d.valid(text[loc[0]:loc[1]]) {
+				continue
+			}
+			matches = append(matches, Match{Kind: d.kind, Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+
+// NewEmailDetector finds email addresses.
+func NewEmailDetector() Detector {
+	return &regexDetector{kind: KindEmail, patterns: []*regexp.Regexp{emailPattern}}
+}
+
+var phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\d{2,4}(?:[ .\-]?\d{2,4}){2,4}`)
+
+// NewPhoneDetector finds phone numbers: 9 to 15 digits, optionally with a +country code,
+// an area code in parentheses and space, dot or dash separators.
+func NewPhoneDetector() Detector {
+	return &regexDetector{kind: KindPhone, patterns: []*regexp.Regexp{phonePattern}, valid: func(v string) bool {
+		n := len(digitsOf(v))
+		return n >= 9 && n <= 15
+	}}
+}
+
+var cardPattern = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+
+// NewCardDetector finds payment card numbers: 13 to 19 digits, optionally separated by spaces or
+// dashes, that pass the Luhn check.
+func NewCardDetector() Detector {
+	return &regexDetector{kind: KindCard, patterns: []*regexp.Regexp{cardPattern}, valid: func(v string) bool {
+		digits := digitsOf(v)
+		return len(digits) >= 13 && len(digits) <= 19 && Luhn(digits)
+	}}
+}
+
+// Luhn reports whether a string of digits passes the Luhn checksum used by payment card numbers.
+func Luhn(digits string) bool {
+	if digits == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		c := digits[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// secretPatterns match credentials with a recognisable format.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{20,}`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`),
+}
+
+// secretCandidate matches tokens long enough to be checked for entropy.
+var secretCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_\-]+`)
+
+// secretDetector finds credentials by known format and, failing that, by looking random: long
+// tokens mixing letters and digits whose Shannon entropy reaches a threshold.
+type secretDetector struct {
+	minLength int
+	threshold float64
+}
+
+// NewSecretDetector finds API keys, tokens and private keys. Tokens of at least minLength
+// characters with entropy of at least threshold bits per character are also reported.
+func NewSecretDetector(minLength int, threshold float64) Detector {
+	return &secretDetector{minLength: minLength, threshold: threshold}
+}
+
+func (d *secretDetector) Kind() Kind { return KindSecret }
+
+func (d *secretDetector) Find(text string) []Match {
+	known := (&regexDetector{kind: KindSecret, patterns: secretPatterns}).Find(text)
+	matches := known
+	for _, loc := range secretCandidate.FindAllStringIndex(text, -1) {
+		token := text[loc[0]:loc[1]]
+		if len(token) < d.minLength || !mixesLettersAndDigits(token) || entropy(token) < d.threshold {
+			continue
+		}
+		m := Match{Kind: KindSecret, Start: loc[0], End: loc[1]}
+		if !overlapsAny(m, known) {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func mixesLettersAndDigits(s string) bool {
+	var letter, digit bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return letter && digit
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+func overlapsAny(m Match, others []Match) bool {
+	for _, o := range others {
+		if m.Start < o.End && o.Start < m.End {
+			return true
+		}
+	}
+	return false
+}
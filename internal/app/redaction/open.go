@@ -0,0 +1,47 @@
+package redaction
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+)
+
+// Open builds a Redactor from the policy file at configFile, masking everything if it is empty.
+// hmacKey enables tokenizing policies, and vaultURL, when set, makes their tokens reversible by
+// recording the original values in that store. The returned func releases the vault.
+func Open(ctx context.Context, configFile, hmacKey, vaultURL string) (*Redactor, func(), error) {
+	cfg := DefaultConfig()
+	if configFile != "" {
+		var err error
+		if cfg, err = LoadConfig(configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	cleanup := func() {}
+	var tokenizer *Tokenizer
+	if hmacKey != "" {
+		var vault Vault
+		if vaultURL != "" {
+			store, err := gcs.OpenURL(ctx, vaultURL)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open redaction vault: %w", err)
+			}
+			cleanup = func() { _ = store.Close() }
+			vault = NewBlobVault(store)
+		}
+		var err error
+		if tokenizer, err = NewTokenizer([]byte(hmacKey), vault); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	redactor, err := NewRedactor(tokenizer, cfg)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return redactor, cleanup, nil
+}
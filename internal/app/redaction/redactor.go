@@ -0,0 +1,197 @@
+package redaction
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mode selects what happens to sensitive values.
+type Mode string
+
+const (
+	// ModeOff leaves content untouched.
+	ModeOff Mode = "off"
+	// ModeMask replaces each value with a placeholder naming its kind, e.g. [REDACTED_EMAIL].
+	ModeMask Mode = "mask"
+	// ModeTokenize replaces each value with a keyed HMAC token; see Tokenizer.
+	ModeTokenize Mode = "tokenize"
+)
+
+// Policy configures redaction for one tenant.
+type Policy struct {
+	Mode Mode `yaml:"mode"`
+	// Kinds limits redaction to these kinds. Empty means every kind.
+	Kinds []Kind `yaml:"kinds"`
+}
+
+// Config is the redaction configuration, usually loaded from YAML:
+//
+//	default:
+//	  mode: mask
+//	tenants:
+//	  acme:
+//	    mode: tokenize
+//	    kinds: [email, card]
+//	  internal-test:
+//	    mode: "off"
+type Config struct {
+	Default Policy            `yaml:"default"`
+	Tenants map[string]Policy `yaml:"tenants"`
+	// SecretMinLength is the shortest token checked for entropy. Defaults to 20.
+	SecretMinLength int `yaml:"secret_min_length"`
+	// SecretEntropy is the entropy, in bits per character, from which a token is treated as a
+	// secret. Defaults to 4.0.
+	SecretEntropy float64 `yaml:"secret_entropy"`
+}
+
+// DefaultConfig masks every kind for every tenant.
+func DefaultConfig() Config {
+	return Config{Default: Policy{Mode: ModeMask}}
+}
+
+// LoadConfig reads a Config from a YAML file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read redaction config %s: %w", path, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse redaction config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// PolicyFor returns the policy for tenant, falling back to the default.
+func (c Config) PolicyFor(tenant string) Policy {
+	if p, ok := c.Tenants[tenant]; ok && tenant != "" {
+		return p
+	}
+	return c.Default
+}
+
+// Result is redacted content and what was removed from it.
+type Result struct {
+	Text string
+	// Counts is the number of values redacted, by kind. It is nil if nothing was redacted.
+	Counts map[string]int
+}
+
+type Redactor struct {
+	config    Config
+	detectors map[Kind]Detector
+	tokenizer *Tokenizer
+}
+
+// NewRedactor creates a Redactor. tokenizer may be nil unless a policy uses ModeTokenize.
+func NewRedactor(tokenizer *Tokenizer, cfg Config) (*Redactor, error) {
+	policies := map[string]Policy{"default": cfg.Default}
+	for tenant, p := range cfg.Tenants {
+		policies["tenant "+tenant] = p
+	}
+	for name, p := range policies {
+		switch p.Mode {
+		case ModeOff, ModeMask, "":
+		case ModeTokenize:
+			if tokenizer == nil {
+				return nil, fmt.Errorf("redaction policy for %s tokenizes but no tokenization key is configured", name)
+			}
+		default:
+			return nil, fmt.Errorf("redaction policy for %s has unknown mode %q", name, p.Mode)
+		}
+		for _, k := range p.Kinds {
+			if !knownKind(k) {
+				return nil, fmt.Errorf("redaction policy for %s has unknown kind %q", name, k)
+			}
+		}
+	}
+
+	minLength := cfg.SecretMinLength
+	if minLength <= 0 {
+		minLength = 20
+	}
+	threshold := cfg.SecretEntropy
+	if threshold <= 0 {
+		threshold = 4.0
+	}
+	return &Redactor{
+		config: cfg,
+		detectors: map[Kind]Detector{
+			KindEmail:  NewEmailDetector(),
+			KindPhone:  NewPhoneDetector(),
+			KindCard:   NewCardDetector(),
+			KindSecret: NewSecretDetector(minLength, threshold),
+		},
+		tokenizer: tokenizer,
+	}, nil
+}
+
+func knownKind(k Kind) bool {
+	for _, known := range AllKinds {
+		if k == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact applies tenant's policy to text.
+func (r *Redactor) Redact(ctx context.Context, tenant, text string) (*Result, error) {
+	policy := r.config.PolicyFor(tenant)
+	if policy.Mode == ModeOff || text == "" {
+		return &Result{Text: text}, nil
+	}
+
+	matches := r.find(text, policy.Kinds)
+	if len(matches) == 0 {
+		return &Result{Text: text}, nil
+	}
+
+	var b strings.Builder
+	counts := make(map[string]int)
+	last := 0
+	for _, m := range matches {
+		replacement := fmt.Sprintf("[REDACTED_%s]", strings.ToUpper(string(m.Kind)))
+		if policy.Mode == ModeTokenize {
+			token, err := r.tokenizer.Token(ctx, m.Kind, text[m.Start:m.End])
+			if err != nil {
+				return nil, err
+			}
+			replacement = token
+		}
+		b.WriteString(text[last:m.Start])
+		b.WriteString(replacement)
+		last = m.End
+		counts[string(m.Kind)]++
+	}
+	b.WriteString(text[last:])
+	return &Result{Text: b.String(), Counts: counts}, nil
+}
+
+// find returns the non-overlapping matches of kinds (all kinds if empty) in text, ordered by
+// position. Overlaps are resolved in favour of the kind earlier in AllKinds.
+func (r *Redactor) find(text string, kinds []Kind) []Match {
+	enabled := make(map[Kind]bool)
+	for _, k := range kinds {
+		enabled[k] = true
+	}
+
+	var accepted []Match
+	for _, kind := range AllKinds {
+		if len(enabled) > 0 && !enabled[kind] {
+			continue
+		}
+		for _, m := range r.detectors[kind].Find(text) {
+			if !overlapsAny(m, accepted) {
+				accepted = append(accepted, m)
+			}
+		}
+	}
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i].Start < accepted[j].Start })
+	return accepted
+}
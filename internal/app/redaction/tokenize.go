@@ -0,0 +1,116 @@
+package redaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+)
+
+// minKeyLength is the shortest HMAC key NewTokenizer accepts.
+const minKeyLength = 32
+
+// ErrNoVault is returned by Reveal when the tokenizer has no vault to look tokens up in.
+var ErrNoVault = errors.New("tokenizer has no vault")
+
+// Vault keeps the original value behind each token so that authorised tooling can reverse it.
+type Vault interface {
+	Put(ctx context.Context, token, value string) error
+	Get(ctx context.Context, token string) (string, error)
+}
+
+// Tokenizer replaces sensitive values with keyed HMAC tokens such as [EMAIL_3f9a0c2b7d41e865].
+// The same value always yields the same token, so redacted events can still be correlated, but
+// the value cannot be recovered from the token without the vault.
+type Tokenizer struct {
+	key   []byte
+	vault Vault
+}
+
+// NewTokenizer creates a Tokenizer with the given HMAC key. Tokens are recorded in vault, when
+// not nil, so they can be revealed later.
+func NewTokenizer(key []byte, vault Vault) (*Tokenizer, error) {
+	if len(key) < minKeyLength {
+		return nil, fmt.Errorf("tokenization key must be at least %d bytes, got %d", minKeyLength, len(key))
+	}
+	return &Tokenizer{key: key, vault: vault}, nil
+}
+
+// Token returns the token for value and records it in the vault.
+func (t *Tokenizer) Token(ctx context.Context, kind Kind, value string) (string, error) {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	token := fmt.Sprintf("[%s_%s]", strings.ToUpper(string(kind)), hex.EncodeToString(mac.Sum(nil)[:8]))
+
+	if t.vault != nil {
+		if err := t.vault.Put(ctx, token, value); err != nil {
+			return "", fmt.Errorf("failed to store token: %w", err)
+		}
+	}
+	return token, nil
+}
+
+// Reveal returns the original value behind token.
+func (t *Tokenizer) Reveal(ctx context.Context, token string) (string, error) {
+	if t.vault == nil {
+		return "", ErrNoVault
+	}
+	return t.vault.Get(ctx, token)
+}
+
+var tokenPattern = regexp.MustCompile(`^\[([A-Z]+)_([0-9a-f]{16})\]$`)
+
+// BlobVault is a Vault kept in a blob store under vault/<kind>/<hash>. It holds the original
+// sensitive values, so it belongs in a store with tighter access than the archive.
+type BlobVault struct {
+	store VaultStore
+}
+
+// VaultStore is the blob storage a BlobVault reads and writes.
+type VaultStore interface {
+	gcs.BlobReader
+	gcs.BlobWriter
+}
+
+// NewBlobVault creates a BlobVault over store.
+func NewBlobVault(store VaultStore) *BlobVault {
+	return &BlobVault{store: store}
+}
+
+func vaultKey(token string) (string, error) {
+	m := tokenPattern.FindStringSubmatch(token)
+	if m == nil {
+		return "", fmt.Errorf("invalid token %q", token)
+	}
+	return fmt.Sprintf("vault/%s/%s", strings.ToLower(m[1]), m[2]), nil
+}
+
+// Put records value behind token.
+func (v *BlobVault) Put(ctx context.Context, token, value string) error {
+	key, err := vaultKey(token)
+	if err != nil {
+		return err
+	}
+	return v.store.Write(ctx, key, []byte(value))
+}
+
+// Get returns the value behind token.
+func (v *BlobVault) Get(ctx context.Context, token string) (string, error) {
+	key, err := vaultKey(token)
+	if err != nil {
+		return "", err
+	}
+	data, err := v.store.Read(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up token %s: %w", token, err)
+	}
+	return string(data), nil
+}
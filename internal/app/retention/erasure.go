@@ -0,0 +1,440 @@
+package retention
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// ErasureRequest identifies the data to erase. At least one of UserID and ConversationID is required.
+type ErasureRequest struct {
+	// UserID erases every conversation containing an event from this user.
+	UserID string
+	// ConversationID erases this conversation.
+	ConversationID string
+	// RequestedBy records who asked for the erasure, e.g. an operator or ticket reference.
+	RequestedBy string
+}
+
+// AuditRecord is the durable record of an erasure. It names what was removed but holds none of
+// the removed content.
+type AuditRecord struct {
+	ID             string `json:"id"`
+	RequestedBy    string `json:"requested_by,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	// Conversations lists every conversation erased, including those found for UserID.
+	Conversations []string  `json:"conversations"`
+	DryRun        bool      `json:"dry_run"`
+	StartedAt     time.Time `json:"started_at"`
+	CompletedAt   time.Time `json:"completed_at"`
+	Removals      []Removal `json:"removals"`
+	// Failures lists objects that could not be removed; the erasure must be retried.
+	Failures []string `json:"failures,omitempty"`
+}
+
+// AuditKey returns the key of the audit record with the given ID for an erasure started at t.
+func AuditKey(t time.Time, id string) string {
+	return fmt.Sprintf("audit/erasure/%s/%s.json", t.UTC().Format("2006/01/02"), id)
+}
+
+// Erase deletes every raw chunk, hourly partition event, staging file and batch result row of the
+// requested conversations, and writes an AuditRecord of what was removed. For a UserID, the raw
+// archive is scanned for the conversations containing that user's events first. Failures to remove
+// individual objects do not stop the erasure; they are listed in the audit record and reported as
+// an error so the request can be retried.
+func (s *Service) Erase(ctx context.Context, req ErasureRequest) (*AuditRecord, error) {
+	if req.UserID == "" && req.ConversationID == "" {
+		return nil, errors.New("erasure requires a user ID or conversation ID")
+	}
+
+	tr := otel.Tracer("retention-service")
+	ctx, span := tr.Start(ctx, "Erase")
+	defer span.End()
+
+	record := &AuditRecord{
+		ID:             uuid.New().String(),
+		RequestedBy:    req.RequestedBy,
+		UserID:         req.UserID,
+		ConversationID: req.ConversationID,
+		DryRun:         s.dryRun,
+		StartedAt:      time.Now(),
+	}
+
+	conversations := make(map[string]bool)
+	if req.ConversationID != "" {
+		conversations[req.ConversationID] = true
+	}
+	if req.UserID != "" {
+		found, err := s.findUserConversations(ctx, req.UserID)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		for _, id := range found {
+			conversations[id] = true
+		}
+	}
+	for id := range conversations {
+		record.Conversations = append(record.Conversations, id)
+	}
+	sort.Strings(record.Conversations)
+
+	fail := func(key string, err error) {
+		slog.Error("Failed to erase object", "key", key, "error", err)
+		record.Failures = append(record.Failures, key)
+	}
+
+	if err := s.eraseSessions(ctx, record, fail); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.eraseHours(ctx, conversations, record, fail); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.eraseStaging(ctx, conversations, record, fail); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.eraseResults(ctx, record, fail); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	record.CompletedAt = time.Now()
+
+	if !s.dryRun {
+		if err := s.writeAudit(ctx, record); err != nil {
+			span.RecordError(err)
+			return record, err
+		}
+	}
+
+	slog.Info("Erasure complete", "audit_id", record.ID, "conversations", len(record.Conversations),
+		"removals", len(record.Removals), "failures", len(record.Failures), "dry_run", s.dryRun)
+	if len(record.Failures) > 0 {
+		return record, fmt.Errorf("erasure %s incomplete: %d objects could not be removed", record.ID, len(record.Failures))
+	}
+	return record, nil
+}
+
+// findUserConversations scans the raw archive, in either layout, for conversations containing an
+// event from userID.
+func (s *Service) findUserConversations(ctx context.Context, userID string) ([]string, error) {
+	found := make(map[string]bool)
+	matches := func(event schema.InteractionEvent) bool {
+		return event.UserId != nil && *event.UserId == userID
+	}
+
+	keys, err := s.raw.ListFiles(ctx, rawPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raw archive: %w", err)
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, hourlyPrefix) || archive.IsManifestKey(key) {
+			continue
+		}
+		conversationID := strings.SplitN(strings.TrimPrefix(key, rawPrefix), "/", 2)[0]
+		if found[conversationID] {
+			continue
+		}
+		ok, err := s.chunkContains(ctx, key, matches)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found[conversationID] = true
+		}
+	}
+
+	hours, err := s.listHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, hour := range hours {
+		manifestKey := archive.ManifestKey(hour)
+		data, err := s.raw.Read(ctx, manifestKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", manifestKey, err)
+		}
+		manifest, err := archive.ParseManifest(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range manifest.Files {
+			data, err := s.raw.Read(ctx, file.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read partition file %s: %w", file.Key, err)
+			}
+			events, err := archive.Decode(file.Format, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode partition file %s: %w", file.Key, err)
+			}
+			for _, event := range events {
+				if matches(event) {
+					found[event.ConversationId] = true
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// chunkContains streams a JSONL chunk and reports whether any event matches.
+func (s *Service) chunkContains(ctx context.Context, key string, matches func(schema.InteractionEvent) bool) (bool, error) {
+	rc, err := gcs.Open(ctx, s.raw, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read chunk %s: %w", key, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	dec := json.NewDecoder(rc)
+	for {
+		var event schema.InteractionEvent
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to parse chunk %s: %w", key, err)
+		}
+		if matches(event) {
+			return true, nil
+		}
+	}
+}
+
+// eraseSessions deletes every object under the conversations' session layout prefixes, including
+// their compaction manifests.
+func (s *Service) eraseSessions(ctx context.Context, record *AuditRecord, fail func(string, error)) error {
+	for _, conversationID := range record.Conversations {
+		keys, err := s.raw.ListFiles(ctx, archive.SessionPrefix(conversationID))
+		if err != nil {
+			return fmt.Errorf("failed to list chunks of %s: %w", conversationID, err)
+		}
+		for _, key := range keys {
+			if err := s.remove(ctx, s.raw, key); err != nil {
+				fail(key, err)
+				continue
+			}
+			record.Removals = append(record.Removals, Removal{Store: storeRaw, Key: key, Action: ActionDeleted})
+		}
+	}
+	return nil
+}
+
+// eraseHours drops the conversations' events from every hourly partition that lists them.
+func (s *Service) eraseHours(ctx context.Context, conversations map[string]bool, record *AuditRecord, fail func(string, error)) error {
+	hours, err := s.listHours(ctx)
+	if err != nil {
+		return err
+	}
+	for _, hour := range hours {
+		removals, err := s.filterHour(ctx, hour,
+			func(file archive.ManifestFile) bool {
+				for _, id := range file.Conversations {
+					if conversations[id] {
+						return true
+					}
+				}
+				return false
+			},
+			func(event schema.InteractionEvent) bool {
+				return conversations[event.ConversationId]
+			})
+		record.Removals = append(record.Removals, removals...)
+		if err != nil {
+			fail(archive.HourPrefix(hour), err)
+		}
+	}
+	return nil
+}
+
+// eraseStaging deletes the conversations' batch input files, staging/YYYY/MM/DD/<conversation_id>.jsonl.
+func (s *Service) eraseStaging(ctx context.Context, conversations map[string]bool, record *AuditRecord, fail func(string, error)) error {
+	if s.staging == nil {
+		return nil
+	}
+	keys, err := s.staging.ListFiles(ctx, stagingPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list staging store: %w", err)
+	}
+	for _, key := range keys {
+		if !conversations[strings.TrimSuffix(path.Base(key), ".jsonl")] {
+			continue
+		}
+		if err := s.remove(ctx, s.staging, key); err != nil {
+			fail(key, err)
+			continue
+		}
+		record.Removals = append(record.Removals, Removal{Store: storeStaging, Key: key, Action: ActionDeleted})
+	}
+	return nil
+}
+
+// eraseResults removes every batch result row whose transcript contains an event from one of the
+// conversations. Files left empty are deleted.
+func (s *Service) eraseResults(ctx context.Context, record *AuditRecord, fail func(string, error)) error {
+	if s.results == nil || len(record.Conversations) == 0 {
+		return nil
+	}
+	keys, err := s.results.ListFiles(ctx, resultsPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list results store: %w", err)
+	}
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".jsonl") {
+			continue
+		}
+		removal, err := s.filterResultRows(ctx, key, record.Conversations)
+		if err != nil {
+			fail(key, err)
+			continue
+		}
+		if removal != nil {
+			record.Removals = append(record.Removals, *removal)
+		}
+	}
+	return nil
+}
+
+// filterResultRows rewrites the results file at key without the rows whose transcript belongs to
+// any of conversations, returning nil if none do.
+func (s *Service) filterResultRows(ctx context.Context, key string, conversations []string) (*Removal, error) {
+	// Capture the generation before reading so a concurrent rewrite fails ours.
+	var generation string
+	if sr, ok := s.results.(gcs.StreamReader); ok {
+		attrs, err := sr.Stat(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		generation = attrs.Generation
+	}
+	data, err := s.results.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	erase := make(map[string]bool, len(conversations))
+	for _, id := range conversations {
+		erase[id] = true
+	}
+	var kept bytes.Buffer
+	removed, remaining := 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		ids, err := resultConversations(line)
+		if err != nil {
+			slog.Warn("Keeping batch result row that could not be parsed", "key", key, "error", err)
+		}
+		if containsAny(erase, ids) {
+			removed++
+			continue
+		}
+		remaining++
+		kept.Write(line)
+		kept.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", key, err)
+	}
+	if removed == 0 {
+		return nil, nil
+	}
+
+	removal := &Removal{Store: storeResults, Key: key, Action: ActionRewritten, Removed: removed}
+	if remaining == 0 {
+		removal.Action = ActionDeleted
+	}
+	if s.dryRun {
+		return removal, nil
+	}
+	if remaining == 0 {
+		if err := s.remove(ctx, s.results, key); err != nil {
+			return nil, err
+		}
+		return removal, nil
+	}
+	if err := s.writeObject(ctx, s.results, key, kept.Bytes(), gcs.WriteOptions{IfGenerationMatch: generation}); err != nil {
+		return nil, err
+	}
+	return removal, nil
+}
+
+// resultConversations returns the conversation IDs of the events in a batch result row's
+// transcript, which the prompt embeds one JSON event per line.
+func resultConversations(line []byte) ([]string, error) {
+	var row schema.Record
+	if err := json.Unmarshal(line, &row); err != nil {
+		return nil, err
+	}
+	if row.Request == nil {
+		return nil, nil
+	}
+	var ids []string
+	for _, content := range row.Request.Contents {
+		for _, part := range content.Parts {
+			if part.Text == nil {
+				continue
+			}
+			for _, text := range strings.Split(*part.Text, "\n") {
+				start := strings.IndexByte(text, '{')
+				if start < 0 {
+					continue
+				}
+				var event schema.InteractionEvent
+				if err := json.Unmarshal([]byte(text[start:]), &event); err == nil && event.ConversationId != "" {
+					ids = append(ids, event.ConversationId)
+				}
+			}
+		}
+	}
+	return ids, nil
+}
+
+func containsAny(set map[string]bool, ids []string) bool {
+	for _, id := range ids {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAudit stores record under AuditKey.
+func (s *Service) writeAudit(ctx context.Context, record *AuditRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	key := AuditKey(record.StartedAt, record.ID)
+	if err := s.audit.Write(ctx, key, data); err != nil {
+		return fmt.Errorf("failed to write audit record %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package retention
+
+import (
+	"time"
+)
+
+// Policy sets how long archived data is kept. A TTL of zero keeps data forever.
+type Policy struct {
+	// DefaultTTL applies to data with no tenant, data whose tenant has no override, and data that
+	// does not record its tenant (staging files and batch results).
+	DefaultTTL time.Duration
+	// TenantTTLs overrides DefaultTTL for individual tenants.
+	TenantTTLs map[string]time.Duration
+}
+
+// TTL returns the retention period for tenant.
+func (p Policy) TTL(tenant string) time.Duration {
+	if ttl, ok := p.TenantTTLs[tenant]; ok && tenant != "" {
+		return ttl
+	}
+	return p.DefaultTTL
+}
+
+// Expired reports whether data for tenant whose newest event is at or before end has outlived
+// its TTL at now.
+func (p Policy) Expired(tenant string, end, now time.Time) bool {
+	ttl := p.TTL(tenant)
+	return ttl > 0 && now.Sub(end) >= ttl
+}
+
+// AnyExpired reports whether data for any of tenants, or for events without a tenant, has
+// outlived its TTL. It is used to decide whether a mixed-tenant file needs to be read at all.
+func (p Policy) AnyExpired(tenants []string, end, now time.Time) bool {
+	if p.Expired("", end, now) {
+		return true
+	}
+	for _, tenant := range tenants {
+		if p.Expired(tenant, end, now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,496 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// Store is the blob storage retention reads, rewrites and prunes.
+type Store interface {
+	gcs.BlobReader
+	gcs.BlobWriter
+	gcs.BlobDeleter
+}
+
+const (
+	rawPrefix     = "raw/"
+	hourlyPrefix  = "raw/dt="
+	stagingPrefix = "staging/"
+	resultsPrefix = "results/"
+)
+
+// Names of the stores in reports and audit records.
+const (
+	storeRaw     = "raw"
+	storeStaging = "staging"
+	storeResults = "results"
+)
+
+// Actions recorded in a Removal.
+const (
+	ActionDeleted   = "deleted"
+	ActionRewritten = "rewritten"
+)
+
+// manifestRetries bounds how often a manifest update is retried after losing a race with the loader.
+const manifestRetries = 5
+
+type Config struct {
+	// Policy sets the TTLs enforced by Sweep.
+	Policy Policy
+	// DryRun reports what would be removed without writing or deleting anything. Erase writes no
+	// audit record in a dry run.
+	DryRun bool
+}
+
+// Removal describes one object deleted, or rewritten without some of its events or rows.
+type Removal struct {
+	Store  string `json:"store"`
+	Key    string `json:"key"`
+	Action string `json:"action"`
+	// Replacement is the object holding what was kept from a rewritten hourly partition file.
+	Replacement string `json:"replacement,omitempty"`
+	// Removed counts the events or result rows dropped from a rewritten object.
+	Removed int `json:"removed,omitempty"`
+}
+
+// SweepReport summarises a retention sweep.
+type SweepReport struct {
+	DryRun   bool      `json:"dry_run"`
+	Removals []Removal `json:"removals"`
+	Failed   int       `json:"failed"`
+}
+
+type Service struct {
+	raw     Store
+	staging Store
+	results Store
+	audit   gcs.BlobWriter
+	policy  Policy
+	dryRun  bool
+}
+
+// NewService creates a retention service over the raw archive and, when not nil, the batch
+// staging and results stores. Erasure audit records are written to audit.
+func NewService(raw, staging, results Store, audit gcs.BlobWriter, cfg Config) *Service {
+	return &Service{
+		raw:     raw,
+		staging: staging,
+		results: results,
+		audit:   audit,
+		policy:  cfg.Policy,
+		dryRun:  cfg.DryRun,
+	}
+}
+
+// Sweep deletes everything that has outlived its TTL: raw chunks and hourly partition events by
+// their tenant's TTL, and staging files and batch results by the default TTL. Data is dated by the
+// hour or day in its key, and expires once the end of that period is older than the TTL.
+// Mixed-tenant hourly files are rewritten without the expired events. A failing object is logged
+// and counted, and does not stop the sweep.
+func (s *Service) Sweep(ctx context.Context) (*SweepReport, error) {
+	tr := otel.Tracer("retention-service")
+	ctx, span := tr.Start(ctx, "Sweep")
+	defer span.End()
+
+	now := time.Now()
+	report := &SweepReport{DryRun: s.dryRun}
+
+	if err := s.sweepSessions(ctx, now, report); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.sweepHours(ctx, now, report); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.sweepDated(ctx, storeStaging, s.staging, stagingPrefix, now, report); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.sweepDated(ctx, storeResults, s.results, resultsPrefix, now, report); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	slog.Info("Retention sweep complete", "removals", len(report.Removals), "failed", report.Failed, "dry_run", s.dryRun)
+	return report, nil
+}
+
+// sweepSessions deletes expired chunks of the session layout. A conversation's manifest is
+// deleted along with its last chunk.
+func (s *Service) sweepSessions(ctx context.Context, now time.Time, report *SweepReport) error {
+	keys, err := s.raw.ListFiles(ctx, rawPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list raw archive: %w", err)
+	}
+
+	manifests := make(map[string]string)
+	live := make(map[string]int)
+	for _, key := range keys {
+		if strings.HasPrefix(key, hourlyPrefix) {
+			continue
+		}
+		conversationID := strings.SplitN(strings.TrimPrefix(key, rawPrefix), "/", 2)[0]
+		if archive.IsManifestKey(key) {
+			manifests[conversationID] = key
+			continue
+		}
+
+		end, ok := keyEnd(key, 2)
+		if !ok {
+			slog.Warn("Skipping raw object with no date in its key", "key", key)
+			live[conversationID]++
+			continue
+		}
+		tenants, err := s.chunkTenants(ctx, key)
+		if err != nil {
+			slog.Error("Failed to read chunk attributes", "key", key, "error", err)
+			report.Failed++
+			live[conversationID]++
+			continue
+		}
+		if !s.allExpired(tenants, end, now) {
+			live[conversationID]++
+			continue
+		}
+
+		if err := s.remove(ctx, s.raw, key); err != nil {
+			slog.Error("Failed to delete expired chunk", "key", key, "error", err)
+			report.Failed++
+			live[conversationID]++
+			continue
+		}
+		report.Removals = append(report.Removals, Removal{Store: storeRaw, Key: key, Action: ActionDeleted})
+	}
+
+	for conversationID, key := range manifests {
+		if live[conversationID] > 0 {
+			continue
+		}
+		if err := s.remove(ctx, s.raw, key); err != nil {
+			slog.Error("Failed to delete manifest of expired conversation", "key", key, "error", err)
+			report.Failed++
+			continue
+		}
+		report.Removals = append(report.Removals, Removal{Store: storeRaw, Key: key, Action: ActionDeleted})
+	}
+	return nil
+}
+
+// chunkTenants returns the tenants the loader recorded on a chunk, or a single empty tenant when
+// there are none or the store keeps no attributes.
+func (s *Service) chunkTenants(ctx context.Context, key string) ([]string, error) {
+	sr, ok := s.raw.(gcs.StreamReader)
+	if !ok {
+		return []string{""}, nil
+	}
+	attrs, err := sr.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if v := attrs.Metadata["tenant_id"]; v != "" {
+		return strings.Split(v, ","), nil
+	}
+	return []string{""}, nil
+}
+
+func (s *Service) allExpired(tenants []string, end, now time.Time) bool {
+	for _, tenant := range tenants {
+		if !s.policy.Expired(tenant, end, now) {
+			return false
+		}
+	}
+	return len(tenants) > 0
+}
+
+// sweepHours drops expired events from every hourly partition.
+func (s *Service) sweepHours(ctx context.Context, now time.Time, report *SweepReport) error {
+	hours, err := s.listHours(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, hour := range hours {
+		end := hour.Add(time.Hour)
+		removals, err := s.filterHour(ctx, hour,
+			func(file archive.ManifestFile) bool {
+				return s.policy.AnyExpired(file.Tenants, end, now)
+			},
+			func(event schema.InteractionEvent) bool {
+				return s.policy.Expired(tenantOf(event), end, now)
+			})
+		if err != nil {
+			slog.Error("Failed to sweep partition", "partition", archive.HourPrefix(hour), "error", err)
+			report.Failed++
+		}
+		report.Removals = append(report.Removals, removals...)
+	}
+	return nil
+}
+
+// sweepDated deletes objects under prefix whose key starts with a YYYY/MM/DD date that has
+// outlived the default TTL.
+func (s *Service) sweepDated(ctx context.Context, name string, store Store, prefix string, now time.Time, report *SweepReport) error {
+	if store == nil {
+		return nil
+	}
+	keys, err := store.ListFiles(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list %s store: %w", name, err)
+	}
+
+	for _, key := range keys {
+		end, ok := keyEnd(key, 1)
+		if !ok {
+			slog.Warn("Skipping object with no date in its key", "store", name, "key", key)
+			continue
+		}
+		if !s.policy.Expired("", end, now) {
+			continue
+		}
+		if err := s.remove(ctx, store, key); err != nil {
+			slog.Error("Failed to delete expired object", "store", name, "key", key, "error", err)
+			report.Failed++
+			continue
+		}
+		report.Removals = append(report.Removals, Removal{Store: name, Key: key, Action: ActionDeleted})
+	}
+	return nil
+}
+
+// listHours returns the start of every hourly partition that has a manifest.
+func (s *Service) listHours(ctx context.Context) ([]time.Time, error) {
+	keys, err := s.raw.ListFiles(ctx, hourlyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hourly partitions: %w", err)
+	}
+
+	var hours []time.Time
+	for _, key := range keys {
+		if !archive.IsManifestKey(key) {
+			continue
+		}
+		// raw/dt=YYYY-MM-DD/hr=HH/manifest.json
+		parts := strings.Split(key, "/")
+		if len(parts) != 4 {
+			continue
+		}
+		hour, err := time.Parse("dt=2006-01-02/hr=15", parts[1]+"/"+parts[2])
+		if err != nil {
+			slog.Warn("Skipping manifest with unexpected key", "key", key)
+			continue
+		}
+		hours = append(hours, hour)
+	}
+	return hours, nil
+}
+
+// filterHour rewrites the data files of the hourly partition containing hour without the events
+// matching drop. Only files for which candidate returns true are read. A file left empty is
+// deleted; otherwise what remains is written to a new file, which replaces the original in the
+// partition manifest before the original is deleted.
+func (s *Service) filterHour(ctx context.Context, hour time.Time, candidate func(archive.ManifestFile) bool, drop func(schema.InteractionEvent) bool) ([]Removal, error) {
+	manifestKey := archive.ManifestKey(hour)
+	data, err := s.raw.Read(ctx, manifestKey)
+	if err != nil {
+		if errors.Is(err, gcs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest %s: %w", manifestKey, err)
+	}
+	manifest, err := archive.ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var removals []Removal
+	for _, file := range manifest.Files {
+		if !candidate(file) {
+			continue
+		}
+
+		data, err := s.raw.Read(ctx, file.Key)
+		if err != nil {
+			return removals, fmt.Errorf("failed to read partition file %s: %w", file.Key, err)
+		}
+		events, err := archive.Decode(file.Format, data)
+		if err != nil {
+			return removals, fmt.Errorf("failed to decode partition file %s: %w", file.Key, err)
+		}
+
+		var kept []schema.InteractionEvent
+		for _, event := range events {
+			if !drop(event) {
+				kept = append(kept, event)
+			}
+		}
+		if len(kept) == len(events) {
+			continue
+		}
+
+		removal := Removal{Store: storeRaw, Key: file.Key, Action: ActionDeleted, Removed: len(events) - len(kept)}
+		var replacement *archive.ManifestFile
+		if len(kept) > 0 {
+			removal.Action = ActionRewritten
+			replacement = &archive.ManifestFile{
+				Key:           archive.DataKey(hour, uuid.New().String(), file.Format),
+				Format:        file.Format,
+				Conversations: conversationsOf(kept),
+				Tenants:       archive.EventTenants(kept),
+				EventCount:    len(kept),
+				CreatedAt:     file.CreatedAt,
+			}
+			removal.Replacement = replacement.Key
+		}
+		if s.dryRun {
+			removals = append(removals, removal)
+			continue
+		}
+
+		if replacement != nil {
+			out, err := archive.Encode(file.Format, kept)
+			if err != nil {
+				return removals, fmt.Errorf("failed to encode partition file %s: %w", replacement.Key, err)
+			}
+			if err := s.raw.Write(ctx, replacement.Key, out); err != nil {
+				return removals, fmt.Errorf("failed to write partition file %s: %w", replacement.Key, err)
+			}
+		}
+		if err := s.replaceInManifest(ctx, hour, file.Key, replacement); err != nil {
+			// The replacement is not referenced by the manifest, so readers never see it.
+			if replacement != nil {
+				if delErr := s.raw.Delete(ctx, replacement.Key); delErr != nil {
+					slog.Warn("Failed to remove unused partition file", "key", replacement.Key, "error", delErr)
+				}
+			}
+			return removals, err
+		}
+		if err := s.raw.Delete(ctx, file.Key); err != nil && !errors.Is(err, gcs.ErrNotExist) {
+			return removals, fmt.Errorf("failed to delete partition file %s: %w", file.Key, err)
+		}
+		removals = append(removals, removal)
+	}
+	return removals, nil
+}
+
+// replaceInManifest swaps the entry for key in the manifest of the hour containing hour for
+// replacement, or just drops it when replacement is nil. When the store supports it the manifest
+// is written with a generation precondition, and the update is retried if a loader or compaction
+// changed it in between.
+func (s *Service) replaceInManifest(ctx context.Context, hour time.Time, key string, replacement *archive.ManifestFile) error {
+	manifestKey := archive.ManifestKey(hour)
+	for attempt := 1; ; attempt++ {
+		var generation string
+		if sr, ok := s.raw.(gcs.StreamReader); ok {
+			attrs, err := sr.Stat(ctx, manifestKey)
+			if err != nil {
+				return fmt.Errorf("failed to stat manifest %s: %w", manifestKey, err)
+			}
+			generation = attrs.Generation
+		}
+
+		data, err := s.raw.Read(ctx, manifestKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-read manifest %s: %w", manifestKey, err)
+		}
+		manifest, err := archive.ParseManifest(data)
+		if err != nil {
+			return err
+		}
+		if !manifest.Remove(key) {
+			return fmt.Errorf("partition file %s is no longer in manifest %s", key, manifestKey)
+		}
+		if replacement != nil {
+			manifest.Add(*replacement)
+		}
+
+		data, err = manifest.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest %s: %w", manifestKey, err)
+		}
+		err = s.writeObject(ctx, s.raw, manifestKey, data, gcs.WriteOptions{IfGenerationMatch: generation})
+		if !errors.Is(err, gcs.ErrPreconditionFailed) || attempt == manifestRetries {
+			return err
+		}
+		slog.Warn("Manifest changed during retention, retrying", "manifest", manifestKey, "attempt", attempt)
+	}
+}
+
+// writeObject writes data to key through gcs.Create so write options are honoured.
+func (s *Service) writeObject(ctx context.Context, store Store, key string, data []byte, opts gcs.WriteOptions) error {
+	w, err := gcs.Create(ctx, store, key, opts)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.CloseWithError(err)
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// remove deletes key unless this is a dry run. Objects that are already gone count as removed.
+func (s *Service) remove(ctx context.Context, store Store, key string) error {
+	if s.dryRun {
+		return nil
+	}
+	if err := store.Delete(ctx, key); err != nil && !errors.Is(err, gcs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// keyEnd returns the end of the period a key is dated by, reading a YYYY/MM/DD date from the
+// path segments starting at index i and, when the segment after it is a directory named by a
+// two-digit hour, narrowing the period to that hour.
+func keyEnd(key string, i int) (time.Time, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) < i+4 {
+		return time.Time{}, false
+	}
+	day, err := time.Parse("2006/01/02", strings.Join(parts[i:i+3], "/"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	if len(parts) > i+4 && len(parts[i+3]) == 2 {
+		if h, err := strconv.Atoi(parts[i+3]); err == nil && h >= 0 && h < 24 {
+			return day.Add(time.Duration(h+1) * time.Hour), true
+		}
+	}
+	return day.AddDate(0, 0, 1), true
+}
+
+func tenantOf(event schema.InteractionEvent) string {
+	if event.TenantId == nil {
+		return ""
+	}
+	return *event.TenantId
+}
+
+// conversationsOf returns the conversation IDs of events in order of first appearance.
+func conversationsOf(events []schema.InteractionEvent) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, event := range events {
+		if !seen[event.ConversationId] {
+			seen[event.ConversationId] = true
+			ids = append(ids, event.ConversationId)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,272 @@
+package retention_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/compact"
+	"github.com/dllewellyn/reflex/internal/app/retention"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr(s string) *string { return &s }
+
+func event(id, conversationID string, ts time.Time, tenant, user string) schema.InteractionEvent {
+	e := schema.InteractionEvent{InteractionId: id, ConversationId: conversationID, Timestamp: ts, Role: schema.RoleUser, Content: "content of " + id}
+	if tenant != "" {
+		e.TenantId = ptr(tenant)
+	}
+	if user != "" {
+		e.UserId = ptr(user)
+	}
+	return e
+}
+
+func sessionKey(conversationID string, t time.Time) string {
+	return fmt.Sprintf("raw/%s/%d/%02d/%02d/%02d/chunk-1.jsonl", conversationID, t.Year(), t.Month(), t.Day(), t.Hour())
+}
+
+func writeChunk(t *testing.T, store *gcs.MemoryClient, key string, events ...schema.InteractionEvent) {
+	t.Helper()
+	metadata := map[string]string{}
+	if tenants := archive.EventTenants(events); len(tenants) > 0 {
+		metadata["tenant_id"] = tenants[0]
+	}
+	w, err := store.Create(context.Background(), key, gcs.WriteOptions{Metadata: metadata})
+	require.NoError(t, err)
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		require.NoError(t, enc.Encode(e))
+	}
+	require.NoError(t, w.Close())
+}
+
+func writeHour(t *testing.T, store *gcs.MemoryClient, hour time.Time, events ...schema.InteractionEvent) string {
+	t.Helper()
+	ctx := context.Background()
+	key := archive.DataKey(hour, "run-1", archive.FormatJSONLGzip)
+	data, err := archive.Encode(archive.FormatJSONLGzip, events)
+	require.NoError(t, err)
+	require.NoError(t, store.Write(ctx, key, data))
+
+	var conversations []string
+	for _, e := range events {
+		conversations = append(conversations, e.ConversationId)
+	}
+	manifest := archive.NewManifest(hour)
+	manifest.Add(archive.ManifestFile{
+		Key:           key,
+		Format:        archive.FormatJSONLGzip,
+		Conversations: conversations,
+		Tenants:       archive.EventTenants(events),
+		EventCount:    len(events),
+	})
+	data, err = manifest.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, store.Write(ctx, archive.ManifestKey(hour), data))
+	return key
+}
+
+func readHour(t *testing.T, store *gcs.MemoryClient, hour time.Time) []schema.InteractionEvent {
+	t.Helper()
+	ctx := context.Background()
+	data, err := store.Read(ctx, archive.ManifestKey(hour))
+	require.NoError(t, err)
+	manifest, err := archive.ParseManifest(data)
+	require.NoError(t, err)
+
+	var events []schema.InteractionEvent
+	for _, file := range manifest.Files {
+		data, err := store.Read(ctx, file.Key)
+		require.NoError(t, err)
+		decoded, err := archive.Decode(file.Format, data)
+		require.NoError(t, err)
+		events = append(events, decoded...)
+	}
+	return events
+}
+
+// resultRow returns a batch result row whose prompt embeds a transcript of conversationID.
+func resultRow(t *testing.T, conversationID string) string {
+	t.Helper()
+	transcript, err := json.Marshal(event("i-"+conversationID, conversationID, time.Now(), "", ""))
+	require.NoError(t, err)
+	text := "Analyse this conversation:\n" + string(transcript) + "\n"
+	row, err := json.Marshal(schema.Record{Request: &schema.RecordRequest{
+		Contents: []schema.RecordRequestContentsElem{{Parts: []schema.RecordRequestContentsElemPartsElem{{Text: &text}}}},
+	}})
+	require.NoError(t, err)
+	return string(row) + "\n"
+}
+
+func TestService_Sweep(t *testing.T) {
+	ctx := context.Background()
+	old := time.Now().UTC().AddDate(0, 0, -10).Truncate(time.Hour)
+	recent := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Hour)
+	policy := retention.Policy{
+		DefaultTTL: 7 * 24 * time.Hour,
+		TenantTTLs: map[string]time.Duration{"long": 30 * 24 * time.Hour},
+	}
+
+	seed := func() (raw, staging *gcs.MemoryClient, hourKey string) {
+		raw = gcs.NewMemoryClient()
+		writeChunk(t, raw, sessionKey("conv-old", old), event("i-1", "conv-old", old, "", ""))
+		require.NoError(t, raw.Write(ctx, archive.SessionManifestKey("conv-old"), []byte(`{"conversation_id":"conv-old"}`)))
+		writeChunk(t, raw, sessionKey("conv-long", old), event("i-2", "conv-long", old, "long", ""))
+		writeChunk(t, raw, sessionKey("conv-new", recent), event("i-3", "conv-new", recent, "", ""))
+		hourKey = writeHour(t, raw, old,
+			event("i-4", "conv-a", old, "", ""),
+			event("i-5", "conv-b", old, "long", ""))
+
+		staging = gcs.NewMemoryClient()
+		require.NoError(t, staging.Write(ctx, "staging/"+old.Format("2006/01/02")+"/conv-old.jsonl", []byte("{}\n")))
+		require.NoError(t, staging.Write(ctx, "staging/"+recent.Format("2006/01/02")+"/conv-new.jsonl", []byte("{}\n")))
+		return raw, staging, hourKey
+	}
+
+	t.Run("removes expired data by tenant", func(t *testing.T) {
+		raw, staging, hourKey := seed()
+		svc := retention.NewService(raw, staging, nil, raw, retention.Config{Policy: policy})
+
+		report, err := svc.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Failed)
+
+		keys, err := raw.ListFiles(ctx, "raw/")
+		require.NoError(t, err)
+		assert.NotContains(t, keys, sessionKey("conv-old", old))
+		assert.NotContains(t, keys, archive.SessionManifestKey("conv-old"))
+		assert.Contains(t, keys, sessionKey("conv-long", old))
+		assert.Contains(t, keys, sessionKey("conv-new", recent))
+		assert.NotContains(t, keys, hourKey)
+
+		events := readHour(t, raw, old)
+		require.Len(t, events, 1)
+		assert.Equal(t, "i-5", events[0].InteractionId)
+
+		stagingKeys, err := staging.ListFiles(ctx, "staging/")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"staging/" + recent.Format("2006/01/02") + "/conv-new.jsonl"}, stagingKeys)
+	})
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		raw, staging, _ := seed()
+		before, err := raw.ListFiles(ctx, "")
+		require.NoError(t, err)
+		svc := retention.NewService(raw, staging, nil, raw, retention.Config{Policy: policy, DryRun: true})
+
+		report, err := svc.Sweep(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Len(t, report.Removals, 4)
+
+		after, err := raw.ListFiles(ctx, "")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, before, after)
+	})
+}
+
+// TestService_SweepCompacted checks that chunks written by compaction carry their tenant, so a
+// tenant's TTL applies to them rather than the default.
+func TestService_SweepCompacted(t *testing.T) {
+	ctx := context.Background()
+	day := time.Now().UTC().AddDate(0, 0, -3).Truncate(24 * time.Hour)
+	raw := gcs.NewMemoryClient()
+	for _, conversation := range []struct{ id, tenant string }{{"conv-short", "short"}, {"conv-default", ""}} {
+		for h := 1; h <= 2; h++ {
+			ts := day.Add(time.Duration(h) * time.Hour)
+			writeChunk(t, raw, sessionKey(conversation.id, ts), event(fmt.Sprintf("%s-%d", conversation.id, h), conversation.id, ts, conversation.tenant, ""))
+		}
+	}
+
+	compactor := compact.NewService(raw, compact.Config{})
+	var compacted []string
+	for _, conversation := range []string{"conv-short", "conv-default"} {
+		unit, err := compactor.CompactConversation(ctx, conversation, day)
+		require.NoError(t, err)
+		require.NotEmpty(t, unit.Output)
+		compacted = append(compacted, unit.Output)
+	}
+	attrs, err := raw.Stat(ctx, compacted[0])
+	require.NoError(t, err)
+	assert.Equal(t, "short", attrs.Metadata["tenant_id"])
+
+	svc := retention.NewService(raw, gcs.NewMemoryClient(), nil, raw, retention.Config{Policy: retention.Policy{
+		DefaultTTL: 30 * 24 * time.Hour,
+		TenantTTLs: map[string]time.Duration{"short": 24 * time.Hour},
+	}})
+	report, err := svc.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Failed)
+
+	keys, err := raw.ListFiles(ctx, "raw/")
+	require.NoError(t, err)
+	assert.NotContains(t, keys, compacted[0], "the short tenant's compacted chunk is past its TTL")
+	assert.Contains(t, keys, compacted[1], "the default tenant's compacted chunk is within the default TTL")
+}
+
+func TestService_Erase(t *testing.T) {
+	ctx := context.Background()
+	hour := time.Date(2025, 12, 16, 10, 0, 0, 0, time.UTC)
+
+	raw := gcs.NewMemoryClient()
+	writeChunk(t, raw, sessionKey("conv-1", hour), event("i-1", "conv-1", hour, "", "user-1"))
+	writeChunk(t, raw, sessionKey("conv-2", hour), event("i-2", "conv-2", hour, "", "user-2"))
+	writeHour(t, raw, hour,
+		event("i-3", "conv-3", hour, "", "user-1"),
+		event("i-4", "conv-4", hour, "", "user-2"))
+
+	staging := gcs.NewMemoryClient()
+	require.NoError(t, staging.Write(ctx, "staging/2025/12/16/conv-1.jsonl", []byte("{}\n")))
+	require.NoError(t, staging.Write(ctx, "staging/2025/12/16/conv-2.jsonl", []byte("{}\n")))
+
+	results := gcs.NewMemoryClient()
+	// conv-10 merely starts with an erased conversation's ID, so its row stays.
+	require.NoError(t, results.Write(ctx, "results/2025/12/16/predictions.jsonl",
+		[]byte(resultRow(t, "conv-1")+resultRow(t, "conv-2")+resultRow(t, "conv-10"))))
+
+	audit := gcs.NewMemoryClient()
+	svc := retention.NewService(raw, staging, results, audit, retention.Config{})
+
+	record, err := svc.Erase(ctx, retention.ErasureRequest{UserID: "user-1", RequestedBy: "ticket-42"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conv-1", "conv-3"}, record.Conversations)
+	assert.Empty(t, record.Failures)
+
+	keys, err := raw.ListFiles(ctx, "raw/")
+	require.NoError(t, err)
+	assert.NotContains(t, keys, sessionKey("conv-1", hour))
+	assert.Contains(t, keys, sessionKey("conv-2", hour))
+	events := readHour(t, raw, hour)
+	require.Len(t, events, 1)
+	assert.Equal(t, "conv-4", events[0].ConversationId)
+
+	stagingKeys, err := staging.ListFiles(ctx, "staging/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"staging/2025/12/16/conv-2.jsonl"}, stagingKeys)
+
+	data, err := results.Read(ctx, "results/2025/12/16/predictions.jsonl")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `conv-1\"`)
+	assert.Contains(t, string(data), `conv-2\"`)
+	assert.Contains(t, string(data), `conv-10\"`)
+
+	data, err = audit.Read(ctx, retention.AuditKey(record.StartedAt, record.ID))
+	require.NoError(t, err)
+	var stored retention.AuditRecord
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.Equal(t, "user-1", stored.UserID)
+	assert.Equal(t, "ticket-42", stored.RequestedBy)
+	assert.Len(t, stored.Removals, len(record.Removals))
+	assert.NotContains(t, string(data), "content of")
+
+	_, err = svc.Erase(ctx, retention.ErasureRequest{})
+	assert.Error(t, err)
+}
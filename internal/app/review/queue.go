@@ -0,0 +1,343 @@
+// Package review holds the injections the extractor finds until a person approves them, so a
+// hallucinated or benign line does not become a detection signature. Candidates the judge was
+// sure enough about can be approved by rule.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"go.opentelemetry.io/otel"
+)
+
+// Status is where a candidate is in review.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+// AutoReviewer is the reviewer recorded on candidates approved by rule.
+const AutoReviewer = "auto"
+
+// SourceFeedback is the source of candidates extracted from missed attacks reported through the
+// ingestor's /feedback endpoint. Their verdict was not given by the judge, so no rule approves
+// them.
+const SourceFeedback = "feedback"
+
+// ErrNotFound is returned by Approve and Reject when some of the requested candidates are not
+// pending.
+var ErrNotFound = errors.New("candidates not pending")
+
+// Candidate is an extracted injection and the judge verdict it was extracted under.
+type Candidate struct {
+	// ID is the ID the record is upserted under.
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Metadata is upserted with the record.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// EventID is the batch result the candidate was extracted from.
+	EventID string `json:"event_id,omitempty"`
+	// Source is SourceFeedback for candidates reported through feedback, and empty for those the
+	// judge flagged.
+	Source     string  `json:"source,omitempty"`
+	Confidence float64 `json:"confidence"`
+	Severity   string  `json:"severity,omitempty"`
+	Analysis   string  `json:"analysis,omitempty"`
+
+	Status    Status     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	DecidedBy string     `json:"decided_by,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// Record returns the record an approved candidate is upserted as, with its review recorded in
+// the review_status and reviewed_by metadata fields.
+func (c *Candidate) Record() *pinecone.InputRecord {
+	metadata := make(map[string]interface{}, len(c.Metadata)+2)
+	for k, v := range c.Metadata {
+		metadata[k] = v
+	}
+	metadata["review_status"] = string(c.Status)
+	if c.DecidedBy != "" {
+		metadata["reviewed_by"] = c.DecidedBy
+	}
+	return &pinecone.InputRecord{ID: c.ID, Text: c.Text, Metadata: metadata}
+}
+
+// Rules approve candidates without review. The zero Rules approve nothing, and no Rules approve
+// candidates from feedback.
+type Rules struct {
+	// All approves every candidate the judge flagged, as the extractor does without a queue.
+	All bool
+	// MinConfidence approves candidates the judge gave at least this confidence; 0 disables it.
+	MinConfidence float64
+	// Severities approves candidates the judge gave one of these severities, compared without
+	// case; empty disables it.
+	Severities []string
+}
+
+// Approves reports whether the rules approve c. When both MinConfidence and Severities are set,
+// both must hold.
+func (r Rules) Approves(c *Candidate) bool {
+	if c.Source == SourceFeedback {
+		return false
+	}
+	if r.All {
+		return true
+	}
+	if r.MinConfidence <= 0 && len(r.Severities) == 0 {
+		return false
+	}
+	if r.MinConfidence > 0 && c.Confidence < r.MinConfidence {
+		return false
+	}
+	if len(r.Severities) > 0 {
+		for _, severity := range r.Severities {
+			if strings.EqualFold(severity, c.Severity) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Blobs is the blob store candidates are kept in.
+type Blobs interface {
+	gcs.BlobReader
+	gcs.BlobWriter
+	gcs.BlobDeleter
+}
+
+type Config struct {
+	Rules Rules
+	// Now stamps candidates. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Queue keeps candidates in a blob store, one object per candidate under
+// review/<status>/<id>.json.
+type Queue struct {
+	blobs Blobs
+	store pinecone.VectorStore
+	rules Rules
+	now   func() time.Time
+}
+
+// NewQueue creates a Queue. store receives approved candidates; it may be nil for a queue that
+// only submits, such as the extractor's, which upserts what Submit approves itself.
+func NewQueue(blobs Blobs, store pinecone.VectorStore, cfg Config) *Queue {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Queue{blobs: blobs, store: store, rules: cfg.Rules, now: cfg.Now}
+}
+
+// Key returns the key of the candidate with id in status.
+func Key(status Status, id string) string {
+	return path.Join("review", string(status), id+".json")
+}
+
+// Submit queues candidates for review and returns those that need no review: approved by the
+// rules, or approved before. Candidates rejected before are dropped, so the same hallucination is
+// not queued again every time it is extracted.
+func (q *Queue) Submit(ctx context.Context, candidates []*Candidate) ([]*Candidate, error) {
+	ctx, span := otel.Tracer("review-queue").Start(ctx, "Submit")
+	defer span.End()
+
+	var approved []*Candidate
+	for _, c := range candidates {
+		if prior, err := q.read(ctx, StatusApproved, c.ID); err != nil {
+			span.RecordError(err)
+			return approved, err
+		} else if prior != nil {
+			approved = append(approved, prior)
+			continue
+		}
+		if prior, err := q.read(ctx, StatusRejected, c.ID); err != nil {
+			span.RecordError(err)
+			return approved, err
+		} else if prior != nil {
+			slog.Info("Dropping candidate rejected before", "id", c.ID, "event_id", c.EventID)
+			continue
+		}
+
+		c.CreatedAt = q.now().UTC()
+		c.Status = StatusPending
+		if q.rules.Approves(c) {
+			q.decide(c, StatusApproved, AutoReviewer, "")
+		}
+		if err := q.write(ctx, c); err != nil {
+			span.RecordError(err)
+			return approved, err
+		}
+		if c.Status == StatusApproved {
+			approved = append(approved, c)
+		}
+	}
+	return approved, nil
+}
+
+// List returns the candidates in status, oldest first.
+func (q *Queue) List(ctx context.Context, status Status) ([]*Candidate, error) {
+	keys, err := q.blobs.ListFiles(ctx, path.Join("review", string(status))+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s candidates: %w", status, err)
+	}
+	candidates := make([]*Candidate, 0, len(keys))
+	for _, key := range keys {
+		c, err := q.decode(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].CreatedAt.Before(candidates[j].CreatedAt) })
+	return candidates, nil
+}
+
+// Approve upserts the pending candidates with ids into the vector store and moves them to
+// approved. If some are not pending, it approves the others and returns an error wrapping
+// ErrNotFound naming them.
+func (q *Queue) Approve(ctx context.Context, ids []string, reviewer, reason string) ([]*Candidate, error) {
+	ctx, span := otel.Tracer("review-queue").Start(ctx, "Approve")
+	defer span.End()
+
+	if q.store == nil {
+		return nil, errors.New("approving candidates requires a vector store")
+	}
+	pending, missing, err := q.pending(ctx, ids)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(pending) > 0 {
+		records := make([]*pinecone.InputRecord, len(pending))
+		for i, c := range pending {
+			q.decide(c, StatusApproved, reviewer, reason)
+			records[i] = c.Record()
+		}
+		if err := q.store.UpsertInputs(ctx, records); err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to upsert approved candidates: %w", err)
+		}
+	}
+	if err := q.move(ctx, pending); err != nil {
+		span.RecordError(err)
+		return pending, err
+	}
+	return pending, notFound(missing)
+}
+
+// Reject moves the pending candidates with ids to rejected, so they are never upserted. If some
+// are not pending, it rejects the others and returns an error wrapping ErrNotFound naming them.
+func (q *Queue) Reject(ctx context.Context, ids []string, reviewer, reason string) ([]*Candidate, error) {
+	ctx, span := otel.Tracer("review-queue").Start(ctx, "Reject")
+	defer span.End()
+
+	pending, missing, err := q.pending(ctx, ids)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	for _, c := range pending {
+		q.decide(c, StatusRejected, reviewer, reason)
+	}
+	if err := q.move(ctx, pending); err != nil {
+		span.RecordError(err)
+		return pending, err
+	}
+	return pending, notFound(missing)
+}
+
+func (q *Queue) decide(c *Candidate, status Status, reviewer, reason string) {
+	now := q.now().UTC()
+	c.Status = status
+	c.DecidedAt = &now
+	c.DecidedBy = reviewer
+	c.Reason = reason
+}
+
+// pending returns the pending candidates with ids, and the IDs that are not pending.
+func (q *Queue) pending(ctx context.Context, ids []string) ([]*Candidate, []string, error) {
+	var found []*Candidate
+	var missing []string
+	for _, id := range ids {
+		c, err := q.read(ctx, StatusPending, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c == nil {
+			missing = append(missing, id)
+			continue
+		}
+		found = append(found, c)
+	}
+	return found, missing, nil
+}
+
+// move writes decided candidates under their new status and removes them from pending.
+func (q *Queue) move(ctx context.Context, candidates []*Candidate) error {
+	for _, c := range candidates {
+		if err := q.write(ctx, c); err != nil {
+			return err
+		}
+		if err := q.blobs.Delete(ctx, Key(StatusPending, c.ID)); err != nil {
+			return fmt.Errorf("failed to remove pending candidate %s: %w", c.ID, err)
+		}
+		slog.Info("Reviewed candidate", "id", c.ID, "status", c.Status, "reviewer", c.DecidedBy)
+	}
+	return nil
+}
+
+// read returns the candidate with id in status, or nil if there is none.
+func (q *Queue) read(ctx context.Context, status Status, id string) (*Candidate, error) {
+	c, err := q.decode(ctx, Key(status, id))
+	if errors.Is(err, gcs.ErrNotExist) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (q *Queue) decode(ctx context.Context, key string) (*Candidate, error) {
+	data, err := q.blobs.Read(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candidate %s: %w", key, err)
+	}
+	var c Candidate
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid candidate %s: %w", key, err)
+	}
+	return &c, nil
+}
+
+func (q *Queue) write(ctx context.Context, c *Candidate) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal candidate %s: %w", c.ID, err)
+	}
+	if err := q.blobs.Write(ctx, Key(c.Status, c.ID), data); err != nil {
+		return fmt.Errorf("failed to write candidate %s: %w", c.ID, err)
+	}
+	return nil
+}
+
+func notFound(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, strings.Join(ids, ", "))
+}
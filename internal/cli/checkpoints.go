@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/state"
+)
+
+// openCheckpoints opens the configured checkpoint store, or returns nil, recording no
+// checkpoints, when none is configured.
+func openCheckpoints(ctx context.Context, cfg *config.Config) (state.Store, func(), error) {
+	if cfg.Storage.CheckpointURL == "" {
+		return nil, func() {}, nil
+	}
+	store, err := state.Open(ctx, cfg.Storage.CheckpointURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open checkpoint store: %w", err)
+	}
+	return store, func() { _ = store.Close() }, nil
+}
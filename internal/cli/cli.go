@@ -0,0 +1,135 @@
+// Package cli implements the reflex subcommands. cmd/reflex runs any of them; the single-purpose
+// binaries in cmd/ each run one, so every component reads the same configuration (see
+// internal/config).
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/joho/godotenv"
+)
+
+type command struct {
+	name     string
+	summary  string
+	sections []string
+	run      func(ctx context.Context, cfg *config.Config) error
+	// flags registers command-specific flags that are not part of the configuration.
+	flags func(fs *flag.FlagSet)
+	// args consumes the positional arguments given before the flags and returns the rest.
+	args func(args []string) ([]string, error)
+}
+
+var commands = []command{
+	{name: "serve", summary: "Run the ingestor API", sections: []string{"kafka", "pinecone", "qdrant", "embedding", "serve"}, run: runServe},
+	{name: "load", summary: "Archive raw interactions from Kafka", sections: []string{"kafka", "storage", "encryption", "load"}, run: runLoad},
+	{name: "judge", summary: "Submit the daily batch analysis", sections: []string{"storage", "encryption", "judge"}, run: runJudge},
+	{name: "extract", summary: "Extract injections from batch results", sections: []string{"kafka", "storage", "pinecone", "qdrant", "embedding", "extract", "review"}, run: runExtract},
+	{name: "eval", summary: "Evaluate judge models", sections: []string{"eval"}, run: runEval},
+	{name: "dataset", summary: "Load a HuggingFace dataset into the vector store", sections: []string{"pinecone", "qdrant", "embedding", "dataset"}, run: runDataset, flags: datasetFlags},
+	{name: "curate", summary: "Search, inspect, delete, export and import signatures", sections: []string{"pinecone", "qdrant", "embedding", "curate"}, run: runCurate, flags: curateFlags},
+	{name: "review", summary: "List, approve and reject extracted injections", sections: []string{"pinecone", "qdrant", "embedding", "review"}, run: runReview, flags: reviewFlags},
+	{name: "compact", summary: "Merge small archive objects into larger ones", sections: []string{"storage", "compact"}, run: runCompact, flags: compactFlags},
+	{name: "retention", summary: "Sweep expired archive data or erase a user or conversation", sections: []string{"storage", "retention"}, run: runRetention, flags: retentionFlags, args: retentionArgs},
+	{name: "dev", summary: "Run the whole pipeline locally", sections: []string{"kafka", "storage", "judge", "extract", "serve", "dev"}, run: runDev},
+}
+
+// Main runs the subcommand named by args[0] with the remaining arguments and returns the
+// process exit code.
+func Main(args []string) int {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
+	if err := godotenv.Load(); err != nil {
+		slog.Debug("No .env file found or error loading it", "error", err)
+	}
+
+	if len(args) < 1 {
+		usage()
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	name, args := args[0], args[1:]
+	var err error
+	switch name {
+	case "config":
+		err = runConfig(args)
+	case "help", "-h", "-help", "--help":
+		usage()
+		return 0
+	default:
+		cmd, ok := lookup(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "reflex: unknown command %q\n\n", name)
+			usage()
+			return 2
+		}
+		err = cmd.execute(ctx, args)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		slog.Error("Command failed", "command", name, "error", err)
+		return 1
+	}
+	return 0
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// execute parses args, loads and validates the configuration and runs the command.
+func (c command) execute(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reflex "+c.name, flag.ContinueOnError)
+	configPath := fs.String("config", "", "YAML config file [$"+config.EnvFile+"]")
+	config.RegisterFlags(fs, c.sections...)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	if c.args != nil {
+		var err error
+		if args, err = c.args(args); err != nil {
+			return err
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath, fs)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(c.name); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return c.run(ctx, cfg)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: reflex <command> [-config file] [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "  %-10s %s\n", "config", "Print the effective configuration (config print [command])")
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/compact"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/telemetry"
+)
+
+var compactOpts struct {
+	conversation string
+	hour         string
+}
+
+func compactFlags(fs *flag.FlagSet) {
+	fs.StringVar(&compactOpts.conversation, "conversation", "", "Compact only this conversation's chunks for compact.date (session layout)")
+	fs.StringVar(&compactOpts.hour, "hour", "", "Compact only this partition, as YYYY-MM-DDTHH (hourly layout)")
+}
+
+// runCompact implements "reflex compact", which compacts a day of the raw archive, or one
+// conversation or hour of it, and prints the report.
+func runCompact(ctx context.Context, cfg *config.Config) error {
+	if compactOpts.conversation != "" && compactOpts.hour != "" {
+		return errors.New("compact needs at most one of -conversation and -hour")
+	}
+	var hour time.Time
+	if compactOpts.hour != "" {
+		var err error
+		if hour, err = time.Parse("2006-01-02T15", compactOpts.hour); err != nil {
+			return fmt.Errorf("invalid -hour: %w", err)
+		}
+	}
+	date := time.Now().AddDate(0, 0, -1)
+	if cfg.Compact.Date != "" {
+		// Validate has already checked the format.
+		date, _ = time.Parse(time.DateOnly, cfg.Compact.Date)
+	}
+
+	cleanup, err := telemetry.SetupTracer(ctx, cfg.Project, "compact", os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracer: %w", err)
+	}
+	defer cleanup()
+
+	store, err := gcs.OpenURL(ctx, cfg.Storage.RawArchiveURL)
+	if err != nil {
+		return fmt.Errorf("failed to open raw archive: %w", err)
+	}
+	defer store.Close()
+
+	svc := compact.NewService(store, compact.Config{
+		Layout: archive.Layout(cfg.Storage.Layout),
+		Format: archive.Format(cfg.Storage.Format),
+		DryRun: cfg.Compact.DryRun,
+	})
+
+	slog.Info("Starting compaction", "layout", cfg.Storage.Layout, "date", date.Format(time.DateOnly), "dry_run", cfg.Compact.DryRun)
+	report := &compact.Report{DryRun: cfg.Compact.DryRun}
+	switch {
+	case compactOpts.hour != "":
+		unit, err := svc.CompactHour(ctx, hour)
+		if err != nil {
+			return err
+		}
+		report.Units = append(report.Units, unit)
+	case compactOpts.conversation != "":
+		unit, err := svc.CompactConversation(ctx, compactOpts.conversation, date)
+		if err != nil {
+			return err
+		}
+		report.Units = append(report.Units, unit)
+	default:
+		if report, err = svc.Run(ctx, date); err != nil {
+			return err
+		}
+	}
+
+	if err := printJSON(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("compaction finished with %d failures", report.Failed)
+	}
+	slog.Info("Compaction completed successfully", "units", len(report.Units))
+	return nil
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/config"
+)
+
+// runConfig implements "reflex config print [command] [flags]". With a command, only that
+// command's flags are accepted and the configuration is validated for it as well.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: reflex config print [command] [-config file] [flags]")
+	}
+	args = args[1:]
+
+	sections := []string{"kafka", "storage", "encryption", "pinecone", "qdrant", "embedding", "serve", "load", "judge", "extract", "eval", "dataset", "curate", "review", "compact", "retention", "dev"}
+	var target string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		cmd, ok := lookup(args[0])
+		if !ok {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+		target, sections, args = cmd.name, cmd.sections, args[1:]
+	}
+
+	fs := flag.NewFlagSet("reflex config print", flag.ContinueOnError)
+	configPath := fs.String("config", "", "YAML config file [$"+config.EnvFile+"]")
+	config.RegisterFlags(fs, sections...)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath, fs)
+	if err != nil {
+		return err
+	}
+	if err := config.Print(os.Stdout, cfg); err != nil {
+		return err
+	}
+	if target != "" {
+		if err := cfg.Validate(target); err != nil {
+			return fmt.Errorf("invalid configuration for %s: %w", target, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/app/curation"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+)
+
+var curate struct {
+	search       string
+	topK         int
+	inspect      string
+	delete       string
+	filter       string
+	requestedBy  string
+	reason       string
+	dryRun       bool
+	exportPath   string
+	exportValues bool
+	importPath   string
+	confirm      string
+	dismiss      string
+	reviewedBy   string
+	namespace    string
+}
+
+func curateFlags(fs *flag.FlagSet) {
+	fs.StringVar(&curate.search, "search", "", "Show the records most similar to this text")
+	fs.IntVar(&curate.topK, "top-k", 10, "Records shown by -search")
+	fs.StringVar(&curate.inspect, "inspect", "", "Show the records with these comma-separated IDs")
+	fs.StringVar(&curate.delete, "delete", "", "Delete the records with these comma-separated IDs, or those matching -filter with -delete=filter")
+	fs.StringVar(&curate.filter, "filter", "", `Metadata filter as JSON for -search, -export and -delete=filter, e.g. '{"source": "auto-extracted"}'`)
+	fs.StringVar(&curate.requestedBy, "requested-by", "", "Who requested the deletion, recorded in the audit record")
+	fs.StringVar(&curate.reason, "reason", "", "Why the records are deleted, recorded in the audit record")
+	fs.BoolVar(&curate.dryRun, "dry-run", false, "Show what -delete would delete without deleting it")
+	fs.StringVar(&curate.exportPath, "export", "", "Write the records to this JSONL file")
+	fs.BoolVar(&curate.exportValues, "values", false, "Include embeddings in -export")
+	fs.StringVar(&curate.importPath, "import", "", "Upsert the records in this JSONL file (- for stdin)")
+	fs.StringVar(&curate.confirm, "confirm", "", "Accept the false positives reported against the pending signatures with these comma-separated IDs, lowering their weight")
+	fs.StringVar(&curate.dismiss, "dismiss", "", "Dismiss the false positives reported against the pending signatures with these comma-separated IDs")
+	fs.StringVar(&curate.reviewedBy, "reviewed-by", "", "Who decided -confirm or -dismiss, recorded on the signatures")
+	fs.StringVar(&curate.namespace, "namespace", "", "Vector store namespace")
+}
+
+// runCurate implements "reflex curate", which runs exactly one of -search, -inspect, -delete,
+// -export, -import, -confirm and -dismiss against the vector store.
+func runCurate(ctx context.Context, cfg *config.Config) error {
+	actions := 0
+	for _, set := range []bool{curate.search != "", curate.inspect != "", curate.delete != "", curate.exportPath != "", curate.importPath != "", curate.confirm != "", curate.dismiss != ""} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return errors.New("curate needs exactly one of -search, -inspect, -delete, -export, -import, -confirm and -dismiss")
+	}
+	var filter pinecone.Filter
+	if curate.filter != "" {
+		if err := json.Unmarshal([]byte(curate.filter), &filter); err != nil {
+			return fmt.Errorf("invalid -filter: %w", err)
+		}
+		if err := filter.Validate(); err != nil {
+			return fmt.Errorf("invalid -filter: %w", err)
+		}
+	}
+
+	store, saveVectors, err := openVectorStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if curate.namespace != "" {
+		store = store.WithNamespace(curate.namespace)
+	}
+	var audit gcs.BlobWriter
+	if cfg.Curate.AuditURL != "" {
+		auditStore, err := gcs.OpenURL(ctx, cfg.Curate.AuditURL)
+		if err != nil {
+			return fmt.Errorf("failed to open audit store: %w", err)
+		}
+		defer auditStore.Close()
+		audit = auditStore
+	}
+	svc := curation.NewService(store, audit, curation.Config{FeedbackPenalty: cfg.Curate.FeedbackPenalty})
+
+	switch {
+	case curate.search != "":
+		records, err := svc.Search(ctx, curate.search, curate.topK, filter)
+		if err != nil {
+			return err
+		}
+		return printJSON(records)
+	case curate.inspect != "":
+		records, err := svc.Inspect(ctx, splitIDs(curate.inspect))
+		if printErr := printJSON(records); printErr != nil {
+			return printErr
+		}
+		return err
+	case curate.delete != "":
+		req := curation.DeleteRequest{RequestedBy: curate.requestedBy, Reason: curate.reason, DryRun: curate.dryRun}
+		if curate.delete == "filter" {
+			if len(filter) == 0 {
+				return errors.New("-delete=filter needs -filter")
+			}
+			req.Filter = filter
+		} else {
+			req.IDs = splitIDs(curate.delete)
+		}
+		if audit == nil && !curate.dryRun {
+			return errors.New("-delete needs curate.audit_url, or -dry-run")
+		}
+		record, err := svc.Delete(ctx, req)
+		if record != nil {
+			if printErr := printJSON(record); printErr != nil {
+				return errors.Join(err, printErr)
+			}
+		}
+		if err != nil || curate.dryRun {
+			return err
+		}
+		return saveVectors()
+	case curate.exportPath != "":
+		f, err := os.Create(curate.exportPath)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		count, err := svc.Export(ctx, f, curation.ExportOptions{Filter: filter, Values: curate.exportValues})
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write export file: %w", closeErr)
+		}
+		if err != nil {
+			return err
+		}
+		slog.Info("Exported signatures", "records", count, "path", curate.exportPath)
+		return nil
+	case curate.confirm != "" || curate.dismiss != "":
+		decision := curation.FeedbackDecision{IDs: splitIDs(curate.confirm + curate.dismiss), Confirm: curate.confirm != "", ReviewedBy: curate.reviewedBy}
+		records, err := svc.ResolveFeedback(ctx, decision)
+		if printErr := printJSON(records); printErr != nil {
+			return errors.Join(err, printErr)
+		}
+		return errors.Join(err, saveVectors())
+	default:
+		var r io.Reader = os.Stdin
+		if curate.importPath != "-" {
+			f, err := os.Open(curate.importPath)
+			if err != nil {
+				return fmt.Errorf("failed to open import file: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		count, err := svc.Import(ctx, r)
+		slog.Info("Imported signatures", "records", count, "path", curate.importPath)
+		return errors.Join(err, saveVectors())
+	}
+}
+
+func splitIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/dataset_loader"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/huggingface"
+)
+
+var (
+	deleteAll bool
+	restart   bool
+)
+
+func datasetFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&deleteAll, "delete-all", false, "Delete every vector in the index instead of loading")
+	fs.BoolVar(&restart, "restart", false, "Ignore checkpoints and load every dataset from its first row")
+}
+
+func runDataset(ctx context.Context, cfg *config.Config) error {
+	vectorStore, saveVectors, err := openVectorStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	hfClient := huggingface.NewClient(&http.Client{Timeout: 10 * time.Minute}, huggingface.Config{
+		Endpoint: cfg.Dataset.Endpoint,
+		Token:    cfg.Dataset.Token,
+		Revision: cfg.Dataset.Revision,
+		CacheDir: cfg.Dataset.CacheDir,
+	})
+	checkpoints, closeCheckpoints, err := openCheckpoints(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeCheckpoints()
+
+	svc := dataset_loader.NewService(dataset_loader.Config{
+		HFDatasetID:       cfg.Dataset.ID,
+		HFSplit:           cfg.Dataset.Split,
+		HFTextCol:         cfg.Dataset.TextColumn,
+		HFLabelCol:        cfg.Dataset.LabelColumn,
+		HFFilterCol:       cfg.Dataset.FilterColumn,
+		HFFilterVal:       cfg.Dataset.FilterValue,
+		Filter:            cfg.Dataset.Filter,
+		Labels:            cfg.Dataset.Labels,
+		HFToken:           cfg.Dataset.Token,
+		HFRevision:        cfg.Dataset.Revision,
+		HFCacheDir:        cfg.Dataset.CacheDir,
+		HFEndpoint:        cfg.Dataset.Endpoint,
+		PineconeAPIKey:    cfg.Pinecone.APIKey,
+		PineconeIndexHost: cfg.Pinecone.IndexHost,
+		BatchSize:         cfg.Dataset.BatchSize,
+		VectorDimension:   cfg.Dataset.VectorDimension,
+		Restart:           restart,
+	}, dataset_loader.NewSources(hfClient), vectorStore, checkpoints)
+
+	if deleteAll {
+		return errors.Join(svc.DeleteAll(ctx), saveVectors())
+	}
+	if cfg.Dataset.Manifest == "" {
+		return errors.Join(svc.Run(ctx), saveVectors())
+	}
+
+	manifest, err := dataset_loader.LoadManifest(cfg.Dataset.Manifest)
+	if err != nil {
+		return err
+	}
+	summary, err := svc.RunManifest(ctx, manifest)
+	if saveErr := saveVectors(); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	if summary != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(summary); encErr != nil {
+			return encErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/app/dev"
+	"github.com/dllewellyn/reflex/internal/app/redaction"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+)
+
+func runDev(ctx context.Context, cfg *config.Config) error {
+	script := dev.DefaultScript()
+	if cfg.Dev.Script != "" {
+		var err error
+		if script, err = dev.LoadScript(cfg.Dev.Script); err != nil {
+			return err
+		}
+	}
+
+	var start time.Time
+	if cfg.Dev.Start != "" {
+		// Validate has already checked the format.
+		start, _ = time.Parse(time.RFC3339, cfg.Dev.Start)
+	}
+
+	redactor, closeRedactor, err := redaction.Open(ctx, cfg.Serve.RedactionConfig, cfg.Serve.RedactionHMACKey, cfg.Serve.RedactionVaultURL)
+	if err != nil {
+		return fmt.Errorf("failed to configure redaction: %w", err)
+	}
+	defer closeRedactor()
+
+	svc, err := dev.NewService(redactor, dev.Config{
+		DataDir:           cfg.Dev.DataDir,
+		Port:              cfg.Serve.Port,
+		Topic:             cfg.Kafka.Topic,
+		ResultsTopic:      cfg.Kafka.ResultsTopic,
+		FeedbackTopic:     cfg.Kafka.FeedbackTopic,
+		FeedbackToken:     cfg.Serve.FeedbackToken,
+		Layout:            archive.Layout(cfg.Storage.Layout),
+		Format:            archive.Format(cfg.Storage.Format),
+		JudgePromptPath:   cfg.Judge.PromptPath,
+		ExtractPromptPath: cfg.Extract.PromptPath,
+		JudgeModel:        cfg.Judge.ModelID,
+		Script:            script,
+		Start:             start,
+	})
+	if err != nil {
+		return err
+	}
+	return svc.Run(ctx)
+}
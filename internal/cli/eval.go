@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dllewellyn/reflex/internal/app/evaluate"
+	"github.com/dllewellyn/reflex/internal/config"
+)
+
+func runEval(ctx context.Context, cfg *config.Config) error {
+	generator, err := evaluate.NewVertexGenerator(ctx, cfg.Project, cfg.Location)
+	if err != nil {
+		return err
+	}
+	results, err := evaluate.NewService(generator, evaluate.Config{
+		ProjectID:    cfg.Project,
+		Location:     cfg.Location,
+		PromptPath:   cfg.Eval.PromptPath,
+		TestDataPath: cfg.Eval.TestData,
+		OutputPath:   cfg.Eval.Output,
+		Models:       cfg.Eval.Models,
+		Interval:     cfg.Eval.Interval,
+	}).Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/app/extract"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/genai"
+	"github.com/dllewellyn/reflex/internal/platform/telemetry"
+)
+
+func runExtract(ctx context.Context, cfg *config.Config) error {
+	cleanup, err := telemetry.SetupTracer(ctx, cfg.Project, "extract-injections", os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracer: %w", err)
+	}
+	defer cleanup()
+
+	extractCfg := extract.Config{
+		GCPProjectID:          cfg.Project,
+		GCPLocation:           cfg.Location,
+		PromptPath:            cfg.Extract.PromptPath,
+		PineconeAPIKey:        cfg.Pinecone.APIKey,
+		PineconeIndexHost:     cfg.Pinecone.IndexHost,
+		KafkaBootstrapServers: cfg.Kafka.BootstrapServers,
+		KafkaTopic:            cfg.Kafka.ResultsTopic,
+		KafkaAPIKey:           cfg.Kafka.APIKey,
+		KafkaAPISecret:        cfg.Kafka.APISecret,
+		IdleTimeoutSeconds:    cfg.Extract.IdleTimeout,
+		DryRun:                cfg.Extract.DryRun,
+		CheckpointStoreURL:    cfg.Storage.CheckpointURL,
+
+		GroundingMinSimilarity: cfg.Extract.GroundingMinSimilarity,
+		DuplicateThreshold:     cfg.Extract.DuplicateThreshold,
+		RunDuplicateThreshold:  cfg.Extract.RunDuplicateThreshold,
+
+		ReviewQueueURL:              cfg.Review.QueueURL,
+		ReviewAutoApproveConfidence: cfg.Review.AutoApproveConfidence,
+		ReviewAutoApproveSeverities: cfg.Review.AutoApproveSeverities,
+	}
+
+	client, err := genai.NewClient(ctx, cfg.Project, cfg.Location)
+	if err != nil {
+		return err
+	}
+	vectorStore, _, err := openVectorStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	checkpoints, closeCheckpoints, err := openCheckpoints(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeCheckpoints()
+	// The extractor upserts what the queue approves itself, so the queue gets no vector store.
+	var queue extract.ReviewQueue
+	if reviewQueue, closeQueue, err := openReviewQueue(ctx, cfg, nil); err != nil {
+		return err
+	} else if reviewQueue != nil {
+		defer closeQueue()
+		queue = reviewQueue
+	}
+
+	extractor := extract.NewExtractor(client, cfg.Extract.PromptPath)
+	processor := extract.NewProcessor(extract.NewKafkaResultReader(extractCfg), extractor, vectorStore, checkpoints, queue, extractCfg)
+	return extract.NewService(processor).Run(ctx)
+}
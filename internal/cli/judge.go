@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+	"github.com/dllewellyn/reflex/internal/app/batch"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/envelope"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/telemetry"
+	"github.com/dllewellyn/reflex/internal/platform/vertex"
+	"google.golang.org/api/option"
+)
+
+func runJudge(ctx context.Context, cfg *config.Config) error {
+	cleanup, err := telemetry.SetupTracer(ctx, cfg.Project, "batch-job", os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracer: %w", err)
+	}
+	defer cleanup()
+
+	targetDate := time.Now()
+	if cfg.Judge.Date != "" {
+		// Validate has already checked the format.
+		targetDate, _ = time.Parse(time.DateOnly, cfg.Judge.Date)
+	}
+
+	prompt, err := batch.LoadPrompt(cfg.Judge.PromptPath)
+	if err != nil {
+		return fmt.Errorf("failed to load prompt: %w", err)
+	}
+	slog.Info("Prompt loaded successfully", "name", prompt.Name)
+
+	endpoint := fmt.Sprintf("%s-aiplatform.googleapis.com:443", cfg.Location)
+	jobClient, err := aiplatform.NewJobClient(ctx, option.WithEndpoint(endpoint))
+	if err != nil {
+		return fmt.Errorf("failed to create Vertex Job Client: %w", err)
+	}
+	defer func() {
+		if err := jobClient.Close(); err != nil {
+			slog.Error("Failed to close Vertex Job Client", "error", err)
+		}
+	}()
+
+	rawStore, err := gcs.OpenURL(ctx, cfg.Storage.RawArchiveURL)
+	if err != nil {
+		return fmt.Errorf("failed to open raw archive: %w", err)
+	}
+	defer rawStore.Close()
+
+	stagingGCS, err := gcs.NewClient(ctx, cfg.Storage.StagingBucket)
+	if err != nil {
+		return fmt.Errorf("failed to create staging GCS client: %w", err)
+	}
+	defer stagingGCS.Close()
+
+	var opener batch.Opener
+	if cfg.Encryption.KeyProvider != "" {
+		provider, closeProvider, err := envelope.OpenKeyProvider(ctx, cfg.Encryption.KeyProvider)
+		if err != nil {
+			return err
+		}
+		defer closeProvider()
+		opener = envelope.NewOpener(provider)
+	}
+
+	checkpoints, closeCheckpoints, err := openCheckpoints(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeCheckpoints()
+
+	svc := batch.NewService(batch.Config{
+		ProjectID:     cfg.Project,
+		Location:      cfg.Location,
+		StagingBucket: cfg.Storage.StagingBucket,
+		OutputBucket:  cfg.Storage.ProcessedBucket,
+		ModelID:       cfg.Judge.ModelID,
+		Layout:        archive.Layout(cfg.Storage.Layout),
+	}, prompt, rawStore, stagingGCS, vertex.NewClient(jobClient), nil, opener, checkpoints)
+
+	slog.Info("Running batch job", "target_date", targetDate.Format(time.DateOnly))
+	return svc.Run(ctx, targetDate)
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/app/loader"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+	"github.com/dllewellyn/reflex/internal/platform/envelope"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/kafka"
+	"github.com/dllewellyn/reflex/internal/platform/telemetry"
+)
+
+func runLoad(ctx context.Context, cfg *config.Config) error {
+	stream := cfg.Load.Mode == "stream"
+	if !stream {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Load.Timeout)
+		defer cancel()
+	}
+
+	cleanup, err := telemetry.SetupTracer(ctx, cfg.Project, "loader", os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracer: %w", err)
+	}
+	defer cleanup()
+
+	consumer, err := kafka.NewConsumerWithConfig(kafka.ConsumerConfig{
+		BootstrapServers: cfg.Kafka.BootstrapServers,
+		APIKey:           cfg.Kafka.APIKey,
+		APISecret:        cfg.Kafka.APISecret,
+		SecurityProtocol: cfg.Kafka.SecurityProtocol,
+		GroupID:          cfg.Load.ConsumerGroup,
+	})
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	store, err := gcs.OpenURL(ctx, cfg.Storage.RawArchiveURL)
+	if err != nil {
+		return fmt.Errorf("failed to open raw archive: %w", err)
+	}
+	defer store.Close()
+
+	var sealer loader.Sealer
+	if cfg.Encryption.KeyProvider != "" {
+		provider, closeProvider, err := envelope.OpenKeyProvider(ctx, cfg.Encryption.KeyProvider)
+		if err != nil {
+			return err
+		}
+		defer closeProvider()
+		if sealer, err = envelope.NewSealer(provider, envelope.Config{
+			DefaultKeyID: cfg.Encryption.DefaultKey,
+			TenantKeyIDs: cfg.Encryption.TenantKeys,
+			DataKeyTTL:   cfg.Encryption.DataKeyTTL,
+		}); err != nil {
+			return err
+		}
+	}
+
+	checkpoints, closeCheckpoints, err := openCheckpoints(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeCheckpoints()
+
+	svc := loader.NewService(consumer, store, sealer, checkpoints, loader.Config{
+		Topic:          cfg.Kafka.Topic,
+		Layout:         archive.Layout(cfg.Storage.Layout),
+		Format:         archive.Format(cfg.Storage.Format),
+		FlushMaxEvents: cfg.Load.FlushMaxEvents,
+		FlushMaxBytes:  cfg.Load.FlushMaxBytes,
+		FlushMaxAge:    cfg.Load.FlushMaxAge,
+		MaxBufferBytes: cfg.Load.MaxBufferBytes,
+	})
+
+	if stream {
+		slog.Info("Starting Streaming Loader...")
+		return svc.RunStream(ctx)
+	}
+	slog.Info("Starting Hourly Loader Job...")
+	return svc.RunOnce(ctx)
+}
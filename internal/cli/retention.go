@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/app/retention"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/telemetry"
+)
+
+var retentionOpts struct {
+	action       string
+	user         string
+	conversation string
+	requestedBy  string
+}
+
+func retentionFlags(fs *flag.FlagSet) {
+	fs.StringVar(&retentionOpts.user, "user", "", "erase: erase every conversation containing this user's events")
+	fs.StringVar(&retentionOpts.conversation, "conversation", "", "erase: erase this conversation")
+	fs.StringVar(&retentionOpts.requestedBy, "requested-by", "", "erase: who requested the erasure, recorded in the audit record")
+}
+
+// retentionArgs takes the action, sweep or erase, that precedes the flags.
+func retentionArgs(args []string) ([]string, error) {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		return args, nil
+	}
+	if len(args) == 0 || (args[0] != "sweep" && args[0] != "erase") {
+		return nil, errors.New("usage: reflex retention sweep|erase [flags]")
+	}
+	retentionOpts.action = args[0]
+	return args[1:], nil
+}
+
+// runRetention implements "reflex retention sweep", which deletes archived data older than its
+// tenant's TTL, and "reflex retention erase", which deletes every archived record of a user or
+// conversation and writes an audit record.
+func runRetention(ctx context.Context, cfg *config.Config) error {
+	if retentionOpts.action == "erase" && retentionOpts.user == "" && retentionOpts.conversation == "" {
+		return errors.New("erase needs -user or -conversation")
+	}
+
+	cleanup, err := telemetry.SetupTracer(ctx, cfg.Project, "retention", os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracer: %w", err)
+	}
+	defer cleanup()
+
+	raw, err := gcs.OpenURL(ctx, cfg.Storage.RawArchiveURL)
+	if err != nil {
+		return fmt.Errorf("failed to open raw archive: %w", err)
+	}
+	defer raw.Close()
+
+	// Staging and results are optional; without them only the raw archive is swept or erased.
+	var staging, results retention.Store
+	for _, s := range []struct {
+		name string
+		url  string
+		dst  *retention.Store
+	}{
+		{"staging", firstNonEmpty(cfg.Retention.StagingURL, cfg.Storage.StagingBucket), &staging},
+		{"results", firstNonEmpty(cfg.Retention.ResultsURL, cfg.Storage.ProcessedBucket), &results},
+	} {
+		if s.url == "" {
+			slog.Warn("No store configured, skipping", "store", s.name)
+			continue
+		}
+		store, err := gcs.OpenURL(ctx, s.url)
+		if err != nil {
+			return fmt.Errorf("failed to open %s store: %w", s.name, err)
+		}
+		defer store.Close()
+		*s.dst = store
+	}
+
+	// Audit records go to the raw archive unless a separate store is configured.
+	var audit retention.Store = raw
+	if cfg.Retention.AuditURL != "" {
+		store, err := gcs.OpenURL(ctx, cfg.Retention.AuditURL)
+		if err != nil {
+			return fmt.Errorf("failed to open audit store: %w", err)
+		}
+		defer store.Close()
+		audit = store
+	}
+
+	svc := retention.NewService(raw, staging, results, audit, retention.Config{
+		Policy: retention.Policy{
+			DefaultTTL: cfg.Retention.DefaultTTL,
+			TenantTTLs: cfg.Retention.TenantTTLs,
+		},
+		DryRun: cfg.Retention.DryRun,
+	})
+
+	if retentionOpts.action == "sweep" {
+		slog.Info("Starting retention sweep", "default_ttl", cfg.Retention.DefaultTTL, "tenant_ttls", cfg.Retention.TenantTTLs, "dry_run", cfg.Retention.DryRun)
+		report, err := svc.Sweep(ctx)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(report); err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("retention sweep finished with %d failures", report.Failed)
+		}
+		slog.Info("Retention sweep completed successfully", "removals", len(report.Removals))
+		return nil
+	}
+
+	slog.Info("Starting erasure", "conversation_id", retentionOpts.conversation, "requested_by", retentionOpts.requestedBy, "dry_run", cfg.Retention.DryRun)
+	record, err := svc.Erase(ctx, retention.ErasureRequest{
+		UserID:         retentionOpts.user,
+		ConversationID: retentionOpts.conversation,
+		RequestedBy:    retentionOpts.requestedBy,
+	})
+	if record != nil {
+		if printErr := printJSON(record); printErr != nil {
+			return errors.Join(err, printErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Erasure completed successfully", "audit_id", record.ID)
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/dllewellyn/reflex/internal/app/review"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+)
+
+var reviewOpts struct {
+	list     string
+	approve  string
+	reject   string
+	reviewer string
+	reason   string
+}
+
+func reviewFlags(fs *flag.FlagSet) {
+	fs.StringVar(&reviewOpts.list, "list", "", "List the candidates with this status: pending, approved or rejected")
+	fs.StringVar(&reviewOpts.approve, "approve", "", "Approve and upsert the pending candidates with these comma-separated IDs")
+	fs.StringVar(&reviewOpts.reject, "reject", "", "Reject the pending candidates with these comma-separated IDs")
+	fs.StringVar(&reviewOpts.reviewer, "reviewer", "", "Who reviewed the candidates, recorded with them")
+	fs.StringVar(&reviewOpts.reason, "reason", "", "Why the candidates were approved or rejected, recorded with them")
+}
+
+// openReviewQueue opens the review queue, or returns nil if review.queue_url is unset. store may
+// be nil for a queue that only submits.
+func openReviewQueue(ctx context.Context, cfg *config.Config, store pinecone.VectorStore) (*review.Queue, func(), error) {
+	if cfg.Review.QueueURL == "" {
+		return nil, func() {}, nil
+	}
+	blobs, err := gcs.OpenURL(ctx, cfg.Review.QueueURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open review queue: %w", err)
+	}
+	queue := review.NewQueue(blobs, store, review.Config{Rules: review.Rules{
+		MinConfidence: cfg.Review.AutoApproveConfidence,
+		Severities:    cfg.Review.AutoApproveSeverities,
+	}})
+	return queue, func() { _ = blobs.Close() }, nil
+}
+
+// runReview implements "reflex review", which runs exactly one of -list, -approve and -reject
+// against the review queue. It lists pending candidates when given none.
+func runReview(ctx context.Context, cfg *config.Config) error {
+	actions := 0
+	for _, set := range []bool{reviewOpts.list != "", reviewOpts.approve != "", reviewOpts.reject != ""} {
+		if set {
+			actions++
+		}
+	}
+	if actions > 1 {
+		return errors.New("review needs at most one of -list, -approve and -reject")
+	}
+	if (reviewOpts.approve != "" || reviewOpts.reject != "") && reviewOpts.reviewer == "" {
+		return errors.New("-approve and -reject need -reviewer")
+	}
+
+	var store pinecone.VectorStore
+	saveVectors := func() error { return nil }
+	if reviewOpts.approve != "" {
+		// Approved candidates move out of pending, so upserting them into an in-memory store that
+		// is thrown away on exit would lose them.
+		if !persistentVectorStore(cfg) {
+			return errors.New("-approve needs a persistent vector store: set pinecone.api_key and pinecone.index_host, qdrant.url, or pinecone.snapshot")
+		}
+		var err error
+		if store, saveVectors, err = openVectorStore(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	queue, closeQueue, err := openReviewQueue(ctx, cfg, store)
+	if err != nil {
+		return err
+	}
+	defer closeQueue()
+
+	switch {
+	case reviewOpts.approve != "":
+		approved, err := queue.Approve(ctx, splitIDs(reviewOpts.approve), reviewOpts.reviewer, reviewOpts.reason)
+		if len(approved) > 0 {
+			if saveErr := saveVectors(); saveErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to save vector snapshot: %w", saveErr))
+			}
+		}
+		if printErr := printJSON(approved); printErr != nil {
+			return errors.Join(err, printErr)
+		}
+		return err
+	case reviewOpts.reject != "":
+		rejected, err := queue.Reject(ctx, splitIDs(reviewOpts.reject), reviewOpts.reviewer, reviewOpts.reason)
+		if printErr := printJSON(rejected); printErr != nil {
+			return errors.Join(err, printErr)
+		}
+		return err
+	default:
+		status := review.StatusPending
+		if reviewOpts.list != "" {
+			status = review.Status(reviewOpts.list)
+		}
+		switch status {
+		case review.StatusPending, review.StatusApproved, review.StatusRejected:
+		default:
+			return fmt.Errorf("-list must be %s, %s or %s", review.StatusPending, review.StatusApproved, review.StatusRejected)
+		}
+		candidates, err := queue.List(ctx, status)
+		if err != nil {
+			return err
+		}
+		return printJSON(candidates)
+	}
+}
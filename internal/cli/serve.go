@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dllewellyn/reflex/internal/app/ingestor"
+	"github.com/dllewellyn/reflex/internal/app/redaction"
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/kafka"
+	"github.com/dllewellyn/reflex/internal/platform/telemetry"
+)
+
+func runServe(ctx context.Context, cfg *config.Config) error {
+	cleanup, err := telemetry.SetupTracer(ctx, cfg.Project, "ingestor", os.Stdout)
+	if err != nil {
+		slog.Error("failed to setup tracer", "error", err)
+		// Proceed without tracer
+	}
+	defer cleanup()
+
+	kafkaCfg, err := kafka.ProducerConfig(cfg.Kafka.ConfigFile, cfg.Kafka.BootstrapServers, cfg.Kafka.APIKey, cfg.Kafka.APISecret)
+	if err != nil {
+		return fmt.Errorf("failed to read kafka config: %w", err)
+	}
+	producer, err := kafka.NewProducer(kafkaCfg)
+	if err != nil {
+		return err
+	}
+
+	vectorStore, _, err := openVectorStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	redactor, closeRedactor, err := redaction.Open(ctx, cfg.Serve.RedactionConfig, cfg.Serve.RedactionHMACKey, cfg.Serve.RedactionVaultURL)
+	if err != nil {
+		return fmt.Errorf("failed to configure redaction: %w", err)
+	}
+	defer closeRedactor()
+
+	svc := ingestor.NewService(producer, vectorStore, redactor, ingestor.Config{
+		TopicName:     cfg.Kafka.Topic,
+		Port:          cfg.Serve.Port,
+		MatchLabels:   cfg.Serve.MatchLabels,
+		FeedbackTopic: cfg.Kafka.FeedbackTopic,
+		ResultsTopic:  cfg.Kafka.ResultsTopic,
+		FeedbackToken: cfg.Serve.FeedbackToken,
+	})
+	slog.Info("Starting server...", "port", cfg.Serve.Port)
+	return svc.Run(ctx)
+}
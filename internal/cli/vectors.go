@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/config"
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/dllewellyn/reflex/internal/platform/qdrant"
+)
+
+// openVectorStore connects to Qdrant if it is configured, then Pinecone, and otherwise returns an
+// in-memory store loaded from the snapshot file if there is one. save writes an in-memory store
+// back to the snapshot; it does nothing for the other stores or without a snapshot file.
+func openVectorStore(ctx context.Context, cfg *config.Config) (store pinecone.VectorStore, save func() error, err error) {
+	noSave := func() error { return nil }
+	embedder := openEmbedder(cfg)
+	if cfg.Qdrant.URL != "" {
+		if embedder == nil {
+			return nil, nil, errors.New("qdrant.url requires embedding.url")
+		}
+		client, err := qdrant.NewClient(ctx, &http.Client{Timeout: time.Minute}, embedder, qdrant.Config{
+			URL:        cfg.Qdrant.URL,
+			APIKey:     cfg.Qdrant.APIKey,
+			Collection: cfg.Qdrant.Collection,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create qdrant client: %w", err)
+		}
+		return client, noSave, nil
+	}
+	if cfg.Pinecone.APIKey != "" && cfg.Pinecone.IndexHost != "" {
+		pcClient, err := pinecone.NewClient(ctx, cfg.Pinecone.APIKey, cfg.Pinecone.IndexHost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create pinecone client: %w", err)
+		}
+		return pcClient, noSave, nil
+	}
+
+	var memoryEmbedder pinecone.Embedder
+	if embedder != nil {
+		memoryEmbedder = embedder
+	}
+	memory := pinecone.NewMemoryStore(memoryEmbedder)
+	path := cfg.Pinecone.Snapshot
+	if path == "" {
+		slog.Warn("pinecone.api_key or pinecone.index_host not set; using an empty in-memory vector store")
+		return memory, noSave, nil
+	}
+	if err := memory.Load(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	stats, _ := memory.DescribeIndexStats(ctx)
+	slog.Info("Using in-memory vector store", "snapshot", path, "vectors", stats.TotalVectorCount)
+	return memory, func() error { return memory.Save(path) }, nil
+}
+
+// persistentVectorStore reports whether the store openVectorStore returns keeps what is written
+// to it: Qdrant, Pinecone, or an in-memory store saved to a snapshot file.
+func persistentVectorStore(cfg *config.Config) bool {
+	return cfg.Qdrant.URL != "" || (cfg.Pinecone.APIKey != "" && cfg.Pinecone.IndexHost != "") || cfg.Pinecone.Snapshot != ""
+}
+
+// openEmbedder returns the configured embeddings API, or nil if there is none.
+func openEmbedder(cfg *config.Config) *pinecone.HTTPEmbedder {
+	if cfg.Embedding.URL == "" {
+		return nil
+	}
+	return pinecone.NewHTTPEmbedder(&http.Client{Timeout: time.Minute}, pinecone.HTTPEmbedderConfig{
+		URL:       cfg.Embedding.URL,
+		Model:     cfg.Embedding.Model,
+		APIKey:    cfg.Embedding.APIKey,
+		Dimension: cfg.Embedding.Dimension,
+		Prefixes:  pinecone.Prefixes{Document: cfg.Embedding.DocumentPrefix, Query: cfg.Embedding.QueryPrefix},
+	})
+}
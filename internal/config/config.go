@@ -0,0 +1,349 @@
+// Package config is the shared configuration of the reflex command. Values are merged from, in
+// increasing order of precedence: field defaults, a YAML file, environment variables and
+// command-line flags.
+//
+// Each field is described by struct tags:
+//
+//	yaml:"name"      key in the YAML file; flags are named <section>.<name>
+//	env:"A,B"        environment variables, the first one set wins
+//	default:"value"  value used when nothing else sets the field
+//	secret:"true"    masked by Print
+//	usage:"text"     flag help
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/archive"
+)
+
+// Config is the configuration of every reflex subcommand. Each subcommand reads the top-level
+// settings and the sections it needs; see Validate.
+type Config struct {
+	Project  string `yaml:"project" env:"GOOGLE_CLOUD_PROJECT,GCP_PROJECT,GCP_PROJECT_ID" usage:"Google Cloud project ID"`
+	Location string `yaml:"location" env:"GOOGLE_CLOUD_LOCATION,GCP_LOCATION" default:"us-central1" usage:"Google Cloud region"`
+
+	Kafka      Kafka      `yaml:"kafka"`
+	Storage    Storage    `yaml:"storage"`
+	Encryption Encryption `yaml:"encryption"`
+	Pinecone   Pinecone   `yaml:"pinecone"`
+	Qdrant     Qdrant     `yaml:"qdrant"`
+	Embedding  Embedding  `yaml:"embedding"`
+
+	Serve     Serve     `yaml:"serve"`
+	Load      Loader    `yaml:"load"`
+	Judge     Judge     `yaml:"judge"`
+	Extract   Extract   `yaml:"extract"`
+	Eval      Eval      `yaml:"eval"`
+	Dataset   Dataset   `yaml:"dataset"`
+	Curate    Curate    `yaml:"curate"`
+	Review    Review    `yaml:"review"`
+	Compact   Compact   `yaml:"compact"`
+	Retention Retention `yaml:"retention"`
+	Dev       Dev       `yaml:"dev"`
+}
+
+type Kafka struct {
+	BootstrapServers string `yaml:"bootstrap_servers" env:"KAFKA_BOOTSTRAP_SERVERS" usage:"Kafka broker addresses"`
+	APIKey           string `yaml:"api_key" env:"KAFKA_API_KEY" secret:"true" usage:"Kafka SASL username"`
+	APISecret        string `yaml:"api_secret" env:"KAFKA_API_SECRET" secret:"true" usage:"Kafka SASL password"`
+	SecurityProtocol string `yaml:"security_protocol" env:"KAFKA_SECURITY_PROTOCOL" default:"SASL_SSL" usage:"Kafka security protocol"`
+	// ConfigFile is a librdkafka properties file used by the ingestor's producer.
+	ConfigFile string `yaml:"config_file" env:"KAFKA_CONFIG_FILE" default:"client.properties" usage:"Kafka client properties file"`
+	Topic      string `yaml:"topic" env:"KAFKA_TOPIC" usage:"Topic for raw interactions"`
+	// ResultsTopic carries batch prediction results to the extractor.
+	ResultsTopic string `yaml:"results_topic" env:"KAFKA_TOPIC_BATCH_RESULTS" usage:"Topic for batch results"`
+	// FeedbackTopic carries corrections posted to the ingestor's /feedback endpoint.
+	FeedbackTopic string `yaml:"feedback_topic" env:"KAFKA_TOPIC_FEEDBACK" usage:"Topic for verdict feedback (/feedback is disabled without it)"`
+}
+
+type Storage struct {
+	RawArchiveURL   string `yaml:"raw_archive_url" env:"RAW_ARCHIVE_URL,GCS_RAW_PROMPT_BUCKET" usage:"Raw archive (gs://, s3://, file:// or a bucket name)"`
+	StagingBucket   string `yaml:"staging_bucket" env:"GCS_BATCH_STAGING_BUCKET,GCS_BUCKET_NAME" usage:"GCS bucket for batch inputs"`
+	ProcessedBucket string `yaml:"processed_bucket" env:"GCS_PROCESSED_PROMPT_BUCKET,GCS_BUCKET_NAME" usage:"GCS bucket for batch results"`
+	Layout          string `yaml:"layout" env:"ARCHIVE_LAYOUT" default:"session" usage:"Archive layout: session or hourly"`
+	Format          string `yaml:"format" env:"ARCHIVE_FORMAT" default:"jsonl.gz" usage:"Hourly data file format: jsonl.gz or parquet"`
+	CheckpointURL   string `yaml:"checkpoint_url" env:"CHECKPOINT_STORE_URL" usage:"Checkpoint store (firestore://, file:// or memory://)"`
+}
+
+type Encryption struct {
+	KeyProvider string            `yaml:"key_provider" env:"ENCRYPTION_KEY_PROVIDER" usage:"Key provider (file:// or gcpkms://); unset disables encryption"`
+	DefaultKey  string            `yaml:"default_key" env:"ENCRYPTION_DEFAULT_KEY" usage:"Key ID for tenants without their own key"`
+	TenantKeys  map[string]string `yaml:"tenant_keys" env:"ENCRYPTION_TENANT_KEYS" usage:"Per-tenant key IDs as tenant:key,..."`
+	DataKeyTTL  time.Duration     `yaml:"data_key_ttl" env:"ENCRYPTION_DATA_KEY_TTL" default:"1h" usage:"How long a data key is reused"`
+}
+
+type Pinecone struct {
+	APIKey    string `yaml:"api_key" env:"PINECONE_API_KEY" secret:"true" usage:"Pinecone API key"`
+	IndexHost string `yaml:"index_host" env:"PINECONE_INDEX_HOST" usage:"Pinecone index host"`
+	Snapshot  string `yaml:"snapshot" env:"VECTOR_SNAPSHOT" usage:"Without Pinecone, keep vectors in memory and load them from (and for dataset, save them to) this file"`
+}
+
+// Qdrant configures a self-hosted vector store, used instead of Pinecone when url is set.
+type Qdrant struct {
+	URL        string `yaml:"url" env:"QDRANT_URL" usage:"Qdrant REST endpoint, e.g. http://localhost:6333"`
+	APIKey     string `yaml:"api_key" env:"QDRANT_API_KEY" secret:"true" usage:"Qdrant API key"`
+	Collection string `yaml:"collection" env:"QDRANT_COLLECTION" default:"reflex" usage:"Qdrant collection, created if missing"`
+}
+
+// Embedding configures the model that embeds text on the client side, for Qdrant and the
+// in-memory store. Pinecone embeds text itself.
+type Embedding struct {
+	URL            string `yaml:"url" env:"EMBEDDING_URL" usage:"OpenAI-compatible embeddings API base, e.g. http://localhost:8081/v1"`
+	Model          string `yaml:"model" env:"EMBEDDING_MODEL" default:"intfloat/multilingual-e5-large" usage:"Embedding model"`
+	APIKey         string `yaml:"api_key" env:"EMBEDDING_API_KEY" secret:"true" usage:"Embeddings API key"`
+	Dimension      int    `yaml:"dimension" env:"EMBEDDING_DIMENSION" default:"1024" usage:"Length of the model's vectors"`
+	DocumentPrefix string `yaml:"document_prefix" env:"EMBEDDING_DOCUMENT_PREFIX" default:"passage: " usage:"Prepended to stored text"`
+	QueryPrefix    string `yaml:"query_prefix" env:"EMBEDDING_QUERY_PREFIX" default:"query: " usage:"Prepended to query text"`
+}
+
+// Serve configures the ingestor API.
+type Serve struct {
+	Port              string   `yaml:"port" env:"PORT" default:"8080" usage:"HTTP port"`
+	RedactionConfig   string   `yaml:"redaction_config" env:"REDACTION_CONFIG" usage:"Redaction policy YAML file"`
+	RedactionHMACKey  string   `yaml:"redaction_hmac_key" env:"REDACTION_HMAC_KEY" secret:"true" usage:"HMAC key for tokenizing redaction"`
+	RedactionVaultURL string   `yaml:"redaction_vault_url" env:"REDACTION_VAULT_URL" usage:"Store for reversible redaction tokens"`
+	MatchLabels       []string `yaml:"match_labels" env:"MATCH_LABELS" default:"injection,jailbreak" usage:"Labels of the stored records prompts are compared with (* for all)"`
+	FeedbackToken     string   `yaml:"feedback_token" env:"FEEDBACK_TOKEN" secret:"true" usage:"Bearer token callers of /feedback must present (/feedback is disabled without it)"`
+}
+
+// Loader configures the loader.
+type Loader struct {
+	Mode           string        `yaml:"mode" env:"LOADER_MODE" default:"batch" usage:"batch (until idle) or stream (until signalled)"`
+	ConsumerGroup  string        `yaml:"consumer_group" env:"LOADER_CONSUMER_GROUP_ID" default:"loader-consumer" usage:"Kafka consumer group"`
+	Timeout        time.Duration `yaml:"timeout" env:"LOADER_TIMEOUT" default:"5m" usage:"Batch mode deadline"`
+	FlushMaxEvents int           `yaml:"flush_max_events" env:"LOADER_FLUSH_MAX_EVENTS" default:"1000" usage:"Stream mode: events per conversation before a flush"`
+	FlushMaxBytes  int           `yaml:"flush_max_bytes" env:"LOADER_FLUSH_MAX_BYTES" default:"1048576" usage:"Stream mode: bytes per conversation before a flush"`
+	FlushMaxAge    time.Duration `yaml:"flush_max_age" env:"LOADER_FLUSH_MAX_AGE" default:"5m" usage:"Stream mode: age of the oldest event before a flush"`
+	MaxBufferBytes int           `yaml:"max_buffer_bytes" env:"LOADER_MAX_BUFFER_BYTES" default:"67108864" usage:"Stream mode: total buffered bytes before a flush"`
+}
+
+// Judge configures the daily batch analyzer.
+type Judge struct {
+	ModelID    string `yaml:"model_id" env:"MODEL_ID" default:"publishers/google/models/gemini-2.5-flash" usage:"Vertex AI model"`
+	PromptPath string `yaml:"prompt_path" env:"JUDGE_PROMPT_PATH" default:"prompts/security-judge.prompt.yml" usage:"Security judge prompt"`
+	Date       string `yaml:"date" env:"JUDGE_DATE" usage:"Day to analyse as YYYY-MM-DD; defaults to today"`
+}
+
+// Extract configures the injection extractor.
+type Extract struct {
+	PromptPath  string `yaml:"prompt_path" env:"EXTRACT_PROMPT_PATH" default:"prompts/extract-injection.prompt.yml" usage:"Extraction prompt"`
+	IdleTimeout int    `yaml:"idle_timeout_seconds" env:"IDLE_TIMEOUT_SECONDS" default:"30" usage:"Stop after this many idle seconds"`
+	DryRun      bool   `yaml:"dry_run" env:"DRY_RUN" usage:"Log records instead of upserting them"`
+	// GroundingMinSimilarity drops extracted lines that match no user turn this closely.
+	GroundingMinSimilarity float64 `yaml:"grounding_min_similarity" env:"GROUNDING_MIN_SIMILARITY" default:"0.8" usage:"Word-level similarity an extracted line needs to a span of a user turn to be kept"`
+	// DuplicateThreshold merges extracted lines into stored signatures at least this similar, as
+	// scored by the vector store.
+	DuplicateThreshold float64 `yaml:"duplicate_threshold" env:"DUPLICATE_THRESHOLD" default:"0.95" usage:"Vector store similarity at which an extracted line is merged into the stored signature it resembles"`
+	// RunDuplicateThreshold merges extracted lines into lines kept earlier in the run whose words
+	// are at least this similar. They are not embedded yet, so a different measure applies.
+	RunDuplicateThreshold float64 `yaml:"run_duplicate_threshold" env:"RUN_DUPLICATE_THRESHOLD" default:"0.85" usage:"Word similarity at which an extracted line is merged into a line kept earlier in the run"`
+}
+
+// Eval configures the judge model evaluation.
+type Eval struct {
+	PromptPath string        `yaml:"prompt_path" env:"EVAL_PROMPT_PATH" default:"prompts/security-judge.prompt.yml" usage:"Prompt whose system message is evaluated"`
+	TestData   string        `yaml:"test_data" env:"EVAL_TEST_DATA" default:"test_prompts.json" usage:"Labelled test prompts"`
+	Output     string        `yaml:"output" env:"EVAL_OUTPUT" default:"evaluation_results.json" usage:"Results file"`
+	Models     []string      `yaml:"models" env:"EVAL_MODELS" default:"gemini-2.5-flash-lite,gemini-2.5-flash" usage:"Models to evaluate"`
+	Interval   time.Duration `yaml:"interval" env:"EVAL_INTERVAL" default:"500ms" usage:"Delay between requests"`
+}
+
+// Dataset configures loading a HuggingFace dataset into the vector store.
+type Dataset struct {
+	Manifest        string            `yaml:"manifest" env:"DATASET_MANIFEST" usage:"YAML manifest listing several datasets to load instead of id"`
+	ID              string            `yaml:"id" env:"HF_DATASET_ID" default:"deepset/prompt-injections" usage:"HuggingFace dataset ID, or file://<path> for local files"`
+	Split           string            `yaml:"split" env:"HF_SPLIT" default:"train" usage:"Dataset split"`
+	TextColumn      string            `yaml:"text_column" env:"HF_TEXT_COL" default:"text" usage:"Text column"`
+	LabelColumn     string            `yaml:"label_column" env:"HF_LABEL_COL" default:"label" usage:"Label column"`
+	FilterColumn    string            `yaml:"filter_column" env:"HF_FILTER_COL" usage:"Only load rows where this column..."`
+	Token           string            `yaml:"token" env:"HF_TOKEN" secret:"true" usage:"HuggingFace token, for private and gated datasets"`
+	Revision        string            `yaml:"revision" env:"HF_REVISION" default:"main" usage:"Branch, tag or commit to read when an ID does not pin one as <id>@<revision>"`
+	CacheDir        string            `yaml:"cache_dir" env:"HF_CACHE_DIR" usage:"Keep downloaded files here, keyed by commit"`
+	Endpoint        string            `yaml:"endpoint" env:"HF_ENDPOINT" usage:"HuggingFace Hub URL (default https://huggingface.co)"`
+	FilterValue     string            `yaml:"filter_value" env:"HF_FILTER_VAL" usage:"...has this value"`
+	Filter          string            `yaml:"filter" env:"DATASET_FILTER" usage:"Expression selecting the rows to load, e.g. 'lang == \"en\" and len(text) <= 4000'"`
+	Labels          map[string]string `yaml:"labels" env:"DATASET_LABELS" default:"0:benign,1:injection" usage:"Label values mapped to canonical labels (injection, jailbreak, benign) as value:label pairs"`
+	BatchSize       int               `yaml:"batch_size" env:"BATCH_SIZE" default:"96" usage:"Records per upsert"`
+	VectorDimension int               `yaml:"vector_dimension" env:"VECTOR_DIMENSION" default:"1024" usage:"Embedding dimension"`
+}
+
+// Curate configures the curation of the vector store's signatures.
+type Curate struct {
+	AuditURL        string  `yaml:"audit_url" env:"AUDIT_STORE_URL" usage:"Blob store for audit records of deleted signatures"`
+	FeedbackPenalty float64 `yaml:"feedback_penalty" env:"FEEDBACK_PENALTY" default:"0.5" usage:"Weight factor applied to a signature when -confirm accepts the false positives reported against it"`
+}
+
+// Review configures the queue extracted injections wait in for approval.
+type Review struct {
+	QueueURL              string   `yaml:"queue_url" env:"REVIEW_QUEUE_URL" usage:"Blob store holding extracted injections for review; unset upserts them without review"`
+	AutoApproveConfidence float64  `yaml:"auto_approve_confidence" env:"REVIEW_AUTO_APPROVE_CONFIDENCE" usage:"Approve injections the judge flagged with at least this confidence; 0 disables it"`
+	AutoApproveSeverities []string `yaml:"auto_approve_severities" env:"REVIEW_AUTO_APPROVE_SEVERITIES" usage:"Approve injections the judge gave one of these severities, e.g. HIGH,CRITICAL"`
+}
+
+// Compact configures the compaction of storage's raw archive, in storage's layout. Hourly
+// partitions are rewritten in storage's format.
+type Compact struct {
+	Date   string `yaml:"date" env:"COMPACT_DATE" usage:"Day to compact as YYYY-MM-DD; defaults to yesterday"`
+	DryRun bool   `yaml:"dry_run" env:"COMPACT_DRY_RUN" usage:"Report what would be compacted without writing or deleting"`
+}
+
+// Retention configures the retention sweep and erasure of storage's raw archive and, when they
+// are set, the staging and results stores.
+type Retention struct {
+	StagingURL string                   `yaml:"staging_url" env:"STAGING_STORE_URL" usage:"Store of batch inputs; defaults to storage.staging_bucket"`
+	ResultsURL string                   `yaml:"results_url" env:"RESULTS_STORE_URL" usage:"Store of batch results; defaults to storage.processed_bucket"`
+	AuditURL   string                   `yaml:"audit_url" env:"AUDIT_STORE_URL" usage:"Blob store for erasure audit records; defaults to the raw archive"`
+	DefaultTTL time.Duration            `yaml:"default_ttl" env:"RETENTION_DEFAULT_TTL" usage:"How long data is kept; 0 keeps it forever"`
+	TenantTTLs map[string]time.Duration `yaml:"tenant_ttls" env:"RETENTION_TENANT_TTLS" usage:"Per-tenant TTLs as tenant:ttl,..."`
+	DryRun     bool                     `yaml:"dry_run" env:"RETENTION_DRY_RUN" usage:"Report what would be removed without writing or deleting"`
+}
+
+// Commands lists the subcommands Validate knows about.
+var Commands = []string{"serve", "load", "judge", "extract", "eval", "dataset", "curate", "review", "compact", "retention", "dev"}
+
+// Dev configures the all-in-one local pipeline. It also uses kafka's topic names, storage's
+// layout, the judge and extract prompts and serve's port and redaction settings.
+type Dev struct {
+	DataDir string `yaml:"data_dir" env:"DEV_DATA_DIR" default:".reflex-dev" usage:"Directory for the local archive and batch files"`
+	Script  string `yaml:"script" env:"DEV_SCRIPT" usage:"YAML file scripting the fake LLM; defaults to a built-in script"`
+	Start   string `yaml:"start" env:"DEV_START" usage:"Initial simulated time as RFC 3339; defaults to now"`
+}
+
+// Validate checks that the settings command needs are present and well formed.
+func (c *Config) Validate(command string) error {
+	var errs []error
+	require := func(value, name string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	oneOf := func(value, name string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %v, got %q", name, allowed, value))
+	}
+	requireKafkaConsumer := func() {
+		require(c.Kafka.BootstrapServers, "kafka.bootstrap_servers")
+		require(c.Kafka.APIKey, "kafka.api_key")
+		require(c.Kafka.APISecret, "kafka.api_secret")
+	}
+
+	switch command {
+	case "serve":
+		require(c.Kafka.Topic, "kafka.topic")
+		if c.Serve.RedactionVaultURL != "" && c.Serve.RedactionHMACKey == "" {
+			errs = append(errs, errors.New("serve.redaction_vault_url requires serve.redaction_hmac_key"))
+		}
+		if c.Qdrant.URL != "" {
+			require(c.Embedding.URL, "embedding.url")
+		}
+	case "load":
+		require(c.Kafka.Topic, "kafka.topic")
+		requireKafkaConsumer()
+		require(c.Storage.RawArchiveURL, "storage.raw_archive_url")
+		oneOf(c.Load.Mode, "load.mode", "batch", "stream")
+		oneOf(c.Storage.Layout, "storage.layout", string(archive.LayoutSession), string(archive.LayoutHourly))
+		oneOf(c.Storage.Format, "storage.format", string(archive.FormatJSONLGzip), string(archive.FormatParquet))
+		if c.Encryption.KeyProvider != "" {
+			require(c.Encryption.DefaultKey, "encryption.default_key")
+		}
+	case "judge":
+		require(c.Project, "project")
+		require(c.Storage.RawArchiveURL, "storage.raw_archive_url")
+		require(c.Storage.StagingBucket, "storage.staging_bucket")
+		require(c.Storage.ProcessedBucket, "storage.processed_bucket")
+		require(c.Judge.ModelID, "judge.model_id")
+		oneOf(c.Storage.Layout, "storage.layout", string(archive.LayoutSession), string(archive.LayoutHourly))
+		if c.Judge.Date != "" {
+			if _, err := time.Parse(time.DateOnly, c.Judge.Date); err != nil {
+				errs = append(errs, fmt.Errorf("judge.date must be YYYY-MM-DD: %w", err))
+			}
+		}
+	case "extract":
+		require(c.Project, "project")
+		if c.Qdrant.URL == "" {
+			require(c.Pinecone.APIKey, "pinecone.api_key")
+			require(c.Pinecone.IndexHost, "pinecone.index_host")
+		} else {
+			require(c.Embedding.URL, "embedding.url")
+		}
+		require(c.Kafka.ResultsTopic, "kafka.results_topic")
+		require(c.Kafka.BootstrapServers, "kafka.bootstrap_servers")
+		if c.Extract.GroundingMinSimilarity <= 0 || c.Extract.GroundingMinSimilarity > 1 {
+			errs = append(errs, errors.New("extract.grounding_min_similarity must be in (0, 1]"))
+		}
+		if c.Extract.DuplicateThreshold <= 0 || c.Extract.DuplicateThreshold > 1 {
+			errs = append(errs, errors.New("extract.duplicate_threshold must be in (0, 1]"))
+		}
+		if c.Extract.RunDuplicateThreshold <= 0 || c.Extract.RunDuplicateThreshold > 1 {
+			errs = append(errs, errors.New("extract.run_duplicate_threshold must be in (0, 1]"))
+		}
+		if c.Review.AutoApproveConfidence < 0 || c.Review.AutoApproveConfidence > 1 {
+			errs = append(errs, errors.New("review.auto_approve_confidence must be in [0, 1]"))
+		}
+	case "eval":
+		require(c.Project, "project")
+		if len(c.Eval.Models) == 0 {
+			errs = append(errs, errors.New("eval.models is required"))
+		}
+	case "dataset":
+		switch {
+		case c.Qdrant.URL != "":
+			require(c.Embedding.URL, "embedding.url")
+		case c.Pinecone.Snapshot == "":
+			require(c.Pinecone.APIKey, "pinecone.api_key")
+			require(c.Pinecone.IndexHost, "pinecone.index_host")
+		}
+		if c.Dataset.BatchSize <= 0 {
+			errs = append(errs, errors.New("dataset.batch_size must be positive"))
+		}
+	case "curate":
+		if c.Qdrant.URL != "" {
+			require(c.Embedding.URL, "embedding.url")
+		}
+		if c.Curate.FeedbackPenalty <= 0 || c.Curate.FeedbackPenalty > 1 {
+			errs = append(errs, errors.New("curate.feedback_penalty must be in (0, 1]"))
+		}
+	case "review":
+		require(c.Review.QueueURL, "review.queue_url")
+		if c.Qdrant.URL != "" {
+			require(c.Embedding.URL, "embedding.url")
+		}
+	case "compact":
+		require(c.Storage.RawArchiveURL, "storage.raw_archive_url")
+		oneOf(c.Storage.Layout, "storage.layout", string(archive.LayoutSession), string(archive.LayoutHourly))
+		oneOf(c.Storage.Format, "storage.format", string(archive.FormatJSONLGzip), string(archive.FormatParquet))
+		if c.Compact.Date != "" {
+			if _, err := time.Parse(time.DateOnly, c.Compact.Date); err != nil {
+				errs = append(errs, fmt.Errorf("compact.date must be YYYY-MM-DD: %w", err))
+			}
+		}
+	case "retention":
+		require(c.Storage.RawArchiveURL, "storage.raw_archive_url")
+		if c.Retention.DefaultTTL < 0 {
+			errs = append(errs, errors.New("retention.default_ttl must not be negative"))
+		}
+		for tenant, ttl := range c.Retention.TenantTTLs {
+			if ttl < 0 {
+				errs = append(errs, fmt.Errorf("retention.tenant_ttls for %q must not be negative", tenant))
+			}
+		}
+	case "dev":
+		require(c.Dev.DataDir, "dev.data_dir")
+		oneOf(c.Storage.Layout, "storage.layout", string(archive.LayoutSession), string(archive.LayoutHourly))
+		if c.Dev.Start != "" {
+			if _, err := time.Parse(time.RFC3339, c.Dev.Start); err != nil {
+				errs = append(errs, fmt.Errorf("dev.start must be RFC 3339: %w", err))
+			}
+		}
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+	return errors.Join(errs...)
+}
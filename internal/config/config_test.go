@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "reflex.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `
+project: from-file
+kafka:
+  topic: file-topic
+load:
+  mode: stream
+  timeout: 10m
+dataset:
+  batch_size: 50
+`)
+	t.Setenv("KAFKA_TOPIC", "env-topic")
+	t.Setenv("LOADER_TIMEOUT", "20m")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs, "kafka", "load")
+	require.NoError(t, fs.Parse([]string{"-load.timeout=30m"}))
+
+	cfg, err := Load(path, fs)
+	require.NoError(t, err)
+
+	assert.Equal(t, "us-central1", cfg.Location, "default")
+	assert.Equal(t, "from-file", cfg.Project, "file over default")
+	assert.Equal(t, "stream", cfg.Load.Mode, "file over default")
+	assert.Equal(t, 50, cfg.Dataset.BatchSize, "file over default")
+	assert.Equal(t, "env-topic", cfg.Kafka.Topic, "env over file")
+	assert.Equal(t, 30*time.Minute, cfg.Load.Timeout, "flag over env")
+	assert.Equal(t, "loader-consumer", cfg.Load.ConsumerGroup, "default")
+}
+
+func TestLoad_EnvFallbacksAndTypes(t *testing.T) {
+	t.Setenv("GCS_BUCKET_NAME", "legacy")
+	t.Setenv("GCS_PROCESSED_PROMPT_BUCKET", "processed")
+	t.Setenv("ENCRYPTION_TENANT_KEYS", "acme:acme-key, globex:globex-key")
+	t.Setenv("EVAL_MODELS", "a, b,,c")
+	t.Setenv("DRY_RUN", "true")
+	t.Setenv("RETENTION_TENANT_TTLS", "acme:720h,globex:24h")
+
+	cfg, err := Load("", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "legacy", cfg.Storage.StagingBucket)
+	assert.Equal(t, "processed", cfg.Storage.ProcessedBucket, "the first variable set wins")
+	assert.Equal(t, map[string]string{"acme": "acme-key", "globex": "globex-key"}, cfg.Encryption.TenantKeys)
+	assert.Equal(t, []string{"a", "b", "c"}, cfg.Eval.Models)
+	assert.True(t, cfg.Extract.DryRun)
+	assert.Equal(t, map[string]time.Duration{"acme": 720 * time.Hour, "globex": 24 * time.Hour}, cfg.Retention.TenantTTLs)
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	t.Setenv(EnvFile, writeConfig(t, "project: from-env-file\n"))
+
+	cfg, err := Load("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "from-env-file", cfg.Project)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"), nil)
+	assert.Error(t, err)
+
+	t.Setenv("LOADER_TIMEOUT", "soon")
+	_, err = Load("", nil)
+	assert.ErrorContains(t, err, "LOADER_TIMEOUT")
+
+	t.Setenv("LOADER_TIMEOUT", "")
+	t.Setenv("RETENTION_TENANT_TTLS", "acme:forever")
+	_, err = Load("", nil)
+	assert.ErrorContains(t, err, "RETENTION_TENANT_TTLS")
+}
+
+func TestRegisterFlags_Sections(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs, "load")
+
+	assert.NotNil(t, fs.Lookup("project"))
+	assert.NotNil(t, fs.Lookup("load.mode"))
+	assert.Nil(t, fs.Lookup("kafka.topic"))
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Load("", nil)
+	require.NoError(t, err)
+
+	err = cfg.Validate("load")
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "kafka.topic is required")
+	assert.ErrorContains(t, err, "storage.raw_archive_url is required")
+
+	cfg.Kafka = Kafka{BootstrapServers: "broker:9092", APIKey: "key", APISecret: "secret", Topic: "raw", SecurityProtocol: "SASL_SSL"}
+	cfg.Storage.RawArchiveURL = "file:///tmp/raw"
+	assert.NoError(t, cfg.Validate("load"))
+
+	cfg.Storage.Layout = "daily"
+	assert.ErrorContains(t, cfg.Validate("load"), "storage.layout")
+
+	cfg.Judge.Date = "12/12/2025"
+	assert.ErrorContains(t, cfg.Validate("judge"), "judge.date")
+
+	cfg.Compact.Date = "yesterday"
+	assert.ErrorContains(t, cfg.Validate("compact"), "compact.date")
+
+	cfg.Retention.TenantTTLs = map[string]time.Duration{"acme": -time.Hour}
+	assert.ErrorContains(t, cfg.Validate("retention"), "retention.tenant_ttls")
+
+	cfg.Dev.Start = "tomorrow"
+	assert.ErrorContains(t, cfg.Validate("dev"), "dev.start")
+
+	assert.ErrorContains(t, cfg.Validate("nope"), "unknown command")
+}
+
+func TestPrint_MasksSecrets(t *testing.T) {
+	t.Setenv("KAFKA_API_SECRET", "hunter2")
+	t.Setenv("PINECONE_API_KEY", "pc-key")
+	t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "broker:9092")
+
+	cfg, err := Load("", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, cfg))
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "pc-key")
+
+	var printed Config
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &printed))
+	assert.Equal(t, mask, printed.Kafka.APISecret)
+	assert.Equal(t, mask, printed.Pinecone.APIKey)
+	assert.Empty(t, printed.Kafka.APIKey, "unset secrets stay empty")
+	assert.Equal(t, "broker:9092", printed.Kafka.BootstrapServers)
+	assert.Equal(t, "hunter2", cfg.Kafka.APISecret, "the original is untouched")
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFile is the environment variable naming a config file when none is given explicitly.
+const EnvFile = "REFLEX_CONFIG"
+
+// Load builds the configuration from defaults, the YAML file at path (or $REFLEX_CONFIG; no
+// file if both are empty), the environment and any flags set on flags. flags may be nil; otherwise
+// it must have been parsed after RegisterFlags.
+func Load(path string, flags *flag.FlagSet) (*Config, error) {
+	cfg := &Config{}
+	if err := walk(cfg, func(f field) error {
+		if def := f.tag.Get("default"); def != "" {
+			return f.set(def)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		path = os.Getenv(EnvFile)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := walk(cfg, func(f field) error {
+		for _, name := range strings.Split(f.tag.Get("env"), ",") {
+			if name == "" {
+				continue
+			}
+			if value, ok := os.LookupEnv(name); ok && value != "" {
+				if err := f.set(value); err != nil {
+					return fmt.Errorf("invalid %s: %w", name, err)
+				}
+				return nil
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if flags != nil {
+		set := make(map[string]string)
+		flags.Visit(func(fl *flag.Flag) { set[fl.Name] = fl.Value.String() })
+		if err := walk(cfg, func(f field) error {
+			if value, ok := set[f.name]; ok {
+				if err := f.set(value); err != nil {
+					return fmt.Errorf("invalid -%s: %w", f.name, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// RegisterFlags adds a flag for every setting in the given sections (see Config's yaml names)
+// and the top-level settings. Flags are named <section>.<key>, e.g. -load.mode.
+func RegisterFlags(fs *flag.FlagSet, sections ...string) {
+	wanted := map[string]bool{"": true}
+	for _, s := range sections {
+		wanted[s] = true
+	}
+	_ = walk(&Config{}, func(f field) error {
+		if !wanted[f.section] {
+			return nil
+		}
+		usage := f.tag.Get("usage")
+		if def := f.tag.Get("default"); def != "" {
+			usage += fmt.Sprintf(" (default %s)", def)
+		}
+		if env := f.tag.Get("env"); env != "" {
+			usage += fmt.Sprintf(" [$%s]", strings.ReplaceAll(env, ",", ", $"))
+		}
+		fs.Var(&flagValue{isBool: f.value.Kind() == reflect.Bool}, f.name, usage)
+		return nil
+	})
+}
+
+// flagValue records a flag's raw value; Load parses it into the field.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string     { return v.value }
+func (v *flagValue) Set(s string) error { v.value = s; return nil }
+func (v *flagValue) IsBoolFlag() bool   { return v.isBool }
+
+// field is a single setting found by walk.
+type field struct {
+	// name is the setting's flag name, <section>.<key> or <key> at the top level.
+	name    string
+	section string
+	tag     reflect.StructTag
+	value   reflect.Value
+}
+
+// walk calls fn for every setting in cfg, descending one level into sections.
+func walk(cfg *Config, fn func(field) error) error {
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		sf := root.Type().Field(i)
+		key := yamlName(sf)
+		if sf.Type.Kind() == reflect.Struct {
+			section := root.Field(i)
+			for j := 0; j < section.NumField(); j++ {
+				inner := section.Type().Field(j)
+				f := field{name: key + "." + yamlName(inner), section: key, tag: inner.Tag, value: section.Field(j)}
+				if err := fn(f); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := fn(field{name: key, tag: sf.Tag, value: root.Field(i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func yamlName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses s into the field. Lists are comma separated and maps are key:value pairs
+// separated by commas.
+func (f field) set(s string) error {
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("expected key:value, got %q", pair)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := (field{value: elem}).set(strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("invalid value for %q: %w", strings.TrimSpace(key), err)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mask replaces a set secret when printing.
+const mask = "********"
+
+// Masked returns a copy of c with every secret that is set replaced by a mask.
+func (c *Config) Masked() *Config {
+	masked := *c
+	_ = walk(&masked, func(f field) error {
+		if f.tag.Get("secret") == "true" && f.value.Kind() == reflect.String && f.value.String() != "" {
+			f.value.SetString(mask)
+		}
+		return nil
+	})
+	return &masked
+}
+
+// Print writes the effective configuration to w as YAML, with secrets masked.
+func Print(w io.Writer, c *Config) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Masked()); err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+	return enc.Close()
+}
@@ -0,0 +1,150 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is the encoding of an hourly partition data file.
+type Format string
+
+const (
+	// FormatJSONLGzip is gzip-compressed JSON lines, one InteractionEvent per line.
+	FormatJSONLGzip Format = "jsonl.gz"
+	// FormatParquet is a zstd-compressed Parquet file with one row per InteractionEvent.
+	FormatParquet Format = "parquet"
+)
+
+// Extension returns the file extension (including the leading dot) for the format.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// parquetRow is the on-disk Parquet schema for an InteractionEvent.
+type parquetRow struct {
+	InteractionID  string         `parquet:"interaction_id"`
+	ConversationID string         `parquet:"conversation_id"`
+	Timestamp      time.Time      `parquet:"timestamp,timestamp(millisecond)"`
+	Role           string         `parquet:"role"`
+	Content        string         `parquet:"content"`
+	TenantID       *string        `parquet:"tenant_id,optional"`
+	UserID         *string        `parquet:"user_id,optional"`
+	Redactions     map[string]int `parquet:"redactions,optional"`
+	Encryption     *string        `parquet:"encryption,optional"`
+}
+
+// Encode serialises events in the given format.
+func Encode(format Format, events []schema.InteractionEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeTo(&buf, format, events); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeTo serialises events in the given format to w.
+func EncodeTo(w io.Writer, format Format, events []schema.InteractionEvent) error {
+	switch format {
+	case FormatJSONLGzip:
+		gz := gzip.NewWriter(w)
+		enc := json.NewEncoder(gz)
+		for _, event := range events {
+			if err := enc.Encode(event); err != nil {
+				return fmt.Errorf("marshal error: %w", err)
+			}
+		}
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to close gzip writer: %w", err)
+		}
+		return nil
+	case FormatParquet:
+		rows := make([]parquetRow, len(events))
+		for i, event := range events {
+			rows[i] = parquetRow{
+				InteractionID:  event.InteractionId,
+				ConversationID: event.ConversationId,
+				Timestamp:      event.Timestamp,
+				Role:           string(event.Role),
+				Content:        event.Content,
+				TenantID:       event.TenantId,
+				UserID:         event.UserId,
+				Redactions:     event.Redactions,
+				Encryption:     event.Encryption,
+			}
+		}
+		pw := parquet.NewGenericWriter[parquetRow](w, parquet.Compression(&parquet.Zstd))
+		if _, err := pw.Write(rows); err != nil {
+			return fmt.Errorf("failed to write parquet rows: %w", err)
+		}
+		if err := pw.Close(); err != nil {
+			return fmt.Errorf("failed to close parquet writer: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
+
+// Decode parses a data file written by Encode.
+func Decode(format Format, data []byte) ([]schema.InteractionEvent, error) {
+	switch format {
+	case FormatJSONLGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip reader: %w", err)
+		}
+		defer gz.Close()
+
+		var events []schema.InteractionEvent
+		dec := json.NewDecoder(gz)
+		for {
+			var event schema.InteractionEvent
+			if err := dec.Decode(&event); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, fmt.Errorf("failed to parse json line: %w", err)
+			}
+			events = append(events, event)
+		}
+		return events, nil
+	case FormatParquet:
+		// NewGenericReader panics on a file it cannot open, so open it first.
+		f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open parquet file: %w", err)
+		}
+		r := parquet.NewGenericReader[parquetRow](f)
+		defer r.Close()
+
+		rows := make([]parquetRow, r.NumRows())
+		n, err := r.Read(rows)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read parquet rows: %w", err)
+		}
+		events := make([]schema.InteractionEvent, n)
+		for i, row := range rows[:n] {
+			events[i] = schema.InteractionEvent{
+				InteractionId:  row.InteractionID,
+				ConversationId: row.ConversationID,
+				Timestamp:      row.Timestamp,
+				Role:           schema.Role(row.Role),
+				Content:        row.Content,
+				TenantId:       row.TenantID,
+				UserId:         row.UserID,
+				Redactions:     row.Redactions,
+				Encryption:     row.Encryption,
+			}
+		}
+		return events, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layout selects how the loader arranges raw interaction archives in the bucket.
+type Layout string
+
+const (
+	// LayoutSession writes one chunk per conversation per run under
+	// raw/<conversation_id>/YYYY/MM/DD/HH/chunk-<uuid>.jsonl.
+	LayoutSession Layout = "session"
+	// LayoutHourly writes one compressed file per hour per run under
+	// raw/dt=YYYY-MM-DD/hr=HH/, alongside a manifest listing the conversations it contains.
+	LayoutHourly Layout = "hourly"
+)
+
+const manifestName = "manifest.json"
+
+// DayPrefix returns the key prefix holding every hourly partition for the given date (UTC).
+func DayPrefix(t time.Time) string {
+	return fmt.Sprintf("raw/dt=%s/", t.UTC().Format("2006-01-02"))
+}
+
+// HourPrefix returns the key prefix of the hourly partition containing t (UTC).
+func HourPrefix(t time.Time) string {
+	return fmt.Sprintf("%shr=%02d/", DayPrefix(t), t.UTC().Hour())
+}
+
+// ManifestKey returns the key of the manifest for the hourly partition containing t.
+func ManifestKey(t time.Time) string {
+	return HourPrefix(t) + manifestName
+}
+
+// IsManifestKey reports whether key refers to an hourly partition manifest.
+func IsManifestKey(key string) bool {
+	return strings.HasSuffix(key, "/"+manifestName)
+}
+
+// DataKey returns the key of a data file written by a single loader run for the hour containing t.
+func DataKey(t time.Time, runID string, format Format) string {
+	return fmt.Sprintf("%spart-%s%s", HourPrefix(t), runID, format.Extension())
+}
@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// Manifest indexes the data files of a single hourly partition so readers can find
+// conversations without listing or opening every object.
+type Manifest struct {
+	Date  string         `json:"date"`
+	Hour  int            `json:"hour"`
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one data file written by a loader run.
+type ManifestFile struct {
+	Key           string   `json:"key"`
+	Format        Format   `json:"format"`
+	Conversations []string `json:"conversations"`
+	// Tenants lists the distinct tenants whose events the file holds, so retention sweeps can
+	// skip files without reading them. Files written before tenants were recorded omit it.
+	Tenants    []string  `json:"tenants,omitempty"`
+	EventCount int       `json:"event_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EventTenants returns the sorted, de-duplicated tenant IDs set on events. Events without a
+// tenant are not represented.
+func EventTenants(events []schema.InteractionEvent) []string {
+	seen := make(map[string]struct{})
+	for _, event := range events {
+		if event.TenantId != nil && *event.TenantId != "" {
+			seen[*event.TenantId] = struct{}{}
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// NewManifest creates an empty manifest for the hourly partition containing t.
+func NewManifest(t time.Time) *Manifest {
+	t = t.UTC()
+	return &Manifest{
+		Date: t.Format("2006-01-02"),
+		Hour: t.Hour(),
+	}
+}
+
+// ParseManifest decodes a manifest previously written with Marshal.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Marshal encodes the manifest as JSON.
+func (m *Manifest) Marshal() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// Add records a data file in the manifest, replacing any previous entry with the same key.
+func (m *Manifest) Add(file ManifestFile) {
+	for i, f := range m.Files {
+		if f.Key == file.Key {
+			m.Files[i] = file
+			return
+		}
+	}
+	m.Files = append(m.Files, file)
+}
+
+// Remove drops the entry for the data file with the given key, reporting whether it was present.
+func (m *Manifest) Remove(key string) bool {
+	for i, f := range m.Files {
+		if f.Key == key {
+			m.Files = append(m.Files[:i], m.Files[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Conversations returns the sorted, de-duplicated conversation IDs across all files.
+func (m *Manifest) Conversations() []string {
+	seen := make(map[string]struct{})
+	for _, f := range m.Files {
+		for _, c := range f.Conversations {
+			seen[c] = struct{}{}
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
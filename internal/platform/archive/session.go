@@ -0,0 +1,93 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const compactedPrefix = "00-compacted-"
+
+// SessionPrefix returns the key prefix holding every chunk of a conversation archived with LayoutSession.
+func SessionPrefix(conversationID string) string {
+	return fmt.Sprintf("raw/%s/", conversationID)
+}
+
+// SessionDayPrefix returns the key prefix holding a conversation's chunks for the day containing t.
+func SessionDayPrefix(conversationID string, t time.Time) string {
+	return fmt.Sprintf("%s%d/%02d/%02d/", SessionPrefix(conversationID), t.Year(), t.Month(), t.Day())
+}
+
+// SessionManifestKey returns the key of a conversation's compaction manifest.
+func SessionManifestKey(conversationID string) string {
+	return SessionPrefix(conversationID) + manifestName
+}
+
+// SessionCompactedKey returns the key of a compacted chunk holding a conversation's events for the
+// day containing t. The "00-" prefix sorts before the day's hour directories, so chunks that arrive
+// after compaction still follow it when keys are read in order.
+func SessionCompactedKey(conversationID string, t time.Time, id string) string {
+	return fmt.Sprintf("%s%s%s.jsonl", SessionDayPrefix(conversationID, t), compactedPrefix, id)
+}
+
+// IsCompactedKey reports whether key refers to a compacted chunk or partition file.
+func IsCompactedKey(key string) bool {
+	base := path.Base(key)
+	return strings.HasPrefix(base, compactedPrefix) || strings.HasPrefix(base, "part-compacted-")
+}
+
+// CompactedDataKey returns the key of a compacted file replacing every data file in the hourly
+// partition containing t.
+func CompactedDataKey(t time.Time, id string, format Format) string {
+	return DataKey(t, "compacted-"+id, format)
+}
+
+// SessionManifest records which of a conversation's chunks are live. Compacted chunks are only
+// read once listed in Files, and chunks they replace are skipped once listed in Superseded, so
+// rewriting the manifest swaps a compaction in atomically.
+type SessionManifest struct {
+	ConversationID string   `json:"conversation_id"`
+	Files          []string `json:"files"`
+	Superseded     []string `json:"superseded"`
+}
+
+// ParseSessionManifest decodes a manifest previously written with Marshal.
+func ParseSessionManifest(data []byte) (*SessionManifest, error) {
+	var m SessionManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse session manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Marshal encodes the manifest as JSON.
+func (m *SessionManifest) Marshal() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// Live filters listed keys down to the chunks a reader should consume: the manifest itself,
+// superseded chunks and compacted chunks not yet swapped in are dropped.
+func (m *SessionManifest) Live(keys []string) []string {
+	files := make(map[string]bool, len(m.Files))
+	for _, k := range m.Files {
+		files[k] = true
+	}
+	superseded := make(map[string]bool, len(m.Superseded))
+	for _, k := range m.Superseded {
+		superseded[k] = true
+	}
+
+	var live []string
+	for _, key := range keys {
+		switch {
+		case IsManifestKey(key), superseded[key]:
+			continue
+		case IsCompactedKey(key) && !files[key]:
+			continue
+		}
+		live = append(live, key)
+	}
+	return live
+}
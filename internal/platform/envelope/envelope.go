@@ -0,0 +1,258 @@
+// Package envelope encrypts archived interaction content with envelope encryption: each tenant's
+// events are encrypted with a data encryption key (DEK) that is itself wrapped by a key
+// encryption key held by a KeyProvider, such as Cloud KMS.
+//
+// Only the content field is encrypted, and the event's encryption field records that it was.
+// Identifiers, timestamps and tenant stay readable, so manifests, compaction, retention and
+// erasure keep working on an encrypted archive, while access to the bucket alone no longer
+// exposes what was said.
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// Scheme is the encryption field of events sealed by a Sealer.
+const Scheme = "envelope-v1"
+
+// Prefix starts sealed content, which has the form
+//
+//	enc:v1:<key id>.<wrapped DEK>.<nonce || ciphertext>
+//
+// with each part base64url encoded. The ciphertext is bound to the event's conversation and
+// interaction IDs, so it cannot be moved to another event. Whether an event is sealed is
+// decided by its encryption field, never by the prefix, which user content can carry too.
+const Prefix = "enc:v1:"
+
+var encoding = base64.RawURLEncoding
+
+// ErrMalformed is returned when sealed content cannot be parsed.
+var ErrMalformed = errors.New("malformed encrypted content")
+
+// IsSealed reports whether event's content was encrypted by a Sealer.
+func IsSealed(event schema.InteractionEvent) bool {
+	return event.Encryption != nil
+}
+
+// Config selects the key encryption key used for each tenant.
+type Config struct {
+	// DefaultKeyID wraps data keys for tenants without an entry in TenantKeyIDs. Required.
+	DefaultKeyID string
+	// TenantKeyIDs maps tenant IDs to their own key encryption key.
+	TenantKeyIDs map[string]string
+	// DataKeyTTL is how long a tenant's data key is reused before a new one is generated.
+	// Defaults to 1 hour.
+	DataKeyTTL time.Duration
+}
+
+// KeyIDFor returns the key encryption key ID for tenant.
+func (c Config) KeyIDFor(tenant string) string {
+	if id, ok := c.TenantKeyIDs[tenant]; ok && tenant != "" {
+		return id
+	}
+	return c.DefaultKeyID
+}
+
+type dataKey struct {
+	keyID   string
+	plain   []byte
+	wrapped []byte
+	created time.Time
+}
+
+// Sealer encrypts event content. Each tenant gets its own data key, generated on first use,
+// wrapped by the tenant's key encryption key and rotated after Config.DataKeyTTL, so the
+// provider is called once per tenant per TTL rather than once per event.
+type Sealer struct {
+	provider KeyProvider
+	config   Config
+	now      func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*dataKey
+}
+
+// NewSealer creates a Sealer.
+func NewSealer(provider KeyProvider, cfg Config) (*Sealer, error) {
+	if cfg.DefaultKeyID == "" {
+		return nil, errors.New("envelope encryption requires a default key ID")
+	}
+	if cfg.DataKeyTTL <= 0 {
+		cfg.DataKeyTTL = time.Hour
+	}
+	return &Sealer{
+		provider: provider,
+		config:   cfg,
+		now:      time.Now,
+		keys:     make(map[string]*dataKey),
+	}, nil
+}
+
+// SealEvents returns copies of events with their content encrypted and their encryption field
+// set to Scheme. Events that are already sealed are left as they are.
+func (s *Sealer) SealEvents(ctx context.Context, events []schema.InteractionEvent) ([]schema.InteractionEvent, error) {
+	sealed := make([]schema.InteractionEvent, len(events))
+	for i, event := range events {
+		if !IsSealed(event) {
+			content, err := s.Seal(ctx, tenantOf(event), additionalData(event), event.Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt interaction %s: %w", event.InteractionId, err)
+			}
+			scheme := Scheme
+			event.Content = content
+			event.Encryption = &scheme
+		}
+		sealed[i] = event
+	}
+	return sealed, nil
+}
+
+// Seal encrypts plaintext with tenant's data key, binding it to additionalData.
+func (s *Sealer) Seal(ctx context.Context, tenant string, additionalData []byte, plaintext string) (string, error) {
+	key, err := s.dataKey(ctx, tenant)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key.plain)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(plaintext), additionalData)
+	if err != nil {
+		return "", err
+	}
+	return Prefix + encoding.EncodeToString([]byte(key.keyID)) + "." +
+		encoding.EncodeToString(key.wrapped) + "." +
+		encoding.EncodeToString(ciphertext), nil
+}
+
+func (s *Sealer) dataKey(ctx context.Context, tenant string) (*dataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyID := s.config.KeyIDFor(tenant)
+	if key, ok := s.keys[tenant]; ok && key.keyID == keyID && s.now().Sub(key.created) < s.config.DataKeyTTL {
+		return key, nil
+	}
+
+	plain := make([]byte, keySize)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := s.provider.WrapKey(ctx, keyID, plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key for tenant %q: %w", tenant, err)
+	}
+	key := &dataKey{keyID: keyID, plain: plain, wrapped: wrapped, created: s.now()}
+	s.keys[tenant] = key
+	return key, nil
+}
+
+// maxCachedKeys bounds the data keys an Opener keeps unwrapped.
+const maxCachedKeys = 1024
+
+// Opener decrypts content sealed by a Sealer. Unwrapped data keys are cached, so reading an
+// archive calls the provider once per data key rather than once per event.
+type Opener struct {
+	provider KeyProvider
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// NewOpener creates an Opener.
+func NewOpener(provider KeyProvider) *Opener {
+	return &Opener{provider: provider, keys: make(map[string][]byte)}
+}
+
+// OpenEvents decrypts the content of sealed events in place and clears their encryption field.
+// Plaintext events are left as they are, so archives written before encryption was enabled can
+// still be read. On error, the events before the one that failed have been decrypted.
+func (o *Opener) OpenEvents(ctx context.Context, events []schema.InteractionEvent) error {
+	for i := range events {
+		if !IsSealed(events[i]) {
+			continue
+		}
+		if scheme := *events[i].Encryption; scheme != Scheme {
+			return fmt.Errorf("failed to decrypt interaction %s: %w: unsupported scheme %q", events[i].InteractionId, ErrMalformed, scheme)
+		}
+		content, err := o.Open(ctx, additionalData(events[i]), events[i].Content)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt interaction %s: %w", events[i].InteractionId, err)
+		}
+		events[i].Content = content
+		events[i].Encryption = nil
+	}
+	return nil
+}
+
+// Open decrypts sealed content bound to additionalData.
+func (o *Opener) Open(ctx context.Context, additionalData []byte, sealed string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(sealed, Prefix), ".")
+	if !strings.HasPrefix(sealed, Prefix) || len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		b, err := encoding.DecodeString(part)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		decoded[i] = b
+	}
+	keyID, wrapped, ciphertext := string(decoded[0]), decoded[1], decoded[2]
+
+	dek, err := o.dataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, ciphertext, additionalData)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt content: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (o *Opener) dataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + "\x00" + string(wrapped)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if dek, ok := o.keys[cacheKey]; ok {
+		return dek, nil
+	}
+	dek, err := o.provider.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.keys) >= maxCachedKeys {
+		o.keys = make(map[string][]byte)
+	}
+	o.keys[cacheKey] = dek
+	return dek, nil
+}
+
+func tenantOf(event schema.InteractionEvent) string {
+	if event.TenantId == nil {
+		return ""
+	}
+	return *event.TenantId
+}
+
+// additionalData binds content to the event it belongs to.
+func additionalData(event schema.InteractionEvent) []byte {
+	return []byte(event.ConversationId + "\x00" + event.InteractionId)
+}
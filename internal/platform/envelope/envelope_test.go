@@ -0,0 +1,171 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// countingProvider counts the calls made to a FileKeyProvider.
+type countingProvider struct {
+	*FileKeyProvider
+	wraps, unwraps int
+}
+
+func (p *countingProvider) WrapKey(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+	p.wraps++
+	return p.FileKeyProvider.WrapKey(ctx, keyID, dek)
+}
+
+func (p *countingProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	p.unwraps++
+	return p.FileKeyProvider.UnwrapKey(ctx, keyID, wrapped)
+}
+
+func newProvider(t *testing.T) *countingProvider {
+	t.Helper()
+	files, err := NewFileKeyProvider(map[string][]byte{
+		"dev":         bytes.Repeat([]byte{1}, keySize),
+		"tenant-acme": bytes.Repeat([]byte{2}, keySize),
+	})
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider: %v", err)
+	}
+	return &countingProvider{FileKeyProvider: files}
+}
+
+func event(id, tenant, content string) schema.InteractionEvent {
+	e := schema.InteractionEvent{InteractionId: id, ConversationId: "c-1", Content: content}
+	if tenant != "" {
+		e.TenantId = &tenant
+	}
+	return e
+}
+
+func TestSealAndOpenEvents(t *testing.T) {
+	ctx := context.Background()
+	provider := newProvider(t)
+	sealer, err := NewSealer(provider, Config{DefaultKeyID: "dev", TenantKeyIDs: map[string]string{"acme": "tenant-acme"}})
+	if err != nil {
+		t.Fatalf("NewSealer: %v", err)
+	}
+
+	events := []schema.InteractionEvent{
+		event("1", "acme", "my card is 4111 1111 1111 1111"),
+		event("2", "acme", "and my address"),
+		event("3", "", "enc:v1: user content that only looks sealed"),
+	}
+	sealed, err := sealer.SealEvents(ctx, events)
+	if err != nil {
+		t.Fatalf("SealEvents: %v", err)
+	}
+	for i, e := range sealed {
+		if !IsSealed(e) || *e.Encryption != Scheme || !strings.HasPrefix(e.Content, Prefix) || e.Content == events[i].Content {
+			t.Errorf("event %d: expected sealed content, got %+v", i, e)
+		}
+		if IsSealed(events[i]) {
+			t.Errorf("event %d: expected the original to be left alone", i)
+		}
+	}
+	if provider.wraps != 2 {
+		t.Errorf("expected one data key per tenant, got %d wraps", provider.wraps)
+	}
+	if resealed, _ := sealer.SealEvents(ctx, sealed); resealed[0].Content != sealed[0].Content {
+		t.Errorf("expected sealed events not to be sealed again")
+	}
+
+	opener := NewOpener(provider)
+	opened := append([]schema.InteractionEvent(nil), sealed...)
+	opened = append(opened, event("4", "", "written before encryption"))
+	if err := opener.OpenEvents(ctx, opened); err != nil {
+		t.Fatalf("OpenEvents: %v", err)
+	}
+	for i, e := range opened[:3] {
+		if e.Content != events[i].Content || e.Encryption != nil {
+			t.Errorf("event %d: expected the plaintext back with encryption cleared, got %+v", i, e)
+		}
+	}
+	if opened[3].Content != "written before encryption" {
+		t.Errorf("expected a plaintext event to be left alone, got %q", opened[3].Content)
+	}
+	if provider.unwraps != 2 {
+		t.Errorf("expected each data key to be unwrapped once, got %d unwraps", provider.unwraps)
+	}
+}
+
+func TestOpenEvents_Failures(t *testing.T) {
+	ctx := context.Background()
+	provider := newProvider(t)
+	sealer, err := NewSealer(provider, Config{DefaultKeyID: "dev"})
+	if err != nil {
+		t.Fatalf("NewSealer: %v", err)
+	}
+	sealed, err := sealer.SealEvents(ctx, []schema.InteractionEvent{event("1", "", "secret")})
+	if err != nil {
+		t.Fatalf("SealEvents: %v", err)
+	}
+
+	unknown := "envelope-v0"
+	scheme := Scheme
+	tests := []struct {
+		name  string
+		event schema.InteractionEvent
+		is    error
+	}{
+		{name: "unknown scheme", event: schema.InteractionEvent{InteractionId: "1", ConversationId: "c-1", Content: sealed[0].Content, Encryption: &unknown}, is: ErrMalformed},
+		{name: "not sealed content", event: schema.InteractionEvent{InteractionId: "1", ConversationId: "c-1", Content: "secret", Encryption: &scheme}, is: ErrMalformed},
+		{name: "bad encoding", event: schema.InteractionEvent{InteractionId: "1", ConversationId: "c-1", Content: Prefix + "a.b.!!", Encryption: &scheme}, is: ErrMalformed},
+		// The ciphertext is bound to its event, so it cannot be moved to another.
+		{name: "moved", event: schema.InteractionEvent{InteractionId: "2", ConversationId: "c-1", Content: sealed[0].Content, Encryption: &scheme}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []schema.InteractionEvent{tt.event}
+			err := NewOpener(provider).OpenEvents(ctx, events)
+			if err == nil || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Errorf("expected an error matching %v, got %v", tt.is, err)
+			}
+			if events[0].Encryption == nil {
+				t.Errorf("expected a failed event to stay marked as encrypted")
+			}
+		})
+	}
+}
+
+func TestSealer_RotatesDataKeys(t *testing.T) {
+	ctx := context.Background()
+	provider := newProvider(t)
+	sealer, err := NewSealer(provider, Config{DefaultKeyID: "dev", DataKeyTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewSealer: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sealer.now = func() time.Time { return now }
+
+	for _, advance := range []time.Duration{0, 30 * time.Second, time.Minute} {
+		now = now.Add(advance)
+		if _, err := sealer.Seal(ctx, "", nil, "content"); err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+	}
+	if provider.wraps != 2 {
+		t.Errorf("expected the data key to be reused within its TTL and rotated after, got %d wraps", provider.wraps)
+	}
+}
+
+func TestKeyProviderErrors(t *testing.T) {
+	if _, err := NewSealer(newProvider(t), Config{}); err == nil {
+		t.Errorf("expected a sealer without a default key ID to be rejected")
+	}
+	if _, err := NewFileKeyProvider(map[string][]byte{"short": []byte("too short")}); err == nil {
+		t.Errorf("expected a key of the wrong size to be rejected")
+	}
+	if _, err := newProvider(t).WrapKey(context.Background(), "missing", make([]byte, keySize)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package envelope
+
+import (
+	"context"
+	"fmt"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// KMSKeyProvider wraps data keys with Cloud KMS symmetric keys. Key IDs are CryptoKey resource
+// names: projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>.
+type KMSKeyProvider struct {
+	client *kms.KeyManagementClient
+}
+
+// NewKMSKeyProvider creates a KMSKeyProvider using application default credentials.
+func NewKMSKeyProvider(ctx context.Context) (*KMSKeyProvider, error) {
+	client, err := kms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS client: %w", err)
+	}
+	return &KMSKeyProvider{client: client}, nil
+}
+
+// WrapKey encrypts dek with the CryptoKey keyID.
+func (p *KMSKeyProvider) WrapKey(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+	resp, err := p.client.Encrypt(ctx, &kmspb.EncryptRequest{Name: keyID, Plaintext: dek})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key with %s: %w", keyID, err)
+	}
+	return resp.Ciphertext, nil
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey.
+func (p *KMSKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	resp, err := p.client.Decrypt(ctx, &kmspb.DecryptRequest{Name: keyID, Ciphertext: wrapped})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %s: %w", keyID, err)
+	}
+	return resp.Plaintext, nil
+}
+
+// Close releases the KMS client.
+func (p *KMSKeyProvider) Close() error {
+	return p.client.Close()
+}
@@ -0,0 +1,155 @@
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// KeyProvider wraps and unwraps data encryption keys with key encryption keys it holds, in the
+// style of a KMS: the key encryption keys never leave the provider.
+type KeyProvider interface {
+	WrapKey(ctx context.Context, keyID string, dek []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// ErrUnknownKey is returned when a provider has no key encryption key with the requested ID.
+var ErrUnknownKey = errors.New("unknown key")
+
+// OpenKeyProvider opens the provider described by rawURL:
+//
+//	file:///path/to/keys.json  a FileKeyProvider, for development
+//	gcpkms://                  Cloud KMS; key IDs are full CryptoKey resource names
+//
+// The returned close function releases the provider's resources.
+func OpenKeyProvider(ctx context.Context, rawURL string) (KeyProvider, func() error, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid key provider URL %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "file":
+		p, err := LoadFileKeyProvider(u.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p, func() error { return nil }, nil
+	case "gcpkms":
+		p, err := NewKMSKeyProvider(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p, p.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported key provider scheme %q in %q", u.Scheme, rawURL)
+	}
+}
+
+// FileKeyProvider keeps key encryption keys in a local JSON file mapping key IDs to base64
+// encoded 32 byte AES keys:
+//
+//	{"dev": "q8n0m1Wl...=", "tenant-acme": "Zk3xP0b..="}
+//
+// Keys are wrapped with AES-256-GCM. It is meant for development and tests; the file must be
+// kept away from the archive it protects.
+type FileKeyProvider struct {
+	keys map[string][]byte
+}
+
+// LoadFileKeyProvider reads a FileKeyProvider's keys from path.
+func LoadFileKeyProvider(path string) (*FileKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+	keys := make(map[string][]byte, len(encoded))
+	for id, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("key %q in %s is not valid base64: %w", id, path, err)
+		}
+		keys[id] = key
+	}
+	return NewFileKeyProvider(keys)
+}
+
+// NewFileKeyProvider creates a FileKeyProvider from raw 32 byte keys.
+func NewFileKeyProvider(keys map[string][]byte) (*FileKeyProvider, error) {
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+	return &FileKeyProvider{keys: keys}, nil
+}
+
+// WrapKey encrypts dek with the key encryption key keyID.
+func (p *FileKeyProvider) WrapKey(_ context.Context, keyID string, dek []byte) ([]byte, error) {
+	aead, err := p.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, dek, []byte(keyID))
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey.
+func (p *FileKeyProvider) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, err := p.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := open(aead, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %q: %w", keyID, err)
+	}
+	return dek, nil
+}
+
+func (p *FileKeyProvider) aead(keyID string) (cipher.AEAD, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	return newAEAD(key)
+}
+
+// keySize is the size of every AES key used here, KEKs in a FileKeyProvider and DEKs alike.
+const keySize = 32
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, returning nonce || ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal.
+func open(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
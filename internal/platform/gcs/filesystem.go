@@ -0,0 +1,320 @@
+package gcs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileClient is a BlobReader and BlobWriter backed by a directory on the local filesystem.
+// Object keys map to paths relative to the root directory; object attributes are kept in a hidden
+// sidecar file next to each object.
+type FileClient struct {
+	root string
+	mu   sync.Mutex
+}
+
+// NewFileClient creates a FileClient rooted at dir, creating the directory if needed.
+func NewFileClient(dir string) (*FileClient, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("filesystem store requires a root directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create root directory %s: %w", dir, err)
+	}
+	return &FileClient{root: dir}, nil
+}
+
+func (f *FileClient) path(key string) (string, error) {
+	p := filepath.Join(f.root, filepath.FromSlash(key))
+	rel, err := filepath.Rel(f.root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return p, nil
+}
+
+// Write writes data to the file for key.
+func (f *FileClient) Write(ctx context.Context, key string, data []byte) error {
+	w, err := f.Create(ctx, key, WriteOptions{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.CloseWithError(err)
+		return fmt.Errorf("failed to write data to %s: %w", key, err)
+	}
+	return w.Close()
+}
+
+// Create returns a writer for the file for key. Data is written to a temporary file that is
+// renamed into place on Close, so readers never observe a partial object.
+func (f *FileClient) Create(ctx context.Context, key string, opts WriteOptions) (Writer, error) {
+	p, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file for %s: %w", key, err)
+	}
+	w := &fileWriter{File: tmp, commit: func() error {
+		return f.commit(key, p, tmp.Name(), opts)
+	}}
+	if opts.Gzip {
+		return newGzipWriteCloser(w), nil
+	}
+	return w, nil
+}
+
+// fileWriter writes to a temporary file and commits it on Close.
+type fileWriter struct {
+	*os.File
+	commit func() error
+}
+
+func (w *fileWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		_ = os.Remove(w.File.Name())
+		return fmt.Errorf("failed to close %s: %w", w.File.Name(), err)
+	}
+	if err := w.commit(); err != nil {
+		_ = os.Remove(w.File.Name())
+		return err
+	}
+	return nil
+}
+
+// CloseWithError removes the temporary file without committing it.
+func (w *fileWriter) CloseWithError(error) error {
+	_ = w.File.Close()
+	_ = os.Remove(w.File.Name())
+	return nil
+}
+
+// fileAttrs is the sidecar record of the attributes the filesystem cannot hold itself.
+type fileAttrs struct {
+	Generation      string            `json:"generation"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// attrsPath returns the hidden sidecar file holding the attributes of the file at p.
+func attrsPath(p string) string {
+	return filepath.Join(filepath.Dir(p), "."+filepath.Base(p)+".attrs.json")
+}
+
+// commit moves the temporary file tmp into place at p and records its attributes. Conditional
+// writes are serialised within this process only.
+func (f *FileClient) commit(key, p, tmp string, opts WriteOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if opts.IfGenerationMatch != "" {
+		current := NoGeneration
+		attrs, err := f.stat(key, p)
+		switch {
+		case err == nil:
+			current = attrs.Generation
+		case !errors.Is(err, ErrNotExist):
+			return err
+		}
+		if current != opts.IfGenerationMatch {
+			return fmt.Errorf("%w: %s is at generation %s, not %s", ErrPreconditionFailed, key, current, opts.IfGenerationMatch)
+		}
+	}
+
+	sidecar := fileAttrs{
+		Generation: strconv.FormatInt(time.Now().UnixNano(), 10),
+		Metadata:   copyMetadata(opts.Metadata),
+	}
+	if opts.Gzip {
+		sidecar.ContentEncoding = encodingGzip
+	}
+	data, err := json.Marshal(sidecar)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes for %s: %w", key, err)
+	}
+	if err := os.WriteFile(attrsPath(p), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write attributes for %s: %w", key, err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("failed to commit file for %s: %w", key, err)
+	}
+	return nil
+}
+
+// Read reads the file for key.
+func (f *FileClient) Read(ctx context.Context, key string) ([]byte, error) {
+	rc, err := f.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data from %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Open returns a reader for the file for key.
+func (f *FileClient) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	if sidecar, err := readFileAttrs(p); err == nil && sidecar.ContentEncoding == encodingGzip {
+		return newGzipReadCloser(file)
+	}
+	return file, nil
+}
+
+// Stat returns the attributes of the file for key.
+func (f *FileClient) Stat(ctx context.Context, key string) (*ObjectAttrs, error) {
+	p, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return f.stat(key, p)
+}
+
+func (f *FileClient) stat(key, p string) (*ObjectAttrs, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return nil, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+	attrs := &ObjectAttrs{
+		Key:     key,
+		Size:    info.Size(),
+		Updated: info.ModTime(),
+		// Files written by other tools have no sidecar; fall back to their modification time.
+		Generation: strconv.FormatInt(info.ModTime().UnixNano(), 10),
+	}
+	if sidecar, err := readFileAttrs(p); err == nil {
+		attrs.Generation = sidecar.Generation
+		attrs.ContentEncoding = sidecar.ContentEncoding
+		attrs.Metadata = sidecar.Metadata
+	}
+	return attrs, nil
+}
+
+func readFileAttrs(p string) (*fileAttrs, error) {
+	data, err := os.ReadFile(attrsPath(p))
+	if err != nil {
+		return nil, err
+	}
+	var attrs fileAttrs
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+	return &attrs, nil
+}
+
+// Delete removes the file for key.
+func (f *FileClient) Delete(ctx context.Context, key string) error {
+	p, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	_ = os.Remove(attrsPath(p))
+	return nil
+}
+
+// ListActiveSessions returns a list of session IDs that had activity on the specified date.
+// Assumes keys are format: raw/<session_id>/YYYY/MM/DD/HH/chunk.jsonl
+func (f *FileClient) ListActiveSessions(ctx context.Context, date time.Time) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(f.root, "raw"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	datePath := fmt.Sprintf("%d/%02d/%02d", date.Year(), date.Month(), date.Day())
+	var sessions []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(f.root, "raw", entry.Name(), filepath.FromSlash(datePath)))
+		if err == nil && info.IsDir() {
+			sessions = append(sessions, entry.Name())
+		}
+	}
+	return sessions, nil
+}
+
+// ListSessionChunks returns all keys for a given session ID.
+func (f *FileClient) ListSessionChunks(ctx context.Context, sessionID string) ([]string, error) {
+	return f.ListFiles(ctx, fmt.Sprintf("raw/%s/", sessionID))
+}
+
+// ListFiles returns a list of all object keys matching the prefix.
+func (f *FileClient) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	// Only walk the deepest directory the prefix fully names.
+	dir := f.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = filepath.Join(f.root, filepath.FromSlash(prefix[:i]))
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(f.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			files = append(files, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files under %s: %w", prefix, err)
+	}
+	return files, nil
+}
+
+// Close is a no-op; it exists so FileClient can be used wherever a Client is.
+func (f *FileClient) Close() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package gcs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+)
+
+func TestFileClient_Keys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files, err := gcs.NewFileClient(dir)
+	if err != nil {
+		t.Fatalf("NewFileClient: %v", err)
+	}
+
+	for _, key := range []string{"../escape", "raw/../../escape", ""} {
+		if err := files.Write(ctx, key, []byte("x")); err == nil {
+			t.Errorf("expected key %q outside the root to be rejected", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected nothing written outside the root, got %v", err)
+	}
+
+	for _, key := range []string{
+		"raw/s-1/2024/05/01/12/chunk-1.jsonl",
+		"raw/s-1/2024/05/01/13/chunk-2.jsonl",
+		"raw/s-2/2024/05/02/09/chunk-1.jsonl",
+		"rawish/chunk.jsonl",
+	} {
+		if err := files.Write(ctx, key, []byte("{}\n")); err != nil {
+			t.Fatalf("Write %s: %v", key, err)
+		}
+	}
+
+	// Attribute sidecars are not objects.
+	keys, err := files.ListFiles(ctx, "raw/")
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	sort.Strings(keys)
+	want := []string{"raw/s-1/2024/05/01/12/chunk-1.jsonl", "raw/s-1/2024/05/01/13/chunk-2.jsonl", "raw/s-2/2024/05/02/09/chunk-1.jsonl"}
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] || keys[2] != want[2] {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+	if chunks, _ := files.ListSessionChunks(ctx, "s-1"); len(chunks) != 2 {
+		t.Errorf("expected two chunks for s-1, got %v", chunks)
+	}
+	if sessions, _ := files.ListActiveSessions(ctx, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)); len(sessions) != 1 || sessions[0] != "s-2" {
+		t.Errorf("expected s-2 active on 2024-05-02, got %v", sessions)
+	}
+	if keys, _ := files.ListFiles(ctx, "missing/"); len(keys) != 0 {
+		t.Errorf("expected no keys under a missing prefix, got %v", keys)
+	}
+
+	// Deleting an object removes its sidecar, so a new object at the key starts afresh.
+	const key = "raw/s-2/2024/05/02/09/chunk-1.jsonl"
+	if err := files.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := files.Stat(ctx, key); !errors.Is(err, gcs.ErrNotExist) {
+		t.Errorf("expected a deleted object to be gone, got %v", err)
+	}
+	if err := files.Delete(ctx, key); !errors.Is(err, gcs.ErrNotExist) {
+		t.Errorf("expected deleting twice to report ErrNotExist, got %v", err)
+	}
+	leftover, _ := filepath.Glob(filepath.Join(dir, "raw", "s-2", "2024", "05", "02", "09", ".*"))
+	if len(leftover) != 0 {
+		t.Errorf("expected the sidecar to be removed, got %v", leftover)
+	}
+}
+
+func TestOpenURL_File(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := gcs.OpenURL(ctx, "file://"+filepath.ToSlash(dir))
+	if err != nil {
+		t.Fatalf("OpenURL: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if err := store.Write(ctx, "a/b.txt", []byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "a", "b.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("expected the object under the URL's directory, got %q, %v", data, err)
+	}
+}
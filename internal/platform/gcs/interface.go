@@ -2,9 +2,13 @@ package gcs
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotExist is returned (wrapped) by Read when the requested object does not exist.
+var ErrNotExist = errors.New("object does not exist")
+
 // BlobWriter defines the interface for writing blobs to GCS.
 type BlobWriter interface {
 	// Write writes data to a GCS object at the specified key.
@@ -26,3 +30,9 @@ type BlobReader interface {
 	// Read reads the content of a GCS object at the specified key.
 	Read(ctx context.Context, key string) ([]byte, error)
 }
+
+// BlobDeleter defines the interface for removing blobs from GCS.
+type BlobDeleter interface {
+	// Delete removes the GCS object at the specified key.
+	Delete(ctx context.Context, key string) error
+}
@@ -1,42 +1,126 @@
 package gcs
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// memoryObject is a stored object and its attributes.
+type memoryObject struct {
+	data  []byte
+	attrs ObjectAttrs
+}
+
 // MemoryClient is an in-memory implementation of BlobReader and BlobWriter.
 type MemoryClient struct {
-	mu   sync.RWMutex
-	data map[string][]byte
+	mu         sync.RWMutex
+	data       map[string]memoryObject
+	generation int64
 }
 
 func NewMemoryClient() *MemoryClient {
 	return &MemoryClient{
-		data: make(map[string][]byte),
+		data: make(map[string]memoryObject),
 	}
 }
 
 // Write writes data to the in-memory map.
 func (m *MemoryClient) Write(ctx context.Context, key string, data []byte) error {
+	return m.commit(key, data, WriteOptions{})
+}
+
+// Create returns a writer that stores the object in the in-memory map on Close.
+func (m *MemoryClient) Create(ctx context.Context, key string, opts WriteOptions) (Writer, error) {
+	plain := &bufferedWriter{commit: func(data []byte) error {
+		return m.commit(key, data, opts)
+	}}
+	if opts.Gzip {
+		return newGzipWriteCloser(plain), nil
+	}
+	return plain, nil
+}
+
+func (m *MemoryClient) commit(key string, data []byte, opts WriteOptions) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.data[key] = data
+
+	if opts.IfGenerationMatch != "" {
+		current := NoGeneration
+		if obj, ok := m.data[key]; ok {
+			current = obj.attrs.Generation
+		}
+		if current != opts.IfGenerationMatch {
+			return fmt.Errorf("%w: %s is at generation %s, not %s", ErrPreconditionFailed, key, current, opts.IfGenerationMatch)
+		}
+	}
+
+	m.generation++
+	attrs := ObjectAttrs{
+		Key:        key,
+		Size:       int64(len(data)),
+		Generation: strconv.FormatInt(m.generation, 10),
+		Metadata:   copyMetadata(opts.Metadata),
+		Updated:    time.Now(),
+	}
+	if opts.Gzip {
+		attrs.ContentEncoding = encodingGzip
+	}
+	m.data[key] = memoryObject{data: data, attrs: attrs}
 	return nil
 }
 
 // Read reads data from the in-memory map.
 func (m *MemoryClient) Read(ctx context.Context, key string) ([]byte, error) {
+	rc, err := m.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return readAll(rc)
+}
+
+// Open returns a reader over the stored object.
+func (m *MemoryClient) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	obj, ok := m.data[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	rc := io.NopCloser(bytes.NewReader(obj.data))
+	if obj.attrs.ContentEncoding == encodingGzip {
+		return newGzipReadCloser(rc)
+	}
+	return rc, nil
+}
+
+// Stat returns the attributes of the stored object.
+func (m *MemoryClient) Stat(ctx context.Context, key string) (*ObjectAttrs, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	data, ok := m.data[key]
+	obj, ok := m.data[key]
 	if !ok {
-		return nil, fmt.Errorf("key not found: %s", key)
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
 	}
-	return data, nil
+	attrs := obj.attrs
+	attrs.Metadata = copyMetadata(obj.attrs.Metadata)
+	return &attrs, nil
+}
+
+// Delete removes data from the in-memory map.
+func (m *MemoryClient) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[key]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	delete(m.data, key)
+	return nil
 }
 
 // ListActiveSessions returns a list of session IDs that have data for the given date.
@@ -0,0 +1,352 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// S3Config configures an S3Client.
+type S3Config struct {
+	// Endpoint is the host[:port] of the S3-compatible service. Defaults to s3.amazonaws.com.
+	Endpoint string
+	// Region is the bucket region. Optional for most S3-compatible services.
+	Region string
+	// Insecure disables TLS, e.g. for a local MinIO.
+	Insecure bool
+}
+
+// S3Client is a BlobReader and BlobWriter backed by an S3-compatible bucket (AWS S3, MinIO, ...).
+// Credentials are read from the AWS_* or MINIO_* environment variables, falling back to the
+// instance's IAM role.
+type S3Client struct {
+	client     *minio.Client
+	bucketName string
+}
+
+func NewS3Client(ctx context.Context, bucketName string, cfg S3Config) (*S3Client, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{},
+		}),
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return &S3Client{
+		client:     client,
+		bucketName: bucketName,
+	}, nil
+}
+
+func isS3NotExist(err error) bool {
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+// s3EncodingKey is the user metadata key recording WriteOptions.Gzip. It is kept out of the HTTP
+// Content-Encoding header so that no HTTP client decompresses the object on our behalf.
+const s3EncodingKey = "reflex-content-encoding"
+
+// s3Metadata returns user metadata as it was written. S3 stores keys in lower case and minio-go
+// returns them as canonical HTTP header names ("Tenant_id"), so keys are lower-cased, and any
+// x-amz-meta- or x-minio-meta- prefix a service leaves on them is removed.
+func s3Metadata(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		k = strings.ToLower(k)
+		k = strings.TrimPrefix(k, "x-amz-meta-")
+		k = strings.TrimPrefix(k, "x-minio-meta-")
+		out[k] = v
+	}
+	return out
+}
+
+// Write writes data to an S3 object at the specified key.
+func (s *S3Client) Write(ctx context.Context, key string, data []byte) error {
+	tr := otel.Tracer("s3-client")
+	ctx, span := tr.Start(ctx, "S3.Write")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("s3.bucket", s.bucketName),
+		attribute.String("s3.key", key),
+	)
+
+	if _, err := s.client.PutObject(ctx, s.bucketName, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to write data to S3 object %s: %w", key, err)
+	}
+	return nil
+}
+
+// Create returns a writer that uploads to an S3 object at the specified key as data is written.
+// The upload completes on Close. The object's generation is its ETag.
+func (s *S3Client) Create(ctx context.Context, key string, opts WriteOptions) (Writer, error) {
+	putOpts := minio.PutObjectOptions{UserMetadata: copyMetadata(opts.Metadata), PartSize: s3PartSize}
+	if opts.Gzip {
+		if putOpts.UserMetadata == nil {
+			putOpts.UserMetadata = make(map[string]string)
+		}
+		putOpts.UserMetadata[s3EncodingKey] = encodingGzip
+	}
+	switch opts.IfGenerationMatch {
+	case "":
+	case NoGeneration:
+		putOpts.SetMatchETagExcept("*")
+	default:
+		putOpts.SetMatchETag(opts.IfGenerationMatch)
+	}
+
+	// Streams of unknown length become multipart uploads, which not every S3-compatible service
+	// applies preconditions to, so conditional writes are buffered and sent as a single PUT.
+	if opts.IfGenerationMatch != "" {
+		w := &bufferedWriter{commit: func(data []byte) error {
+			_, err := s.client.PutObject(ctx, s.bucketName, key, bytes.NewReader(data), int64(len(data)), putOpts)
+			return s3WriteError(key, err)
+		}}
+		if opts.Gzip {
+			return newGzipWriteCloser(w), nil
+		}
+		return w, nil
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		tr := otel.Tracer("s3-client")
+		ctx, span := tr.Start(ctx, "S3.Create")
+		defer span.End()
+
+		span.SetAttributes(
+			attribute.String("s3.bucket", s.bucketName),
+			attribute.String("s3.key", key),
+		)
+
+		_, err := s.client.PutObject(ctx, s.bucketName, key, pr, -1, putOpts)
+		_ = pr.CloseWithError(err)
+		done <- err
+	}()
+
+	w := &s3WriteCloser{PipeWriter: pw, done: done, key: key}
+	if opts.Gzip {
+		return newGzipWriteCloser(w), nil
+	}
+	return w, nil
+}
+
+// s3PartSize is the part size of streamed uploads. minio-go otherwise sizes parts for the
+// largest possible object, buffering over 500 MiB per writer.
+const s3PartSize = 16 << 20
+
+// s3WriteCloser feeds a background PutObject and waits for it on Close.
+type s3WriteCloser struct {
+	*io.PipeWriter
+	done chan error
+	key  string
+}
+
+func (w *s3WriteCloser) Close() error {
+	_ = w.PipeWriter.Close()
+	return s3WriteError(w.key, <-w.done)
+}
+
+// CloseWithError fails the upload, which aborts the multipart upload instead of completing it.
+func (w *s3WriteCloser) CloseWithError(err error) error {
+	if err == nil {
+		err = errAborted
+	}
+	_ = w.PipeWriter.CloseWithError(err)
+	<-w.done
+	return nil
+}
+
+func s3WriteError(key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if minio.ToErrorResponse(err).Code == minio.PreconditionFailed {
+		return fmt.Errorf("%w: %s", ErrPreconditionFailed, key)
+	}
+	return fmt.Errorf("failed to write data to S3 object %s: %w", key, err)
+}
+
+// Read reads the content of an S3 object at the specified key.
+func (s *S3Client) Read(ctx context.Context, key string) ([]byte, error) {
+	rc, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data from %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Open returns a reader for an S3 object at the specified key.
+func (s *S3Client) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	tr := otel.Tracer("s3-client")
+	ctx, span := tr.Start(ctx, "S3.Open")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("s3.bucket", s.bucketName),
+		attribute.String("s3.key", key),
+	)
+
+	obj, err := s.client.GetObject(ctx, s.bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reader for %s: %w", key, err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		_ = obj.Close()
+		if isS3NotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	if s3Metadata(info.UserMetadata)[s3EncodingKey] == encodingGzip {
+		return newGzipReadCloser(obj)
+	}
+	return obj, nil
+}
+
+// Stat returns the attributes of an S3 object at the specified key.
+func (s *S3Client) Stat(ctx context.Context, key string) (*ObjectAttrs, error) {
+	tr := otel.Tracer("s3-client")
+	ctx, span := tr.Start(ctx, "S3.Stat")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("s3.bucket", s.bucketName),
+		attribute.String("s3.key", key),
+	)
+
+	info, err := s.client.StatObject(ctx, s.bucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		if isS3NotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return nil, fmt.Errorf("failed to stat S3 object %s: %w", key, err)
+	}
+	metadata := s3Metadata(info.UserMetadata)
+	encoding := metadata[s3EncodingKey]
+	delete(metadata, s3EncodingKey)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return &ObjectAttrs{
+		Key:             key,
+		Size:            info.Size,
+		Generation:      info.ETag,
+		ContentEncoding: encoding,
+		Metadata:        metadata,
+		Updated:         info.LastModified,
+	}, nil
+}
+
+// Delete removes the S3 object at the specified key.
+func (s *S3Client) Delete(ctx context.Context, key string) error {
+	tr := otel.Tracer("s3-client")
+	ctx, span := tr.Start(ctx, "S3.Delete")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("s3.bucket", s.bucketName),
+		attribute.String("s3.key", key),
+	)
+
+	// S3 deletes succeed for missing keys, so check first to report ErrNotExist like the other stores.
+	if _, err := s.client.StatObject(ctx, s.bucketName, key, minio.StatObjectOptions{}); err != nil {
+		if isS3NotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return fmt.Errorf("failed to stat S3 object %s: %w", key, err)
+	}
+	if err := s.client.RemoveObject(ctx, s.bucketName, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete S3 object %s: %w", key, err)
+	}
+	return nil
+}
+
+// ListActiveSessions returns a list of session IDs that had activity on the specified date.
+// Structure: raw/<session_id>/YYYY/MM/DD/HH/chunk.jsonl
+func (s *S3Client) ListActiveSessions(ctx context.Context, date time.Time) ([]string, error) {
+	var sessions []string
+	for obj := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: "raw/"}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		// Without Recursive, sessions come back as common prefixes "raw/<session_id>/".
+		if !strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		datePath := fmt.Sprintf("%s%d/%02d/%02d/", obj.Key, date.Year(), date.Month(), date.Day())
+
+		found, err := s.exists(ctx, datePath)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			sessions = append(sessions, strings.TrimSuffix(strings.TrimPrefix(obj.Key, "raw/"), "/"))
+		}
+	}
+	return sessions, nil
+}
+
+// exists reports whether any object has the given prefix.
+func (s *S3Client) exists(ctx context.Context, prefix string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for obj := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, MaxKeys: 1}) {
+		if obj.Err != nil {
+			return false, obj.Err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// ListSessionChunks returns all keys for a given session ID.
+func (s *S3Client) ListSessionChunks(ctx context.Context, sessionID string) ([]string, error) {
+	return s.ListFiles(ctx, fmt.Sprintf("raw/%s/", sessionID))
+}
+
+// ListFiles returns a list of all object keys matching the prefix.
+func (s *S3Client) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	var files []string
+	for obj := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		files = append(files, obj.Key)
+	}
+	return files, nil
+}
+
+// Close is a no-op; the underlying HTTP client needs no cleanup.
+func (s *S3Client) Close() error {
+	return nil
+}
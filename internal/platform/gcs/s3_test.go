@@ -0,0 +1,304 @@
+package gcs_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+)
+
+// fakeS3 is enough of the S3 API for S3Client: single and multipart PUTs with preconditions,
+// HEAD, GET, DELETE and ListObjectsV2 without a delimiter. Like S3, it stores user metadata keys
+// in lower case.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]*fakeUpload
+	nextID  int
+	aborted int
+}
+
+type fakeObject struct {
+	data     []byte
+	etag     string
+	metadata map[string]string
+	modified time.Time
+}
+
+type fakeUpload struct {
+	key      string
+	metadata map[string]string
+	parts    map[int][]byte
+}
+
+// newS3Client starts a fakeS3 and returns a client for its bucket "bucket".
+func newS3Client(t *testing.T) (*gcs.S3Client, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string]*fakeObject), uploads: make(map[string]*fakeUpload)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+
+	u, _ := url.Parse(srv.URL)
+	client, err := gcs.NewS3Client(context.Background(), "bucket", gcs.S3Config{Endpoint: u.Host, Region: "us-east-1", Insecure: true})
+	if err != nil {
+		t.Fatalf("failed to create S3 client: %v", err)
+	}
+	return client, fake
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "bucket" {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, q.Get("prefix"))
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = &fakeUpload{key: key, metadata: userMetadata(r.Header), parts: make(map[int][]byte)}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: bucket, Key: key, UploadId: id})
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		upload, ok := f.uploads[q.Get("uploadId")]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		data := readBody(r)
+		upload.parts[n] = data
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		upload, ok := f.uploads[q.Get("uploadId")]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		delete(f.uploads, q.Get("uploadId"))
+		var numbers []int
+		for n := range upload.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, n := range numbers {
+			data = append(data, upload.parts[n]...)
+		}
+		obj := f.put(upload.key, data, upload.metadata)
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucket, Key: key, ETag: obj.etag})
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(f.uploads, q.Get("uploadId"))
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		current, exists := f.objects[key]
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || strings.Trim(match, `"`) != strings.Trim(current.etag, `"`)) {
+			s3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			s3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		obj := f.put(key, readBody(r), userMetadata(r.Header))
+		w.Header().Set("ETag", obj.etag)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for k, v := range obj.metadata {
+			w.Header().Set("x-amz-meta-"+k, v)
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) put(key string, data []byte, metadata map[string]string) *fakeObject {
+	obj := &fakeObject{data: data, etag: etag(data), metadata: metadata, modified: time.Now().UTC()}
+	f.objects[key] = obj
+	return obj
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		Size         int
+		ETag         string
+		LastModified string
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []content
+	}{Name: "bucket", Prefix: prefix, MaxKeys: 1000}
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		obj := f.objects[k]
+		result.Contents = append(result.Contents, content{Key: k, Size: len(obj.data), ETag: obj.etag, LastModified: obj.modified.Format(time.RFC3339)})
+	}
+	result.KeyCount = len(result.Contents)
+	writeXML(w, result)
+}
+
+// userMetadata returns the x-amz-meta- headers of a request, keyed in lower case as S3 stores them.
+func userMetadata(h http.Header) map[string]string {
+	metadata := make(map[string]string)
+	for k, v := range h {
+		if lower := strings.ToLower(k); strings.HasPrefix(lower, "x-amz-meta-") {
+			metadata[strings.TrimPrefix(lower, "x-amz-meta-")] = v[0]
+		}
+	}
+	return metadata
+}
+
+// readBody reads a request body, decoding the aws-chunked encoding minio-go uses over plain HTTP.
+func readBody(r *http.Request) []byte {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, _ := io.ReadAll(r.Body)
+		return data
+	}
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return data
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 {
+			return data
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return data
+		}
+		data = append(data, chunk...)
+		_, _ = br.ReadString('\n')
+	}
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func TestS3Client_MetadataRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newS3Client(t)
+	metadata := map[string]string{"tenant_id": "acme", "conversation_id": "c-1"}
+
+	for _, opts := range []gcs.WriteOptions{
+		{Metadata: metadata, IfGenerationMatch: gcs.NoGeneration},
+		{Metadata: metadata, IfGenerationMatch: gcs.NoGeneration, Gzip: true},
+	} {
+		key := fmt.Sprintf("raw/c-1/chunk-gzip-%t.jsonl", opts.Gzip)
+		w, err := client.Create(ctx, key, opts)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := io.WriteString(w, `{"content":"hello"}`+"\n"); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		attrs, err := client.Stat(ctx, key)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if len(attrs.Metadata) != 2 || attrs.Metadata["tenant_id"] != "acme" || attrs.Metadata["conversation_id"] != "c-1" {
+			t.Errorf("gzip=%t: expected the metadata written, got %v", opts.Gzip, attrs.Metadata)
+		}
+		if wantEncoding := map[bool]string{true: "gzip"}[opts.Gzip]; attrs.ContentEncoding != wantEncoding {
+			t.Errorf("gzip=%t: expected content encoding %q, got %q", opts.Gzip, wantEncoding, attrs.ContentEncoding)
+		}
+		data, err := client.Read(ctx, key)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if string(data) != `{"content":"hello"}`+"\n" {
+			t.Errorf("gzip=%t: read back %q", opts.Gzip, data)
+		}
+
+		// The generation is the ETag, which conditional writes compare against.
+		w, _ = client.Create(ctx, key, gcs.WriteOptions{IfGenerationMatch: gcs.NoGeneration})
+		if err := w.Close(); !errors.Is(err, gcs.ErrPreconditionFailed) {
+			t.Errorf("expected a create-only write to fail, got %v", err)
+		}
+		w, _ = client.Create(ctx, key, gcs.WriteOptions{IfGenerationMatch: attrs.Generation})
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 3))
+		if err := w.Close(); err != nil {
+			t.Errorf("expected a write at the current generation to succeed, got %v", err)
+		}
+	}
+}
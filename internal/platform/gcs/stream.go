@@ -0,0 +1,193 @@
+package gcs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrPreconditionFailed is returned (wrapped) by a conditional write whose IfGenerationMatch no
+// longer matches the stored object.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// NoGeneration, used as WriteOptions.IfGenerationMatch, requires that the object does not exist yet.
+const NoGeneration = "0"
+
+// encodingGzip is the Content-Encoding of objects written with WriteOptions.Gzip.
+const encodingGzip = "gzip"
+
+// ObjectAttrs describes a stored object.
+type ObjectAttrs struct {
+	Key string
+	// Size is the stored size in bytes, i.e. after compression for gzip-encoded objects.
+	Size int64
+	// Generation identifies this version of the object for conditional writes. It is opaque:
+	// a GCS generation number, an S3 ETag, or a counter for the local stores.
+	Generation string
+	// ContentEncoding is "gzip" for objects written with WriteOptions.Gzip.
+	ContentEncoding string
+	// Metadata holds the custom attributes set by WriteOptions.Metadata.
+	Metadata map[string]string
+	Updated  time.Time
+}
+
+// WriteOptions configures Create.
+type WriteOptions struct {
+	// Gzip compresses the object as it is written. Open and Read decompress it transparently.
+	Gzip bool
+	// Metadata is stored with the object as custom attributes. Keys should be lower case: S3 does
+	// not preserve their case, and returns them lower-cased.
+	Metadata map[string]string
+	// IfGenerationMatch, when set, only commits the write if the object's current generation
+	// equals it (NoGeneration: if the object does not exist). Otherwise Close returns
+	// ErrPreconditionFailed and the stored object is left untouched.
+	IfGenerationMatch string
+}
+
+// StreamReader reads blobs incrementally rather than as a single []byte.
+type StreamReader interface {
+	// Open returns a reader for the object at key, decompressing it if it was written with gzip.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Stat returns the attributes of the object at key.
+	Stat(ctx context.Context, key string) (*ObjectAttrs, error)
+}
+
+// Writer writes an object returned by Create. Close commits it; CloseWithError abandons it,
+// leaving whatever was stored at the key before untouched. Callers that fail part way through
+// a write must use CloseWithError, as Close would commit a truncated object.
+type Writer interface {
+	io.WriteCloser
+	CloseWithError(err error) error
+}
+
+// errAborted is what a Writer abandoned with CloseWithError(nil) is aborted with.
+var errAborted = errors.New("write aborted")
+
+// StreamWriter writes blobs incrementally rather than as a single []byte.
+type StreamWriter interface {
+	// Create returns a writer for the object at key. Nothing is visible to readers until Close
+	// succeeds; conditional writes report ErrPreconditionFailed from Close.
+	Create(ctx context.Context, key string, opts WriteOptions) (Writer, error)
+}
+
+// Open returns a reader for the object at key, streaming it if r supports StreamReader and
+// reading it whole otherwise.
+func Open(ctx context.Context, r BlobReader, key string) (io.ReadCloser, error) {
+	if sr, ok := r.(StreamReader); ok {
+		return sr.Open(ctx, key)
+	}
+	data, err := r.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Create returns a writer for the object at key, streaming it if w supports StreamWriter and
+// buffering it for a single Write on Close otherwise. Without StreamWriter, metadata is dropped
+// and gzip or conditional writes are an error.
+func Create(ctx context.Context, w BlobWriter, key string, opts WriteOptions) (Writer, error) {
+	if sw, ok := w.(StreamWriter); ok {
+		return sw.Create(ctx, key, opts)
+	}
+	if opts.IfGenerationMatch != "" || opts.Gzip {
+		return nil, fmt.Errorf("blob writer for %s does not support gzip or conditional writes", key)
+	}
+	return &bufferedWriter{commit: func(data []byte) error {
+		return w.Write(ctx, key, data)
+	}}, nil
+}
+
+// bufferedWriter collects writes in memory and hands them to commit on Close.
+type bufferedWriter struct {
+	buf    bytes.Buffer
+	commit func(data []byte) error
+	closed bool
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.closed {
+		return 0, errors.New("write on closed writer")
+	}
+	return b.buf.Write(p)
+}
+
+func (b *bufferedWriter) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.commit(b.buf.Bytes())
+}
+
+// CloseWithError discards the buffered data without committing it.
+func (b *bufferedWriter) CloseWithError(error) error {
+	b.closed = true
+	b.buf.Reset()
+	return nil
+}
+
+// gzipWriteCloser compresses into an underlying writer, committing it on Close.
+type gzipWriteCloser struct {
+	*gzip.Writer
+	underlying Writer
+}
+
+func newGzipWriteCloser(w Writer) *gzipWriteCloser {
+	return &gzipWriteCloser{Writer: gzip.NewWriter(w), underlying: w}
+}
+
+func (g *gzipWriteCloser) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		_ = g.underlying.CloseWithError(err)
+		return err
+	}
+	return g.underlying.Close()
+}
+
+// CloseWithError abandons the underlying writer without flushing the compressed stream.
+func (g *gzipWriteCloser) CloseWithError(err error) error {
+	return g.underlying.CloseWithError(err)
+}
+
+// gzipReadCloser decompresses an underlying reader, closing it on Close.
+type gzipReadCloser struct {
+	*gzip.Reader
+	underlying io.ReadCloser
+}
+
+func newGzipReadCloser(r io.ReadCloser) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		_ = r.Close()
+		return nil, fmt.Errorf("failed to open gzip reader: %w", err)
+	}
+	return &gzipReadCloser{Reader: gz, underlying: r}, nil
+}
+
+func (g *gzipReadCloser) Close() error {
+	_ = g.Reader.Close()
+	return g.underlying.Close()
+}
+
+// readAll drains and closes rc.
+func readAll(rc io.ReadCloser) ([]byte, error) {
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,209 @@
+package gcs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dllewellyn/reflex/internal/platform/gcs"
+)
+
+// store is a backend under test. Its writer may not implement gcs.StreamWriter, in which case
+// gcs.Create falls back to buffering.
+type store struct {
+	name   string
+	writer gcs.BlobWriter
+	reader gcs.BlobReader
+	// leftovers lists the temporary files or uploads an abandoned write left behind.
+	leftovers func() []string
+}
+
+// blobWriterOnly hides everything but Write, so gcs.Create takes its buffered fallback.
+type blobWriterOnly struct {
+	gcs.BlobWriter
+}
+
+func stores(t *testing.T) []store {
+	t.Helper()
+	memory := gcs.NewMemoryClient()
+	fallback := gcs.NewMemoryClient()
+	dir := t.TempDir()
+	files, err := gcs.NewFileClient(dir)
+	if err != nil {
+		t.Fatalf("NewFileClient: %v", err)
+	}
+	s3, fake := newS3Client(t)
+	return []store{
+		{name: "memory", writer: memory, reader: memory, leftovers: func() []string { return nil }},
+		{name: "fallback", writer: blobWriterOnly{fallback}, reader: fallback, leftovers: func() []string { return nil }},
+		{name: "file", writer: files, reader: files, leftovers: func() []string {
+			var paths []string
+			_ = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+				if err == nil && strings.Contains(info.Name(), ".tmp-") {
+					paths = append(paths, p)
+				}
+				return nil
+			})
+			return paths
+		}},
+		{name: "s3", writer: s3, reader: s3, leftovers: func() []string {
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			var uploads []string
+			for id := range fake.uploads {
+				uploads = append(uploads, id)
+			}
+			return uploads
+		}},
+	}
+}
+
+func TestCreate_CloseWithErrorAbandonsWrite(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range stores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			const key = "raw/c-1/chunk.jsonl"
+			w, err := gcs.Create(ctx, s.writer, key, gcs.WriteOptions{Metadata: map[string]string{"conversation_id": "c-1"}})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, err := io.WriteString(w, `{"content":"trunc`); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := w.CloseWithError(errors.New("encode failed")); err != nil {
+				t.Fatalf("CloseWithError: %v", err)
+			}
+			if _, err := s.reader.Read(ctx, key); !errors.Is(err, gcs.ErrNotExist) {
+				t.Errorf("expected an abandoned write to leave no object, got %v", err)
+			}
+
+			// An abandoned overwrite leaves the previous object in place.
+			if err := s.writer.Write(ctx, key, []byte("v1\n")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			w, err = gcs.Create(ctx, s.writer, key, gcs.WriteOptions{})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			_, _ = io.WriteString(w, "v2, trunc")
+			_ = w.CloseWithError(nil)
+			data, err := s.reader.Read(ctx, key)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if string(data) != "v1\n" {
+				t.Errorf("expected the previous object to survive, got %q", data)
+			}
+			if left := s.leftovers(); len(left) != 0 {
+				t.Errorf("expected nothing left behind, got %v", left)
+			}
+		})
+	}
+}
+
+func TestCreate_GzipCloseWithError(t *testing.T) {
+	ctx := context.Background()
+	files, err := gcs.NewFileClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileClient: %v", err)
+	}
+	w, err := files.Create(ctx, "raw/c-1/chunk.jsonl", gcs.WriteOptions{Gzip: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, _ = io.WriteString(w, "partial")
+	_ = w.CloseWithError(nil)
+	if _, err := files.Stat(ctx, "raw/c-1/chunk.jsonl"); !errors.Is(err, gcs.ErrNotExist) {
+		t.Errorf("expected an abandoned gzip write to leave no object, got %v", err)
+	}
+}
+
+func TestCreate_ConditionalWritesAndAttributes(t *testing.T) {
+	ctx := context.Background()
+	metadata := map[string]string{"tenant_id": "acme", "conversation_id": "c-1"}
+	for _, s := range stores(t) {
+		if _, ok := s.writer.(gcs.StreamWriter); !ok {
+			continue
+		}
+		reader := s.reader.(gcs.StreamReader)
+		for _, compressed := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/gzip=%t", s.name, compressed), func(t *testing.T) {
+				key := fmt.Sprintf("raw/c-1/chunk-gzip-%t.jsonl", compressed)
+				write := func(opts gcs.WriteOptions, data string) error {
+					t.Helper()
+					opts.Gzip = compressed
+					w, err := gcs.Create(ctx, s.writer, key, opts)
+					if err != nil {
+						t.Fatalf("Create: %v", err)
+					}
+					if _, err := io.WriteString(w, data); err != nil {
+						t.Fatalf("Write: %v", err)
+					}
+					return w.Close()
+				}
+
+				if err := write(gcs.WriteOptions{Metadata: metadata, IfGenerationMatch: gcs.NoGeneration}, "v1\n"); err != nil {
+					t.Fatalf("expected a create-only write of a new object to succeed, got %v", err)
+				}
+				attrs, err := reader.Stat(ctx, key)
+				if err != nil {
+					t.Fatalf("Stat: %v", err)
+				}
+				if len(attrs.Metadata) != 2 || attrs.Metadata["tenant_id"] != "acme" || attrs.Metadata["conversation_id"] != "c-1" {
+					t.Errorf("expected the metadata written, got %v", attrs.Metadata)
+				}
+				if wantEncoding := map[bool]string{true: "gzip"}[compressed]; attrs.ContentEncoding != wantEncoding {
+					t.Errorf("expected content encoding %q, got %q", wantEncoding, attrs.ContentEncoding)
+				}
+
+				if err := write(gcs.WriteOptions{IfGenerationMatch: gcs.NoGeneration}, "v2\n"); !errors.Is(err, gcs.ErrPreconditionFailed) {
+					t.Errorf("expected a create-only write of an existing object to fail, got %v", err)
+				}
+				if err := write(gcs.WriteOptions{IfGenerationMatch: "stale"}, "v2\n"); !errors.Is(err, gcs.ErrPreconditionFailed) {
+					t.Errorf("expected a write at a stale generation to fail, got %v", err)
+				}
+				if data, err := s.reader.Read(ctx, key); err != nil || string(data) != "v1\n" {
+					t.Errorf("expected failed writes to leave v1, got %q, %v", data, err)
+				}
+
+				if err := write(gcs.WriteOptions{IfGenerationMatch: attrs.Generation}, "v2\n"); err != nil {
+					t.Fatalf("expected a write at the current generation to succeed, got %v", err)
+				}
+				rc, err := reader.Open(ctx, key)
+				if err != nil {
+					t.Fatalf("Open: %v", err)
+				}
+				data, err := io.ReadAll(rc)
+				_ = rc.Close()
+				if err != nil || string(data) != "v2\n" {
+					t.Errorf("expected v2 to be read back decompressed, got %q, %v", data, err)
+				}
+				updated, err := reader.Stat(ctx, key)
+				if err != nil {
+					t.Fatalf("Stat: %v", err)
+				}
+				if updated.Generation == attrs.Generation {
+					t.Errorf("expected a new generation after an overwrite, still %s", updated.Generation)
+				}
+				if len(updated.Metadata) != 0 {
+					t.Errorf("expected an overwrite without metadata to clear it, got %v", updated.Metadata)
+				}
+			})
+		}
+	}
+}
+
+func TestCreate_FallbackRejectsUnsupportedOptions(t *testing.T) {
+	ctx := context.Background()
+	writer := blobWriterOnly{gcs.NewMemoryClient()}
+	for _, opts := range []gcs.WriteOptions{{Gzip: true}, {IfGenerationMatch: gcs.NoGeneration}} {
+		if _, err := gcs.Create(ctx, writer, "raw/c-1/chunk.jsonl", opts); err == nil {
+			t.Errorf("expected %+v to be rejected by a writer without StreamWriter", opts)
+		}
+	}
+}
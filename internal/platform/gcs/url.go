@@ -0,0 +1,82 @@
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+)
+
+// Store is a blob store that can be read, written and pruned, as returned by OpenURL.
+type Store interface {
+	BlobReader
+	BlobWriter
+	BlobDeleter
+	StreamReader
+	StreamWriter
+	Close() error
+}
+
+// OpenURL opens the blob store named by rawURL:
+//
+//	gs://<bucket>                   Google Cloud Storage
+//	s3://<bucket>[?endpoint=host:port&region=<region>&insecure=true]
+//	                                S3, or an S3-compatible service such as MinIO
+//	file:///path/to/dir             a directory on the local filesystem
+//
+// A value with no scheme is treated as a GCS bucket name, so existing *_BUCKET settings keep working.
+func OpenURL(ctx context.Context, rawURL string) (Store, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("blob store URL is empty")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob store URL %q: %w", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "":
+		return NewClient(ctx, rawURL)
+	case "gs":
+		if err := checkBucketURL(u); err != nil {
+			return nil, err
+		}
+		return NewClient(ctx, u.Host)
+	case "s3":
+		if err := checkBucketURL(u); err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		cfg := S3Config{
+			Endpoint: q.Get("endpoint"),
+			Region:   q.Get("region"),
+		}
+		if v := q.Get("insecure"); v != "" {
+			if cfg.Insecure, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("invalid insecure value %q in %s: %w", v, rawURL, err)
+			}
+		}
+		return NewS3Client(ctx, u.Host, cfg)
+	case "file":
+		dir := u.Path
+		if u.Host != "" {
+			// file://relative/dir
+			dir = filepath.Join(u.Host, u.Path)
+		}
+		return NewFileClient(filepath.FromSlash(dir))
+	default:
+		return nil, fmt.Errorf("unsupported blob store scheme %q in %s (want gs, s3 or file)", u.Scheme, rawURL)
+	}
+}
+
+func checkBucketURL(u *url.URL) error {
+	if u.Host == "" {
+		return fmt.Errorf("blob store URL %s has no bucket", u.Redacted())
+	}
+	if u.Path != "" && u.Path != "/" {
+		return fmt.Errorf("blob store URL %s must name a bucket without an object prefix", u.Redacted())
+	}
+	return nil
+}
@@ -2,13 +2,17 @@ package gcs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
 
@@ -39,9 +43,13 @@ func (w *Client) Write(ctx context.Context, key string, data []byte) error {
 		attribute.String("gcs.key", key),
 	)
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	wc := w.client.Bucket(w.bucketName).Object(key).NewWriter(ctx)
 
 	if _, err := wc.Write(data); err != nil {
+		// Cancelling the context stops the upload without committing it.
+		cancel()
 		_ = wc.Close()
 		return fmt.Errorf("failed to write data to GCS object %s: %w", key, err)
 	}
@@ -66,6 +74,9 @@ func (w *Client) Read(ctx context.Context, key string) ([]byte, error) {
 
 	rc, err := w.client.Bucket(w.bucketName).Object(key).NewReader(ctx)
 	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
 		return nil, fmt.Errorf("failed to create reader for %s: %w", key, err)
 	}
 	defer func() { _ = rc.Close() }()
@@ -77,6 +88,138 @@ func (w *Client) Read(ctx context.Context, key string) ([]byte, error) {
 	return data, nil
 }
 
+// Create returns a writer for a GCS object at the specified key. The object is committed on Close.
+// Gzip-encoded objects are decompressed by GCS when read, so Open and Read return the original data.
+func (w *Client) Create(ctx context.Context, key string, opts WriteOptions) (Writer, error) {
+	tr := otel.Tracer("gcs-writer")
+	ctx, span := tr.Start(ctx, "GCS.Create")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("gcs.bucket", w.bucketName),
+		attribute.String("gcs.key", key),
+	)
+
+	obj := w.client.Bucket(w.bucketName).Object(key)
+	switch opts.IfGenerationMatch {
+	case "":
+	case NoGeneration:
+		obj = obj.If(storage.Conditions{DoesNotExist: true})
+	default:
+		gen, err := strconv.ParseInt(opts.IfGenerationMatch, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GCS generation %q for %s: %w", opts.IfGenerationMatch, key, err)
+		}
+		obj = obj.If(storage.Conditions{GenerationMatch: gen})
+	}
+
+	// The writer outlives this call; cancelling its context is how CloseWithError abandons it.
+	wctx, cancel := context.WithCancel(ctx)
+	wc := obj.NewWriter(wctx)
+	wc.Metadata = copyMetadata(opts.Metadata)
+	gw := &gcsWriteCloser{Writer: wc, cancel: cancel, key: key}
+	if opts.Gzip {
+		wc.ContentEncoding = encodingGzip
+		return newGzipWriteCloser(gw), nil
+	}
+	return gw, nil
+}
+
+// gcsWriteCloser maps a failed precondition on commit to ErrPreconditionFailed.
+type gcsWriteCloser struct {
+	*storage.Writer
+	cancel context.CancelFunc
+	key    string
+}
+
+func (g *gcsWriteCloser) Close() error {
+	defer g.cancel()
+	if err := g.Writer.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return fmt.Errorf("%w: %s", ErrPreconditionFailed, g.key)
+		}
+		return fmt.Errorf("failed to close/commit GCS object %s: %w", g.key, err)
+	}
+	return nil
+}
+
+// CloseWithError cancels the upload, so the object is not committed.
+func (g *gcsWriteCloser) CloseWithError(error) error {
+	g.cancel()
+	_ = g.Writer.Close()
+	return nil
+}
+
+// Open returns a reader for a GCS object at the specified key.
+func (w *Client) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	tr := otel.Tracer("gcs-writer")
+	ctx, span := tr.Start(ctx, "GCS.Open")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("gcs.bucket", w.bucketName),
+		attribute.String("gcs.key", key),
+	)
+
+	rc, err := w.client.Bucket(w.bucketName).Object(key).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return nil, fmt.Errorf("failed to create reader for %s: %w", key, err)
+	}
+	return rc, nil
+}
+
+// Stat returns the attributes of a GCS object at the specified key.
+func (w *Client) Stat(ctx context.Context, key string) (*ObjectAttrs, error) {
+	tr := otel.Tracer("gcs-writer")
+	ctx, span := tr.Start(ctx, "GCS.Stat")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("gcs.bucket", w.bucketName),
+		attribute.String("gcs.key", key),
+	)
+
+	attrs, err := w.client.Bucket(w.bucketName).Object(key).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return nil, fmt.Errorf("failed to stat GCS object %s: %w", key, err)
+	}
+	return &ObjectAttrs{
+		Key:             key,
+		Size:            attrs.Size,
+		Generation:      strconv.FormatInt(attrs.Generation, 10),
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+		Updated:         attrs.Updated,
+	}, nil
+}
+
+// Delete removes the GCS object at the specified key.
+func (w *Client) Delete(ctx context.Context, key string) error {
+	tr := otel.Tracer("gcs-writer")
+	ctx, span := tr.Start(ctx, "GCS.Delete")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("gcs.bucket", w.bucketName),
+		attribute.String("gcs.key", key),
+	)
+
+	if err := w.client.Bucket(w.bucketName).Object(key).Delete(ctx); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotExist, key)
+		}
+		return fmt.Errorf("failed to delete GCS object %s: %w", key, err)
+	}
+	return nil
+}
+
 // ListActiveSessions returns a list of session IDs that had activity on the specified date.
 func (w *Client) ListActiveSessions(ctx context.Context, date time.Time) ([]string, error) {
 	// Strategy: List all objects in the bucket, but that's too many.
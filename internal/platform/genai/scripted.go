@@ -0,0 +1,97 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"text/template"
+)
+
+// ErrNoScriptedResponse is returned by ScriptedClient when no rule matches a prompt.
+var ErrNoScriptedResponse = errors.New("no scripted response for prompt")
+
+// Rule scripts one response of a ScriptedClient.
+type Rule struct {
+	// Match is a regular expression the prompt must match. Empty matches every prompt.
+	Match string `yaml:"match"`
+	// Response is a text/template rendered with .Prompt, .Model, .Match (the first match of
+	// Match) and .Matches (every match).
+	Response string `yaml:"response"`
+}
+
+type compiledRule struct {
+	match    *regexp.Regexp
+	response *template.Template
+}
+
+// ScriptedClient is a ClientInterface that answers from a list of rules instead of a model, so
+// the pipeline can run offline with predictable verdicts. The first rule whose Match matches the
+// prompt supplies the response.
+type ScriptedClient struct {
+	rules []compiledRule
+
+	mu    sync.Mutex
+	calls int
+}
+
+var _ ClientInterface = (*ScriptedClient)(nil)
+
+// NewScriptedClient compiles rules into a ScriptedClient.
+func NewScriptedClient(rules []Rule) (*ScriptedClient, error) {
+	c := &ScriptedClient{}
+	for i, rule := range rules {
+		var compiled compiledRule
+		if rule.Match != "" {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid match: %w", i, err)
+			}
+			compiled.match = re
+		}
+		tmpl, err := template.New(fmt.Sprintf("rule-%d", i)).Parse(rule.Response)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid response: %w", i, err)
+		}
+		compiled.response = tmpl
+		c.rules = append(c.rules, compiled)
+	}
+	return c, nil
+}
+
+// GenerateContent returns the response of the first rule matching prompt.
+func (c *ScriptedClient) GenerateContent(ctx context.Context, modelName, prompt string) (string, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+
+	for _, rule := range c.rules {
+		data := map[string]any{"Prompt": prompt, "Model": modelName, "Match": "", "Matches": []string(nil)}
+		if rule.match != nil {
+			matches := rule.match.FindAllString(prompt, -1)
+			if len(matches) == 0 {
+				continue
+			}
+			data["Match"], data["Matches"] = matches[0], matches
+		}
+		var buf bytes.Buffer
+		if err := rule.response.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render scripted response: %w", err)
+		}
+		return buf.String(), nil
+	}
+	return "", ErrNoScriptedResponse
+}
+
+// Calls returns how many prompts the client has answered or rejected.
+func (c *ScriptedClient) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func (c *ScriptedClient) Close() error {
+	return nil
+}
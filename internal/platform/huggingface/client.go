@@ -3,182 +3,371 @@ package huggingface
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-
-	"github.com/parquet-go/parquet-go"
 )
 
+// DefaultEndpoint is the HuggingFace Hub.
+const DefaultEndpoint = "https://huggingface.co"
+
+// Config configures a Client.
+type Config struct {
+	// Endpoint is the Hub URL. Defaults to DefaultEndpoint.
+	Endpoint string
+	// Token authenticates requests, for private and gated datasets. Optional.
+	Token string
+	// Revision is the branch, tag or commit read when a dataset ID does not pin one as
+	// "<id>@<revision>". Defaults to "main".
+	Revision string
+	// CacheDir keeps downloaded files under <dataset>/<commit>/, so later runs at the same commit
+	// read them from disk. If empty, files are downloaded to temporary files removed on Close.
+	CacheDir string
+}
+
 // Client is a client for the HuggingFace API.
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	config     Config
 }
 
 // NewClient creates a new HuggingFace client.
-func NewClient(httpClient *http.Client) *Client {
+func NewClient(httpClient *http.Client, cfg Config) *Client {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.Revision == "" {
+		cfg.Revision = "main"
+	}
 	return &Client{
-		baseURL:    "https://huggingface.co",
+		baseURL:    strings.TrimSuffix(cfg.Endpoint, "/"),
 		httpClient: httpClient,
+		config:     cfg,
 	}
 }
 
 type datasetInfo struct {
+	SHA      string `json:"sha"`
 	Siblings []struct {
 		RFilename string `json:"rfilename"`
 	} `json:"siblings"`
 }
 
-// DownloadAndRead downloads the dataset file (parquet, jsonl, or json) for the given dataset and split
-// and returns a DatasetReader.
+// ParseDatasetID splits a dataset ID of the form "<owner>/<name>[@<revision>]". revision is empty
+// if the ID does not pin one.
+func ParseDatasetID(datasetID string) (repo, revision string) {
+	repo, revision, _ = strings.Cut(datasetID, "@")
+	return repo, revision
+}
+
+// DownloadAndRead reads every file of the given split of a dataset, in shard order, as one
+// DatasetReader. Files are taken from the dataset repository, preferring parquet over JSON
+// Lines, JSON and CSV; if the repository has none for the split, the parquet files the Hub
+// converted it to are read instead, at the commit of the conversion. The Hub converts only the
+// main branch, so a pinned revision must have files of its own. Files are downloaded as they are
+// reached.
 func (c *Client) DownloadAndRead(ctx context.Context, datasetID, split string) (DatasetReader, error) {
-	// 1. Get dataset info to find the file
-	url := fmt.Sprintf("%s/api/datasets/%s", c.baseURL, datasetID)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	repo, revision := ParseDatasetID(datasetID)
+	if revision == "" {
+		revision = c.config.Revision
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
+	// 1. Get dataset info at the revision, to resolve it to a commit and list its files
+	var info datasetInfo
+	infoURL := fmt.Sprintf("%s/api/datasets/%s/revision/%s", c.baseURL, repo, url.PathEscape(revision))
+	if err := c.getJSON(ctx, infoURL, &info); err != nil {
 		return nil, fmt.Errorf("failed to fetch dataset info: %w", err)
 	}
-	defer resp.Body.Close()
+	commit := info.SHA
+	if commit == "" {
+		commit = revision
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch dataset info: status %d", resp.StatusCode)
+	// 2. Resolve the split to its files
+	var files []string
+	for _, s := range info.Siblings {
+		files = append(files, s.RFilename)
 	}
+	var sources []source
+	if matched := SplitFiles(files, split); len(matched) > 0 {
+		for _, name := range matched {
+			sources = append(sources, source{
+				url:  fmt.Sprintf("%s/datasets/%s/resolve/%s/%s", c.baseURL, repo, url.PathEscape(commit), name),
+				name: name,
+			})
+		}
+	} else {
+		if revision != convertedRevision {
+			return nil, fmt.Errorf("no supported file (parquet, jsonl, json, csv) found for split %q in dataset %q at %s, and the Hub converts only %s to parquet", split, repo, revision, convertedRevision)
+		}
+		converted, convertCommit, err := c.convertedParquet(ctx, repo, split)
+		if err != nil {
+			return nil, err
+		}
+		if len(converted) == 0 {
+			log.Printf("Debug: No file found for split. Available files: %v\n", files)
+			return nil, fmt.Errorf("no supported file (parquet, jsonl, json, csv) found for split %q in dataset %q at %s", split, repo, revision)
+		}
+		sources, commit = converted, convertCommit
+	}
+	log.Printf("Resolved dataset %s split %q at %s to %d file(s)", repo, split, commit, len(sources))
 
-	var info datasetInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return nil, fmt.Errorf("failed to decode dataset info: %w", err)
-	}
-
-	// 2. Find the file (prioritize extensions)
-	var targetFile string
-	var fileType string // "parquet" or "jsonl" (covers json too)
-
-	// Priority: .parquet -> .jsonl -> .json
-	// Note: huggingface usually has "data/" prefix or similar. We look for the split name in the filename.
-	// Simple heuristic: check suffix and if it contains split.
-
-	// Helper to find file by extensions
-	findFile := func(exts []string, matchSplit bool) (string, string) {
-		for _, s := range info.Siblings {
-			for _, ext := range exts {
-				if strings.HasSuffix(s.RFilename, ext) {
-					if !matchSplit || strings.Contains(s.RFilename, split) {
-						return s.RFilename, ext
-					}
-				}
+	// 3. Read the files in order, downloading each when it is reached
+	byName := make(map[string]source, len(sources))
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		byName[s.name] = s
+		names[i] = s.name
+	}
+	return NewMultiReader(commit, names, func(name string) (DatasetReader, error) {
+		return c.open(ctx, repo, commit, byName[name])
+	}), nil
+}
+
+// source is a file to download, and its name within the dataset.
+type source struct {
+	url  string
+	name string
+}
+
+// shardPattern matches the part of a file name after the split, following the Hub's data file
+// conventions: nothing, separators and digits ("train.parquet", "train-0.jsonl"), or a shard
+// suffix ("train-00000-of-00004.parquet").
+var shardPattern = regexp.MustCompile(`^(?:[-._ 0-9]*|-\d{5}-of-\d{5}.*)$`)
+
+// dataExtensions are the file types read, in order of preference.
+var dataExtensions = []string{".parquet", ".jsonl", ".json", ".csv"}
+
+// SplitFiles returns the data files of split among files, sorted so shards are read in order.
+// A file belongs to the split if it is named after it ("data/train-00000-of-00002.parquet",
+// "test.jsonl") or sits in a directory named after it ("default/train/0000.parquet"), so "train"
+// does not match "train_adversarial". Only files of the most preferred type present are returned.
+// A repository whose files name no split at all is treated as a single "train" split.
+func SplitFiles(files []string, split string) []string {
+	byExt := make(map[string][]string)
+	anySplit := false
+	for _, name := range files {
+		ext := path.Ext(name)
+		if !isDataExtension(ext) {
+			continue
+		}
+		if splitOf(name) != "" {
+			anySplit = true
+		}
+		if belongsToSplit(name, split) {
+			byExt[ext] = append(byExt[ext], name)
+		}
+	}
+	if !anySplit && split == "train" {
+		for _, name := range files {
+			if ext := path.Ext(name); isDataExtension(ext) {
+				byExt[ext] = append(byExt[ext], name)
 			}
 		}
-		return "", ""
 	}
+	for _, ext := range dataExtensions {
+		if matched := byExt[ext]; len(matched) > 0 {
+			sort.Strings(matched)
+			return matched
+		}
+	}
+	return nil
+}
 
-	// 1. Try to find precise match (extension + split name)
-	targetFile, ext := findFile([]string{".parquet"}, true)
-	if targetFile != "" {
-		fileType = "parquet"
-	} else {
-		targetFile, ext = findFile([]string{".jsonl", ".json"}, true)
-		if targetFile != "" {
-			fileType = "jsonl"
-		}
-	}
-
-	// 2. Fallback: find any supported file
-	if targetFile == "" {
-		log.Printf("Warning: no file found matching split %q. Falling back to any supported file.", split)
-		targetFile, ext = findFile([]string{".parquet"}, false)
-		if targetFile != "" {
-			fileType = "parquet"
-		} else {
-			targetFile, ext = findFile([]string{".jsonl", ".json"}, false)
-			if targetFile != "" {
-				fileType = "jsonl"
-			}
+func isDataExtension(ext string) bool {
+	for _, e := range dataExtensions {
+		if ext == e {
+			return true
 		}
 	}
+	return false
+}
 
-	if targetFile == "" {
-		// Log available files for debugging
-		var files []string
-		for _, s := range info.Siblings {
-			files = append(files, s.RFilename)
+func belongsToSplit(name, split string) bool {
+	if split == "" {
+		return false
+	}
+	dir := path.Dir(name)
+	for _, part := range strings.Split(dir, "/") {
+		if part == split || part == "partial-"+split {
+			return true
 		}
-		log.Printf("Debug: No matching file found. Available files: %v\n", files)
-		return nil, fmt.Errorf("no supported file (parquet, jsonl, json) found for split %q in dataset %q", split, datasetID)
 	}
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	rest, ok := strings.CutPrefix(base, split)
+	return ok && shardPattern.MatchString(rest)
+}
 
-	// Log found file for debugging
-	// fmt.Printf("Found file: %s (type: %s)\n", targetFile, fileType)
+// commonSplits are the split names splitOf recognises when deciding whether a repository names
+// its splits at all.
+var commonSplits = []string{"train", "test", "validation", "valid", "dev", "eval"}
 
-	// 3. Download the file
-	fileURL := fmt.Sprintf("%s/datasets/%s/resolve/main/%s", c.baseURL, datasetID, targetFile)
-	reqFile, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create file request: %w", err)
+func splitOf(name string) string {
+	for _, split := range commonSplits {
+		if belongsToSplit(name, split) {
+			return split
+		}
 	}
+	return ""
+}
 
-	respFile, err := c.httpClient.Do(reqFile)
+// convertedRevision is the only revision the Hub converts to parquet.
+const convertedRevision = "main"
+
+// convertedParquet lists the parquet files the Hub converted the dataset's split to, kept on the
+// refs/convert/parquet branch as <config>/<split>/NNNN.parquet, and returns them with the
+// branch's commit. If several configurations have the split, the "default" one is used.
+func (c *Client) convertedParquet(ctx context.Context, repo, split string) ([]source, string, error) {
+	var info datasetInfo
+	err := c.getJSON(ctx, fmt.Sprintf("%s/api/datasets/%s/revision/%s", c.baseURL, repo, url.PathEscape("refs/convert/parquet")), &info)
+	if errors.Is(err, errNotFound) {
+		return nil, "", nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w", err)
+		return nil, "", fmt.Errorf("failed to list converted parquet files: %w", err)
+	}
+	if info.SHA == "" {
+		return nil, "", errors.New("failed to list converted parquet files: no commit for refs/convert/parquet")
 	}
-	defer respFile.Body.Close()
 
-	if respFile.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download file: status %d", respFile.StatusCode)
+	configs := make(map[string][]string)
+	for _, s := range info.Siblings {
+		parts := strings.Split(s.RFilename, "/")
+		if len(parts) != 3 || path.Ext(s.RFilename) != ".parquet" {
+			continue
+		}
+		if parts[1] == split || parts[1] == "partial-"+split {
+			configs[parts[0]] = append(configs[parts[0]], s.RFilename)
+		}
+	}
+	if len(configs) == 0 {
+		return nil, "", nil
+	}
+	var names []string
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	config := names[0]
+	if _, ok := configs["default"]; ok {
+		config = "default"
 	}
 
-	tmpFile, err := os.CreateTemp("", fmt.Sprintf("hf-dataset-*%s", ext))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	files := configs[config]
+	sort.Strings(files)
+	var sources []source
+	for _, name := range files {
+		sources = append(sources, source{
+			url:  fmt.Sprintf("%s/datasets/%s/resolve/%s/%s", c.baseURL, repo, url.PathEscape(info.SHA), name),
+			name: name,
+		})
 	}
+	return sources, info.SHA, nil
+}
+
+var errNotFound = errors.New("not found")
 
-	_, err = io.Copy(tmpFile, respFile.Body)
+func (c *Client) getJSON(ctx context.Context, u string, v any) error {
+	resp, err := c.get(ctx, u)
 	if err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return nil, fmt.Errorf("failed to write temp file: %w", err)
+		return err
 	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
 
-	// Rewind file for reading
-	if _, err := tmpFile.Seek(0, 0); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return nil, fmt.Errorf("failed to seek temp file: %w", err)
+// get requests u, authenticating with the token if there is one.
+func (c *Client) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// 4. Create Reader based on type
-	if fileType == "parquet" {
-		stat, err := tmpFile.Stat()
-		if err != nil {
-			tmpFile.Close()
-			os.Remove(tmpFile.Name())
-			return nil, fmt.Errorf("failed to stat temp file: %w", err)
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		resp.Body.Close()
+		if c.config.Token == "" {
+			return nil, fmt.Errorf("status %d: the dataset may be private or gated; set a HuggingFace token", resp.StatusCode)
 		}
+		return nil, fmt.Errorf("status %d: the token does not grant access to the dataset", resp.StatusCode)
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %d: %w", resp.StatusCode, errNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
 
-		file, err := parquet.OpenFile(tmpFile, stat.Size())
+// open downloads a file, or finds it in the cache, and opens it.
+func (c *Client) open(ctx context.Context, repo, commit string, src source) (DatasetReader, error) {
+	if c.config.CacheDir == "" {
+		tmpFile, err := os.CreateTemp("", "hf-dataset-*"+path.Ext(src.name))
 		if err != nil {
-			tmpFile.Close()
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		if err := c.download(ctx, src.url, tmpFile); err != nil {
 			os.Remove(tmpFile.Name())
-			return nil, fmt.Errorf("failed to open parquet file: %w", err)
+			return nil, err
 		}
+		return openFile(tmpFile.Name(), true)
+	}
 
-		// Create a reader for all row groups
-		rows := parquet.MultiRowGroup(file.RowGroups()...).Rows()
+	cached := filepath.Join(c.config.CacheDir, filepath.FromSlash(repo), commit, filepath.FromSlash(src.name))
+	if _, err := os.Stat(cached); err == nil {
+		log.Printf("Reading %s from cache", src.name)
+		return openFile(cached, false)
+	}
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// Download beside the cached file and rename it into place, so an interrupted download is
+	// never mistaken for a complete one.
+	tmpFile, err := os.CreateTemp(filepath.Dir(cached), ".download-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if err := c.download(ctx, src.url, tmpFile); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+	if err := os.Rename(tmpFile.Name(), cached); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, fmt.Errorf("failed to cache %s: %w", src.name, err)
+	}
+	return openFile(cached, false)
+}
 
-		return &ParquetReader{
-			rows: rows,
-			file: tmpFile,
-		}, nil
-	} else {
-		// JSON or JSONL
-		return NewJSONLReader(tmpFile), nil
+// download writes the file at u to f and closes f.
+func (c *Client) download(ctx context.Context, u string, f *os.File) error {
+	log.Printf("Downloading %s", u)
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write file: %w", err)
 	}
+	return f.Close()
 }
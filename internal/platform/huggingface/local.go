@@ -0,0 +1,254 @@
+package huggingface
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalScheme prefixes dataset IDs that name local files rather than HuggingFace datasets.
+const LocalScheme = "file://"
+
+// IsLocal reports whether datasetID names a local file or directory.
+func IsLocal(datasetID string) bool {
+	return strings.HasPrefix(datasetID, LocalScheme)
+}
+
+// LocalClient reads datasets from local CSV, JSON, JSONL and Parquet files, for corpora that are
+// not on HuggingFace and for working offline. Dataset IDs have the form file://<path>, where the
+// path is absolute (file:///data/redteam) or relative to the working directory (file://corpus).
+type LocalClient struct{}
+
+// NewLocalClient creates a LocalClient.
+func NewLocalClient() *LocalClient {
+	return &LocalClient{}
+}
+
+// DownloadAndRead opens the file or directory named by datasetID. For a directory, split selects
+// the files in a <split>/ subdirectory or named <split>.<ext> or <split>-*.<ext>, as HuggingFace
+// lays out splits; if none match, every supported file is read. Files are read in name order.
+func (c *LocalClient) DownloadAndRead(ctx context.Context, datasetID, split string) (DatasetReader, error) {
+	if !IsLocal(datasetID) {
+		return nil, fmt.Errorf("not a local dataset: %q", datasetID)
+	}
+	root := strings.TrimPrefix(datasetID, LocalScheme)
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local dataset: %w", err)
+	}
+	if !info.IsDir() {
+		return OpenFile(root)
+	}
+
+	var all, matched []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isSupported(path) {
+			return nil
+		}
+		all = append(all, path)
+		if matchesSplit(root, path, split) {
+			matched = append(matched, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local dataset: %w", err)
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no supported file (csv, json, jsonl, parquet) found in %s", root)
+	}
+	if len(matched) == 0 {
+		matched = all
+	}
+	return NewMultiReader("", matched, OpenFile), nil
+}
+
+func isSupported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".json", ".jsonl", ".parquet":
+		return true
+	}
+	return false
+}
+
+func matchesSplit(root, path, split string) bool {
+	if split == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	if dir, _, ok := strings.Cut(filepath.ToSlash(rel), "/"); ok && dir == split {
+		return true
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return name == split || strings.HasPrefix(name, split+"-")
+}
+
+// OpenFile reads a local dataset file, chosen by extension: .csv (with a header row), .json (an
+// array of objects, or one object per line), .jsonl or .parquet. The file is left in place on Close.
+func OpenFile(path string) (DatasetReader, error) {
+	return openFile(path, false)
+}
+
+// openFile opens a dataset file, removing it on Close if it is temporary.
+func openFile(path string, temporary bool) (DatasetReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset file: %w", err)
+	}
+
+	var reader DatasetReader
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		reader, err = NewParquetReader(file)
+	case ".jsonl":
+		reader = NewJSONLReader(file)
+	case ".json":
+		reader, err = newJSONReader(file)
+	case ".csv":
+		reader, err = newCSVReader(file)
+	default:
+		err = fmt.Errorf("unsupported dataset file %s", path)
+	}
+	if err != nil {
+		file.Close()
+		if temporary {
+			os.Remove(path)
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if temporary {
+		return &removeOnClose{DatasetReader: reader, path: path}, nil
+	}
+	return reader, nil
+}
+
+// removeOnClose removes a downloaded file once its reader is closed.
+type removeOnClose struct {
+	DatasetReader
+	path string
+}
+
+func (r *removeOnClose) Close() error {
+	return errors.Join(r.DatasetReader.Close(), os.Remove(r.path))
+}
+
+// newJSONReader reads a JSON file holding either an array of objects or one object per line.
+func newJSONReader(file *os.File) (DatasetReader, error) {
+	buffered := bufio.NewReader(file)
+	for {
+		b, err := buffered.Peek(1)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return &JSONArrayReader{file: file}, nil
+			}
+			return nil, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = buffered.ReadByte()
+			continue
+		case '[':
+			decoder := json.NewDecoder(buffered)
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return &JSONArrayReader{decoder: decoder, file: file}, nil
+		}
+		return &JSONLReader{scanner: bufio.NewScanner(buffered), file: file}, nil
+	}
+}
+
+// JSONArrayReader reads a JSON file holding an array of objects, one element at a time.
+type JSONArrayReader struct {
+	decoder *json.Decoder
+	file    *os.File
+}
+
+// Read reads the next elements of the array.
+func (r *JSONArrayReader) Read(rows []map[string]interface{}) (int, error) {
+	count := 0
+	for r.decoder != nil && count < len(rows) && r.decoder.More() {
+		var row map[string]interface{}
+		if err := r.decoder.Decode(&row); err != nil {
+			return count, fmt.Errorf("failed to parse json array element: %w", err)
+		}
+		rows[count] = row
+		count++
+	}
+	if count == 0 {
+		return 0, io.EOF
+	}
+	return count, nil
+}
+
+// Close closes the underlying file.
+func (r *JSONArrayReader) Close() error {
+	return r.file.Close()
+}
+
+// CSVReader reads a CSV file whose first row names the columns. Values are read as strings.
+type CSVReader struct {
+	reader  *csv.Reader
+	columns []string
+	file    *os.File
+}
+
+func newCSVReader(file *os.File) (*CSVReader, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	columns, err := reader.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	// Spreadsheet exports often start with a byte order mark.
+	if len(columns) > 0 {
+		columns[0] = strings.TrimPrefix(columns[0], "\ufeff")
+	}
+	return &CSVReader{reader: reader, columns: columns, file: file}, nil
+}
+
+// Read reads the next records. Columns missing from a short record are left out of its row.
+func (r *CSVReader) Read(rows []map[string]interface{}) (int, error) {
+	count := 0
+	for count < len(rows) {
+		record, err := r.reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to parse csv record: %w", err)
+		}
+		row := make(map[string]interface{}, len(r.columns))
+		for i, column := range r.columns {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows[count] = row
+		count++
+	}
+	if count == 0 {
+		return 0, io.EOF
+	}
+	return count, nil
+}
+
+// Close closes the underlying file.
+func (r *CSVReader) Close() error {
+	return r.file.Close()
+}
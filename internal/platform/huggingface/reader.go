@@ -3,6 +3,7 @@ package huggingface
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,15 +17,46 @@ type DatasetReader interface {
 	Close() error
 }
 
+// Position is how far a dataset has been read: the shard being read, at the dataset's revision,
+// and the number of rows read from it.
+type Position struct {
+	Revision string `json:"revision,omitempty"`
+	Shard    string `json:"shard,omitempty"`
+	Row      int64  `json:"row"`
+}
+
+// Seeker is implemented by DatasetReaders that can report their position and resume from one
+// without reading the shards before it.
+type Seeker interface {
+	Position() Position
+	Seek(pos Position) error
+}
+
 // ParquetReader wraps the parquet reader and the underlying file to ensure cleanup.
 type ParquetReader struct {
 	rows parquet.Rows
 	file *os.File
 }
 
-// Close closes the underlying file and removes it.
+// NewParquetReader reads every row group of a parquet file.
+func NewParquetReader(file *os.File) (*ParquetReader, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat parquet file: %w", err)
+	}
+	pf, err := parquet.OpenFile(file, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+	// Create a reader for all row groups
+	return &ParquetReader{
+		rows: parquet.MultiRowGroup(pf.RowGroups()...).Rows(),
+		file: file,
+	}, nil
+}
+
+// Close closes the underlying file.
 func (r *ParquetReader) Close() error {
-	defer os.Remove(r.file.Name())
 	if err := r.rows.Close(); err != nil {
 		r.file.Close()
 		return err
@@ -103,9 +135,8 @@ func NewJSONLReader(file *os.File) *JSONLReader {
 	}
 }
 
-// Close closes the underlying file and removes it.
+// Close closes the underlying file.
 func (r *JSONLReader) Close() error {
-	defer os.Remove(r.file.Name())
 	return r.file.Close()
 }
 
@@ -133,3 +164,114 @@ func (r *JSONLReader) Read(rows []map[string]interface{}) (int, error) {
 
 	return count, nil
 }
+
+// MultiReader reads several dataset files in order as one dataset, opening each only once the
+// previous one is exhausted.
+type MultiReader struct {
+	revision string
+	paths    []string
+	open     func(path string) (DatasetReader, error)
+	current  DatasetReader
+	// shard and row are the file being read and the rows read from it.
+	shard string
+	row   int64
+}
+
+var _ Seeker = (*MultiReader)(nil)
+
+// NewMultiReader creates a MultiReader over paths, opening each with open. revision identifies
+// the version of the files, e.g. a commit, and is reported in Position.
+func NewMultiReader(revision string, paths []string, open func(path string) (DatasetReader, error)) *MultiReader {
+	return &MultiReader{revision: revision, paths: paths, open: open}
+}
+
+// Read reads rows from the current file, moving on to the next when it is exhausted.
+func (r *MultiReader) Read(rows []map[string]interface{}) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.paths) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := r.current.Read(rows)
+		r.row += int64(n)
+		if errors.Is(err, io.EOF) {
+			if closeErr := r.current.Close(); closeErr != nil {
+				return n, closeErr
+			}
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *MultiReader) next() error {
+	reader, err := r.open(r.paths[0])
+	if err != nil {
+		return err
+	}
+	r.current, r.shard, r.row, r.paths = reader, r.paths[0], 0, r.paths[1:]
+	return nil
+}
+
+// Position returns the shard being read and the rows read from it.
+func (r *MultiReader) Position() Position {
+	return Position{Revision: r.revision, Shard: r.shard, Row: r.row}
+}
+
+// Seek moves to pos, which must be at the reader's revision. Earlier shards are skipped without
+// being opened; rows before pos in its shard are read and discarded.
+func (r *MultiReader) Seek(pos Position) error {
+	if pos.Revision != r.revision {
+		return fmt.Errorf("cannot seek to revision %q in revision %q", pos.Revision, r.revision)
+	}
+	if pos.Shard == "" {
+		return nil
+	}
+	for len(r.paths) > 0 && r.paths[0] != pos.Shard {
+		r.paths = r.paths[1:]
+	}
+	if len(r.paths) == 0 {
+		return fmt.Errorf("shard %s not found", pos.Shard)
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	if err := r.next(); err != nil {
+		return err
+	}
+
+	buf := make([]map[string]interface{}, 256)
+	for r.row < pos.Row {
+		want := pos.Row - r.row
+		if want > int64(len(buf)) {
+			want = int64(len(buf))
+		}
+		n, err := r.current.Read(buf[:want])
+		r.row += int64(n)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the file being read.
+func (r *MultiReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
@@ -0,0 +1,228 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dllewellyn/reflex/internal/platform/schema"
+)
+
+// MemoryBroker is an in-process stand-in for a Kafka cluster. Unlike MemoryProducer and
+// MemoryConsumer, which are independent test doubles, messages published to a MemoryBroker can be
+// consumed from it: each topic is an ordered log, and each consumer group has its own committed
+// offset per topic, so several components can share topics as they would in production.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	topics  map[string][]Message
+	offsets map[string]map[string]int64 // group -> topic -> committed offset
+	// changed is closed and replaced whenever a message is published, waking waiting consumers.
+	changed chan struct{}
+}
+
+// NewMemoryBroker creates an empty MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:  make(map[string][]Message),
+		offsets: make(map[string]map[string]int64),
+		changed: make(chan struct{}),
+	}
+}
+
+// Publish appends message, encoded as JSON, to topic.
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, key string, message any) error {
+	val, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.topics[topic] = append(b.topics[topic], Message{
+		Topic:     topic,
+		Key:       key,
+		Value:     val,
+		Timestamp: time.Now(),
+		Offset:    int64(len(b.topics[topic])),
+	})
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return nil
+}
+
+// Messages returns every message published to topic.
+func (b *MemoryBroker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := make([]Message, len(b.topics[topic]))
+	copy(result, b.topics[topic])
+	return result
+}
+
+// Lag returns how many messages on topic group has not committed yet.
+func (b *MemoryBroker) Lag(group, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int64(len(b.topics[topic])) - b.offsets[group][topic]
+}
+
+// fetch returns the messages on topic from offset onwards, and a channel that is closed when
+// another message is published.
+func (b *MemoryBroker) fetch(topic string, offset int64) ([]Message, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	log := b.topics[topic]
+	if offset >= int64(len(log)) {
+		return nil, b.changed
+	}
+	result := make([]Message, int64(len(log))-offset)
+	copy(result, log[offset:])
+	return result, b.changed
+}
+
+func (b *MemoryBroker) committed(group, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.offsets[group][topic]
+}
+
+func (b *MemoryBroker) commit(group, topic string, offset int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.offsets[group] == nil {
+		b.offsets[group] = make(map[string]int64)
+	}
+	if offset > b.offsets[group][topic] {
+		b.offsets[group][topic] = offset
+	}
+}
+
+// Consumer returns a consumer in group. Consumption resumes from the group's committed offset
+// each time one of the Consume methods is called, as it would after a Kafka rebalance.
+func (b *MemoryBroker) Consumer(group string) *BrokerConsumer {
+	return &BrokerConsumer{broker: b, group: group, positions: make(map[string]int64)}
+}
+
+// BrokerConsumer consumes from a MemoryBroker on behalf of a consumer group. It implements
+// StreamingConsumer for interaction events, and Fetch/CommitMessage for other message types.
+type BrokerConsumer struct {
+	broker *MemoryBroker
+	group  string
+
+	mu        sync.Mutex
+	positions map[string]int64 // topic -> offset of the next message to consume
+}
+
+var _ StreamingConsumer = (*BrokerConsumer)(nil)
+
+// Fetch returns the messages on topic that have not been consumed yet, and marks them consumed.
+// The first Fetch for a topic starts from the group's committed offset.
+func (c *BrokerConsumer) Fetch(topic string) []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offset, ok := c.positions[topic]
+	if !ok {
+		offset = c.broker.committed(c.group, topic)
+	}
+	messages, _ := c.broker.fetch(topic, offset)
+	c.positions[topic] = offset + int64(len(messages))
+	return messages
+}
+
+// CommitMessage commits the group's offset on msg's topic past msg.
+func (c *BrokerConsumer) CommitMessage(msg Message) {
+	c.broker.commit(c.group, msg.Topic, msg.Offset+1)
+}
+
+// Commit commits the offsets of all messages consumed so far.
+func (c *BrokerConsumer) Commit() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, offset := range c.positions {
+		c.broker.commit(c.group, topic, offset)
+	}
+	return nil
+}
+
+// Close releases the consumer. Uncommitted messages are delivered again to the next consumer
+// in the group.
+func (c *BrokerConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.positions = make(map[string]int64)
+	return nil
+}
+
+// Consume delivers messages on topic to handler as they are published, until ctx is cancelled
+// or handler fails.
+func (c *BrokerConsumer) Consume(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error) error {
+	return c.consume(ctx, topic, handler, 0, nil, false)
+}
+
+// ConsumeBatch delivers every message on topic that has not been committed, then returns. The
+// topic is an in-process log, so there is nothing to wait for and timeout is ignored.
+func (c *BrokerConsumer) ConsumeBatch(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error, timeout time.Duration) error {
+	return c.consume(ctx, topic, handler, 0, nil, true)
+}
+
+// ConsumeStream delivers messages on topic to handler as they are published, calling tick after
+// each message and every interval, until ctx is cancelled or handler or tick fails.
+func (c *BrokerConsumer) ConsumeStream(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error, interval time.Duration, tick func(ctx context.Context) error) error {
+	return c.consume(ctx, topic, handler, interval, tick, false)
+}
+
+func (c *BrokerConsumer) consume(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error, interval time.Duration, tick func(ctx context.Context) error, once bool) error {
+	c.mu.Lock()
+	c.positions[topic] = c.broker.committed(c.group, topic)
+	c.mu.Unlock()
+
+	var ticks <-chan time.Time
+	if tick != nil {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		c.mu.Lock()
+		offset := c.positions[topic]
+		c.mu.Unlock()
+		messages, changed := c.broker.fetch(topic, offset)
+
+		for _, msg := range messages {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var event schema.InteractionEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				return fmt.Errorf("failed to decode message %d on %s: %w", msg.Offset, topic, err)
+			}
+			if err := handler(ctx, &event); err != nil {
+				return err
+			}
+			c.mu.Lock()
+			c.positions[topic] = msg.Offset + 1
+			c.mu.Unlock()
+			if tick != nil {
+				if err := tick(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-ticks:
+			if err := tick(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
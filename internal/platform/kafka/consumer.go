@@ -20,29 +20,45 @@ type ConfluentConsumer struct {
 	consumer *kafka.Consumer
 }
 
-// NewConsumer creates a new Kafka consumer.
+// ConsumerConfig holds the settings for a ConfluentConsumer.
+type ConsumerConfig struct {
+	BootstrapServers string
+	APIKey           string
+	APISecret        string
+	// SecurityProtocol defaults to SASL_SSL.
+	SecurityProtocol string
+	GroupID          string
+}
+
+// NewConsumer creates a new Kafka consumer configured from the KAFKA_* environment variables.
 func NewConsumer(ctx context.Context) (*ConfluentConsumer, error) {
-	bootstrapServers := os.Getenv("KAFKA_BOOTSTRAP_SERVERS")
-	apiKey := os.Getenv("KAFKA_API_KEY")
-	apiSecret := os.Getenv("KAFKA_API_SECRET")
-	consumerGroupID := os.Getenv("KAFKA_CONSUMER_GROUP_ID")
+	return NewConsumerWithConfig(ConsumerConfig{
+		BootstrapServers: os.Getenv("KAFKA_BOOTSTRAP_SERVERS"),
+		APIKey:           os.Getenv("KAFKA_API_KEY"),
+		APISecret:        os.Getenv("KAFKA_API_SECRET"),
+		SecurityProtocol: os.Getenv("KAFKA_SECURITY_PROTOCOL"),
+		GroupID:          os.Getenv("KAFKA_CONSUMER_GROUP_ID"),
+	})
+}
 
-	if bootstrapServers == "" || apiKey == "" || apiSecret == "" || consumerGroupID == "" {
+// NewConsumerWithConfig creates a new Kafka consumer from explicit settings.
+func NewConsumerWithConfig(cfg ConsumerConfig) (*ConfluentConsumer, error) {
+	if cfg.BootstrapServers == "" || cfg.APIKey == "" || cfg.APISecret == "" || cfg.GroupID == "" {
 		return nil, fmt.Errorf("KAFKA environment variables not set")
 	}
 
-	securityProtocol := os.Getenv("KAFKA_SECURITY_PROTOCOL")
+	securityProtocol := cfg.SecurityProtocol
 	if securityProtocol == "" {
 		securityProtocol = "SASL_SSL"
 	}
 
 	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers":  bootstrapServers,
+		"bootstrap.servers":  cfg.BootstrapServers,
 		"security.protocol":  securityProtocol,
 		"sasl.mechanisms":    "PLAIN",
-		"sasl.username":      apiKey,
-		"sasl.password":      apiSecret,
-		"group.id":           consumerGroupID,
+		"sasl.username":      cfg.APIKey,
+		"sasl.password":      cfg.APISecret,
+		"group.id":           cfg.GroupID,
 		"auto.offset.reset":  "earliest",
 		"enable.auto.commit": false,
 	})
@@ -158,6 +174,58 @@ func (c *ConfluentConsumer) ConsumeBatch(ctx context.Context, topic string, hand
 	}
 }
 
+// ConsumeStream consumes messages from the Kafka topic until the context is cancelled, calling tick
+// from the consuming goroutine every interval. Handler and tick errors stop consumption.
+func (c *ConfluentConsumer) ConsumeStream(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error, interval time.Duration, tick func(ctx context.Context) error) error {
+	err := c.consumer.SubscribeTopics([]string{topic}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to topic %s: %w", topic, err)
+	}
+
+	log.Printf("Kafka consumer subscribed to topic %s (Stream Mode), tick interval: %v", topic, interval)
+
+	lastTick := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// Proceed
+		}
+
+		msg, err := c.consumer.ReadMessage(interval)
+		if err == nil {
+			// Extract the context
+			propagator := otel.GetTextMapPropagator()
+			carrier := kafkaMessageCarrier{msg: msg}
+			msgCtx := propagator.Extract(context.Background(), carrier)
+
+			tr := otel.Tracer("kafka-consumer")
+			var span trace.Span
+			msgCtx, span = tr.Start(msgCtx, "kafka.consume")
+
+			var event schema.InteractionEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				slog.Warn("Error unmarshaling message", "error", err)
+			} else if err := handler(msgCtx, &event); err != nil {
+				span.RecordError(err)
+				span.End()
+				return fmt.Errorf("handler error: %w", err)
+			}
+			span.End()
+		} else if kafkaErr, ok := err.(kafka.Error); !ok || kafkaErr.Code() != kafka.ErrTimedOut {
+			log.Printf("Kafka consumer error: %v\n", err)
+		}
+
+		if time.Since(lastTick) >= interval {
+			lastTick = time.Now()
+			if err := tick(ctx); err != nil {
+				return fmt.Errorf("tick error: %w", err)
+			}
+		}
+	}
+}
+
 // Commit commits the offsets of all messages consumed so far.
 func (c *ConfluentConsumer) Commit() error {
 	// Commit the current assignment offsets.
@@ -25,3 +25,16 @@ type Consumer interface {
 	// Commit commits the offsets of all messages consumed so far.
 	Commit() error
 }
+
+// StreamingConsumer is a Consumer that can also run a periodic callback on the consuming goroutine.
+// Because the callback never runs concurrently with the handler, it can flush buffered messages and
+// call Commit without acknowledging a message that has been read but not yet handled.
+type StreamingConsumer interface {
+	Consumer
+
+	// ConsumeStream consumes messages from a Kafka topic, invoking handler for each message and tick
+	// whenever interval has elapsed since the last tick, including while the topic is idle.
+	// Unlike Consume, an error from handler or tick stops consumption and is returned.
+	// It blocks until the context is cancelled or an error occurs.
+	ConsumeStream(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error, interval time.Duration, tick func(ctx context.Context) error) error
+}
@@ -21,6 +21,8 @@ type Message struct {
 	Key       string
 	Value     []byte
 	Timestamp time.Time
+	// Offset is the message's position in its topic. It is only set by MemoryBroker.
+	Offset int64
 }
 
 // NewMemoryProducer creates a new in-memory Kafka producer.
@@ -154,6 +156,47 @@ func (m *MemoryConsumer) ConsumeBatch(ctx context.Context, topic string, handler
 	return nil
 }
 
+// ConsumeStream consumes messages from an in-memory topic, calling tick after each message and then
+// every interval while waiting for the context to be cancelled.
+func (m *MemoryConsumer) ConsumeStream(ctx context.Context, topic string, handler func(ctx context.Context, msg *schema.InteractionEvent) error, interval time.Duration, tick func(ctx context.Context) error) error {
+	m.mu.Lock()
+	messages := m.messages[topic]
+	startOffset := m.offset[topic]
+	msgsCopy := make([]schema.InteractionEvent, len(messages[startOffset:]))
+	copy(msgsCopy, messages[startOffset:])
+	m.mu.Unlock()
+
+	for i, event := range msgsCopy {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := handler(ctx, &event); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		m.offset[topic] = startOffset + i + 1
+		m.mu.Unlock()
+
+		if err := tick(ctx); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := tick(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // Clear resets the consumer state.
 func (m *MemoryConsumer) Clear() {
 	m.mu.Lock()
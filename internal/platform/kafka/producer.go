@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/magiconair/properties"
 	"go.opentelemetry.io/otel"
 )
 
@@ -13,6 +16,39 @@ type ConfluentProducer struct {
 	p *kafka.Producer
 }
 
+// ProducerConfig reads librdkafka settings from a properties file, falling back to a local broker
+// if the file does not exist. bootstrapServers and apiKey/apiSecret, when set, override the file
+// and enable SASL_SSL.
+func ProducerConfig(configFile, bootstrapServers, apiKey, apiSecret string) (*kafka.ConfigMap, error) {
+	cfg := &kafka.ConfigMap{"bootstrap.servers": "localhost:9092"}
+	props, err := properties.LoadFile(configFile, properties.UTF8)
+	switch {
+	case err == nil:
+		cfg = &kafka.ConfigMap{}
+		for _, key := range props.Keys() {
+			val, _ := props.Get(key)
+			(*cfg)[key] = val
+		}
+	case os.IsNotExist(err):
+		slog.Warn("Kafka config file not found, using defaults", "file", configFile)
+	default:
+		return nil, err
+	}
+
+	if bootstrapServers != "" {
+		slog.Info("Overriding bootstrap.servers from env")
+		(*cfg)["bootstrap.servers"] = bootstrapServers
+	}
+	if apiKey != "" {
+		slog.Info("Overriding SASL credentials from env")
+		(*cfg)["sasl.username"] = apiKey
+		(*cfg)["sasl.password"] = apiSecret
+		(*cfg)["security.protocol"] = "SASL_SSL"
+		(*cfg)["sasl.mechanisms"] = "PLAIN"
+	}
+	return cfg, nil
+}
+
 // NewProducer creates a new ConfluentProducer.
 // It returns a pointer to ConfluentProducer which implicitly implements the Producer interface.
 func NewProducer(cfg *kafka.ConfigMap) (*ConfluentProducer, error) {
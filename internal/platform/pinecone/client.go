@@ -3,6 +3,7 @@ package pinecone
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pinecone-io/go-pinecone/v4/pinecone"
 	"go.opentelemetry.io/otel"
@@ -10,12 +11,19 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
-// Client is a wrapper around the Pinecone SDK.
+// defaultNamespace is how Pinecone reports the default namespace in index stats.
+const defaultNamespace = "__default__"
+
+// Client is a wrapper around the Pinecone SDK. Text is embedded by the index's integrated
+// inference model, an E5 model, so it is prefixed following E5Prefixes.
 type Client struct {
 	client        *pinecone.Client
 	idxConnection *pinecone.IndexConnection
+	prefixes      Prefixes
 }
 
+var _ VectorStore = (*Client)(nil)
+
 // NewClient creates a new Pinecone client and initializes the index connection.
 func NewClient(ctx context.Context, apiKey, indexHost string) (*Client, error) {
 	pc, err := pinecone.NewClient(pinecone.NewClientParams{
@@ -35,6 +43,7 @@ func NewClient(ctx context.Context, apiKey, indexHost string) (*Client, error) {
 	return &Client{
 		client:        pc,
 		idxConnection: idxConnection,
+		prefixes:      E5Prefixes,
 	}, nil
 }
 
@@ -92,7 +101,7 @@ func (c *Client) UpsertInputs(ctx context.Context, inputs []*InputRecord) error
 
 		record := pinecone.IntegratedRecord{
 			"id":         v.ID,
-			"chunk_text": "passage: " + v.Text,
+			"chunk_text": c.prefixes.Document + v.Text,
 		}
 
 		// Add metadata fields to the record
@@ -111,8 +120,9 @@ func (c *Client) UpsertInputs(ctx context.Context, inputs []*InputRecord) error
 	return nil
 }
 
-// QueryInput queries the index using a text input for integrated inference.
-func (c *Client) QueryInput(ctx context.Context, text string, topK int) ([]*Match, error) {
+// QueryInput queries the index using a text input for integrated inference, among the records
+// matching filter.
+func (c *Client) QueryInput(ctx context.Context, text string, topK int, filter Filter) ([]*Match, error) {
 	tr := otel.Tracer("pinecone-client")
 	ctx, span := tr.Start(ctx, "Pinecone.QueryInput")
 	defer span.End()
@@ -123,15 +133,25 @@ func (c *Client) QueryInput(ctx context.Context, text string, topK int) ([]*Matc
 	)
 
 	inputs := map[string]interface{}{
-		"text": "query: " + text,
+		"text": c.prefixes.Query + text,
 	}
 
-	resp, err := c.idxConnection.SearchRecords(ctx, &pinecone.SearchRecordsRequest{
-		Query: pinecone.SearchRecordsQuery{
-			Inputs: &inputs,
-			TopK:   int32(topK),
-		},
-	})
+	query := pinecone.SearchRecordsQuery{
+		Inputs: &inputs,
+		TopK:   int32(topK),
+	}
+	if len(filter) > 0 {
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+		pcFilter, err := filter.toJSON()
+		if err != nil {
+			return nil, err
+		}
+		query.Filter = &pcFilter
+	}
+
+	resp, err := c.idxConnection.SearchRecords(ctx, &pinecone.SearchRecordsRequest{Query: query})
 	if err != nil {
 		return nil, fmt.Errorf("failed to search records: %w", err)
 	}
@@ -141,7 +161,7 @@ func (c *Client) QueryInput(ctx context.Context, text string, topK int) ([]*Matc
 		matches[i] = &Match{
 			ID:       hit.Id,
 			Score:    hit.Score,
-			Metadata: hit.Fields,
+			Metadata: c.trimDocumentPrefix(hit.Fields),
 		}
 	}
 
@@ -164,13 +184,29 @@ func (c *Client) Fetch(ctx context.Context, ids []string) (map[string]*Vector, e
 		vectors[id] = &Vector{
 			ID:       vec.Id,
 			Values:   *vec.Values,
-			Metadata: vec.Metadata.AsMap(),
+			Metadata: c.trimDocumentPrefix(vec.Metadata.AsMap()),
 		}
 	}
 
 	return vectors, nil
 }
 
+// UpdateMetadata sets metadata fields of the vector with id.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	tr := otel.Tracer("pinecone-client")
+	ctx, span := tr.Start(ctx, "Pinecone.UpdateMetadata")
+	defer span.End()
+
+	fields, err := structpb.NewStruct(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to convert metadata to protobuf struct: %w", err)
+	}
+	if err := c.idxConnection.UpdateVector(ctx, &pinecone.UpdateVectorRequest{Id: id, Metadata: fields}); err != nil {
+		return fmt.Errorf("failed to update vector %s: %w", id, err)
+	}
+	return nil
+}
+
 // DescribeIndexStats retrieves statistics about the index.
 func (c *Client) DescribeIndexStats(ctx context.Context) (*IndexStats, error) {
 	tr := otel.Tracer("pinecone-client")
@@ -182,18 +218,122 @@ func (c *Client) DescribeIndexStats(ctx context.Context) (*IndexStats, error) {
 		return nil, fmt.Errorf("failed to describe index stats: %w", err)
 	}
 
+	namespaces := make(map[string]uint32, len(resp.Namespaces))
+	for name, summary := range resp.Namespaces {
+		if name == defaultNamespace {
+			name = ""
+		}
+		namespaces[name] = summary.VectorCount
+	}
+
 	return &IndexStats{
 		TotalVectorCount: resp.TotalVectorCount,
+		Namespaces:       namespaces,
 	}, nil
 }
 
-// DeleteAll deletes all vectors from the index.
+// List lists record IDs by prefix. Pinecone only supports listing serverless indexes.
+func (c *Client) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	tr := otel.Tracer("pinecone-client")
+	ctx, span := tr.Start(ctx, "Pinecone.List")
+	defer span.End()
+
+	limit := uint32(opts.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+	req := &pinecone.ListVectorsRequest{Limit: &limit}
+	if opts.Prefix != "" {
+		req.Prefix = &opts.Prefix
+	}
+	if opts.PaginationToken != "" {
+		req.PaginationToken = &opts.PaginationToken
+	}
+
+	resp, err := c.idxConnection.ListVectors(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vectors: %w", err)
+	}
+
+	page := &ListPage{IDs: make([]string, 0, len(resp.VectorIds))}
+	for _, id := range resp.VectorIds {
+		if id != nil {
+			page.IDs = append(page.IDs, *id)
+		}
+	}
+	if resp.NextPaginationToken != nil {
+		page.NextPaginationToken = *resp.NextPaginationToken
+	}
+	return page, nil
+}
+
+// Delete deletes vectors by their IDs.
+func (c *Client) Delete(ctx context.Context, ids []string) error {
+	tr := otel.Tracer("pinecone-client")
+	ctx, span := tr.Start(ctx, "Pinecone.Delete")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("pinecone.delete_count", len(ids)),
+	)
+
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := c.idxConnection.DeleteVectorsById(ctx, ids); err != nil {
+		return fmt.Errorf("failed to delete vectors: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByFilter deletes the vectors matching filter. Serverless indexes do not support deleting
+// by metadata, so there the matching IDs have to be found with QueryInput or List and passed to
+// Delete instead.
+func (c *Client) DeleteByFilter(ctx context.Context, filter Filter) error {
+	tr := otel.Tracer("pinecone-client")
+	ctx, span := tr.Start(ctx, "Pinecone.DeleteByFilter")
+	defer span.End()
+
+	if err := ValidateDeleteFilter(filter); err != nil {
+		return err
+	}
+	pcFilter, err := filter.toJSON()
+	if err != nil {
+		return err
+	}
+	metadataFilter, err := structpb.NewStruct(pcFilter)
+	if err != nil {
+		return fmt.Errorf("failed to convert filter to protobuf struct: %w", err)
+	}
+
+	if err := c.idxConnection.DeleteVectorsByFilter(ctx, metadataFilter); err != nil {
+		return fmt.Errorf("failed to delete vectors by filter: %w", err)
+	}
+
+	return nil
+}
+
+// Namespace returns the namespace the client targets.
+func (c *Client) Namespace() string {
+	return c.idxConnection.Namespace()
+}
+
+// WithNamespace returns a client for namespace sharing this client's connection.
+func (c *Client) WithNamespace(namespace string) VectorStore {
+	return &Client{
+		client:        c.client,
+		idxConnection: c.idxConnection.WithNamespace(namespace),
+		prefixes:      c.prefixes,
+	}
+}
+
+// DeleteAll deletes all vectors in the client's namespace.
 func (c *Client) DeleteAll(ctx context.Context) error {
 	tr := otel.Tracer("pinecone-client")
 	ctx, span := tr.Start(ctx, "Pinecone.DeleteAll")
 	defer span.End()
 
-	// Use DeleteAllVectorsInNamespace with empty namespace (default)
 	err := c.idxConnection.DeleteAllVectorsInNamespace(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to delete all vectors: %w", err)
@@ -201,3 +341,12 @@ func (c *Client) DeleteAll(ctx context.Context) error {
 
 	return nil
 }
+
+// trimDocumentPrefix removes the prefix UpsertInputs adds to chunk_text, so callers get back the
+// text they stored.
+func (c *Client) trimDocumentPrefix(metadata map[string]interface{}) map[string]interface{} {
+	if text, ok := metadata["chunk_text"].(string); ok {
+		metadata["chunk_text"] = strings.TrimPrefix(text, c.prefixes.Document)
+	}
+	return metadata
+}
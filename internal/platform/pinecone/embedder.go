@@ -0,0 +1,177 @@
+package pinecone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Embedder computes vectors for text on the client side, for stores without integrated inference.
+type Embedder interface {
+	// EmbedDocuments returns a vector for each text to be stored.
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+	// EmbedQuery returns a vector for text to search for.
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+	// Dimension is the length of the vectors returned.
+	Dimension() int
+}
+
+// Prefixes are prepended to text before it is embedded. E5 models, such as the
+// multilingual-e5-large model behind the Pinecone index, are trained with different prefixes for
+// stored passages and queries and score poorly without them.
+type Prefixes struct {
+	Document string
+	Query    string
+}
+
+// E5Prefixes is the prefix convention of E5 embedding models.
+var E5Prefixes = Prefixes{Document: "passage: ", Query: "query: "}
+
+// NGramEmbedder embeds text by hashing its character n-grams, lower-cased with whitespace
+// collapsed, into a unit vector. It needs no model and finds near-identical strings, which is
+// enough to run the pipeline offline, but it does not capture meaning.
+type NGramEmbedder struct {
+	n         int
+	dimension int
+}
+
+var _ Embedder = (*NGramEmbedder)(nil)
+
+// NewNGramEmbedder creates an NGramEmbedder hashing n-grams of n characters (3 if n <= 0) into
+// vectors of dimension (512 if dimension <= 0).
+func NewNGramEmbedder(n, dimension int) *NGramEmbedder {
+	if n <= 0 {
+		n = 3
+	}
+	if dimension <= 0 {
+		dimension = 512
+	}
+	return &NGramEmbedder{n: n, dimension: dimension}
+}
+
+func (e *NGramEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *NGramEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func (e *NGramEmbedder) Dimension() int {
+	return e.dimension
+}
+
+func (e *NGramEmbedder) embed(text string) []float32 {
+	runes := []rune(" " + strings.Join(strings.Fields(strings.ToLower(text)), " ") + " ")
+	values := make([]float32, e.dimension)
+	for i := 0; i+e.n <= len(runes); i++ {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(string(runes[i : i+e.n])))
+		values[h.Sum32()%uint32(e.dimension)]++
+	}
+	return normalize(values)
+}
+
+// HTTPEmbedderConfig configures an HTTPEmbedder.
+type HTTPEmbedderConfig struct {
+	// URL is the API base, e.g. http://localhost:8081/v1; requests go to URL/embeddings.
+	URL    string
+	Model  string
+	APIKey string
+	// Dimension is the length of the vectors the model returns.
+	Dimension int
+	Prefixes  Prefixes
+}
+
+// HTTPEmbedder computes embeddings with an OpenAI-compatible /embeddings endpoint, as served by
+// text-embeddings-inference, Ollama, vLLM and most hosted APIs.
+type HTTPEmbedder struct {
+	httpClient *http.Client
+	config     HTTPEmbedderConfig
+}
+
+var _ Embedder = (*HTTPEmbedder)(nil)
+
+// NewHTTPEmbedder creates an HTTPEmbedder.
+func NewHTTPEmbedder(httpClient *http.Client, cfg HTTPEmbedderConfig) *HTTPEmbedder {
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	return &HTTPEmbedder{httpClient: httpClient, config: cfg}
+}
+
+func (e *HTTPEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		inputs[i] = e.config.Prefixes.Document + text
+	}
+	return e.embed(ctx, inputs)
+}
+
+func (e *HTTPEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.embed(ctx, []string{e.config.Prefixes.Query + text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *HTTPEmbedder) Dimension() int {
+	return e.config.Dimension
+}
+
+func (e *HTTPEmbedder) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.config.Model, "input": inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("embedding request failed with status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings: %w", err)
+	}
+	if len(out.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(out.Data))
+	}
+	vectors := make([][]float32, len(inputs))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		if e.config.Dimension > 0 && len(d.Embedding) != e.config.Dimension {
+			return nil, fmt.Errorf("expected embeddings of dimension %d, got %d", e.config.Dimension, len(d.Embedding))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
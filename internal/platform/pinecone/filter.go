@@ -0,0 +1,344 @@
+package pinecone
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Filter selects records by their metadata, in Pinecone's metadata filter syntax, e.g.
+//
+//	Filter{"label": Filter{"$in": []string{"injection", "jailbreak"}}, "source": "extract"}
+//
+// Each key is either a metadata field, whose condition is a value (meaning $eq) or a map of
+// operators, or $and/$or with a list of filters. The operators are $eq, $ne, $gt, $gte, $lt,
+// $lte, $in, $nin and $exists. A field holding a list matches $eq and $in if any element does.
+// As in Pinecone, $ne and $nin also match records without the field. A nil or empty Filter
+// matches every record.
+type Filter map[string]interface{}
+
+// LabelFilter matches records whose label is one of labels.
+func LabelFilter(labels ...string) Filter {
+	return Filter{"label": Filter{"$in": labels}}
+}
+
+// ValidateDeleteFilter checks a filter for DeleteByFilter, rejecting an empty one, which would
+// delete every record.
+func ValidateDeleteFilter(filter Filter) error {
+	if len(filter) == 0 {
+		return fmt.Errorf("delete filter must not be empty, use DeleteAll to delete every record")
+	}
+	return filter.Validate()
+}
+
+// Validate checks that f only uses supported operators with operands of the right type.
+func (f Filter) Validate() error {
+	for key, cond := range f {
+		switch key {
+		case "$and", "$or":
+			subs, err := subFilters(key, cond)
+			if err != nil {
+				return err
+			}
+			for _, sub := range subs {
+				if err := sub.Validate(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "$") {
+			return fmt.Errorf("unsupported filter operator %s", key)
+		}
+		ops, ok := operators(cond)
+		if !ok {
+			if !isScalar(cond) {
+				return fmt.Errorf("filter on %s: unsupported value %v", key, cond)
+			}
+			continue
+		}
+		for op, operand := range ops {
+			switch op {
+			case "$eq", "$ne":
+				if !isScalar(operand) {
+					return fmt.Errorf("filter on %s: %s needs a string, number or boolean", key, op)
+				}
+			case "$gt", "$gte", "$lt", "$lte":
+				if _, ok := toFloat(operand); !ok {
+					return fmt.Errorf("filter on %s: %s needs a number", key, op)
+				}
+			case "$in", "$nin":
+				if _, ok := toList(operand); !ok {
+					return fmt.Errorf("filter on %s: %s needs a list", key, op)
+				}
+			case "$exists":
+				if _, ok := operand.(bool); !ok {
+					return fmt.Errorf("filter on %s: $exists needs a boolean", key)
+				}
+			default:
+				return fmt.Errorf("filter on %s: unsupported operator %s", key, op)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether metadata passes the filter. Invalid conditions never match.
+func (f Filter) Match(metadata map[string]interface{}) bool {
+	for key, cond := range f {
+		switch key {
+		case "$and", "$or":
+			subs, err := subFilters(key, cond)
+			if err != nil {
+				return false
+			}
+			anyMatched := false
+			for _, sub := range subs {
+				matched := sub.Match(metadata)
+				if key == "$and" && !matched {
+					return false
+				}
+				anyMatched = anyMatched || matched
+			}
+			if key == "$or" && !anyMatched {
+				return false
+			}
+			continue
+		}
+		value, present := metadata[key]
+		ops, ok := operators(cond)
+		if !ok {
+			ops = map[string]interface{}{"$eq": cond}
+		}
+		for op, operand := range ops {
+			if !matchOperator(op, operand, value, present) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Normalize validates f and returns it in a canonical form for translating to other filter
+// languages: every field condition is a Filter of operators, $and and $or hold a []Filter and
+// $in and $nin hold a []interface{}.
+func (f Filter) Normalize() (Filter, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	out := make(Filter, len(f))
+	for key, cond := range f {
+		if key == "$and" || key == "$or" {
+			subs, _ := subFilters(key, cond)
+			normalized := make([]Filter, len(subs))
+			for i, sub := range subs {
+				normalized[i], _ = sub.Normalize()
+			}
+			out[key] = normalized
+			continue
+		}
+		ops, ok := operators(cond)
+		if !ok {
+			ops = map[string]interface{}{"$eq": cond}
+		}
+		normalized := make(Filter, len(ops))
+		for op, operand := range ops {
+			if op == "$in" || op == "$nin" {
+				operand, _ = toList(operand)
+			}
+			normalized[op] = operand
+		}
+		out[key] = normalized
+	}
+	return out, nil
+}
+
+// toJSON converts f to plain JSON values, e.g. []string to []interface{}, as the Pinecone SDK
+// needs them.
+func (f Filter) toJSON() (map[string]interface{}, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode filter: %w", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to encode filter: %w", err)
+	}
+	return out, nil
+}
+
+func matchOperator(op string, operand, value interface{}, present bool) bool {
+	switch op {
+	case "$exists":
+		want, _ := operand.(bool)
+		return present == want
+	case "$ne":
+		return !present || !anyEqual(value, operand)
+	case "$nin":
+		list, _ := toList(operand)
+		for _, item := range list {
+			if present && anyEqual(value, item) {
+				return false
+			}
+		}
+		return true
+	}
+	if !present {
+		return false
+	}
+	switch op {
+	case "$eq":
+		return anyEqual(value, operand)
+	case "$in":
+		list, _ := toList(operand)
+		for _, item := range list {
+			if anyEqual(value, item) {
+				return true
+			}
+		}
+		return false
+	}
+	v, vok := toFloat(value)
+	o, ook := toFloat(operand)
+	if !vok || !ook {
+		return false
+	}
+	switch op {
+	case "$gt":
+		return v > o
+	case "$gte":
+		return v >= o
+	case "$lt":
+		return v < o
+	case "$lte":
+		return v <= o
+	}
+	return false
+}
+
+// anyEqual reports whether value, or any element of it if it is a list, equals operand.
+func anyEqual(value, operand interface{}) bool {
+	if list, ok := toList(value); ok {
+		for _, item := range list {
+			if scalarEqual(item, operand) {
+				return true
+			}
+		}
+		return false
+	}
+	return scalarEqual(value, operand)
+}
+
+func scalarEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return a == b
+}
+
+// operators returns cond as a map of operators, if it is one.
+func operators(cond interface{}) (map[string]interface{}, bool) {
+	var m map[string]interface{}
+	switch c := cond.(type) {
+	case Filter:
+		m = c
+	case map[string]interface{}:
+		m = c
+	default:
+		return nil, false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+func subFilters(key string, cond interface{}) ([]Filter, error) {
+	switch c := cond.(type) {
+	case []Filter:
+		return c, nil
+	case []map[string]interface{}:
+		subs := make([]Filter, len(c))
+		for i, m := range c {
+			subs[i] = m
+		}
+		return subs, nil
+	case []interface{}:
+		subs := make([]Filter, len(c))
+		for i, item := range c {
+			switch m := item.(type) {
+			case Filter:
+				subs[i] = m
+			case map[string]interface{}:
+				subs[i] = m
+			default:
+				return nil, fmt.Errorf("%s needs a list of filters", key)
+			}
+		}
+		return subs, nil
+	}
+	return nil, fmt.Errorf("%s needs a list of filters", key)
+}
+
+func isScalar(v interface{}) bool {
+	if _, ok := toFloat(v); ok {
+		return true
+	}
+	switch v.(type) {
+	case string, bool:
+		return true
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case []string:
+		out := make([]interface{}, len(l))
+		for i, s := range l {
+			out[i] = s
+		}
+		return out, true
+	case []float64:
+		out := make([]interface{}, len(l))
+		for i, n := range l {
+			out[i] = n
+		}
+		return out, true
+	case []int:
+		out := make([]interface{}, len(l))
+		for i, n := range l {
+			out[i] = n
+		}
+		return out, true
+	}
+	return nil, false
+}
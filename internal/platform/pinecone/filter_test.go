@@ -0,0 +1,88 @@
+package pinecone_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+)
+
+func TestFilter_Match(t *testing.T) {
+	metadata := map[string]interface{}{
+		"label":      "injection",
+		"source":     "extract",
+		"confidence": 0.9,
+		"seen_count": 3,
+		"tags":       []interface{}{"dan", "roleplay"},
+	}
+	tests := []struct {
+		name   string
+		filter pinecone.Filter
+		match  bool
+	}{
+		{name: "nil", filter: nil, match: true},
+		{name: "implicit eq", filter: pinecone.Filter{"label": "injection"}, match: true},
+		{name: "implicit eq mismatch", filter: pinecone.Filter{"label": "benign"}, match: false},
+		{name: "eq across number types", filter: pinecone.Filter{"seen_count": pinecone.Filter{"$eq": 3.0}}, match: true},
+		{name: "ne", filter: pinecone.Filter{"label": pinecone.Filter{"$ne": "benign"}}, match: true},
+		{name: "ne matches a missing field", filter: pinecone.Filter{"reviewer": pinecone.Filter{"$ne": "alice"}}, match: true},
+		{name: "in", filter: pinecone.LabelFilter("injection", "jailbreak"), match: true},
+		{name: "nin", filter: pinecone.Filter{"label": pinecone.Filter{"$nin": []string{"injection"}}}, match: false},
+		{name: "nin matches a missing field", filter: pinecone.Filter{"reviewer": pinecone.Filter{"$nin": []string{"alice"}}}, match: true},
+		{name: "list field eq", filter: pinecone.Filter{"tags": "dan"}, match: true},
+		{name: "list field in", filter: pinecone.Filter{"tags": pinecone.Filter{"$in": []string{"roleplay", "other"}}}, match: true},
+		{name: "list field ne", filter: pinecone.Filter{"tags": pinecone.Filter{"$ne": "dan"}}, match: false},
+		{name: "range", filter: pinecone.Filter{"confidence": pinecone.Filter{"$gt": 0.5, "$lte": 0.9}}, match: true},
+		{name: "range mismatch", filter: pinecone.Filter{"confidence": pinecone.Filter{"$lt": 0.9}}, match: false},
+		{name: "range on a string", filter: pinecone.Filter{"label": pinecone.Filter{"$gt": 1}}, match: false},
+		{name: "range on a missing field", filter: pinecone.Filter{"weight": pinecone.Filter{"$lt": 1}}, match: false},
+		{name: "exists", filter: pinecone.Filter{"confidence": pinecone.Filter{"$exists": true}}, match: true},
+		{name: "not exists", filter: pinecone.Filter{"weight": pinecone.Filter{"$exists": false}}, match: true},
+		{name: "and", filter: pinecone.Filter{"$and": []pinecone.Filter{{"label": "injection"}, {"source": "dataset"}}}, match: false},
+		{name: "or", filter: pinecone.Filter{"$or": []interface{}{map[string]interface{}{"source": "dataset"}, pinecone.Filter{"source": "extract"}}}, match: true},
+		{name: "invalid sub-filters", filter: pinecone.Filter{"$or": "source"}, match: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.filter.Match(metadata))
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	for name, filter := range map[string]pinecone.Filter{
+		"unknown top-level operator": {"$not": pinecone.Filter{"label": "benign"}},
+		"unknown field operator":     {"label": pinecone.Filter{"$like": "inj%"}},
+		"non-scalar value":           {"label": []string{"injection"}},
+		"range of a string":          {"confidence": pinecone.Filter{"$gt": "high"}},
+		"in of a scalar":             {"label": pinecone.Filter{"$in": "injection"}},
+		"exists of a string":         {"label": pinecone.Filter{"$exists": "yes"}},
+		"and of a filter":            {"$and": pinecone.Filter{"label": "injection"}},
+		"invalid nested filter":      {"$or": []pinecone.Filter{{"label": pinecone.Filter{"$regex": "."}}}},
+	} {
+		assert.Error(t, filter.Validate(), name)
+	}
+	assert.NoError(t, pinecone.Filter{"$or": []pinecone.Filter{{"label": "injection"}, {"seen_count": pinecone.Filter{"$gte": 2}}}}.Validate())
+
+	assert.Error(t, pinecone.ValidateDeleteFilter(nil), "an empty delete filter would delete everything")
+	assert.NoError(t, pinecone.ValidateDeleteFilter(pinecone.Filter{"source": "extract"}))
+}
+
+func TestFilter_Normalize(t *testing.T) {
+	normalized, err := pinecone.Filter{
+		"label": "injection",
+		"tags":  pinecone.Filter{"$in": []string{"dan"}},
+		"$or":   []interface{}{map[string]interface{}{"source": "extract"}},
+	}.Normalize()
+	require.NoError(t, err)
+	assert.Equal(t, pinecone.Filter{
+		"label": pinecone.Filter{"$eq": "injection"},
+		"tags":  pinecone.Filter{"$in": []interface{}{"dan"}},
+		"$or":   []pinecone.Filter{{"source": pinecone.Filter{"$eq": "extract"}}},
+	}, normalized)
+
+	_, err = pinecone.Filter{"label": pinecone.Filter{"$like": "inj%"}}.Normalize()
+	assert.Error(t, err)
+}
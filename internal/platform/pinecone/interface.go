@@ -25,15 +25,86 @@ type Match struct {
 
 // IndexStats represents statistics about the index.
 type IndexStats struct {
+	// TotalVectorCount counts the vectors in every namespace.
 	TotalVectorCount uint32
+	// Namespaces counts the vectors in each namespace the store reports, keyed by name; the
+	// default namespace is "".
+	Namespaces map[string]uint32
 }
 
-// VectorStore defines the interface for vector storage operations.
+// ListOptions selects a page of record IDs.
+type ListOptions struct {
+	// Prefix restricts the page to IDs starting with it.
+	Prefix string
+	// Limit is the page size; zero means 100.
+	Limit int
+	// PaginationToken continues from the page that returned it.
+	PaginationToken string
+}
+
+// ListPage is a page of record IDs. NextPaginationToken is empty on the last page.
+type ListPage struct {
+	IDs                 []string
+	NextPaginationToken string
+}
+
+// VectorStore defines the interface for vector storage operations. Every operation except
+// DescribeIndexStats applies to the store's namespace, the default one unless the store was
+// returned by WithNamespace.
 type VectorStore interface {
 	UpsertBatch(ctx context.Context, vectors []*Vector) error
 	UpsertInputs(ctx context.Context, inputs []*InputRecord) error
-	QueryInput(ctx context.Context, text string, topK int) ([]*Match, error)
+	// QueryInput returns the topK records most similar to text among those matching filter,
+	// which may be nil.
+	QueryInput(ctx context.Context, text string, topK int, filter Filter) ([]*Match, error)
 	Fetch(ctx context.Context, ids []string) (map[string]*Vector, error)
+	// UpdateMetadata sets the given metadata fields of the record with id, keeping its other
+	// fields and its values. Updating a record that is not stored does nothing.
+	UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}) error
+	// List returns a page of the stored record IDs, see ListPaginator.
+	List(ctx context.Context, opts ListOptions) (*ListPage, error)
+	// Delete removes the records with ids; IDs that are not stored are ignored.
+	Delete(ctx context.Context, ids []string) error
+	// DeleteByFilter removes the records matching filter, which must not be empty.
+	DeleteByFilter(ctx context.Context, filter Filter) error
 	DeleteAll(ctx context.Context) error
 	DescribeIndexStats(ctx context.Context) (*IndexStats, error)
+	// Namespace returns the store's namespace; "" is the default namespace.
+	Namespace() string
+	// WithNamespace returns a store for namespace sharing this store's connection.
+	WithNamespace(namespace string) VectorStore
+}
+
+// ListPaginator walks every page of a store's record IDs.
+//
+//	p := pinecone.NewListPaginator(store, pinecone.ListOptions{Prefix: "extract-"})
+//	for p.HasMorePages() {
+//		ids, err := p.NextPage(ctx)
+//		...
+//	}
+type ListPaginator struct {
+	store   VectorStore
+	opts    ListOptions
+	started bool
+}
+
+// NewListPaginator creates a paginator starting from opts.PaginationToken, if set.
+func NewListPaginator(store VectorStore, opts ListOptions) *ListPaginator {
+	return &ListPaginator{store: store, opts: opts}
+}
+
+// HasMorePages reports whether NextPage has another page to return.
+func (p *ListPaginator) HasMorePages() bool {
+	return !p.started || p.opts.PaginationToken != ""
+}
+
+// NextPage returns the next page of IDs. A page may be empty without being the last one.
+func (p *ListPaginator) NextPage(ctx context.Context) ([]string, error) {
+	page, err := p.store.List(ctx, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	p.started = true
+	p.opts.PaginationToken = page.NextPaginationToken
+	return page.IDs, nil
 }
@@ -0,0 +1,335 @@
+package pinecone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is an in-process VectorStore, for running offline and in tests. Text inputs are
+// embedded by an Embedder, and queries rank every stored vector of the same dimension by cosine
+// similarity. The contents can be saved to and loaded from a snapshot file.
+type MemoryStore struct {
+	embedder  Embedder
+	namespace string
+	data      *memoryData
+}
+
+// memoryData holds the vectors of every namespace, shared by the stores WithNamespace returns.
+type memoryData struct {
+	mu         sync.RWMutex
+	namespaces map[string]map[string]*Vector
+}
+
+var _ VectorStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty MemoryStore. embedder may be nil, in which case text is
+// embedded by an NGramEmbedder hashing character trigrams.
+func NewMemoryStore(embedder Embedder) *MemoryStore {
+	if embedder == nil {
+		embedder = NewNGramEmbedder(3, 512)
+	}
+	return &MemoryStore{embedder: embedder, data: &memoryData{namespaces: make(map[string]map[string]*Vector)}}
+}
+
+// vectors returns the namespace's vectors, creating the namespace if create is set. The caller
+// must hold the lock.
+func (m *MemoryStore) vectors(create bool) map[string]*Vector {
+	vectors, ok := m.data.namespaces[m.namespace]
+	if !ok && create {
+		vectors = make(map[string]*Vector)
+		m.data.namespaces[m.namespace] = vectors
+	}
+	return vectors
+}
+
+func (m *MemoryStore) UpsertBatch(ctx context.Context, vectors []*Vector) error {
+	m.data.mu.Lock()
+	defer m.data.mu.Unlock()
+	stored := m.vectors(true)
+	for _, v := range vectors {
+		stored[v.ID] = &Vector{ID: v.ID, Values: normalize(v.Values), Metadata: copyMetadata(v.Metadata)}
+	}
+	return nil
+}
+
+// UpsertInputs embeds and stores text records. As with Pinecone's integrated inference, the text
+// is kept in the chunk_text field.
+func (m *MemoryStore) UpsertInputs(ctx context.Context, inputs []*InputRecord) error {
+	texts := make([]string, len(inputs))
+	for i, in := range inputs {
+		texts[i] = in.Text
+	}
+	values, err := m.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed inputs: %w", err)
+	}
+
+	m.data.mu.Lock()
+	defer m.data.mu.Unlock()
+	stored := m.vectors(true)
+	for i, in := range inputs {
+		metadata := copyMetadata(in.Metadata)
+		metadata["chunk_text"] = in.Text
+		stored[in.ID] = &Vector{ID: in.ID, Values: normalize(values[i]), Metadata: metadata}
+	}
+	return nil
+}
+
+// QueryInput returns the topK records matching filter most similar to text by cosine similarity.
+func (m *MemoryStore) QueryInput(ctx context.Context, text string, topK int, filter Filter) ([]*Match, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	query, err := m.embedder.EmbedQuery(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	query = normalize(query)
+
+	m.data.mu.RLock()
+	stored := m.vectors(false)
+	matches := make([]*Match, 0, len(stored))
+	for _, v := range stored {
+		if len(v.Values) != len(query) || !filter.Match(v.Metadata) {
+			continue
+		}
+		var score float32
+		for i := range query {
+			score += query[i] * v.Values[i]
+		}
+		matches = append(matches, &Match{ID: v.ID, Score: score, Metadata: copyMetadata(v.Metadata)})
+	}
+	m.data.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+func (m *MemoryStore) Fetch(ctx context.Context, ids []string) (map[string]*Vector, error) {
+	m.data.mu.RLock()
+	defer m.data.mu.RUnlock()
+	stored := m.vectors(false)
+	vectors := make(map[string]*Vector)
+	for _, id := range ids {
+		if v, ok := stored[id]; ok {
+			vectors[id] = &Vector{ID: v.ID, Values: append([]float32(nil), v.Values...), Metadata: copyMetadata(v.Metadata)}
+		}
+	}
+	return vectors, nil
+}
+
+func (m *MemoryStore) UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	m.data.mu.Lock()
+	defer m.data.mu.Unlock()
+	v, ok := m.vectors(false)[id]
+	if !ok {
+		return nil
+	}
+	updated := copyMetadata(v.Metadata)
+	if updated == nil {
+		updated = make(map[string]interface{}, len(metadata))
+	}
+	for k, value := range metadata {
+		updated[k] = value
+	}
+	v.Metadata = updated
+	return nil
+}
+
+// List returns IDs in order; the pagination token is the last ID of the previous page.
+func (m *MemoryStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	m.data.mu.RLock()
+	var ids []string
+	for id := range m.vectors(false) {
+		if strings.HasPrefix(id, opts.Prefix) && id > opts.PaginationToken {
+			ids = append(ids, id)
+		}
+	}
+	m.data.mu.RUnlock()
+	sort.Strings(ids)
+
+	page := &ListPage{IDs: ids}
+	if len(ids) > limit {
+		page.IDs = ids[:limit]
+		page.NextPaginationToken = ids[limit-1]
+	}
+	return page, nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, ids []string) error {
+	m.data.mu.Lock()
+	defer m.data.mu.Unlock()
+	stored := m.vectors(false)
+	for _, id := range ids {
+		delete(stored, id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteByFilter(ctx context.Context, filter Filter) error {
+	if err := ValidateDeleteFilter(filter); err != nil {
+		return err
+	}
+	m.data.mu.Lock()
+	defer m.data.mu.Unlock()
+	stored := m.vectors(false)
+	for id, v := range stored {
+		if filter.Match(v.Metadata) {
+			delete(stored, id)
+		}
+	}
+	return nil
+}
+
+// DeleteAll deletes every vector in the store's namespace.
+func (m *MemoryStore) DeleteAll(ctx context.Context) error {
+	m.data.mu.Lock()
+	defer m.data.mu.Unlock()
+	delete(m.data.namespaces, m.namespace)
+	return nil
+}
+
+func (m *MemoryStore) DescribeIndexStats(ctx context.Context) (*IndexStats, error) {
+	m.data.mu.RLock()
+	defer m.data.mu.RUnlock()
+	stats := &IndexStats{Namespaces: make(map[string]uint32)}
+	for name, vectors := range m.data.namespaces {
+		if len(vectors) == 0 {
+			continue
+		}
+		stats.Namespaces[name] = uint32(len(vectors))
+		stats.TotalVectorCount += uint32(len(vectors))
+	}
+	return stats, nil
+}
+
+func (m *MemoryStore) Namespace() string {
+	return m.namespace
+}
+
+// WithNamespace returns a store for namespace sharing this store's contents, so saving either
+// saves every namespace.
+func (m *MemoryStore) WithNamespace(namespace string) VectorStore {
+	return &MemoryStore{embedder: m.embedder, namespace: namespace, data: m.data}
+}
+
+// snapshot is the file format written by Save.
+type snapshot struct {
+	Vectors []snapshotVector `json:"vectors"`
+}
+
+type snapshotVector struct {
+	Namespace string                 `json:"namespace,omitempty"`
+	ID        string                 `json:"id"`
+	Values    []float32              `json:"values"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Save writes every vector, in every namespace, to a JSON snapshot at path, replacing it
+// atomically.
+func (m *MemoryStore) Save(path string) error {
+	m.data.mu.RLock()
+	var snap snapshot
+	for name, vectors := range m.data.namespaces {
+		for _, v := range vectors {
+			snap.Vectors = append(snap.Vectors, snapshotVector{Namespace: name, ID: v.ID, Values: v.Values, Metadata: v.Metadata})
+		}
+	}
+	m.data.mu.RUnlock()
+	sort.Slice(snap.Vectors, func(i, j int) bool {
+		if snap.Vectors[i].Namespace != snap.Vectors[j].Namespace {
+			return snap.Vectors[i].Namespace < snap.Vectors[j].Namespace
+		}
+		return snap.Vectors[i].ID < snap.Vectors[j].ID
+	})
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load replaces the store's contents, in every namespace, with the snapshot at path. The snapshot
+// must have been written with the same embedder for text queries to find its vectors.
+func (m *MemoryStore) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+	var snap snapshot
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+
+	namespaces := make(map[string]map[string]*Vector)
+	for _, v := range snap.Vectors {
+		if namespaces[v.Namespace] == nil {
+			namespaces[v.Namespace] = make(map[string]*Vector)
+		}
+		namespaces[v.Namespace][v.ID] = &Vector{ID: v.ID, Values: v.Values, Metadata: copyMetadata(v.Metadata)}
+	}
+	m.data.mu.Lock()
+	m.data.namespaces = namespaces
+	m.data.mu.Unlock()
+	return nil
+}
+
+func normalize(values []float32) []float32 {
+	var sum float64
+	for _, v := range values {
+		sum += float64(v) * float64(v)
+	}
+	out := make([]float32, len(values))
+	if sum == 0 {
+		return out
+	}
+	norm := float32(math.Sqrt(sum))
+	for i, v := range values {
+		out[i] = v / norm
+	}
+	return out
+}
+
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
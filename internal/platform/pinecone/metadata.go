@@ -0,0 +1,18 @@
+package pinecone
+
+// Metadata fields of the signatures reported through the ingestor's /feedback endpoint. Feedback
+// sets ReviewStatusField to pending; reflex curate -confirm or -dismiss resolves it and, on
+// confirmation, lowers WeightField.
+const (
+	ReviewStatusField = "review_status"
+	WeightField       = "weight"
+)
+
+// Weight returns the factor a signature's similarity scores are multiplied by: 1 unless confirmed
+// false positives have lowered it.
+func Weight(metadata map[string]interface{}) float64 {
+	if w, ok := metadata[WeightField].(float64); ok && w >= 0 && w < 1 {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,30 @@
+package pinecone_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+)
+
+func TestWeight(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		want     float64
+	}{
+		{"unset", map[string]interface{}{}, 1},
+		{"nil metadata", nil, 1},
+		{"lowered", map[string]interface{}{pinecone.WeightField: 0.25}, 0.25},
+		{"zero", map[string]interface{}{pinecone.WeightField: 0.0}, 0},
+		{"not lowered", map[string]interface{}{pinecone.WeightField: 1.5}, 1},
+		{"negative", map[string]interface{}{pinecone.WeightField: -0.5}, 1},
+		{"not a number", map[string]interface{}{pinecone.WeightField: "0.5"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pinecone.Weight(tt.metadata))
+		})
+	}
+}
@@ -0,0 +1,457 @@
+// Package qdrant stores vectors in a Qdrant collection through its REST API, so the vector store
+// can run on our own infrastructure instead of Pinecone. Unlike Pinecone's integrated inference,
+// text is embedded on the client side by a pinecone.Embedder.
+package qdrant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// idField is the payload field holding a record's ID. Qdrant only accepts integers and UUIDs as
+// point IDs, so points are stored under a UUID derived from the record ID.
+const idField = "record_id"
+
+// namespaceField is the payload field holding the namespace of a record outside the default one.
+// Namespaces share the collection and every request is filtered on this field.
+const namespaceField = "record_namespace"
+
+// pointNamespace derives point UUIDs from record IDs.
+var pointNamespace = uuid.MustParse("8f5b2c43-5d0e-4c55-9a3e-7f4d2b1e6a90")
+
+// Config configures a Client.
+type Config struct {
+	// URL is the Qdrant REST endpoint, e.g. http://localhost:6333.
+	URL    string
+	APIKey string
+	// Collection holds the vectors. It is created, with cosine distance and the embedder's
+	// dimension, if it does not exist.
+	Collection string
+}
+
+// Client is a pinecone.VectorStore backed by a Qdrant collection.
+type Client struct {
+	httpClient *http.Client
+	embedder   pinecone.Embedder
+	config     Config
+	namespace  string
+}
+
+var _ pinecone.VectorStore = (*Client)(nil)
+
+// NewClient creates a Client and creates its collection if it does not exist yet.
+func NewClient(ctx context.Context, httpClient *http.Client, embedder pinecone.Embedder, cfg Config) (*Client, error) {
+	if cfg.Collection == "" {
+		return nil, fmt.Errorf("qdrant collection is required")
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	c := &Client{httpClient: httpClient, embedder: embedder, config: cfg}
+	if err := c.ensureCollection(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) ensureCollection(ctx context.Context) error {
+	status, err := c.do(ctx, http.MethodGet, "", nil, nil)
+	if err == nil {
+		return nil
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("failed to get collection %s: %w", c.config.Collection, err)
+	}
+	return c.createCollection(ctx)
+}
+
+func (c *Client) createCollection(ctx context.Context) error {
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{"size": c.embedder.Dimension(), "distance": "Cosine"},
+	}
+	if _, err := c.do(ctx, http.MethodPut, "", body, nil); err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", c.config.Collection, err)
+	}
+	return nil
+}
+
+type point struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+	Score   float32                `json:"score,omitempty"`
+}
+
+// UpsertBatch upserts vectors computed elsewhere.
+func (c *Client) UpsertBatch(ctx context.Context, vectors []*pinecone.Vector) error {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.UpsertBatch")
+	defer span.End()
+	span.SetAttributes(attribute.Int("qdrant.batch_size", len(vectors)))
+
+	points := make([]point, len(vectors))
+	for i, v := range vectors {
+		points[i] = c.newPoint(v.ID, v.Values, v.Metadata)
+	}
+	return c.upsert(ctx, points)
+}
+
+// UpsertInputs embeds text records and upserts them. As with Pinecone, the text is kept in the
+// chunk_text field.
+func (c *Client) UpsertInputs(ctx context.Context, inputs []*pinecone.InputRecord) error {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.UpsertInputs")
+	defer span.End()
+	span.SetAttributes(attribute.Int("qdrant.batch_size", len(inputs)))
+
+	texts := make([]string, len(inputs))
+	for i, in := range inputs {
+		texts[i] = in.Text
+	}
+	values, err := c.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed inputs: %w", err)
+	}
+
+	points := make([]point, len(inputs))
+	for i, in := range inputs {
+		p := c.newPoint(in.ID, values[i], in.Metadata)
+		p.Payload["chunk_text"] = in.Text
+		points[i] = p
+	}
+	return c.upsert(ctx, points)
+}
+
+func (c *Client) upsert(ctx context.Context, points []point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if _, err := c.do(ctx, http.MethodPut, "/points?wait=true", map[string]interface{}{"points": points}, nil); err != nil {
+		return fmt.Errorf("failed to upsert points: %w", err)
+	}
+	return nil
+}
+
+// QueryInput embeds text and returns the topK most similar records matching filter.
+func (c *Client) QueryInput(ctx context.Context, text string, topK int, filter pinecone.Filter) ([]*pinecone.Match, error) {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.QueryInput")
+	defer span.End()
+	span.SetAttributes(attribute.Int("qdrant.top_k", topK))
+
+	scope, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	query, err := c.embedder.EmbedQuery(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	var result []point
+	body := map[string]interface{}{"vector": query, "limit": topK, "with_payload": true, "filter": scope}
+	if _, err := c.do(ctx, http.MethodPost, "/points/search", body, &result); err != nil {
+		return nil, fmt.Errorf("failed to search points: %w", err)
+	}
+
+	matches := make([]*pinecone.Match, len(result))
+	for i, p := range result {
+		id, metadata := splitPayload(p)
+		matches[i] = &pinecone.Match{ID: id, Score: p.Score, Metadata: metadata}
+	}
+	return matches, nil
+}
+
+// Fetch returns the stored vectors for ids; IDs that are not stored are left out.
+func (c *Client) Fetch(ctx context.Context, ids []string) (map[string]*pinecone.Vector, error) {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.Fetch")
+	defer span.End()
+
+	vectors := make(map[string]*pinecone.Vector, len(ids))
+	if len(ids) == 0 {
+		return vectors, nil
+	}
+	pointIDs := make([]string, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = c.pointID(id)
+	}
+	var result []point
+	body := map[string]interface{}{"ids": pointIDs, "with_payload": true, "with_vector": true}
+	if _, err := c.do(ctx, http.MethodPost, "/points", body, &result); err != nil {
+		return nil, fmt.Errorf("failed to fetch points: %w", err)
+	}
+	for _, p := range result {
+		id, metadata := splitPayload(p)
+		vectors[id] = &pinecone.Vector{ID: id, Values: p.Vector, Metadata: metadata}
+	}
+	return vectors, nil
+}
+
+// UpdateMetadata sets payload fields of the point of id.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.UpdateMetadata")
+	defer span.End()
+
+	body := map[string]interface{}{"payload": metadata, "points": []string{c.pointID(id)}}
+	status, err := c.do(ctx, http.MethodPost, "/points/payload?wait=true", body, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update point payload: %w", err)
+	}
+	return nil
+}
+
+// List returns record IDs in the order of their point IDs. Qdrant cannot match IDs by prefix,
+// so pages are filtered after they are read and may hold fewer than opts.Limit IDs.
+func (c *Client) List(ctx context.Context, opts pinecone.ListOptions) (*pinecone.ListPage, error) {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.List")
+	defer span.End()
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	scope, err := c.scope(nil)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"filter":       scope,
+		"limit":        limit,
+		"with_payload": []string{idField},
+		"with_vector":  false,
+	}
+	if opts.PaginationToken != "" {
+		body["offset"] = opts.PaginationToken
+	}
+	var result struct {
+		Points         []point     `json:"points"`
+		NextPageOffset interface{} `json:"next_page_offset"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/points/scroll", body, &result); err != nil {
+		return nil, fmt.Errorf("failed to scroll points: %w", err)
+	}
+
+	page := &pinecone.ListPage{IDs: make([]string, 0, len(result.Points))}
+	for _, p := range result.Points {
+		if id, _ := splitPayload(p); strings.HasPrefix(id, opts.Prefix) {
+			page.IDs = append(page.IDs, id)
+		}
+	}
+	if result.NextPageOffset != nil {
+		page.NextPaginationToken = fmt.Sprint(result.NextPageOffset)
+	}
+	return page, nil
+}
+
+// Delete deletes the points of ids.
+func (c *Client) Delete(ctx context.Context, ids []string) error {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.Delete")
+	defer span.End()
+	span.SetAttributes(attribute.Int("qdrant.delete_count", len(ids)))
+
+	if len(ids) == 0 {
+		return nil
+	}
+	pointIDs := make([]string, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = c.pointID(id)
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]interface{}{"points": pointIDs}, nil); err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	return nil
+}
+
+// DeleteByFilter deletes the points matching filter.
+func (c *Client) DeleteByFilter(ctx context.Context, filter pinecone.Filter) error {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.DeleteByFilter")
+	defer span.End()
+
+	if err := pinecone.ValidateDeleteFilter(filter); err != nil {
+		return err
+	}
+	return c.deleteScope(ctx, filter)
+}
+
+// DeleteAll deletes every point in the client's namespace.
+func (c *Client) DeleteAll(ctx context.Context) error {
+	ctx, span := otel.Tracer("qdrant-client").Start(ctx, "Qdrant.DeleteAll")
+	defer span.End()
+
+	return c.deleteScope(ctx, nil)
+}
+
+func (c *Client) deleteScope(ctx context.Context, filter pinecone.Filter) error {
+	scope, err := c.scope(filter)
+	if err != nil {
+		return err
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]interface{}{"filter": scope}, nil); err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	return nil
+}
+
+// DescribeIndexStats counts the points in the collection and in the client's namespace. Other
+// namespaces are not counted separately.
+func (c *Client) DescribeIndexStats(ctx context.Context) (*pinecone.IndexStats, error) {
+	total, err := c.count(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := c.scope(nil)
+	if err != nil {
+		return nil, err
+	}
+	inNamespace, err := c.count(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &pinecone.IndexStats{
+		TotalVectorCount: total,
+		Namespaces:       map[string]uint32{c.namespace: inNamespace},
+	}, nil
+}
+
+func (c *Client) count(ctx context.Context, filter map[string]interface{}) (uint32, error) {
+	var result struct {
+		Count uint32 `json:"count"`
+	}
+	body := map[string]interface{}{"exact": true}
+	if filter != nil {
+		body["filter"] = filter
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/points/count", body, &result); err != nil {
+		return 0, fmt.Errorf("failed to count points: %w", err)
+	}
+	return result.Count, nil
+}
+
+// Namespace returns the namespace the client targets.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// WithNamespace returns a client for namespace in the same collection.
+func (c *Client) WithNamespace(namespace string) pinecone.VectorStore {
+	return &Client{httpClient: c.httpClient, embedder: c.embedder, config: c.config, namespace: namespace}
+}
+
+// do sends a request to the collection's path and decodes the response's result into out, if
+// given. It returns the response status, or 0 if there was no response.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.config.URL+"/collections/"+c.config.Collection+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.APIKey != "" {
+		req.Header.Set("api-key", c.config.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Status json.RawMessage `json:"status"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Status struct {
+				Error string `json:"error"`
+			} `json:"status"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &failure) == nil && failure.Status.Error != "" {
+			msg = failure.Status.Error
+		}
+		return resp.StatusCode, fmt.Errorf("qdrant returned %s: %s", resp.Status, msg)
+	}
+	if out == nil {
+		return resp.StatusCode, nil
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode result: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+func (c *Client) newPoint(id string, values []float32, metadata map[string]interface{}) point {
+	payload := make(map[string]interface{}, len(metadata)+3)
+	for k, v := range metadata {
+		payload[k] = v
+	}
+	payload[idField] = id
+	if c.namespace != "" {
+		payload[namespaceField] = c.namespace
+	}
+	return point{ID: c.pointID(id), Vector: values, Payload: payload}
+}
+
+// pointID derives the UUID a record is stored under, which differs between namespaces.
+func (c *Client) pointID(id string) string {
+	if c.namespace != "" {
+		id = c.namespace + "\x00" + id
+	}
+	return uuid.NewSHA1(pointNamespace, []byte(id)).String()
+}
+
+// scope returns the Qdrant filter for the records in the client's namespace matching filter.
+func (c *Client) scope(filter pinecone.Filter) (map[string]interface{}, error) {
+	namespace := map[string]interface{}{"is_empty": map[string]interface{}{"key": namespaceField}}
+	if c.namespace != "" {
+		namespace = matchCondition(namespaceField, c.namespace)
+	}
+	must := []interface{}{namespace}
+	if len(filter) > 0 {
+		translated, err := translateFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		must = append(must, translated)
+	}
+	return map[string]interface{}{"must": must}, nil
+}
+
+// splitPayload returns a point's record ID and its metadata without the ID field.
+func splitPayload(p point) (string, map[string]interface{}) {
+	metadata := make(map[string]interface{}, len(p.Payload))
+	for k, v := range p.Payload {
+		metadata[k] = v
+	}
+	id, _ := metadata[idField].(string)
+	delete(metadata, idField)
+	delete(metadata, namespaceField)
+	if id == "" {
+		id = p.ID
+	}
+	return id, metadata
+}
@@ -0,0 +1,92 @@
+package qdrant
+
+import (
+	"math"
+	"strings"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+)
+
+// translateFilter converts a Pinecone-style metadata filter to a Qdrant filter. $and and $or
+// become nested filters, $ne and $nin must_not conditions, so that like in Pinecone they match
+// points without the field, and $exists an is_empty condition.
+func translateFilter(filter pinecone.Filter) (map[string]interface{}, error) {
+	normalized, err := filter.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	return translateNormalized(normalized), nil
+}
+
+func translateNormalized(filter pinecone.Filter) map[string]interface{} {
+	must := []interface{}{}
+	mustNot := []interface{}{}
+	for key, cond := range filter {
+		switch key {
+		case "$and":
+			for _, sub := range cond.([]pinecone.Filter) {
+				must = append(must, translateNormalized(sub))
+			}
+			continue
+		case "$or":
+			var should []interface{}
+			for _, sub := range cond.([]pinecone.Filter) {
+				should = append(should, translateNormalized(sub))
+			}
+			must = append(must, map[string]interface{}{"should": should})
+			continue
+		}
+		for op, operand := range cond.(pinecone.Filter) {
+			switch op {
+			case "$eq":
+				must = append(must, matchCondition(key, operand))
+			case "$ne":
+				mustNot = append(mustNot, matchCondition(key, operand))
+			case "$in":
+				must = append(must, anyCondition(key, operand.([]interface{})))
+			case "$nin":
+				mustNot = append(mustNot, anyCondition(key, operand.([]interface{})))
+			case "$gt", "$gte", "$lt", "$lte":
+				must = append(must, map[string]interface{}{
+					"key":   key,
+					"range": map[string]interface{}{strings.TrimPrefix(op, "$"): operand},
+				})
+			case "$exists":
+				empty := map[string]interface{}{"is_empty": map[string]interface{}{"key": key}}
+				if operand.(bool) {
+					mustNot = append(mustNot, empty)
+				} else {
+					must = append(must, empty)
+				}
+			}
+		}
+	}
+	out := map[string]interface{}{"must": must}
+	if len(mustNot) > 0 {
+		out["must_not"] = mustNot
+	}
+	return out
+}
+
+// matchCondition matches a field equal to value. Qdrant only matches keywords, integers and
+// booleans exactly, so fractional numbers are matched as a single-point range.
+func matchCondition(key string, value interface{}) map[string]interface{} {
+	if f, ok := value.(float64); ok {
+		if f != math.Trunc(f) {
+			return map[string]interface{}{"key": key, "range": map[string]interface{}{"gte": f, "lte": f}}
+		}
+		value = int64(f)
+	}
+	return map[string]interface{}{"key": key, "match": map[string]interface{}{"value": value}}
+}
+
+func anyCondition(key string, values []interface{}) map[string]interface{} {
+	converted := make([]interface{}, len(values))
+	for i, v := range values {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			v = int64(f)
+		}
+		converted[i] = v
+	}
+	return map[string]interface{}{"key": key, "match": map[string]interface{}{"any": converted}}
+}
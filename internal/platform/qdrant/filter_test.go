@@ -0,0 +1,107 @@
+package qdrant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dllewellyn/reflex/internal/platform/pinecone"
+)
+
+func TestTranslateFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter pinecone.Filter
+		want   map[string]interface{}
+	}{
+		{
+			name:   "nil",
+			filter: nil,
+			want:   map[string]interface{}{"must": []interface{}{}},
+		},
+		{
+			name:   "eq",
+			filter: pinecone.Filter{"label": "injection"},
+			want: map[string]interface{}{"must": []interface{}{
+				map[string]interface{}{"key": "label", "match": map[string]interface{}{"value": "injection"}},
+			}},
+		},
+		{
+			name:   "whole numbers match as integers, fractions as a range",
+			filter: pinecone.Filter{"$and": []pinecone.Filter{{"seen_count": 2.0}, {"confidence": 0.5}}},
+			want: map[string]interface{}{"must": []interface{}{
+				map[string]interface{}{"must": []interface{}{
+					map[string]interface{}{"key": "seen_count", "match": map[string]interface{}{"value": int64(2)}},
+				}},
+				map[string]interface{}{"must": []interface{}{
+					map[string]interface{}{"key": "confidence", "range": map[string]interface{}{"gte": 0.5, "lte": 0.5}},
+				}},
+			}},
+		},
+		{
+			name:   "ne and nin match points without the field",
+			filter: pinecone.Filter{"label": pinecone.Filter{"$nin": []string{"benign"}}},
+			want: map[string]interface{}{
+				"must": []interface{}{},
+				"must_not": []interface{}{
+					map[string]interface{}{"key": "label", "match": map[string]interface{}{"any": []interface{}{"benign"}}},
+				},
+			},
+		},
+		{
+			name:   "in",
+			filter: pinecone.Filter{"seen_count": pinecone.Filter{"$in": []interface{}{1.0, 2.5}}},
+			want: map[string]interface{}{"must": []interface{}{
+				map[string]interface{}{"key": "seen_count", "match": map[string]interface{}{"any": []interface{}{int64(1), 2.5}}},
+			}},
+		},
+		{
+			name:   "range",
+			filter: pinecone.Filter{"confidence": pinecone.Filter{"$gte": 0.8}},
+			want: map[string]interface{}{"must": []interface{}{
+				map[string]interface{}{"key": "confidence", "range": map[string]interface{}{"gte": 0.8}},
+			}},
+		},
+		{
+			name:   "exists",
+			filter: pinecone.Filter{"weight": pinecone.Filter{"$exists": true}},
+			want: map[string]interface{}{
+				"must":     []interface{}{},
+				"must_not": []interface{}{map[string]interface{}{"is_empty": map[string]interface{}{"key": "weight"}}},
+			},
+		},
+		{
+			name:   "not exists",
+			filter: pinecone.Filter{"weight": pinecone.Filter{"$exists": false}},
+			want: map[string]interface{}{"must": []interface{}{
+				map[string]interface{}{"is_empty": map[string]interface{}{"key": "weight"}},
+			}},
+		},
+		{
+			name:   "or",
+			filter: pinecone.Filter{"$or": []pinecone.Filter{{"source": "extract"}, {"label": pinecone.Filter{"$ne": "benign"}}}},
+			want: map[string]interface{}{"must": []interface{}{
+				map[string]interface{}{"should": []interface{}{
+					map[string]interface{}{"must": []interface{}{
+						map[string]interface{}{"key": "source", "match": map[string]interface{}{"value": "extract"}},
+					}},
+					map[string]interface{}{
+						"must":     []interface{}{},
+						"must_not": []interface{}{map[string]interface{}{"key": "label", "match": map[string]interface{}{"value": "benign"}}},
+					},
+				}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := translateFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := translateFilter(pinecone.Filter{"label": pinecone.Filter{"$like": "inj%"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,6 @@
+package schema
+
+// FeedbackResultPrefix starts the event IDs of the batch results the ingestor publishes for
+// missed attacks reported through /feedback. Their verdict was made up by the ingestor rather
+// than given by the judge, so what is extracted from them is always held for review.
+const FeedbackResultPrefix = "feedback-"
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps checkpoints in a local JSON file, for development and single-host deployments.
+// The file is re-read on every call and replaced atomically on every write, but writes from
+// separate processes sharing the file are not coordinated.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a FileStore at path. The file is created on the first write.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Get(_ context.Context, name string, v any) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return NoVersion, err
+	}
+	return get(records, name, v)
+}
+
+func (s *FileStore) Set(_ context.Context, name string, v any) (Version, error) {
+	return s.update(name, nil, v)
+}
+
+func (s *FileStore) CompareAndSwap(_ context.Context, name string, version Version, v any) (Version, error) {
+	return s.update(name, &version, v)
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+func (s *FileStore) update(name string, expected *Version, v any) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return NoVersion, err
+	}
+	version, err := put(records, name, expected, v)
+	if err != nil {
+		return version, err
+	}
+	return version, s.save(records)
+}
+
+func (s *FileStore) load() (map[string]record, error) {
+	records := make(map[string]record)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints %s: %w", s.path, err)
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoints %s: %w", s.path, err)
+	}
+	return records, nil
+}
+
+// save writes records to a temporary file and renames it over the store, so a crash mid-write
+// never leaves a truncated file behind.
+func (s *FileStore) save(records map[string]record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoints %s: %w", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write checkpoints %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoints %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write checkpoints %s: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultCollection = "checkpoints"
+	fieldName         = "name"
+	fieldValue        = "value"
+	fieldVersion      = "version"
+	fieldUpdatedAt    = "updated_at"
+)
+
+type firestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreStore creates a Store backed by Firestore, with one document per checkpoint in
+// collection ("checkpoints" if empty). It assumes ADC (Application Default Credentials) are set up.
+func NewFirestoreStore(ctx context.Context, projectID, collection string) (Store, error) {
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore client: %w", err)
+	}
+	if collection == "" {
+		collection = defaultCollection
+	}
+	return &firestoreStore{client: client, collection: collection}, nil
+}
+
+// doc returns the checkpoint's document. Names may contain slashes, which Firestore treats as
+// path separators, so they are escaped.
+func (s *firestoreStore) doc(name string) *firestore.DocumentRef {
+	return s.client.Collection(s.collection).Doc(url.PathEscape(name))
+}
+
+func (s *firestoreStore) Get(ctx context.Context, name string, v any) (Version, error) {
+	snap, err := s.doc(name).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return NoVersion, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return NoVersion, fmt.Errorf("failed to get checkpoint %s: %w", name, err)
+	}
+	version, value, err := parseSnapshot(name, snap)
+	if err != nil {
+		return NoVersion, err
+	}
+	return version, decode(name, value, v)
+}
+
+func (s *firestoreStore) Set(ctx context.Context, name string, v any) (Version, error) {
+	return s.update(ctx, name, nil, v)
+}
+
+func (s *firestoreStore) CompareAndSwap(ctx context.Context, name string, version Version, v any) (Version, error) {
+	return s.update(ctx, name, &version, v)
+}
+
+// update writes the checkpoint in a transaction, so the version check and increment are atomic.
+func (s *firestoreStore) update(ctx context.Context, name string, expected *Version, v any) (Version, error) {
+	value, err := encode(name, v)
+	if err != nil {
+		return NoVersion, err
+	}
+	ref := s.doc(name)
+	var next Version
+	err = s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		current := NoVersion
+		snap, err := tx.Get(ref)
+		switch {
+		case err == nil:
+			if current, _, err = parseSnapshot(name, snap); err != nil {
+				return err
+			}
+		case status.Code(err) != codes.NotFound:
+			return fmt.Errorf("failed to get checkpoint %s: %w", name, err)
+		}
+		if expected != nil && *expected != current {
+			return fmt.Errorf("%w: %s is at version %d, not %d", ErrConflict, name, current, *expected)
+		}
+		next = current + 1
+		return tx.Set(ref, map[string]interface{}{
+			fieldName:      name,
+			fieldValue:     string(value),
+			fieldVersion:   int64(next),
+			fieldUpdatedAt: time.Now().UTC(),
+		})
+	})
+	if err != nil {
+		return NoVersion, err
+	}
+	return next, nil
+}
+
+func parseSnapshot(name string, snap *firestore.DocumentSnapshot) (Version, []byte, error) {
+	data := snap.Data()
+	version, ok := data[fieldVersion].(int64)
+	if !ok {
+		return NoVersion, nil, fmt.Errorf("checkpoint %s has no version", name)
+	}
+	value, ok := data[fieldValue].(string)
+	if !ok {
+		return NoVersion, nil, fmt.Errorf("checkpoint %s has no value", name)
+	}
+	return Version(version), []byte(value), nil
+}
+
+func (s *firestoreStore) Close() error {
+	return s.client.Close()
+}
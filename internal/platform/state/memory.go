@@ -0,0 +1,63 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store held in memory, for tests and local runs.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]record
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]record)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, name string, v any) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(s.records, name, v)
+}
+
+func (s *MemoryStore) Set(_ context.Context, name string, v any) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return put(s.records, name, nil, v)
+}
+
+func (s *MemoryStore) CompareAndSwap(_ context.Context, name string, version Version, v any) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return put(s.records, name, &version, v)
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func get(records map[string]record, name string, v any) (Version, error) {
+	r, ok := records[name]
+	if !ok {
+		return NoVersion, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return r.Version, decode(name, r.Value, v)
+}
+
+// put stores v in records. If expected is not nil the current version must match it.
+func put(records map[string]record, name string, expected *Version, v any) (Version, error) {
+	current := records[name].Version
+	if expected != nil && *expected != current {
+		return current, fmt.Errorf("%w: %s is at version %d, not %d", ErrConflict, name, current, *expected)
+	}
+	value, err := encode(name, v)
+	if err != nil {
+		return current, err
+	}
+	records[name] = record{Value: value, Version: current + 1, UpdatedAt: time.Now().UTC()}
+	return current + 1, nil
+}
@@ -2,75 +2,108 @@ package state
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
-
-	"cloud.google.com/go/firestore"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-const (
-	collectionName = "ingestor_state"
-	docName        = "checkpoint"
-	fieldLastRun   = "last_run"
+// Version identifies a revision of a checkpoint. It increases with every write.
+type Version int64
+
+// NoVersion is the version of a checkpoint that has never been set. Passing it to
+// CompareAndSwap creates the checkpoint only if it does not exist yet.
+const NoVersion Version = 0
+
+var (
+	// ErrNotFound is returned by Get when a checkpoint has never been set.
+	ErrNotFound = errors.New("checkpoint not found")
+	// ErrConflict is returned by CompareAndSwap when the checkpoint changed since it was read.
+	ErrConflict = errors.New("checkpoint version conflict")
 )
 
-// Store defines the interface for state management.
+// Store keeps named checkpoints: small JSON documents, such as watermarks, that jobs use to record
+// what they have processed so they can resume after a failure. Names are free-form, e.g.
+// "loader/raw-interactions" or "batch/2025-12-12".
 type Store interface {
-	GetLastRun(ctx context.Context) (time.Time, error)
-	SetLastRun(ctx context.Context, t time.Time) error
+	// Get decodes checkpoint name into v and returns its version.
+	Get(ctx context.Context, name string, v any) (Version, error)
+	// Set stores v as checkpoint name, whatever its current version, and returns the new version.
+	Set(ctx context.Context, name string, v any) (Version, error)
+	// CompareAndSwap stores v as checkpoint name only if its current version is version, and
+	// returns the new version. It returns ErrConflict otherwise.
+	CompareAndSwap(ctx context.Context, name string, version Version, v any) (Version, error)
 	Close() error
 }
 
-type firestoreStore struct {
-	client    *firestore.Client
-	projectID string
-}
-
-// NewFirestoreStore creates a new state store backed by Firestore.
-// It assumes ADC (Application Default Credentials) are set up.
-func NewFirestoreStore(ctx context.Context, projectID string) (Store, error) {
-	client, err := firestore.NewClient(ctx, projectID)
+// Open opens the store described by rawURL:
+//
+//	memory://                          an in-memory store, lost on exit
+//	file:///path/to/checkpoints.json   a local JSON file
+//	firestore://<project>[/<collection>] Firestore, in collection "checkpoints" by default
+func Open(ctx context.Context, rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create firestore client: %w", err)
+		return nil, fmt.Errorf("invalid checkpoint store URL %q: %w", rawURL, err)
 	}
-	return &firestoreStore{client: client, projectID: projectID}, nil
-}
-
-func (s *firestoreStore) GetLastRun(ctx context.Context) (time.Time, error) {
-	doc, err := s.client.Collection(collectionName).Doc(docName).Get(ctx)
-	if err != nil {
-		// Strictly check for NotFound. Any other error is fatal.
-		if status.Code(err) == codes.NotFound {
-			// If not found, it means this is the first run. Return zero time.
-			return time.Time{}, nil
+	switch u.Scheme {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(u.Path), nil
+	case "firestore":
+		if u.Host == "" {
+			return nil, fmt.Errorf("checkpoint store URL %q has no project", rawURL)
 		}
-		return time.Time{}, fmt.Errorf("failed to get checkpoint: %w", err)
+		return NewFirestoreStore(ctx, u.Host, strings.Trim(u.Path, "/"))
+	default:
+		return nil, fmt.Errorf("unsupported checkpoint store scheme %q in %q", u.Scheme, rawURL)
 	}
+}
 
-	if t, ok := doc.Data()[fieldLastRun].(time.Time); ok {
-		return t, nil
+// Update applies fn to checkpoint name and stores the result with CompareAndSwap, retrying from
+// a fresh read if another writer got there first. fn receives the zero value when the checkpoint
+// does not exist yet.
+func Update[T any](ctx context.Context, s Store, name string, fn func(*T) error) (T, error) {
+	const maxAttempts = 5
+	for attempt := 1; ; attempt++ {
+		var cp T
+		version, err := s.Get(ctx, name, &cp)
+		if errors.Is(err, ErrNotFound) {
+			version = NoVersion
+		} else if err != nil {
+			return cp, err
+		}
+		if err := fn(&cp); err != nil {
+			return cp, err
+		}
+		_, err = s.CompareAndSwap(ctx, name, version, &cp)
+		if err == nil || !errors.Is(err, ErrConflict) || attempt == maxAttempts {
+			return cp, err
+		}
 	}
+}
 
-	// Field missing or invalid type?
-	// This implies data corruption or schema change.
-	// Returning zero time risks re-ingestion, but failing risks getting stuck.
-	// For safety against data loss/duplication, we should probably fail or log loudly.
-	// For this PoC, we'll treat it as a fresh start (zero time) but ideally we'd alert.
-	return time.Time{}, nil
+// record is the stored form of a checkpoint, shared by the file and memory stores.
+type record struct {
+	Value     json.RawMessage `json:"value"`
+	Version   Version         `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
 }
 
-func (s *firestoreStore) SetLastRun(ctx context.Context, t time.Time) error {
-	_, err := s.client.Collection(collectionName).Doc(docName).Set(ctx, map[string]interface{}{
-		fieldLastRun: t,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to save checkpoint: %w", err)
+func decode(name string, value []byte, v any) error {
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("failed to decode checkpoint %s: %w", name, err)
 	}
 	return nil
 }
 
-func (s *firestoreStore) Close() error {
-	return s.client.Close()
+func encode(name string, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoint %s: %w", name, err)
+	}
+	return data, nil
 }
@@ -0,0 +1,178 @@
+package state_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dllewellyn/reflex/internal/platform/state"
+)
+
+type watermark struct {
+	Offset int `json:"offset"`
+}
+
+// stores returns the stores under test. Firestore is only included when FIRESTORE_EMULATOR_HOST
+// points at an emulator.
+func stores(t *testing.T) map[string]state.Store {
+	t.Helper()
+	ctx := context.Background()
+	stores := map[string]state.Store{
+		"memory": state.NewMemoryStore(),
+		"file":   state.NewFileStore(filepath.Join(t.TempDir(), "nested", "checkpoints.json")),
+	}
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") != "" {
+		s, err := state.NewFirestoreStore(ctx, "reflex-test", "checkpoints-"+filepath.Base(t.TempDir()))
+		if err != nil {
+			t.Fatalf("NewFirestoreStore: %v", err)
+		}
+		stores["firestore"] = s
+	}
+	for _, s := range stores {
+		t.Cleanup(func() { _ = s.Close() })
+	}
+	return stores
+}
+
+func TestStore_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			var cp watermark
+			if _, err := s.Get(ctx, "loader/raw", &cp); !errors.Is(err, state.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound for an unset checkpoint, got %v", err)
+			}
+
+			v1, err := s.CompareAndSwap(ctx, "loader/raw", state.NoVersion, watermark{Offset: 1})
+			if err != nil {
+				t.Fatalf("expected a create-only swap of an unset checkpoint to succeed, got %v", err)
+			}
+			if _, err := s.CompareAndSwap(ctx, "loader/raw", state.NoVersion, watermark{Offset: 2}); !errors.Is(err, state.ErrConflict) {
+				t.Errorf("expected a create-only swap of an existing checkpoint to conflict, got %v", err)
+			}
+			v2, err := s.CompareAndSwap(ctx, "loader/raw", v1, watermark{Offset: 2})
+			if err != nil {
+				t.Fatalf("expected a swap at the current version to succeed, got %v", err)
+			}
+			if v2 <= v1 {
+				t.Errorf("expected the version to increase, got %d after %d", v2, v1)
+			}
+			if _, err := s.CompareAndSwap(ctx, "loader/raw", v1, watermark{Offset: 3}); !errors.Is(err, state.ErrConflict) {
+				t.Errorf("expected a swap at a stale version to conflict, got %v", err)
+			}
+
+			version, err := s.Get(ctx, "loader/raw", &cp)
+			if err != nil || version != v2 || cp.Offset != 2 {
+				t.Errorf("expected offset 2 at version %d, got %+v at %d, %v", v2, cp, version, err)
+			}
+
+			// Set ignores the version.
+			v3, err := s.Set(ctx, "loader/raw", watermark{Offset: 10})
+			if err != nil || v3 <= v2 {
+				t.Errorf("expected Set to write a new version, got %d, %v", v3, err)
+			}
+			if _, err := s.Get(ctx, "batch/2024-05-01", &cp); !errors.Is(err, state.ErrNotFound) {
+				t.Errorf("expected checkpoints to be independent, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdate_RetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			const writers = 4
+			var wg sync.WaitGroup
+			errs := make(chan error, writers)
+			for range writers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := state.Update(ctx, s, "jobs/compact", func(cp *watermark) error {
+						cp.Offset++
+						return nil
+					})
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Errorf("Update: %v", err)
+				}
+			}
+			var cp watermark
+			if _, err := s.Get(ctx, "jobs/compact", &cp); err != nil || cp.Offset != writers {
+				t.Errorf("expected every increment to land, got %+v, %v", cp, err)
+			}
+
+			failed := errors.New("not this time")
+			if _, err := state.Update(ctx, s, "jobs/compact", func(cp *watermark) error {
+				cp.Offset = 0
+				return failed
+			}); !errors.Is(err, failed) {
+				t.Errorf("expected fn's error, got %v", err)
+			}
+			if _, err := s.Get(ctx, "jobs/compact", &cp); err != nil || cp.Offset != writers {
+				t.Errorf("expected a failed update to store nothing, got %+v, %v", cp, err)
+			}
+		})
+	}
+}
+
+// conflictingStore makes every CompareAndSwap conflict, as if another writer always got there
+// first.
+type conflictingStore struct {
+	state.Store
+	swaps int
+}
+
+func (s *conflictingStore) CompareAndSwap(ctx context.Context, name string, version state.Version, v any) (state.Version, error) {
+	s.swaps++
+	_, _ = s.Store.Set(ctx, name, v)
+	return version, state.ErrConflict
+}
+
+func TestUpdate_GivesUp(t *testing.T) {
+	s := &conflictingStore{Store: state.NewMemoryStore()}
+	_, err := state.Update(context.Background(), s, "jobs/compact", func(cp *watermark) error {
+		cp.Offset++
+		return nil
+	})
+	if !errors.Is(err, state.ErrConflict) || s.swaps != 5 {
+		t.Errorf("expected Update to give up with ErrConflict after 5 attempts, got %v after %d", err, s.swaps)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	for _, rawURL := range []string{"memory://", "file://" + filepath.ToSlash(path)} {
+		s, err := state.Open(ctx, rawURL)
+		if err != nil {
+			t.Errorf("Open(%q): %v", rawURL, err)
+			continue
+		}
+		_ = s.Close()
+	}
+	for _, rawURL := range []string{"firestore://", "redis://localhost", "::"} {
+		if _, err := state.Open(ctx, rawURL); err == nil {
+			t.Errorf("expected Open(%q) to fail", rawURL)
+		}
+	}
+
+	// The file store survives being reopened.
+	s := state.NewFileStore(path)
+	if _, err := s.Set(ctx, "loader/raw", watermark{Offset: 7}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	var cp watermark
+	if _, err := state.NewFileStore(path).Get(ctx, "loader/raw", &cp); err != nil || cp.Offset != 7 {
+		t.Errorf("expected the checkpoint to be read back from the file, got %+v, %v", cp, err)
+	}
+}
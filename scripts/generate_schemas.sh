@@ -86,4 +86,10 @@ generate "specifications/schemas/batch-result-event.schema.json" \
          "schema" \
          "--minimal-names"
 
+# Feedback Event
+generate "specifications/schemas/feedback-event.schema.json" \
+         "internal/platform/schema/feedback_event_gen.go" \
+         "schema" \
+         "--minimal-names"
+
 echo "Generation complete."
@@ -18,6 +18,14 @@ resource "google_cloud_run_v2_service" "ingestor" {
         name  = "KAFKA_TOPIC"
         value = var.kafka_topic
       }
+      env {
+        name  = "KAFKA_TOPIC_FEEDBACK"
+        value = var.kafka_feedback_topic
+      }
+      env {
+        name  = "KAFKA_TOPIC_BATCH_RESULTS"
+        value = var.kafka_batch_results_topic
+      }
       env {
         name  = "KAFKA_BOOTSTRAP_SERVERS"
         value = var.kafka_bootstrap_servers
@@ -104,7 +104,7 @@ resource "google_cloud_run_v2_job" "llm_judge_trigger" {
           value = "publishers/google/models/gemini-2.5-flash"
         }
         env {
-          name  = "PROMPT_PATH"
+          name  = "JUDGE_PROMPT_PATH"
           value = "/prompts/security-judge.prompt.yml"
         }
       }
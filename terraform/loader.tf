@@ -80,7 +80,7 @@ resource "google_cloud_run_v2_job" "interaction_loader" {
           value = var.kafka_api_secret
         }
         env {
-          name  = "KAFKA_CONSUMER_GROUP_ID"
+          name  = "LOADER_CONSUMER_GROUP_ID"
           value = "gcs-loader-v1"
         }
       }
@@ -43,6 +43,12 @@ variable "kafka_batch_results_topic" {
   default     = "batch-job-results"
 }
 
+variable "kafka_feedback_topic" {
+  description = "Kafka Topic for verdict feedback posted to the ingestor"
+  type        = string
+  default     = "verdict-feedback"
+}
+
 variable "pinecone_api_key" {
   description = "Pinecone API Key"
   type        = string